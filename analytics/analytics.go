@@ -1,9 +1,26 @@
 package analytics
 
 import (
+	"strings"
+
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// CategoryUsage is the category of the events about the commands and phases that are run
+	CategoryUsage = "usage"
+	// CategoryErrors is the category of the events about failures and crashes
+	CategoryErrors = "errors"
+)
+
+// Category returns the category of an event
+func Category(event string) string {
+	if event == "panic" || strings.HasSuffix(event, "-failure") {
+		return CategoryErrors
+	}
+	return CategoryUsage
+}
+
 type publisher interface {
 	Publish(string, map[string]interface{}) error
 	Close()
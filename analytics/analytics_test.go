@@ -0,0 +1,15 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategory(t *testing.T) {
+	require.Equal(t, CategoryErrors, Category("panic"))
+	require.Equal(t, CategoryErrors, Category("apply-failure"))
+	require.Equal(t, CategoryErrors, Category("phase-failure"))
+	require.Equal(t, CategoryUsage, Category("apply-start"))
+	require.Equal(t, CategoryUsage, Category("phase-success"))
+}
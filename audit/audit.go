@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// EventSession is the first record written by a k0sctl run
+	EventSession = "session"
+	// EventCommand is the record of a command run on a host
+	EventCommand = "command"
+)
+
+// Record is a line of the audit log. Each record includes the hash of the previous line, so that removing
+// or modifying a line breaks the chain.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	User    string    `json:"user,omitempty"`
+	Session string    `json:"session"`
+
+	Version    string `json:"version,omitempty"`
+	Command    string `json:"command,omitempty"`
+	ConfigHash string `json:"configSHA256,omitempty"`
+
+	Phase    string `json:"phase,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Exec     string `json:"exec,omitempty"`
+	ExitCode *int   `json:"exitCode,omitempty"`
+
+	PrevHash string `json:"prevHash"`
+}
+
+// Log appends records to an audit log file
+type Log struct {
+	mu       sync.Mutex
+	file     *os.File
+	user     string
+	session  string
+	prevHash string
+	now      func() time.Time
+}
+
+// Open opens the audit log for appending, the file is created when it does not exist
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log: %w", err)
+	}
+	last, err := lastLine(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read the audit log %s: %w", path, err)
+	}
+
+	l := &Log{file: f, user: os.Getenv("USER"), now: time.Now}
+	if len(last) > 0 {
+		l.prevHash = lineHash(last)
+	}
+	return l, nil
+}
+
+// lastLine returns the last non-empty line of the file
+func lastLine(r io.Reader) ([]byte, error) {
+	var last []byte
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			last = append(last[:0], line...)
+		}
+	}
+	return last, scanner.Err()
+}
+
+func lineHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// ConfigHash returns the hash recorded for the configuration content
+func ConfigHash(content []byte) string {
+	return lineHash(content)
+}
+
+// Session writes the record that starts a k0sctl run, the session is identified by its start time
+func (l *Log) Session(version, command, configHash string) error {
+	now := l.now().UTC()
+	l.session = now.Format(time.RFC3339Nano)
+	return l.write(Record{Time: now, Event: EventSession, Version: version, Command: command, ConfigHash: configHash})
+}
+
+// Command writes the record of a command run on a host
+func (l *Log) Command(phase, host, cmd string, exitCode int) error {
+	return l.write(Record{Time: l.now().UTC(), Event: EventCommand, Phase: phase, Host: host, Exec: cmd, ExitCode: &exitCode})
+}
+
+func (l *Log) write(r Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r.User = l.user
+	r.Session = l.session
+	r.PrevHash = l.prevHash
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write the audit log: %w", err)
+	}
+	l.prevHash = lineHash(line)
+	return nil
+}
+
+// Close closes the audit log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
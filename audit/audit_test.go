@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readRecords(t *testing.T, fn string) ([]Record, []string) {
+	t.Helper()
+	f, err := os.Open(fn)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []Record
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	return records, lines
+}
+
+func TestLog(t *testing.T) {
+	t.Setenv("USER", "operator")
+	fn := filepath.Join(t.TempDir(), "audit.log")
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		l, err := Open(fn)
+		require.NoError(t, err)
+		l.now = func() time.Time { return now }
+		require.NoError(t, l.Session("v0.11.0", "apply", ConfigHash([]byte("apiVersion: k0sctl.k0sproject.io/v1beta1\n"))))
+		require.NoError(t, l.Command("Install workers", "[ssh] 10.0.0.2:22", "k0s install worker", 0))
+		require.NoError(t, l.Command("Install workers", "[ssh] 10.0.0.3:22", "k0s install worker", 1))
+		require.NoError(t, l.Close())
+		now = now.Add(time.Hour)
+	}
+
+	records, lines := readRecords(t, fn)
+	require.Len(t, records, 6)
+
+	session := records[0]
+	require.Equal(t, EventSession, session.Event)
+	require.Equal(t, "operator", session.User)
+	require.Equal(t, "v0.11.0", session.Version)
+	require.Equal(t, "apply", session.Command)
+	require.Len(t, session.ConfigHash, 64)
+	require.Empty(t, session.PrevHash)
+
+	cmd := records[2]
+	require.Equal(t, EventCommand, cmd.Event)
+	require.Equal(t, session.Session, cmd.Session)
+	require.Equal(t, "[ssh] 10.0.0.3:22", cmd.Host)
+	require.Equal(t, "k0s install worker", cmd.Exec)
+	require.Equal(t, 1, *cmd.ExitCode)
+	require.NotEqual(t, session.Session, records[3].Session)
+
+	// each record refers to the hash of the line before it, also across the sessions
+	for i := 1; i < len(lines); i++ {
+		require.Equal(t, lineHash([]byte(lines[i-1])), records[i].PrevHash, "line %d", i+1)
+	}
+}
@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/k0sproject/k0sctl/analytics"
 	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/integration/s3"
 	"github.com/k0sproject/k0sctl/phase"
 	log "github.com/sirupsen/logrus"
 
@@ -18,19 +25,142 @@ var applyCommand = &cli.Command{
 	Usage: "Apply a k0sctl configuration",
 	Flags: []cli.Flag{
 		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		sudoPasswordFlag,
+		sshProxyFlag,
+		sshKeepAliveFlag,
+		connectTimeoutFlag,
+		connectRetriesFlag,
+		connectRetryIntervalFlag,
+		concurrencyFlag,
+		skipPhaseFlag,
+		onlyPhaseFlag,
+		errorFileFlag,
+		auditLogFlag,
+		outputFlag,
+		&cli.StringSliceFlag{
+			Name:  "hosts",
+			Usage: "Only operate on the hosts with a matching address or name, such as 10.0.0.2,worker-* (default: all hosts)",
+		},
 		&cli.BoolFlag{
 			Name:  "no-wait",
-			Usage: "Do not wait for worker nodes to join",
+			Usage: "Do not wait for worker nodes to become ready after the k0s service has been started, the cluster may not have converged when apply returns",
 		},
 		&cli.BoolFlag{
 			Name:  "no-drain",
-			Usage: "Do not drain worker nodes when upgrading",
+			Usage: "Do not drain worker nodes when upgrading even when spec.k0s.upgrade.drain is enabled",
+		},
+		&cli.BoolFlag{
+			Name:  "no-drain-on-failure",
+			Usage: "Leave the worker nodes drained during the upgrade cordoned when the apply fails, for inspecting the drained state",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Abort the apply when it has not finished in the given time, such as 30m (default: no timeout)",
+		},
+		&cli.DurationFlag{
+			Name:  "start-timeout",
+			Usage: "How long to wait for the k0s service to become ready on a host after starting it",
+			Value: cluster.K0sStartTimeout,
+		},
+		&cli.DurationFlag{
+			Name:  "wait-timeout",
+			Usage: "How long to wait for each node to become ready after installing or upgrading k0s on it",
+			Value: cluster.NodeReadyTimeout,
+		},
+		&cli.StringFlag{
+			Name:  "download-proxy",
+			Usage: "Proxy URL for downloading the k0s binaries, overrides the HTTP_PROXY and HTTPS_PROXY environment variables",
+		},
+		downloadURLBaseFlag,
+		&cli.StringFlag{
+			Name:      "metrics-file",
+			Usage:     "Write the phase durations and the result of the run as prometheus metrics to a file, for the node_exporter textfile collector",
+			TakesFile: true,
+		},
+		&cli.StringFlag{
+			Name:      "kubeconfig-out",
+			Usage:     "Write the admin kubeconfig of the cluster to a file after a successful apply",
+			TakesFile: true,
+		},
+		&cli.StringFlag{
+			Name:  "kubeconfig-api-address",
+			Usage: "Kubernetes API server address as host[:port] or an URL for the kubeconfig written with --kubeconfig-out (default: spec.kubeconfig.apiAddress or auto-detect)",
+		},
+		&cli.BoolFlag{
+			Name:  "skip-prerequisites",
+			Usage: "Do not install the packages needed by k0s or the spec.hosts[*].prerequisites.packages, the hosts are expected to have them already",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "Reinstall k0s and rewrite its configuration even when the desired version is already installed",
+		},
+		&cli.IntFlag{
+			Name:  "upgrade-batch-size",
+			Usage: "Number of worker nodes to upgrade at a time (default: spec.k0s.upgrade.batchSize)",
+		},
+		&cli.StringFlag{
+			Name:      "binary-dir",
+			Usage:     "Directory of pre-staged k0s binaries named like k0s-v<version>-<arch> to upload to the hosts instead of downloading (default: spec.k0s.binaryDir)",
+			TakesFile: true,
+		},
+		&cli.BoolFlag{
+			Name:  "no-binary-cache",
+			Usage: "Always download the k0s binaries instead of using the ones in the local cache, the cached binaries are replaced",
+		},
+		&cli.BoolFlag{
+			Name:  "clear-binary-cache",
+			Usage: "Remove the cached k0s binaries from the local cache before applying",
+		},
+		&cli.IntFlag{
+			Name:  "parallel-downloads",
+			Usage: "Maximum number of hosts to download or upload the k0s binary on at the same time (default: --concurrency)",
+		},
+		&cli.IntFlag{
+			Name:  "upload-retries",
+			Usage: "Number of times to retry a failed k0s binary upload, the upload is resumed where it was interrupted",
+			Value: 3,
+		},
+		&cli.StringFlag{
+			Name:  "k0s-sha256",
+			Usage: "Expected SHA256 checksum of the k0s binary, verified on the hosts after download or upload (default: spec.k0s.sha256)",
+		},
+		&cli.BoolFlag{
+			Name:  "fetch-sha256",
+			Usage: "Verify the k0s binary against the .sha256 file published with the release when no checksum is configured",
 		},
 		&cli.StringFlag{
 			Name:      "restore-from",
-			Usage:     "Path to cluster backup archive to restore the state from",
+			Usage:     "Path or s3://bucket/key URL of a cluster backup archive to restore the state from",
+			TakesFile: true,
+		},
+		s3RegionFlag,
+		s3EndpointFlag,
+		&cli.BoolFlag{
+			Name:  "skip-port-checks",
+			Usage: "Don't check that the connection ports of the hosts and the k0s ports of the controllers can be reached before connecting and joining",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only gather facts from the hosts and report the changes that would be made",
+		},
+		&cli.BoolFlag{
+			Name:  "plan",
+			Usage: "Only gather facts from the hosts and print the current and the target k0s version and the planned action of each host",
+		},
+		&cli.StringFlag{
+			Name:      "save-config",
+			Usage:     "Write the fully resolved configuration to a file and exit without connecting to the hosts. Secrets are redacted unless --no-redact is given",
 			TakesFile: true,
 		},
+		&cli.BoolFlag{
+			Name:  "allow-version-skip",
+			Usage: "Allow upgrading k0s by more than one minor version at a time, which is not supported by k0s and can break the cluster",
+		},
 		&cli.BoolFlag{
 			Name:   "disable-downgrade-check",
 			Usage:  "Skip downgrade check",
@@ -38,75 +168,168 @@ var applyCommand = &cli.Command{
 		},
 		debugFlag,
 		traceFlag,
+		verboseCommandsFlag,
 		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
 		analyticsFlag,
 	},
-	Before: actions(initLogging, initConfig, displayLogo, initAnalytics, displayCopyright),
+	Before: actions(validateConcurrencyFlag, validateSSHFlags, validateOutputFlag, validateRestoreFromFlag, initLogging, initConfig, handleSignals, displayLogo, initAnalytics, displayCopyright),
 	After: func(ctx *cli.Context) error {
 		analytics.Client.Close()
 		return nil
 	},
 	Action: func(ctx *cli.Context) error {
 		start := time.Now()
-		content := ctx.String("config")
+		content := configContent(ctx)
 		log.Debugf("Loaded configuration:\n%s", content)
 
 		c := config.Cluster{}
 		if err := yaml.UnmarshalStrict([]byte(content), &c); err != nil {
 			return err
 		}
+		applyDownloadURLBase(ctx, &c)
+		if err := resolveVersionChannel(&c); err != nil {
+			return err
+		}
+
+		if fn := ctx.String("save-config"); fn != "" {
+			if err := saveConfig(ctx, fn, &c); err != nil {
+				return err
+			}
+			log.Infof("Saved the resolved configuration to %s", fn)
+			return c.Validate()
+		}
 
 		if err := c.Validate(); err != nil {
 			return err
 		}
 
+		if patterns := ctx.StringSlice("hosts"); len(patterns) > 0 {
+			if err := selectHosts(&c, patterns); err != nil {
+				return err
+			}
+		}
+
+		if timeout := ctx.Duration("start-timeout"); timeout <= 0 {
+			return fmt.Errorf("invalid --start-timeout %s, must be greater than zero", timeout)
+		}
+		cluster.K0sStartTimeout = ctx.Duration("start-timeout")
+		if timeout := ctx.Duration("wait-timeout"); timeout <= 0 {
+			return fmt.Errorf("invalid --wait-timeout %s, must be greater than zero", timeout)
+		}
+		cluster.NodeReadyTimeout = ctx.Duration("wait-timeout")
+		if address := ctx.String("kubeconfig-api-address"); address != "" {
+			if _, err := cluster.KubeconfigAPIURL(address, 6443); err != nil {
+				return fmt.Errorf("invalid --kubeconfig-api-address: %w", err)
+			}
+		}
 		phase.NoWait = ctx.Bool("no-wait")
+		phase.Force = ctx.Bool("force")
+		phase.SkipPrerequisites = ctx.Bool("skip-prerequisites")
+		phase.K0sSHA256 = ctx.String("k0s-sha256")
+		phase.FetchChecksum = ctx.Bool("fetch-sha256")
+		if n := ctx.Int("parallel-downloads"); n < 0 {
+			return fmt.Errorf("invalid --parallel-downloads %d, must not be negative", n)
+		}
+		uploadRetries := ctx.Int("upload-retries")
+		if uploadRetries < 0 {
+			return fmt.Errorf("invalid --upload-retries %d, must not be negative", uploadRetries)
+		}
+		phase.UploadRetries = uploadRetries
+		phase.NoBinaryCache = ctx.Bool("no-binary-cache")
+		if ctx.Bool("clear-binary-cache") && !ctx.Bool("dry-run") && !ctx.Bool("plan") {
+			if err := phase.ClearBinaryCache(); err != nil {
+				return err
+			}
+		}
+		if proxy := ctx.String("download-proxy"); proxy != "" {
+			if u, err := url.Parse(proxy); err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("invalid --download-proxy %q, must be an url such as http://proxy.example.com:3128", proxy)
+			}
+			phase.DownloadProxy = proxy
+		}
+		if phase.Force {
+			log.Warnf("--force given, k0s will be reinstalled on hosts already running k0s %s", c.Spec.K0s.Version)
+		}
 
-		manager := phase.Manager{Config: &c}
-
-		manager.AddPhase(
-			&phase.Connect{},
-			&phase.DetectOS{},
-			&phase.PrepareHosts{},
-			&phase.GatherFacts{},
-			&phase.DownloadBinaries{},
-			&phase.UploadFiles{},
-			&phase.ValidateHosts{},
-			&phase.GatherK0sFacts{},
-			&phase.ValidateFacts{SkipDowngradeCheck: ctx.Bool("disable-downgrade-check")},
-			&phase.UploadBinaries{},
-			&phase.DownloadK0s{},
-			&phase.RunHooks{Stage: "before", Action: "apply"},
-			&phase.PrepareArm{},
-			&phase.ConfigureK0s{},
-			&phase.Restore{
-				RestoreFrom: ctx.String("restore-from"),
-			},
-			&phase.InitializeK0s{},
-			&phase.InstallControllers{},
-			&phase.InstallWorkers{},
-			&phase.UpgradeControllers{},
-			&phase.UpgradeWorkers{
-				NoDrain: ctx.Bool("no-drain"),
-			},
-			&phase.RunHooks{Stage: "after", Action: "apply"},
-			&phase.Disconnect{},
-		)
+		if ctx.Bool("plan") {
+			if ctx.Bool("dry-run") {
+				return fmt.Errorf("--plan and --dry-run can not be used together")
+			}
+			return runPlan(ctx, &c)
+		}
+
+		manager := phase.Manager{Config: &c, Concurrency: ctx.Int("concurrency"), ParallelDownloads: ctx.Int("parallel-downloads"), SkipPhases: ctx.StringSlice("skip-phase"), OnlyPhases: ctx.StringSlice("only-phase"), DryRun: ctx.Bool("dry-run")}
+
+		addApplyPhases(ctx, &manager)
+
+		stopAudit, err := startAuditLog(ctx, &manager)
+		if err != nil {
+			return err
+		}
+		defer stopAudit()
 
 		if err := analytics.Client.Publish("apply-start", map[string]interface{}{}); err != nil {
 			return err
 		}
 
-		if err := manager.Run(); err != nil {
+		runCtx := ctx.Context
+		if timeout := ctx.Duration("timeout"); timeout > 0 {
+			var cancel context.CancelFunc
+			runCtx, cancel = context.WithTimeout(runCtx, timeout)
+			defer cancel()
+		}
+
+		err = manager.RunContext(runCtx)
+		if fn := ctx.String("metrics-file"); fn != "" {
+			if merr := writeMetrics(fn, manager.Results, time.Since(start), err); merr != nil {
+				log.Warn(merr.Error())
+			}
+		}
+		if err != nil && ctx.String("error-file") != "" {
+			if ferr := writeErrorFile(ctx, &c, manager.Results, err); ferr != nil {
+				log.Warn(ferr.Error())
+			}
+		}
+		if ctx.String("output") == "json" {
+			summary := newRunSummary(&c, manager.Results, time.Since(start), err)
+			if manager.DryRun {
+				summary.DryRun = true
+				summary.Changes = manager.DryMessages()
+			}
+			if perr := printJSON(summary); perr != nil {
+				return perr
+			}
+		}
+
+		if err != nil {
 			_ = analytics.Client.Publish("apply-failure", map[string]interface{}{"clusterID": manager.Config.Spec.K0s.Metadata.ClusterID})
-			if lf, err := LogFile(); err == nil {
-				if ln, ok := lf.(interface{ Name() string }); ok {
-					log.Errorf("apply failed - log file saved to %s", ln.Name())
-				}
+			if !ctx.Bool("no-file-log") {
+				log.Errorf("apply failed - log file saved to %s", logFilePath(ctx))
 			}
 			return err
 		}
 
+		if manager.DryRun {
+			changes := manager.DryMessages()
+			if len(changes) == 0 {
+				log.Infof(Colorize.Green("==> Dry run finished: no changes, the cluster matches the configuration").String())
+				return nil
+			}
+			log.Infof(Colorize.Yellow("==> Dry run finished, the following changes would be made:").String())
+			for _, change := range changes {
+				log.Infof("  * %s", change)
+			}
+			return nil
+		}
+
 		_ = analytics.Client.Publish("apply-success", map[string]interface{}{"duration": time.Since(start), "clusterID": manager.Config.Spec.K0s.Metadata.ClusterID})
 
 		duration := time.Since(start).Truncate(time.Second)
@@ -114,9 +337,130 @@ var applyCommand = &cli.Command{
 		log.Infof(Colorize.Green(text).String())
 
 		log.Infof("k0s cluster version %s is now installed", c.Spec.K0s.Version)
+		if phase.NoWait {
+			log.Warnf("--no-wait given, the worker nodes may not have joined the cluster or become ready yet")
+		}
+		if fn := ctx.String("kubeconfig-out"); fn != "" {
+			if err := writeKubeconfig(fn, c.Metadata.Kubeconfig, false); err != nil {
+				return fmt.Errorf("failed to write the kubeconfig: %w", err)
+			}
+			log.Infof("the admin kubeconfig has been written to %s", fn)
+			return nil
+		}
+
 		log.Infof("Tip: To access the cluster you can now fetch the admin kubeconfig using:")
-		log.Infof("     " + Colorize.Cyan("k0sctl kubeconfig").String())
+		if address := ctx.String("kubeconfig-api-address"); address != "" {
+			log.Infof("     " + Colorize.Cyan(fmt.Sprintf("k0sctl kubeconfig --address %s", address)).String())
+		} else {
+			log.Infof("     " + Colorize.Cyan("k0sctl kubeconfig").String())
+		}
 
 		return nil
 	},
 }
+
+// selectHosts restricts the hosts of the cluster to the ones with an address or a name matching one of the glob patterns
+func selectHosts(c *config.Cluster, patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid --hosts pattern %q: %w", p, err)
+		}
+	}
+
+	total := len(c.Spec.Hosts)
+	extra, err := c.Spec.SelectHosts(func(h *cluster.Host) bool {
+		for _, p := range patterns {
+			for _, v := range []string{h.Address(), h.Name} {
+				if ok, _ := path.Match(p, v); ok && v != "" {
+					return true
+				}
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("no hosts match --hosts %s", strings.Join(patterns, ","))
+	}
+
+	if extra != nil {
+		log.WithField("host", extra).Warn("using the controller for creating the join tokens and running kubectl only, it is not selected with --hosts")
+	}
+	log.Infof("operating on %d of the %d hosts, selected with --hosts", len(c.Spec.Hosts), total)
+	return nil
+}
+
+func validateRestoreFromFlag(ctx *cli.Context) error {
+	from := ctx.String("restore-from")
+	if from == "" {
+		return nil
+	}
+
+	if s3.IsS3URL(from) {
+		loc, err := s3.ParseURL(from, "")
+		if err != nil {
+			return err
+		}
+		if loc.Key == "" || strings.HasSuffix(loc.Key, "/") {
+			return fmt.Errorf("invalid --restore-from %q: the url must point to a backup archive", from)
+		}
+		return nil
+	}
+
+	if _, err := os.Stat(from); err != nil {
+		return fmt.Errorf("invalid --restore-from: %w", err)
+	}
+
+	return nil
+}
+
+// addApplyPhases adds the phases of the apply command to the manager
+func addApplyPhases(ctx *cli.Context, manager *phase.Manager) {
+	restore := &phase.Restore{
+		RestoreFrom: ctx.String("restore-from"),
+		S3Options:   s3Options(ctx),
+	}
+
+	connect := connectPhase(ctx)
+	portChecks := !ctx.Bool("skip-port-checks")
+	if portChecks {
+		manager.AddPhase(&phase.CheckConnectionPorts{Retries: connect.Retries, RetryInterval: connect.RetryInterval})
+	}
+	manager.AddPhase(
+		connect,
+		&phase.DetectOS{},
+		&phase.ValidateRestore{Restore: restore},
+		&phase.PrepareHosts{},
+		&phase.GatherFacts{},
+		&phase.DownloadBinaries{BinaryDir: ctx.String("binary-dir")},
+		&phase.UploadFiles{},
+		&phase.ValidateHosts{},
+		&phase.GatherK0sFacts{},
+	)
+	if portChecks {
+		manager.AddPhase(&phase.CheckK0sPorts{})
+	}
+	manager.AddPhase(
+		&phase.ValidateFacts{SkipDowngradeCheck: ctx.Bool("disable-downgrade-check"), AllowVersionSkip: ctx.Bool("allow-version-skip")},
+		&phase.UploadBinaries{},
+		&phase.DownloadK0s{},
+		&phase.RunHooks{Stage: "before", Action: "apply"},
+		&phase.PrepareArm{},
+		&phase.ConfigureK0s{},
+		restore,
+		&phase.InitializeK0s{},
+		&phase.InstallControllers{},
+		&phase.InstallWorkers{},
+		&phase.UpgradeControllers{},
+		&phase.UpgradeWorkers{
+			NoDrain:          ctx.Bool("no-drain"),
+			NoDrainOnFailure: ctx.Bool("no-drain-on-failure"),
+			BatchSize:        ctx.Int("upgrade-batch-size"),
+		},
+		&phase.ConfigureNodes{},
+		&phase.RunHooks{Stage: "after", Action: "apply"},
+	)
+	if ctx.String("kubeconfig-out") != "" && !ctx.Bool("dry-run") {
+		manager.AddPhase(&phase.GetKubeconfig{APIAddress: ctx.String("kubeconfig-api-address")})
+	}
+	manager.AddPhase(&phase.Disconnect{})
+}
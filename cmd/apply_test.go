@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectHosts(t *testing.T) {
+	newCluster := func() *config.Cluster {
+		return &config.Cluster{Spec: &cluster.Spec{Hosts: cluster.Hosts{
+			{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1"}}},
+			{Role: "worker", Name: "worker-1", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2"}}},
+			{Role: "worker", Name: "worker-2", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.3"}}},
+		}}}
+	}
+
+	c := newCluster()
+	require.NoError(t, selectHosts(c, []string{"worker-*"}))
+	require.Len(t, c.Spec.Hosts, 2)
+
+	c = newCluster()
+	require.NoError(t, selectHosts(c, []string{"10.0.0.3"}))
+	require.Len(t, c.Spec.Hosts, 1)
+	require.Equal(t, "10.0.0.3", c.Spec.Hosts[0].Address())
+	require.Equal(t, "10.0.0.1", c.Spec.K0sLeader().Address(), "the controller was not kept as the leader")
+	require.Len(t, c.Spec.ConnectHosts(), 2)
+
+	c = newCluster()
+	err := selectHosts(c, []string{"db-*"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no hosts match")
+
+	err = selectHosts(c, []string{"[worker"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid --hosts pattern")
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/k0sproject/k0sctl/audit"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/k0sproject/k0sctl/version"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+var auditLogFlag = &cli.StringFlag{
+	Name:      "audit-log",
+	Usage:     "Append a JSON line to `PATH` for each command run and file uploaded on the hosts by the phases that make changes",
+	TakesFile: true,
+}
+
+// auditExitCode returns the exit code recorded for the result of a command, -1 when the command failed
+// without an exit code, such as when the connection was lost
+func auditExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var cerr *cluster.CommandError
+	if errors.As(err, &cerr) {
+		return cerr.ExitCode
+	}
+	return -1
+}
+
+// startAuditLog opens the --audit-log and writes the record of the session, the commands run by the phases
+// of the manager that make changes to the hosts are written to the log. The returned function closes the log.
+func startAuditLog(ctx *cli.Context, manager *phase.Manager) (func(), error) {
+	fn := ctx.String("audit-log")
+	if fn == "" {
+		return func() {}, nil
+	}
+
+	patterns, err := redactPatterns(ctx.StringSlice("redact-pattern"))
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Bool("no-redact") {
+		patterns = nil
+	}
+
+	l, err := audit.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.Session(version.Version, ctx.Command.FullName(), audit.ConfigHash([]byte(configContent(ctx)))); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	manager.Audit = func(title string, h *cluster.Host, cmd string, err error) {
+		cmd = string(redactText([]byte(cmd), patterns))
+		if werr := l.Command(title, h.String(), cmd, auditExitCode(err)); werr != nil {
+			log.Warn(werr.Error())
+		}
+	}
+
+	return func() {
+		if err := l.Close(); err != nil {
+			log.Warnf("failed to close the audit log: %s", err.Error())
+		}
+	}, nil
+}
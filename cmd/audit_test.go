@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditExitCode(t *testing.T) {
+	require.Equal(t, 0, auditExitCode(nil))
+	require.Equal(t, 2, auditExitCode(fmt.Errorf("install failed: %w", &cluster.CommandError{ExitCode: 2, Err: errors.New("exit status 2")})))
+	require.Equal(t, -1, auditExitCode(errors.New("connection lost")))
+}
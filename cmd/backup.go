@@ -6,6 +6,7 @@ import (
 
 	"github.com/k0sproject/k0sctl/analytics"
 	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/integration/s3"
 	"github.com/k0sproject/k0sctl/phase"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
@@ -16,20 +17,69 @@ var backupCommand = &cli.Command{
 	Name:  "backup",
 	Usage: "Take backup of existing clusters state",
 	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:      "backup-file",
+			Usage:     "Name of the backup archive, {cluster}, {timestamp}, {date} and {unix} are replaced with the cluster name, the UTC time and date and the unix time",
+			Value:     phase.DefaultBackupFileName,
+			TakesFile: true,
+		},
+		&cli.StringFlag{
+			Name:  "backup-url",
+			Usage: "Upload the backup archive to a s3://bucket/prefix/ URL using the AWS credentials from the environment or the shared credentials file",
+		},
+		&cli.StringFlag{
+			Name:  "backup-s3-sse",
+			Usage: "Server-side encryption for the uploaded backup (AES256 or aws:kms)",
+		},
+		&cli.StringFlag{
+			Name:  "backup-s3-sse-kms-key-id",
+			Usage: "KMS key ID to use with --backup-s3-sse aws:kms (default: the AWS managed key)",
+		},
+		&cli.BoolFlag{
+			Name:  "keep-local",
+			Usage: "Keep the local backup archive after a successful upload",
+		},
+		s3RegionFlag,
+		s3EndpointFlag,
 		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		sudoPasswordFlag,
+		sshProxyFlag,
+		sshKeepAliveFlag,
+		connectTimeoutFlag,
+		connectRetriesFlag,
+		connectRetryIntervalFlag,
+		concurrencyFlag,
+		skipPhaseFlag,
+		onlyPhaseFlag,
+		errorFileFlag,
+		auditLogFlag,
 		debugFlag,
 		traceFlag,
+		verboseCommandsFlag,
 		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
 		analyticsFlag,
 	},
-	Before: actions(initLogging, initConfig, displayLogo, initAnalytics, displayCopyright),
+	Before: actions(validateConcurrencyFlag, validateSSHFlags, validateBackupFlags, initLogging, initConfig, handleSignals, displayLogo, initAnalytics, displayCopyright),
 	After: func(ctx *cli.Context) error {
 		analytics.Client.Close()
 		return nil
 	},
 	Action: func(ctx *cli.Context) error {
 		start := time.Now()
-		content := ctx.String("config")
+		content := configContent(ctx)
 		log.Debugf("Loaded configuration:\n%s", content)
 
 		c := config.Cluster{}
@@ -41,29 +91,29 @@ var backupCommand = &cli.Command{
 			return err
 		}
 
-		manager := phase.Manager{Config: &c}
-		manager.AddPhase(
-			&phase.Connect{},
-			&phase.DetectOS{},
-			&phase.GatherFacts{},
-			&phase.GatherK0sFacts{},
-			&phase.RunHooks{Stage: "before", Action: "backup"},
-			&phase.Backup{},
-			&phase.RunHooks{Stage: "after", Action: "backup"},
-			&phase.Disconnect{},
-		)
+		manager := phase.Manager{Config: &c, Concurrency: ctx.Int("concurrency"), SkipPhases: ctx.StringSlice("skip-phase"), OnlyPhases: ctx.StringSlice("only-phase")}
+		addBackupPhases(ctx, &manager)
+
+		stopAudit, err := startAuditLog(ctx, &manager)
+		if err != nil {
+			return err
+		}
+		defer stopAudit()
 
 		if err := analytics.Client.Publish("backup-start", map[string]interface{}{}); err != nil {
 			return err
 		}
 
-		if err := manager.Run(); err != nil {
-			_ = analytics.Client.Publish("backup-failure", map[string]interface{}{"clusterID": manager.Config.Spec.K0s.Metadata.ClusterID})
-			if lf, err := LogFile(); err == nil {
-				if ln, ok := lf.(interface{ Name() string }); ok {
-					log.Errorf("backup failed - log file saved to %s", ln.Name())
+		if err := manager.RunContext(ctx.Context); err != nil {
+			if ctx.String("error-file") != "" {
+				if ferr := writeErrorFile(ctx, &c, manager.Results, err); ferr != nil {
+					log.Warn(ferr.Error())
 				}
 			}
+			_ = analytics.Client.Publish("backup-failure", map[string]interface{}{"clusterID": manager.Config.Spec.K0s.Metadata.ClusterID})
+			if !ctx.Bool("no-file-log") {
+				log.Errorf("backup failed - log file saved to %s", logFilePath(ctx))
+			}
 			return err
 		}
 
@@ -75,3 +125,56 @@ var backupCommand = &cli.Command{
 		return nil
 	},
 }
+
+// addBackupPhases adds the phases of the backup command to the manager
+func addBackupPhases(ctx *cli.Context, manager *phase.Manager) {
+	s3opts := s3Options(ctx)
+	s3opts.SSE = ctx.String("backup-s3-sse")
+	s3opts.SSEKMSKeyID = ctx.String("backup-s3-sse-kms-key-id")
+
+	manager.AddPhase(
+		connectPhase(ctx),
+		&phase.DetectOS{},
+		&phase.GatherFacts{},
+		&phase.GatherK0sFacts{},
+		&phase.RunHooks{Stage: "before", Action: "backup"},
+		&phase.Backup{
+			UploadURL: ctx.String("backup-url"),
+			S3Options: s3opts,
+			KeepLocal: ctx.Bool("keep-local"),
+			FileName:  ctx.String("backup-file"),
+		},
+		&phase.RunHooks{Stage: "after", Action: "backup"},
+		&phase.Disconnect{},
+	)
+}
+
+func validateBackupFlags(ctx *cli.Context) error {
+	if err := phase.ValidateBackupFileName(ctx.String("backup-file")); err != nil {
+		return err
+	}
+
+	url := ctx.String("backup-url")
+	if url == "" {
+		for _, f := range []string{"backup-s3-sse", "backup-s3-sse-kms-key-id", "keep-local", "s3-region", "s3-endpoint"} {
+			if ctx.IsSet(f) {
+				return fmt.Errorf("--%s requires --backup-url", f)
+			}
+		}
+		return nil
+	}
+
+	if !s3.IsS3URL(url) {
+		return fmt.Errorf("unsupported --backup-url %q, only s3:// URLs are supported", url)
+	}
+
+	if _, err := s3.ParseURL(url, ""); err != nil {
+		return err
+	}
+
+	if ctx.IsSet("backup-s3-sse-kms-key-id") && ctx.String("backup-s3-sse") != "aws:kms" {
+		return fmt.Errorf("--backup-s3-sse-kms-key-id requires --backup-s3-sse aws:kms")
+	}
+
+	return s3.ValidateSSE(ctx.String("backup-s3-sse"))
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+const bashCompletion = `#!/usr/bin/env bash
+# bash completion for k0sctl
+
+_k0sctl_completion() {
+  local cur prev opts
+  COMPREPLY=()
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+  case "${prev}" in
+    --config|-c|--log-file)
+      COMPREPLY=( $(compgen -f -- "${cur}") )
+      return 0
+      ;;
+  esac
+
+  if [[ "${cur}" == "-"* ]]; then
+    opts=$( "${COMP_WORDS[@]:0:$COMP_CWORD}" "${cur}" --generate-bash-completion 2>/dev/null )
+  else
+    opts=$( "${COMP_WORDS[@]:0:$COMP_CWORD}" --generate-bash-completion 2>/dev/null )
+  fi
+  COMPREPLY=( $(compgen -W "${opts}" -- "${cur}") )
+  return 0
+}
+
+complete -o bashdefault -o default -F _k0sctl_completion k0sctl
+`
+
+const zshCompletion = `#compdef k0sctl
+# zsh completion for k0sctl
+
+_k0sctl() {
+  local -a opts
+  local cur prev
+  cur=${words[-1]}
+  prev=${words[-2]}
+
+  case "${prev}" in
+    --config|-c|--log-file)
+      _files
+      return
+      ;;
+  esac
+
+  if [[ "${cur}" == "-"* ]]; then
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion 2>/dev/null)}")
+  else
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} --generate-bash-completion 2>/dev/null)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+compdef _k0sctl k0sctl
+`
+
+var completionCommand = &cli.Command{
+	Name:      "completion",
+	Usage:     "Output shell completion script for bash, zsh or fish",
+	ArgsUsage: "bash|zsh|fish",
+	Description: `Outputs a shell completion script for k0sctl.
+
+To load the completions in the current shell session:
+
+   bash: source <(k0sctl completion bash)
+   zsh:  source <(k0sctl completion zsh)
+   fish: k0sctl completion fish | source
+
+To load the completions for every session, add the line to your shell's startup file (~/.bashrc, ~/.zshrc)
+or for fish, write the output to ~/.config/fish/completions/k0sctl.fish.`,
+	Action: func(ctx *cli.Context) error {
+		switch shell := ctx.Args().First(); shell {
+		case "bash":
+			fmt.Print(bashCompletion)
+		case "zsh":
+			fmt.Print(zshCompletion)
+		case "fish":
+			script, err := ctx.App.ToFishCompletion()
+			if err != nil {
+				return err
+			}
+			fmt.Print(script)
+		case "":
+			return fmt.Errorf("missing shell argument, must be one of: bash, zsh, fish")
+		default:
+			return fmt.Errorf("unsupported shell %q, must be one of: bash, zsh, fish", shell)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	validator "github.com/go-playground/validator/v10"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+var configCommand = &cli.Command{
+	Name:  "config",
+	Usage: "Configuration related subcommands",
+	Subcommands: []*cli.Command{
+		configValidateCommand,
+	},
+}
+
+var configValidateCommand = &cli.Command{
+	Name:  "validate",
+	Usage: "Validate a k0sctl configuration without connecting to the hosts",
+	Flags: []cli.Flag{
+		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		downloadURLBaseFlag,
+		&cli.BoolFlag{
+			Name:  "check-downloads",
+			Usage: "Check that the k0s binaries of the configured versions can be downloaded",
+		},
+		outputFlag,
+		debugFlag,
+		traceFlag,
+		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
+	},
+	Before: actions(validateOutputFlag, initSilentLogging, initConfig),
+	Action: func(ctx *cli.Context) error {
+		errs := validateConfig(ctx)
+
+		if ctx.String("output") == "json" {
+			result := struct {
+				Valid  bool     `json:"valid"`
+				Config []string `json:"config"`
+				Errors []string `json:"errors"`
+			}{
+				Valid:  len(errs) == 0,
+				Config: ctx.StringSlice("config"),
+				Errors: []string{},
+			}
+			for _, err := range errs {
+				result.Errors = append(result.Errors, err.Error())
+			}
+			if err := printJSON(result); err != nil {
+				return err
+			}
+			if !result.Valid {
+				os.Exit(1)
+			}
+			return nil
+		}
+
+		if len(errs) > 0 {
+			msgs := make([]string, len(errs))
+			for i, err := range errs {
+				msgs[i] = err.Error()
+			}
+			return fmt.Errorf("configuration is not valid:\n%s", strings.Join(msgs, "\n"))
+		}
+
+		fmt.Println("configuration is valid")
+		return nil
+	},
+}
+
+// validateConfig parses the configuration and returns all of the validation errors. With --check-downloads
+// the k0s binaries of a valid configuration are looked up from the download location.
+func validateConfig(ctx *cli.Context) []error {
+	c := config.Cluster{}
+	if err := yaml.UnmarshalStrict([]byte(configContent(ctx)), &c); err != nil {
+		return []error{err}
+	}
+	applyDownloadURLBase(ctx, &c)
+
+	err := c.Validate()
+	if err == nil {
+		if ctx.Bool("check-downloads") {
+			if err := resolveVersionChannel(&c); err != nil {
+				return []error{err}
+			}
+			return phase.CheckDownloads(&c)
+		}
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []error{err}
+	}
+
+	res := make([]error, len(verrs))
+	for i, verr := range verrs {
+		res[i] = verr
+	}
+	return res
+}
+
+// saveConfig writes the resolved configuration to a file. The inline ssh keys, the ssh and winRM passwords
+// and the other secrets are redacted unless --no-redact is given.
+func saveConfig(ctx *cli.Context, fn string, c *config.Cluster) error {
+	redact := !ctx.Bool("no-redact")
+	if redact {
+		for _, h := range c.Spec.Hosts {
+			if h.WinRM != nil && h.WinRM.Password != "" {
+				h.WinRM.Password = "[REDACTED]"
+			}
+			if h.SSHAuth.Password != "" {
+				h.SSHAuth.Password = "[REDACTED]"
+			}
+			if h.SudoPassword != "" {
+				h.SudoPassword = "[REDACTED]"
+			}
+		}
+	}
+
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if original, ok := ctx.Context.Value(ctxKeyDataConfigKey{}).([]byte); ok {
+		var replace func(string) string
+		if redact {
+			replace = func(string) string { return "[REDACTED]" }
+		}
+		out, err = config.InsertSSHKeyData(out, original, replace)
+		if err != nil {
+			return err
+		}
+	}
+
+	if redact {
+		patterns, err := redactPatterns(ctx.StringSlice("redact-pattern"))
+		if err != nil {
+			return err
+		}
+		out = redactText(out, patterns)
+	}
+
+	if err := os.WriteFile(fn, out, 0600); err != nil {
+		return fmt.Errorf("failed to save the configuration: %w", err)
+	}
+	return nil
+}
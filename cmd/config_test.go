@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSaveConfig(t *testing.T) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(pk)
+	require.NoError(t, err)
+	key := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+
+	original := fmt.Sprintf(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+        keyData: %q
+        keepAliveInterval: 10s
+    - role: worker
+      winRM:
+        address: 10.0.0.2
+        password: secret-password
+  k0s:
+    version: 1.23.3+k0s.0
+`, key)
+	content := `
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+        keyPath: /tmp/k0sctl-keys/key-0
+        keepAliveInterval: 10s
+    - role: worker
+      sudoPassword: secret-sudo
+      winRM:
+        address: 10.0.0.2
+        password: secret-password
+  k0s:
+    version: 1.23.3+k0s.0
+`
+	set := flag.NewFlagSet("apply", flag.ContinueOnError)
+	set.Bool("no-redact", false, "")
+	ctx := cli.NewContext(App, set, nil)
+	ctx.Context = context.WithValue(context.Background(), ctxKeyDataConfigKey{}, []byte(original))
+
+	load := func() *config.Cluster {
+		c := &config.Cluster{}
+		require.NoError(t, yaml.UnmarshalStrict([]byte(content), c))
+		return c
+	}
+
+	fn := filepath.Join(t.TempDir(), "resolved.yaml")
+	require.NoError(t, saveConfig(ctx, fn, load()))
+	out, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.NotContains(t, string(out), "secret")
+	require.NotContains(t, string(out), "PRIVATE KEY")
+	require.NotContains(t, string(out), "/tmp/k0sctl-keys")
+	require.Contains(t, string(out), "keyData: '[REDACTED]'")
+	require.Contains(t, string(out), "password: '[REDACTED]'")
+	require.Contains(t, string(out), "sudoPassword: '[REDACTED]'")
+	require.Contains(t, string(out), "keepAliveInterval: 10s")
+	require.Contains(t, string(out), "user: root")
+
+	require.NoError(t, set.Set("no-redact", "true"))
+	require.NoError(t, saveConfig(ctx, fn, load()))
+	out, err = os.ReadFile(fn)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "password: secret-password")
+
+	stripped, keys, err := config.ExtractSSHKeyData(out, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{key}, keys)
+	c := &config.Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(stripped, c))
+	require.Equal(t, load().Spec.Hosts[0].SSHKeepAlive, c.Spec.Hosts[0].SSHKeepAlive)
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// secretConfigScheme is the prefix of the --config values that refer to a key of a kubernetes secret
+const secretConfigScheme = "k8s-secret://"
+
+type secretRef struct {
+	Namespace string
+	Name      string
+	Key       string
+}
+
+func (s secretRef) String() string {
+	return s.Namespace + "/" + s.Name
+}
+
+// parseSecretRef parses a k8s-secret://namespace/name/key config location
+func parseSecretRef(f string) (secretRef, error) {
+	parts := strings.Split(strings.TrimPrefix(f, secretConfigScheme), "/")
+	if len(parts) != 3 {
+		return secretRef{}, fmt.Errorf("invalid config location %q, must be given as %snamespace/name/key", f, secretConfigScheme)
+	}
+	ref := secretRef{Namespace: parts[0], Name: parts[1], Key: parts[2]}
+	if errs := validation.IsDNS1123Label(ref.Namespace); len(errs) > 0 {
+		return secretRef{}, fmt.Errorf("invalid namespace %q in config location %s: %s", ref.Namespace, f, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsDNS1123Subdomain(ref.Name); len(errs) > 0 {
+		return secretRef{}, fmt.Errorf("invalid secret name %q in config location %s: %s", ref.Name, f, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsConfigMapKey(ref.Key); len(errs) > 0 {
+		return secretRef{}, fmt.Errorf("invalid secret key %q in config location %s: %s", ref.Key, f, strings.Join(errs, "; "))
+	}
+	return ref, nil
+}
+
+// kubeRESTConfig returns the configuration for connecting to the kubernetes API from the $KUBECONFIG or
+// the default kubeconfig file, or the service account of the pod when running inside a cluster
+func kubeRESTConfig() (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig for reading the config secret: %w", err)
+	}
+	return cfg, nil
+}
+
+// configSecretReader reads the config from a key of a kubernetes secret
+func configSecretReader(f string, timeout time.Duration) (io.ReadCloser, error) {
+	ref, err := parseSecretRef(f)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := kubeRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := rest.TransportFor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the kubernetes client: %w", err)
+	}
+	client := &http.Client{Timeout: timeout, Transport: transport}
+
+	host := strings.TrimSuffix(cfg.Host, "/")
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	resp, err := client.Get(fmt.Sprintf("%s/api/v1/namespaces/%s/secrets/%s", host, url.PathEscape(ref.Namespace), url.PathEscape(ref.Name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the config secret %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("config secret %s not found", ref)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("not allowed to read the config secret %s: server responded with %s", ref, resp.Status)
+	default:
+		return nil, fmt.Errorf("failed to read the config secret %s: server responded with %s", ref, resp.Status)
+	}
+
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("failed to decode the config secret %s: %w", ref, err)
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		if len(secret.Data) == 0 {
+			return nil, fmt.Errorf("config secret %s has no key %q, the secret is empty", ref, ref.Key)
+		}
+		keys := make([]string, 0, len(secret.Data))
+		for k := range secret.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("config secret %s has no key %q, the keys are: %s", ref, ref.Key, strings.Join(keys, ", "))
+	}
+	content, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the key %q of the config secret %s: %w", ref.Key, ref, err)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
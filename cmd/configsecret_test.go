@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	ref, err := parseSecretRef("k8s-secret://clusters/prod/k0sctl.yaml")
+	require.NoError(t, err)
+	require.Equal(t, secretRef{Namespace: "clusters", Name: "prod", Key: "k0sctl.yaml"}, ref)
+
+	for _, f := range []string{"k8s-secret://clusters/prod", "k8s-secret://clusters/prod/k0sctl.yaml/extra", "k8s-secret://Clusters/prod/k0sctl.yaml", "k8s-secret://clusters/prod/bad key"} {
+		_, err := parseSecretRef(f)
+		require.Error(t, err, f)
+	}
+}
+
+func TestConfigReaderSecret(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/v1/namespaces/clusters/secrets/prod" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"kind":"Secret","data":{"k0sctl.yaml":%q}}`, base64.StdEncoding.EncodeToString([]byte("apiVersion: k0sctl.k0sproject.io/v1beta1\n")))
+	}))
+	defer server.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: management
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+contexts:
+- name: management
+  context:
+    cluster: management
+    user: operator
+current-context: management
+users:
+- name: operator
+  user:
+    token: secret-token
+`, server.URL)), 0600))
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	r, err := configReader("k8s-secret://clusters/prod/k0sctl.yaml", time.Second)
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	require.Equal(t, "apiVersion: k0sctl.k0sproject.io/v1beta1\n", string(content))
+
+	_, err = configReader("k8s-secret://clusters/prod/config.yaml", time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `config secret clusters/prod has no key "config.yaml", the keys are: k0sctl.yaml`)
+
+	_, err = configReader("k8s-secret://clusters/staging/k0sctl.yaml", time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "config secret clusters/staging not found")
+
+	require.Equal(t, "", configDir("k8s-secret://clusters/prod/k0sctl.yaml"))
+}
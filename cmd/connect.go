@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/k0sproject/k0sctl/analytics"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+var connectCommand = &cli.Command{
+	Name:  "connect",
+	Usage: "Check that all of the hosts can be connected to, exits with an error when a host can not be reached",
+	Flags: []cli.Flag{
+		outputFlag,
+		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		sudoPasswordFlag,
+		sshProxyFlag,
+		connectTimeoutFlag,
+		concurrencyFlag,
+		debugFlag,
+		traceFlag,
+		verboseCommandsFlag,
+		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
+		analyticsFlag,
+	},
+	Before: actions(validateConcurrencyFlag, validateSSHFlags, validateOutputFlag, initSilentLogging, initConfig, handleSignals, initAnalytics),
+	After: func(ctx *cli.Context) error {
+		analytics.Client.Close()
+		return nil
+	},
+	Action: func(ctx *cli.Context) error {
+		content := configContent(ctx)
+		c := config.Cluster{}
+		if err := yaml.UnmarshalStrict([]byte(content), &c); err != nil {
+			return err
+		}
+
+		if err := c.Validate(); err != nil {
+			return err
+		}
+
+		check := &phase.CheckConnections{}
+		manager := phase.Manager{Config: &c, Concurrency: ctx.Int("concurrency")}
+		manager.AddPhase(
+			check,
+			&phase.Disconnect{},
+		)
+
+		if err := manager.RunContext(ctx.Context); err != nil {
+			return err
+		}
+
+		if ctx.String("output") == "json" {
+			if err := printJSON(check.Hosts); err != nil {
+				return err
+			}
+		} else if err := writeConnectTable(os.Stdout, check.Hosts); err != nil {
+			return err
+		}
+
+		var failed int
+		for _, h := range check.Hosts {
+			if !h.OK() {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d hosts can not be connected to", failed, len(check.Hosts))
+		}
+
+		return nil
+	},
+}
+
+// writeConnectTable writes the connection check results as a table
+func writeConnectTable(w io.Writer, hosts []*phase.HostConnection) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tPROTOCOL\tROLE\tSTATUS\tERROR")
+	for _, h := range hosts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", h.Address, h.Protocol, h.Role, h.Status, dash(h.Error))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteConnectTable(t *testing.T) {
+	hosts := []*phase.HostConnection{
+		{Address: "10.0.0.1", Protocol: "SSH", Role: "controller", Status: phase.HostReachable},
+		{Address: "10.0.0.2", Protocol: "WinRM", Role: "worker", Status: phase.HostAuthFailed, Error: "unauthorized"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeConnectTable(&buf, hosts))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"ADDRESS", "PROTOCOL", "ROLE", "STATUS", "ERROR"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"10.0.0.1", "SSH", "controller", "reachable", "-"}, strings.Fields(lines[1]))
+	require.Equal(t, []string{"10.0.0.2", "WinRM", "worker", "auth-failed", "unauthorized"}, strings.Fields(lines[2]))
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k0sproject/k0sctl/analytics"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+var describeCommand = &cli.Command{
+	Name:  "describe",
+	Usage: "Describe the hosts of the cluster without making changes",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "Output format (text, json, yaml)",
+			Value:   "text",
+		},
+		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		sudoPasswordFlag,
+		sshProxyFlag,
+		sshKeepAliveFlag,
+		connectTimeoutFlag,
+		concurrencyFlag,
+		debugFlag,
+		traceFlag,
+		verboseCommandsFlag,
+		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
+		analyticsFlag,
+	},
+	Before: actions(validateConcurrencyFlag, validateSSHFlags, validateDescribeOutputFlag, initSilentLogging, initConfig, handleSignals, initAnalytics),
+	After: func(ctx *cli.Context) error {
+		analytics.Client.Close()
+		return nil
+	},
+	Action: func(ctx *cli.Context) error {
+		content := configContent(ctx)
+		c := config.Cluster{}
+		if err := yaml.UnmarshalStrict([]byte(content), &c); err != nil {
+			return err
+		}
+
+		if err := c.Validate(); err != nil {
+			return err
+		}
+
+		describe := &phase.Describe{}
+		manager := phase.Manager{Config: &c, Concurrency: ctx.Int("concurrency")}
+		manager.AddPhase(
+			describe,
+			&phase.Disconnect{},
+		)
+
+		if err := manager.RunContext(ctx.Context); err != nil {
+			return err
+		}
+
+		switch ctx.String("output") {
+		case "json":
+			return printJSON(describe.Hosts)
+		case "yaml":
+			out, err := yaml.Marshal(describe.Hosts)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(out))
+			return nil
+		default:
+			return writeDescribeTable(os.Stdout, describe.Hosts)
+		}
+	},
+}
+
+func validateDescribeOutputFlag(ctx *cli.Context) error {
+	switch ctx.String("output") {
+	case "text", "json", "yaml":
+		return nil
+	default:
+		return fmt.Errorf("invalid --output %q, must be one of: text, json, yaml", ctx.String("output"))
+	}
+}
+
+// writeDescribeTable writes the host descriptions as a table
+func writeDescribeTable(w io.Writer, hosts []*phase.HostDescription) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tROLE\tSTATUS\tHOSTNAME\tOS\tARCH\tKERNEL\tUPTIME\tK0S")
+	for _, h := range hosts {
+		k0s := "-"
+		if h.K0sVersion != "" {
+			k0s = h.K0sVersion
+			if !h.K0sRunning {
+				k0s += " (not running)"
+			}
+		}
+		uptime := "-"
+		if h.Uptime > 0 {
+			uptime = (time.Duration(h.Uptime) * time.Second).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", h.Address, h.Role, h.Status, dash(h.Hostname), dash(h.OS), dash(h.Arch), dash(h.Kernel), uptime, k0s)
+	}
+	return tw.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDescribeTable(t *testing.T) {
+	hosts := []*phase.HostDescription{
+		{Address: "10.0.0.1", Role: "controller", Status: phase.HostReachable, Hostname: "ctrl", OS: "Ubuntu 20.04", Arch: "amd64", Kernel: "5.4.0", Uptime: 3661, K0sVersion: "1.23.3+k0s.0", K0sRunning: true},
+		{Address: "10.0.0.2", Role: "worker", Status: phase.HostUnreachable, Error: "connection refused"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeDescribeTable(&buf, hosts))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"ADDRESS", "ROLE", "STATUS", "HOSTNAME", "OS", "ARCH", "KERNEL", "UPTIME", "K0S"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"10.0.0.1", "controller", "reachable", "ctrl", "Ubuntu", "20.04", "amd64", "5.4.0", "1h1m1s", "1.23.3+k0s.0"}, strings.Fields(lines[1]))
+	require.Equal(t, []string{"10.0.0.2", "worker", "unreachable", "-", "-", "-", "-", "-", "-"}, strings.Fields(lines[2]))
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/urfave/cli/v2"
+)
+
+type failedHost struct {
+	Address  string `json:"address"`
+	Error    string `json:"error"`
+	ExitCode *int   `json:"exitCode,omitempty"`
+	Command  string `json:"command,omitempty"`
+}
+
+// failureReport is the document written to the --error-file when a run fails
+type failureReport struct {
+	Phase string       `json:"phase,omitempty"`
+	Error string       `json:"error"`
+	Hosts []failedHost `json:"hosts"`
+}
+
+// newFailureReport describes the phase that failed and the errors of the hosts it failed on. The
+// exit code and the command are included when the host error came from running a command.
+func newFailureReport(c *config.Cluster, results []phase.Result, err error) *failureReport {
+	report := &failureReport{Error: err.Error(), Hosts: []failedHost{}}
+
+	addresses := make(map[string]string, len(c.Spec.Hosts))
+	for _, h := range c.Spec.Hosts {
+		addresses[h.String()] = h.Address()
+	}
+
+	failed := func(host string, herr error) failedHost {
+		fh := failedHost{Address: host, Error: herr.Error()}
+		var cerr *cluster.CommandError
+		if errors.As(herr, &cerr) {
+			if host == "" {
+				fh.Address = cerr.Host
+			}
+			if cerr.ExitCode >= 0 {
+				code := cerr.ExitCode
+				fh.ExitCode = &code
+			}
+			fh.Command = cerr.Command
+		}
+		if addr, ok := addresses[fh.Address]; ok {
+			fh.Address = addr
+		}
+		return fh
+	}
+
+	phaseErr := err
+	for _, r := range results {
+		if r.Err != nil {
+			report.Phase = r.Title
+			phaseErr = r.Err
+		}
+	}
+
+	var perr *cluster.ParallelError
+	var cerr *cluster.CommandError
+	switch {
+	case errors.As(phaseErr, &perr):
+		for _, he := range perr.Errors {
+			report.Hosts = append(report.Hosts, failed(he.Host, he.Err))
+		}
+	case errors.As(phaseErr, &cerr):
+		report.Hosts = append(report.Hosts, failed("", cerr))
+	}
+
+	return report
+}
+
+// writeErrorFile writes the failure report as json to the --error-file, text matching the
+// --redact-pattern expressions and known secrets are redacted the same way as in the logs
+func writeErrorFile(ctx *cli.Context, c *config.Cluster, results []phase.Result, runErr error) error {
+	out, err := json.MarshalIndent(newFailureReport(c, results, runErr), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write the error file: %w", err)
+	}
+
+	if !ctx.Bool("no-redact") {
+		patterns, err := redactPatterns(ctx.StringSlice("redact-pattern"))
+		if err != nil {
+			return err
+		}
+		out = redactText(out, patterns)
+	}
+
+	if err := os.WriteFile(ctx.String("error-file"), append(out, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write the error file: %w", err)
+	}
+	return nil
+}
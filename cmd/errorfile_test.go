@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+func errorFileTestCluster() *config.Cluster {
+	return &config.Cluster{Spec: &cluster.Spec{Hosts: cluster.Hosts{
+		&cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}, Role: "controller"},
+		&cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2", Port: 22}}, Role: "worker"},
+	}}}
+}
+
+func TestNewFailureReport(t *testing.T) {
+	c := errorFileTestCluster()
+	perr := &cluster.ParallelError{Errors: []cluster.HostError{
+		{Host: "[ssh] 10.0.0.2:22", Err: &cluster.CommandError{Host: "[ssh] 10.0.0.2:22", Command: "k0s install worker", ExitCode: 1, Err: errors.New("exit status 1")}},
+	}}
+	err := errors.New("apply failed")
+	results := []phase.Result{
+		{Title: "Connect to hosts"},
+		{Title: "Install workers", Err: perr},
+	}
+
+	report := newFailureReport(c, results, err)
+	require.Equal(t, "Install workers", report.Phase)
+	require.Equal(t, "apply failed", report.Error)
+	require.Len(t, report.Hosts, 1)
+	require.Equal(t, "10.0.0.2", report.Hosts[0].Address)
+	require.Equal(t, "exit status 1", report.Hosts[0].Error)
+	require.Equal(t, "k0s install worker", report.Hosts[0].Command)
+	require.NotNil(t, report.Hosts[0].ExitCode)
+	require.Equal(t, 1, *report.Hosts[0].ExitCode)
+
+	t.Run("single host", func(t *testing.T) {
+		cerr := &cluster.CommandError{Host: "[ssh] 10.0.0.1:22", Command: "k0s token create", ExitCode: -1, Err: errors.New("connection lost")}
+		report := newFailureReport(c, []phase.Result{{Title: "Initialize the k0s cluster", Err: cerr}}, cerr)
+		require.Equal(t, "Initialize the k0s cluster", report.Phase)
+		require.Len(t, report.Hosts, 1)
+		require.Equal(t, "10.0.0.1", report.Hosts[0].Address)
+		require.Nil(t, report.Hosts[0].ExitCode)
+	})
+
+	t.Run("no phase", func(t *testing.T) {
+		report := newFailureReport(c, nil, errors.New("context deadline exceeded"))
+		require.Empty(t, report.Phase)
+		require.Empty(t, report.Hosts)
+	})
+}
+
+func TestWriteErrorFile(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "error.json")
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("error-file", fn, "")
+	set.Bool("no-redact", false, "")
+	redact := cli.NewStringSlice("secret-\\d+")
+	set.Var(redact, "redact-pattern", "")
+	ctx := cli.NewContext(cli.NewApp(), set, nil)
+
+	cerr := &cluster.CommandError{Host: "[ssh] 10.0.0.1:22", Command: "echo secret-123", ExitCode: 2, Err: errors.New("exit status 2")}
+	require.NoError(t, writeErrorFile(ctx, errorFileTestCluster(), []phase.Result{{Title: "Run hooks", Err: cerr}}, cerr))
+
+	stat, err := os.Stat(fn)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	data, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	var report map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &report))
+	require.Equal(t, "Run hooks", report["phase"])
+	hosts := report["hosts"].([]interface{})
+	require.Len(t, hosts, 1)
+	host := hosts[0].(map[string]interface{})
+	require.Equal(t, "echo [REDACTED]", host["command"])
+	require.Equal(t, float64(2), host["exitCode"])
+}
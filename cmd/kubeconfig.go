@@ -1,36 +1,101 @@
 package cmd
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"github.com/k0sproject/k0sctl/analytics"
 	"github.com/k0sproject/k0sctl/config"
 	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/config/sshconfig"
 	"github.com/k0sproject/k0sctl/phase"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 var kubeconfigCommand = &cli.Command{
 	Name:  "kubeconfig",
-	Usage: "Output the admin kubeconfig of the cluster",
+	Usage: "Output the admin or an OIDC user kubeconfig of the cluster",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
-			Name:  "address",
-			Usage: "Set kubernetes API address (default: auto-detect)",
-			Value: "",
+			Name:    "address",
+			Aliases: []string{"server"},
+			Usage:   "Set the kubernetes API server address as host[:port] or an URL, such as https://lb.example.com:6443 (default: spec.kubeconfig.apiAddress or auto-detect)",
+			Value:   "",
+		},
+		&cli.StringFlag{
+			Name:      "ca-cert",
+			Usage:     "Path to a PEM encoded CA certificate to use in the kubeconfig instead of the cluster CA, such as when the API is behind a load balancer with its own certificate",
+			TakesFile: true,
+		},
+		&cli.StringFlag{
+			Name:      "output",
+			Aliases:   []string{"o"},
+			Usage:     "Write the kubeconfig to a file instead of stdout",
+			TakesFile: true,
+		},
+		&cli.BoolFlag{
+			Name:  "merge",
+			Usage: "Merge the kubeconfig into an existing kubeconfig file given in --output instead of overwriting it",
+		},
+		&cli.StringFlag{
+			Name:  "context-name",
+			Usage: "Name of the cluster and context in the kubeconfig (default: cluster name from metadata.name)",
+		},
+		&cli.StringFlag{
+			Name:  "user",
+			Usage: "Write a kubeconfig for the named user authenticating through OIDC with the kubelogin exec plugin instead of the admin client certificate, requires --oidc-issuer-url and --oidc-client-id",
+		},
+		&cli.StringFlag{
+			Name:  "oidc-issuer-url",
+			Usage: "Issuer URL of the OIDC provider for --user",
+		},
+		&cli.StringFlag{
+			Name:  "oidc-client-id",
+			Usage: "OIDC client ID for --user",
+		},
+		&cli.StringSliceFlag{
+			Name:  "oidc-extra-scope",
+			Usage: "Additional OIDC scope to request for --user, can be given multiple times",
 		},
 		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		sudoPasswordFlag,
+		sshProxyFlag,
+		sshKeepAliveFlag,
+		connectTimeoutFlag,
+		connectRetriesFlag,
+		connectRetryIntervalFlag,
 		debugFlag,
 		traceFlag,
+		verboseCommandsFlag,
 		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
 		analyticsFlag,
 	},
-	Before: actions(initSilentLogging, initConfig, initAnalytics),
+	Before: actions(validateKubeconfigFlags, validateSSHFlags, initSilentLogging, initConfig, handleSignals, initAnalytics),
 	After: func(ctx *cli.Context) error {
 		analytics.Client.Close()
 		return nil
 	},
 	Action: func(ctx *cli.Context) error {
-		content := ctx.String("config")
+		content := configContent(ctx)
 		c := config.Cluster{}
 		if err := yaml.UnmarshalStrict([]byte(content), &c); err != nil {
 			return err
@@ -39,18 +104,173 @@ var kubeconfigCommand = &cli.Command{
 		if err := c.Validate(); err != nil {
 			return err
 		}
+		caCert, err := readCACert(ctx.String("ca-cert"))
+		if err != nil {
+			return err
+		}
+		user, err := oidcUser(ctx)
+		if err != nil {
+			return err
+		}
+
 		// Change so that the internal config has only single controller host as we
 		// do not need to connect to all nodes
 		c.Spec.Hosts = cluster.Hosts{c.Spec.K0sLeader()}
 		manager := phase.Manager{Config: &c}
 
 		manager.AddPhase(
-			&phase.Connect{},
+			connectPhase(ctx),
 			&phase.DetectOS{},
-			&phase.GetKubeconfig{APIAddress: ctx.String("address")},
+			&phase.GetKubeconfig{APIAddress: ctx.String("address"), ContextName: ctx.String("context-name"), CACert: caCert, User: user},
 			&phase.Disconnect{},
 		)
 
-		return manager.Run()
+		if err := manager.RunContext(ctx.Context); err != nil {
+			return err
+		}
+
+		if ctx.String("output") == "" {
+			fmt.Println(c.Metadata.Kubeconfig)
+			return nil
+		}
+
+		return writeKubeconfig(ctx.String("output"), c.Metadata.Kubeconfig, ctx.Bool("merge"))
 	},
 }
+
+func validateKubeconfigFlags(ctx *cli.Context) error {
+	if ctx.Bool("merge") && ctx.String("output") == "" {
+		return fmt.Errorf("--merge requires --output")
+	}
+	if address := ctx.String("address"); address != "" {
+		if _, err := cluster.KubeconfigAPIURL(address, 6443); err != nil {
+			return fmt.Errorf("invalid --address: %w", err)
+		}
+	}
+	if _, err := oidcUser(ctx); err != nil {
+		return err
+	}
+	_, err := readCACert(ctx.String("ca-cert"))
+	return err
+}
+
+// oidcUser returns the OIDC user from the --user flags, nil when --user is not given
+func oidcUser(ctx *cli.Context) (*phase.OIDCUser, error) {
+	if ctx.String("user") == "" {
+		for _, f := range []string{"oidc-issuer-url", "oidc-client-id", "oidc-extra-scope"} {
+			if ctx.IsSet(f) {
+				return nil, fmt.Errorf("--%s requires --user", f)
+			}
+		}
+		return nil, nil
+	}
+	user := &phase.OIDCUser{
+		Name:        ctx.String("user"),
+		IssuerURL:   ctx.String("oidc-issuer-url"),
+		ClientID:    ctx.String("oidc-client-id"),
+		ExtraScopes: ctx.StringSlice("oidc-extra-scope"),
+	}
+	if err := user.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid --user: %w", err)
+	}
+	return user, nil
+}
+
+// readCACert reads a PEM encoded CA certificate file and makes sure it only contains valid certificates,
+// nil is returned when the path is empty
+func readCACert(fn string) ([]byte, error) {
+	if fn == "" {
+		return nil, nil
+	}
+	fn, err := sshconfig.ExpandHome(fn)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --ca-cert: %w", err)
+	}
+
+	var count int
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("invalid --ca-cert %s: unexpected PEM block %q, only certificates are allowed", fn, block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("invalid --ca-cert %s: %w", fn, err)
+		}
+		count++
+	}
+	if count == 0 || len(bytes.TrimSpace(rest)) > 0 {
+		return nil, fmt.Errorf("invalid --ca-cert %s: must be a PEM encoded certificate", fn)
+	}
+
+	return data, nil
+}
+
+// writeKubeconfig writes the kubeconfig to a file with 0600 permissions. When merge is true and the
+// file exists, the clusters, contexts and users of the kubeconfig are merged into it and the existing
+// entries with other names are preserved.
+func writeKubeconfig(fn, content string, merge bool) error {
+	fn, err := sshconfig.ExpandHome(fn)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for kubeconfig: %w", err)
+	}
+
+	data := []byte(content)
+	if merge {
+		if _, err := os.Stat(fn); err == nil {
+			data, err = mergeKubeconfig(fn, data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.WriteFile(fn, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile does not change the permissions of an existing file
+	return os.Chmod(fn, 0600)
+}
+
+func mergeKubeconfig(fn string, content []byte) ([]byte, error) {
+	existing, err := clientcmd.LoadFromFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing kubeconfig %s: %w", fn, err)
+	}
+
+	cfg, err := clientcmd.Load(content)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, cluster := range cfg.Clusters {
+		existing.Clusters[name] = cluster
+	}
+	for name, context := range cfg.Contexts {
+		// the admin user name is the same for all k0s clusters, prefix it with the context name to avoid
+		// overwriting the credentials of other clusters
+		user := fmt.Sprintf("%s-%s", name, context.AuthInfo)
+		if auth, ok := cfg.AuthInfos[context.AuthInfo]; ok {
+			existing.AuthInfos[user] = auth
+		}
+		context.AuthInfo = user
+		existing.Contexts[name] = context
+	}
+	if existing.CurrentContext == "" {
+		existing.CurrentContext = cfg.CurrentContext
+	}
+
+	return clientcmd.Write(*existing)
+}
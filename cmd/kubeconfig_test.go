@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func testKubeconfig(t *testing.T, name, server string) string {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters[name] = &clientcmdapi.Cluster{Server: server}
+	cfg.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: name + "-token"}
+	cfg.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: "admin"}
+	cfg.CurrentContext = name
+	out, err := clientcmd.Write(*cfg)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestWriteKubeconfigMerge(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "kube", "config")
+
+	require.NoError(t, writeKubeconfig(fn, testKubeconfig(t, "prod", "https://10.0.0.1:6443"), false))
+	stat, err := os.Stat(fn)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	require.NoError(t, writeKubeconfig(fn, testKubeconfig(t, "staging", "https://10.0.1.1:6443"), true))
+
+	cfg, err := clientcmd.LoadFromFile(fn)
+	require.NoError(t, err)
+	require.Equal(t, "prod", cfg.CurrentContext)
+	require.Contains(t, cfg.Contexts, "prod")
+	require.Contains(t, cfg.Contexts, "staging")
+	require.Equal(t, "https://10.0.1.1:6443", cfg.Clusters["staging"].Server)
+	require.Equal(t, "staging-admin", cfg.Contexts["staging"].AuthInfo)
+	require.Equal(t, "staging-token", cfg.AuthInfos["staging-admin"].Token)
+	require.Equal(t, "prod-token", cfg.AuthInfos["admin"].Token)
+}
+
+func TestValidateKubeconfigFlags(t *testing.T) {
+	set := flag.NewFlagSet("kubeconfig", flag.ContinueOnError)
+	set.Bool("merge", true, "")
+	set.String("output", "", "")
+	ctx := cli.NewContext(App, set, nil)
+	require.EqualError(t, validateKubeconfigFlags(ctx), "--merge requires --output")
+
+	require.NoError(t, set.Set("output", "kubeconfig"))
+	require.NoError(t, validateKubeconfigFlags(ctx))
+}
+
+func TestOIDCUser(t *testing.T) {
+	set := flag.NewFlagSet("kubeconfig", flag.ContinueOnError)
+	set.String("user", "", "")
+	set.String("oidc-issuer-url", "", "")
+	set.String("oidc-client-id", "", "")
+	ctx := cli.NewContext(App, set, nil)
+
+	user, err := oidcUser(ctx)
+	require.NoError(t, err)
+	require.Nil(t, user)
+
+	require.NoError(t, set.Set("oidc-client-id", "k0s"))
+	_, err = oidcUser(ctx)
+	require.EqualError(t, err, "--oidc-client-id requires --user")
+
+	require.NoError(t, set.Set("user", "alice"))
+	_, err = oidcUser(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "the OIDC issuer URL is required")
+
+	require.NoError(t, set.Set("oidc-issuer-url", "https://sso.example.com/realms/k0s"))
+	user, err = oidcUser(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &phase.OIDCUser{Name: "alice", IssuerURL: "https://sso.example.com/realms/k0s", ClientID: "k0s"}, user)
+}
+
+func testCACert(t *testing.T) []byte {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "lb-ca"}, IsCA: true}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &pk.PublicKey, pk)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestReadCACert(t *testing.T) {
+	data, err := readCACert("")
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	dir := t.TempDir()
+	ca := testCACert(t)
+	fn := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(fn, ca, 0600))
+	data, err = readCACert(fn)
+	require.NoError(t, err)
+	require.Equal(t, ca, data)
+
+	require.NoError(t, os.WriteFile(fn, []byte("not a certificate"), 0600))
+	_, err = readCACert(fn)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must be a PEM encoded certificate")
+
+	require.NoError(t, os.WriteFile(fn, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")}), 0600))
+	_, err = readCACert(fn)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only certificates are allowed")
+
+	require.NoError(t, os.WriteFile(fn, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}), 0600))
+	_, err = readCACert(fn)
+	require.Error(t, err)
+
+	_, err = readCACert(filepath.Join(dir, "missing.pem"))
+	require.Error(t, err)
+}
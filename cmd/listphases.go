@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/urfave/cli/v2"
+)
+
+// phaseCommands are the commands that accept --skip-phase and --only-phase, by name
+var phaseCommands = map[string]func(*cli.Context, *phase.Manager){
+	"apply":  addApplyPhases,
+	"backup": addBackupPhases,
+	"reset":  addResetPhases,
+}
+
+type phaseListItem struct {
+	Name      string `json:"name"`
+	Mutates   bool   `json:"mutates"`
+	Parallel  bool   `json:"parallel"`
+	Mandatory bool   `json:"mandatory"`
+}
+
+var listPhasesCommand = &cli.Command{
+	Name:      "list-phases",
+	Usage:     "List the phases of a command in the order they are run, for use with --skip-phase and --only-phase",
+	ArgsUsage: "<apply|backup|reset>",
+	Hidden:    true,
+	Flags: []cli.Flag{
+		outputFlag,
+	},
+	Before: actions(validateOutputFlag),
+	Action: func(ctx *cli.Context) error {
+		items, err := listPhases(ctx, ctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		if ctx.String("output") == "json" {
+			out, err := json.MarshalIndent(items, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(ctx.App.Writer, string(out))
+			return nil
+		}
+
+		for _, item := range items {
+			fmt.Fprintln(ctx.App.Writer, item.Name)
+		}
+		return nil
+	},
+}
+
+// listPhases returns the phases the command adds to the phase manager without additional flags given
+func listPhases(ctx *cli.Context, command string) ([]phaseListItem, error) {
+	add, ok := phaseCommands[command]
+	if !ok {
+		names := make([]string, 0, len(phaseCommands))
+		for name := range phaseCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown command %q, the phases can be listed for: %s", command, strings.Join(names, ", "))
+	}
+
+	manager := &phase.Manager{}
+	add(ctx, manager)
+
+	phases := manager.Phases()
+	items := make([]phaseListItem, len(phases))
+	for i, p := range phases {
+		items[i] = phaseListItem{Name: p.Title, Mutates: !p.ReadOnly, Parallel: p.Parallel, Mandatory: p.Mandatory}
+	}
+	return items, nil
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+func TestListPhases(t *testing.T) {
+	ctx := cli.NewContext(App, flag.NewFlagSet("list-phases", flag.ContinueOnError), nil)
+
+	items, err := listPhases(ctx, "apply")
+	require.NoError(t, err)
+	require.Equal(t, phaseListItem{Name: "Check connection ports", Parallel: true}, items[0])
+	require.Equal(t, phaseListItem{Name: "Connect to hosts", Parallel: true, Mandatory: true}, items[1])
+	require.Equal(t, "Disconnect from hosts", items[len(items)-1].Name)
+
+	byName := make(map[string]phaseListItem, len(items))
+	for _, item := range items {
+		byName[item.Name] = item
+	}
+	require.True(t, byName["Install workers"].Mutates)
+	require.True(t, byName["Install workers"].Parallel)
+	require.False(t, byName["Upgrade controllers"].Parallel)
+	require.False(t, byName["Validate hosts"].Mutates)
+	require.False(t, byName["Check k0s ports"].Mutates)
+	require.NotContains(t, byName, "Get admin kubeconfig")
+
+	items, err = listPhases(ctx, "reset")
+	require.NoError(t, err)
+	require.Contains(t, items, phaseListItem{Name: "Reset hosts", Mutates: true, Parallel: true})
+
+	_, err = listPhases(ctx, "status")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "apply, backup, reset")
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// a rotation lock older than this is considered to be left behind by a crashed process
+const staleRotateLock = time.Minute
+
+// rotateLog renames the log file to <fn>.1 (and existing backups to .2, .3, ...) when it has grown over maxSize bytes.
+// Backups over the maxBackups limit are deleted. A lock file is used to make sure only one process
+// performs the rotation when multiple k0sctl processes start at the same time.
+func rotateLog(fn string, maxSize int64, maxBackups int) error {
+	if maxSize <= 0 || !logNeedsRotation(fn, maxSize) {
+		return nil
+	}
+
+	lockfn := fn + ".lock"
+	if stat, err := os.Stat(lockfn); err == nil && time.Since(stat.ModTime()) > staleRotateLock {
+		_ = os.Remove(lockfn)
+	}
+	lock, err := os.OpenFile(lockfn, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			// another process is rotating the log right now
+			return nil
+		}
+		return fmt.Errorf("failed to create log rotation lock %s: %w", lockfn, err)
+	}
+	defer func() {
+		lock.Close()
+		_ = os.Remove(lockfn)
+	}()
+
+	// the file may have been rotated by another process between the first check and acquiring the lock
+	if !logNeedsRotation(fn, maxSize) {
+		return nil
+	}
+
+	for i := maxBackups + 1; ; i++ {
+		old := fmt.Sprintf("%s.%d", fn, i)
+		if _, err := os.Stat(old); err != nil {
+			break
+		}
+		if err := os.Remove(old); err != nil {
+			return fmt.Errorf("failed to remove old log backup %s: %w", old, err)
+		}
+	}
+
+	if maxBackups < 1 {
+		return os.Remove(fn)
+	}
+
+	for i := maxBackups - 1; i > 0; i-- {
+		src := fmt.Sprintf("%s.%d", fn, i)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", fn, i+1)); err != nil {
+			return fmt.Errorf("failed to rotate log backup %s: %w", src, err)
+		}
+	}
+
+	if err := os.Rename(fn, fn+".1"); err != nil {
+		return fmt.Errorf("failed to rotate log %s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func logNeedsRotation(fn string, maxSize int64) bool {
+	stat, err := os.Stat(fn)
+	if err != nil {
+		return false
+	}
+	return stat.Size() > maxSize
+}
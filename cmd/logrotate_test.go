@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateLog(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "k0sctl.log")
+
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(name, []byte(content), 0600))
+	}
+	read := func(name string) string {
+		content, err := os.ReadFile(name)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	write(fn, "small")
+	require.NoError(t, rotateLog(fn, 10, 2))
+	require.Equal(t, "small", read(fn))
+	require.NoFileExists(t, fn+".1")
+
+	write(fn, "first session")
+	require.NoError(t, rotateLog(fn, 10, 2))
+	require.NoFileExists(t, fn)
+	require.Equal(t, "first session", read(fn+".1"))
+
+	write(fn, "second session")
+	require.NoError(t, rotateLog(fn, 10, 2))
+	require.Equal(t, "second session", read(fn+".1"))
+	require.Equal(t, "first session", read(fn+".2"))
+
+	write(fn, "third session")
+	write(fn+".3", "leftover")
+	require.NoError(t, rotateLog(fn, 10, 2))
+	require.Equal(t, "third session", read(fn+".1"))
+	require.Equal(t, "second session", read(fn+".2"))
+	require.NoFileExists(t, fn+".3")
+	require.NoFileExists(t, fn+".lock")
+}
+
+func TestRotateLogLocked(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "k0sctl.log")
+	require.NoError(t, os.WriteFile(fn, []byte("a session in progress"), 0600))
+	require.NoError(t, os.WriteFile(fn+".lock", nil, 0600))
+
+	require.NoError(t, rotateLog(fn, 10, 2))
+	require.FileExists(t, fn)
+	require.NoFileExists(t, fn+".1")
+}
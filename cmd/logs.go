@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// sessionMarker is written to the log file at the start of each k0sctl run
+const sessionMarker = "###### New session ######"
+
+// how often the log file is checked for new lines with --follow
+var followInterval = 500 * time.Millisecond
+
+var logsCommand = &cli.Command{
+	Name:  "logs",
+	Usage: "Output the k0sctl log file starting from the beginning of the last session",
+	Flags: []cli.Flag{
+		logFileFlag,
+		&cli.IntFlag{
+			Name:  "session",
+			Usage: "Output the Nth previous session instead of the last one (0 is the last session)",
+			Value: 0,
+		},
+		&cli.BoolFlag{
+			Name:    "follow",
+			Usage:   "Keep printing new lines as they are appended to the log file",
+			Aliases: []string{"f"},
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		n := ctx.Int("session")
+		if n < 0 {
+			return fmt.Errorf("--session can not be negative")
+		}
+
+		fn := logFilePath(ctx)
+		f, err := os.Open(fn)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("log file %s does not exist", fn)
+			}
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		defer f.Close()
+
+		offset, err := sessionOffset(f, n)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+		if !ctx.Bool("follow") {
+			if _, err := f.Seek(offset, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to read log file: %w", err)
+			}
+			_, err := io.Copy(ctx.App.Writer, f)
+			return err
+		}
+
+		sigctx, cancel := signal.NotifyContext(ctx.Context, os.Interrupt)
+		defer cancel()
+		return followLog(sigctx, fn, offset, ctx.App.Writer)
+	},
+}
+
+// sessionOffset returns the offset of the line holding the session marker of the nth previous session,
+// 0 being the last session in the log
+func sessionOffset(r io.Reader, n int) (int64, error) {
+	var offsets []int64
+	var pos int64
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if bytes.Contains(line, []byte(sessionMarker)) {
+			offsets = append(offsets, pos)
+		}
+		pos += int64(len(line))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read log file: %w", err)
+		}
+	}
+
+	switch {
+	case len(offsets) == 0:
+		return 0, fmt.Errorf("no sessions found in the log file")
+	case n >= len(offsets):
+		return 0, fmt.Errorf("the log file only contains %d sessions", len(offsets))
+	}
+	return offsets[len(offsets)-1-n], nil
+}
+
+// followLog prints the lines appended to the log file after the offset until the context is cancelled.
+// When the file is rotated or truncated, the new file is printed from the beginning.
+func followLog(ctx context.Context, fn string, offset int64, w io.Writer) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer func() { f.Close() }()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := io.Copy(w, f); err != nil {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := os.Stat(fn)
+		if err != nil {
+			// the file is being rotated
+			continue
+		}
+		open, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+		pos, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+
+		switch {
+		case !os.SameFile(open, current):
+			// print what was left in the rotated file before switching to the new one
+			if _, err := io.Copy(w, f); err != nil {
+				return fmt.Errorf("failed to read log file: %w", err)
+			}
+			f.Close()
+			if f, err = os.Open(fn); err != nil {
+				return fmt.Errorf("failed to open log file: %w", err)
+			}
+		case current.Size() < pos:
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to read log file: %w", err)
+			}
+		}
+	}
+}
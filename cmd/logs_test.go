@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionOffset(t *testing.T) {
+	content := strings.Join([]string{
+		"INFO ###### New session ######",
+		"INFO first",
+		`{"level":"info","msg":"###### New session ######"}`,
+		`{"level":"info","msg":"second"}`,
+		"INFO ###### New session ######",
+		"INFO third",
+		"",
+	}, "\n")
+
+	offset, err := sessionOffset(strings.NewReader(content), 0)
+	require.NoError(t, err)
+	require.Equal(t, "INFO ###### New session ######\nINFO third\n", content[offset:])
+
+	offset, err = sessionOffset(strings.NewReader(content), 1)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(content[offset:], `{"level":"info","msg":"###### New session ######"}`))
+
+	offset, err = sessionOffset(strings.NewReader(content), 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), offset)
+
+	_, err = sessionOffset(strings.NewReader(content), 3)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only contains 3 sessions")
+
+	_, err = sessionOffset(strings.NewReader("INFO no markers\n"), 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no sessions found")
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestFollowLog(t *testing.T) {
+	defer func(i time.Duration) { followInterval = i }(followInterval)
+	followInterval = 10 * time.Millisecond
+
+	fn := filepath.Join(t.TempDir(), "k0sctl.log")
+	require.NoError(t, os.WriteFile(fn, []byte("old\n"+sessionMarker+"\n"), 0600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() { done <- followLog(ctx, fn, 4, out) }()
+
+	require.Eventually(t, func() bool { return out.String() == sessionMarker+"\n" }, time.Second, 5*time.Millisecond)
+
+	f, err := os.OpenFile(fn, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString("appended\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Eventually(t, func() bool { return strings.HasSuffix(out.String(), "appended\n") }, time.Second, 5*time.Millisecond)
+
+	// rotation replaces the file, the new file is followed from the beginning
+	require.NoError(t, os.Rename(fn, fn+".1"))
+	require.NoError(t, os.WriteFile(fn, []byte("rotated\n"), 0600))
+	require.Eventually(t, func() bool { return strings.HasSuffix(out.String(), "appended\nrotated\n") }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/k0sproject/k0sctl/phase"
+)
+
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatMetrics renders the phase manager results in the prometheus text exposition format. Skipped
+// phases are left out and the durations of phases with the same title are summed.
+func formatMetrics(results []phase.Result, duration time.Duration, err error, now time.Time) string {
+	var titles []string
+	durations := make(map[string]time.Duration)
+	for _, r := range results {
+		if r.Skipped {
+			continue
+		}
+		if _, ok := durations[r.Title]; !ok {
+			titles = append(titles, r.Title)
+		}
+		durations[r.Title] += r.Duration
+	}
+
+	var b strings.Builder
+	b.WriteString("# HELP k0sctl_phase_duration_seconds Duration of the k0sctl apply phases in seconds.\n")
+	b.WriteString("# TYPE k0sctl_phase_duration_seconds gauge\n")
+	for _, title := range titles {
+		fmt.Fprintf(&b, "k0sctl_phase_duration_seconds{phase=\"%s\"} %g\n", metricsLabelEscaper.Replace(title), durations[title].Seconds())
+	}
+
+	b.WriteString("# HELP k0sctl_duration_seconds Total duration of the k0sctl apply run in seconds.\n")
+	b.WriteString("# TYPE k0sctl_duration_seconds gauge\n")
+	fmt.Fprintf(&b, "k0sctl_duration_seconds %g\n", duration.Seconds())
+
+	success := 1
+	if err != nil {
+		success = 0
+	}
+	b.WriteString("# HELP k0sctl_success Whether the k0sctl apply run succeeded (1) or failed (0).\n")
+	b.WriteString("# TYPE k0sctl_success gauge\n")
+	fmt.Fprintf(&b, "k0sctl_success %d\n", success)
+
+	b.WriteString("# HELP k0sctl_last_run_timestamp_seconds Unix time of the end of the k0sctl apply run.\n")
+	b.WriteString("# TYPE k0sctl_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "k0sctl_last_run_timestamp_seconds %d\n", now.Unix())
+
+	return b.String()
+}
+
+// writeMetrics writes the metrics for the prometheus node_exporter textfile collector. The file is
+// written to a temporary file in the same directory and renamed so that a partial file is never read.
+func writeMetrics(fn string, results []phase.Result, duration time.Duration, runErr error) error {
+	f, err := os.CreateTemp(filepath.Dir(fn), "."+filepath.Base(fn)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(formatMetrics(results, duration, runErr, time.Now())); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), fn); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
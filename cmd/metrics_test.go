@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatMetrics(t *testing.T) {
+	results := []phase.Result{
+		{Title: "Connect to hosts", Duration: 1500 * time.Millisecond},
+		{Title: "Upgrade workers", Skipped: true},
+		{Title: `Run "before" hooks`, Duration: time.Second},
+		{Title: `Run "before" hooks`, Duration: time.Second, Err: fmt.Errorf("hook failed")},
+	}
+
+	out := formatMetrics(results, 5*time.Second, fmt.Errorf("hook failed"), time.Unix(1600000000, 0))
+	require.Contains(t, out, "k0sctl_phase_duration_seconds{phase=\"Connect to hosts\"} 1.5\n")
+	require.Contains(t, out, "k0sctl_phase_duration_seconds{phase=\"Run \\\"before\\\" hooks\"} 2\n")
+	require.NotContains(t, out, "Upgrade workers")
+	require.Contains(t, out, "k0sctl_duration_seconds 5\n")
+	require.Contains(t, out, "k0sctl_success 0\n")
+	require.Contains(t, out, "k0sctl_last_run_timestamp_seconds 1600000000\n")
+}
+
+func TestWriteMetrics(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "k0sctl.prom")
+	require.NoError(t, os.WriteFile(fn, []byte("old"), 0644))
+
+	require.NoError(t, writeMetrics(fn, []phase.Result{{Title: "Connect to hosts", Duration: time.Second}}, time.Second, nil))
+	content, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "k0sctl_success 1\n")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temporary file was left behind")
+}
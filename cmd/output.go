@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/phase"
+)
+
+type phaseSummary struct {
+	Title    string  `json:"title"`
+	Duration float64 `json:"duration"`
+	Skipped  bool    `json:"skipped,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+type hostSummary struct {
+	Address    string `json:"address"`
+	Protocol   string `json:"protocol"`
+	Role       string `json:"role"`
+	K0sVersion string `json:"k0sVersion,omitempty"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// runSummary is the document printed at the end of a run when --output json is used
+type runSummary struct {
+	Success  bool           `json:"success"`
+	Duration float64        `json:"duration"`
+	Phases   []phaseSummary `json:"phases"`
+	Hosts    []hostSummary  `json:"hosts"`
+	Error    string         `json:"error,omitempty"`
+	DryRun   bool           `json:"dryRun,omitempty"`
+	Changes  []string       `json:"changes,omitempty"`
+}
+
+// newRunSummary builds a summary of the phase manager results. Durations are in seconds.
+// Hosts are marked as "failed" when the failing phase reported an error for them and as
+// "incomplete" when the run failed for other reasons.
+func newRunSummary(c *config.Cluster, results []phase.Result, duration time.Duration, err error) *runSummary {
+	summary := &runSummary{
+		Success:  err == nil,
+		Duration: duration.Seconds(),
+		Phases:   make([]phaseSummary, 0, len(results)),
+		Hosts:    make([]hostSummary, 0, len(c.Spec.Hosts)),
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+
+	hostErrors := make(map[string]error)
+	for _, r := range results {
+		ps := phaseSummary{Title: r.Title, Duration: r.Duration.Seconds(), Skipped: r.Skipped}
+		if r.Err != nil {
+			ps.Error = r.Err.Error()
+			var perr *cluster.ParallelError
+			if errors.As(r.Err, &perr) {
+				for _, he := range perr.Errors {
+					hostErrors[he.Host] = he.Err
+				}
+			}
+		}
+		summary.Phases = append(summary.Phases, ps)
+	}
+
+	for _, h := range c.Spec.Hosts {
+		hs := hostSummary{
+			Address:    h.Address(),
+			Protocol:   h.Protocol(),
+			Role:       h.Role,
+			K0sVersion: h.Metadata.K0sRunningVersion,
+			Status:     "succeeded",
+		}
+		if herr, ok := hostErrors[h.String()]; ok {
+			hs.Status = "failed"
+			hs.Error = herr.Error()
+		} else if err != nil {
+			hs.Status = "incomplete"
+		}
+		summary.Hosts = append(summary.Hosts, hs)
+	}
+
+	return summary
+}
+
+func printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
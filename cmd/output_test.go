@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSummary(t *testing.T) {
+	h1 := &cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+	h2 := &cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2", Port: 22}}}
+	c := &config.Cluster{Spec: &cluster.Spec{Hosts: cluster.Hosts{h1, h2}}}
+
+	err := &cluster.ParallelError{Errors: []cluster.HostError{{Host: h2.String(), Err: fmt.Errorf("no space left")}}}
+	results := []phase.Result{
+		{Title: "Connect to hosts", Duration: time.Second},
+		{Title: "Install workers", Duration: 2 * time.Second, Err: err},
+	}
+
+	summary := newRunSummary(c, results, 3*time.Second, err)
+	require.False(t, summary.Success)
+	require.Equal(t, 3.0, summary.Duration)
+	require.Len(t, summary.Phases, 2)
+	require.Equal(t, "Install workers", summary.Phases[1].Title)
+	require.Equal(t, err.Error(), summary.Phases[1].Error)
+	require.Equal(t, "incomplete", summary.Hosts[0].Status)
+	require.Equal(t, "failed", summary.Hosts[1].Status)
+	require.Equal(t, "no space left", summary.Hosts[1].Error)
+
+	summary = newRunSummary(c, results[:1], time.Second, nil)
+	require.True(t, summary.Success)
+	require.Equal(t, "succeeded", summary.Hosts[0].Status)
+}
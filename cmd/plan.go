@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/phase"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// runPlan gathers the facts from the hosts and prints the k0s version changes apply would make without
+// making any changes
+func runPlan(ctx *cli.Context, c *config.Cluster) error {
+	plan := &phase.Plan{
+		AllowDowngrade:   ctx.Bool("disable-downgrade-check"),
+		AllowVersionSkip: ctx.Bool("allow-version-skip"),
+	}
+	manager := phase.Manager{Config: c, Concurrency: ctx.Int("concurrency")}
+	manager.AddPhase(
+		connectPhase(ctx),
+		&phase.DetectOS{},
+		&phase.GatherFacts{},
+		&phase.GatherK0sFacts{},
+		// the plan reports the blocked changes instead of failing on them
+		&phase.ValidateFacts{SkipDowngradeCheck: true, AllowVersionSkip: true},
+		plan,
+		&phase.Disconnect{},
+	)
+
+	if err := manager.RunContext(ctx.Context); err != nil {
+		return err
+	}
+
+	if ctx.String("output") == "json" {
+		return printJSON(plan.Hosts)
+	}
+	if err := writePlanTable(os.Stdout, plan.Hosts); err != nil {
+		return err
+	}
+	for _, h := range plan.Hosts {
+		if h.Blocked != "" {
+			log.Warnf("%s: %s", h.Address, h.Blocked)
+		}
+	}
+	return nil
+}
+
+// writePlanTable writes the planned changes as a table
+func writePlanTable(w io.Writer, hosts []*phase.HostPlan) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tCURRENT K0S\tTARGET K0S\tACTION")
+	for _, h := range hosts {
+		action := h.Action
+		if h.Blocked != "" {
+			action += " (blocked)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", h.Address, dash(h.Current), h.Target, action)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePlanTable(t *testing.T) {
+	hosts := []*phase.HostPlan{
+		{Address: "10.0.0.1", Role: "controller", Current: "1.24.1+k0s.0", Target: "1.25.0+k0s.0", Action: phase.PlanUpgrade},
+		{Address: "10.0.0.2", Role: "worker", Target: "1.25.0+k0s.0", Action: phase.PlanInstall},
+		{Address: "10.0.0.3", Role: "worker", Current: "1.26.0+k0s.0", Target: "1.25.0+k0s.0", Action: phase.PlanDowngrade, Blocked: "can't perform a downgrade"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writePlanTable(&buf, hosts))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, []string{"HOST", "CURRENT", "K0S", "TARGET", "K0S", "ACTION"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"10.0.0.1", "1.24.1+k0s.0", "1.25.0+k0s.0", "upgrade"}, strings.Fields(lines[1]))
+	require.Equal(t, []string{"10.0.0.2", "-", "1.25.0+k0s.0", "install"}, strings.Fields(lines[2]))
+	require.Equal(t, []string{"10.0.0.3", "1.26.0+k0s.0", "1.25.0+k0s.0", "downgrade", "(blocked)"}, strings.Fields(lines[3]))
+}
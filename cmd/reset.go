@@ -3,10 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/k0sproject/k0sctl/analytics"
 	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
 	"github.com/k0sproject/k0sctl/phase"
 	log "github.com/sirupsen/logrus"
 
@@ -21,38 +23,53 @@ var resetCommand = &cli.Command{
 	Usage: "Remove traces of k0s from all of the hosts",
 	Flags: []cli.Flag{
 		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		sudoPasswordFlag,
+		sshProxyFlag,
+		sshKeepAliveFlag,
+		connectTimeoutFlag,
+		connectRetriesFlag,
+		connectRetryIntervalFlag,
+		concurrencyFlag,
+		skipPhaseFlag,
+		onlyPhaseFlag,
+		errorFileFlag,
+		auditLogFlag,
 		debugFlag,
 		traceFlag,
+		verboseCommandsFlag,
 		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
 		analyticsFlag,
+		&cli.StringFlag{
+			Name:  "role",
+			Usage: "Only reset the hosts with the role, \"controller\" (including controller+worker) or \"worker\". The worker nodes are drained and deleted from the cluster.",
+		},
 		&cli.BoolFlag{
-			Name:    "force",
+			Name:    "confirm",
 			Usage:   "Don't ask for confirmation",
-			Aliases: []string{"f"},
+			Aliases: []string{"force", "f"},
 		},
 	},
-	Before: actions(initLogging, initConfig, initAnalytics, displayCopyright),
+	Before: actions(validateConcurrencyFlag, validateResetRoleFlag, validateSSHFlags, initLogging, initConfig, handleSignals, initAnalytics, displayCopyright),
 	After: func(ctx *cli.Context) error {
 		analytics.Client.Close()
 		return nil
 	},
 	Action: func(ctx *cli.Context) error {
-		if !ctx.Bool("force") {
-			if !isatty.IsTerminal(os.Stdout.Fd()) {
-				return fmt.Errorf("reset requires --force")
-			}
-			confirmed := false
-			prompt := &survey.Confirm{
-				Message: "Going to reset all of the hosts, which will destroy all configuration and data, Are you sure?",
-			}
-			_ = survey.AskOne(prompt, &confirmed)
-			if !confirmed {
-				return fmt.Errorf("confirmation or --force required to proceed")
-			}
-		}
-
 		start := time.Now()
-		content := ctx.String("config")
+		content := configContent(ctx)
 
 		c := config.Cluster{}
 		if err := yaml.UnmarshalStrict([]byte(content), &c); err != nil {
@@ -63,24 +80,37 @@ var resetCommand = &cli.Command{
 			return err
 		}
 
-		manager := phase.Manager{Config: &c}
+		hosts, err := resetHosts(&c, ctx.String("role"))
+		if err != nil {
+			return err
+		}
+
+		if !ctx.Bool("confirm") {
+			if err := confirmReset(&c, hosts); err != nil {
+				return err
+			}
+		}
+
+		manager := phase.Manager{Config: &c, Concurrency: ctx.Int("concurrency"), SkipPhases: ctx.StringSlice("skip-phase"), OnlyPhases: ctx.StringSlice("only-phase")}
 
-		manager.AddPhase(
-			&phase.Connect{},
-			&phase.DetectOS{},
-			&phase.PrepareHosts{},
-			&phase.GatherK0sFacts{},
-			&phase.RunHooks{Stage: "before", Action: "reset"},
-			&phase.Reset{},
-			&phase.RunHooks{Stage: "after", Action: "reset"},
-			&phase.Disconnect{},
-		)
+		addResetPhases(ctx, &manager)
+
+		stopAudit, err := startAuditLog(ctx, &manager)
+		if err != nil {
+			return err
+		}
+		defer stopAudit()
 
 		if err := analytics.Client.Publish("reset-start", map[string]interface{}{}); err != nil {
 			return err
 		}
 
-		if err := manager.Run(); err != nil {
+		if err := manager.RunContext(ctx.Context); err != nil {
+			if ctx.String("error-file") != "" {
+				if ferr := writeErrorFile(ctx, &c, manager.Results, err); ferr != nil {
+					log.Warn(ferr.Error())
+				}
+			}
 			_ = analytics.Client.Publish("reset-failure", map[string]interface{}{"clusterID": manager.Config.Spec.K0s.Metadata.ClusterID})
 			return err
 		}
@@ -94,3 +124,87 @@ var resetCommand = &cli.Command{
 		return nil
 	},
 }
+
+// addResetPhases adds the phases of the reset command to the manager
+func addResetPhases(ctx *cli.Context, manager *phase.Manager) {
+	role := ctx.String("role")
+	only := phase.ResetRoleFilter(role)
+	manager.AddPhase(
+		connectPhase(ctx),
+		&phase.DetectOS{},
+		&phase.PrepareHosts{},
+		&phase.GatherK0sFacts{},
+		&phase.RunHooks{Stage: "before", Action: "reset", Only: only},
+		&phase.Reset{Role: role},
+		&phase.RunHooks{Stage: "after", Action: "reset", Only: only},
+		&phase.Disconnect{},
+	)
+}
+
+func validateResetRoleFlag(ctx *cli.Context) error {
+	switch ctx.String("role") {
+	case "", "controller", "worker":
+		return nil
+	default:
+		return fmt.Errorf("invalid --role %q, must be controller or worker", ctx.String("role"))
+	}
+}
+
+// resetHosts returns the hosts reset with the role. The controller+worker hosts are left out when
+// resetting the workers, so that the control plane is never reset by accident.
+func resetHosts(c *config.Cluster, role string) (cluster.Hosts, error) {
+	only := phase.ResetRoleFilter(role)
+	if only == nil {
+		return c.Spec.Hosts, nil
+	}
+	hosts := c.Spec.Hosts.Filter(only)
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts with the %s role to reset", role)
+	}
+
+	switch role {
+	case "worker":
+		for _, h := range c.Spec.Hosts {
+			if h.Role == "controller+worker" {
+				log.Warnf("%s: not resetting the controller+worker host, use --role controller to reset it", h)
+			}
+		}
+	case "controller":
+		if len(hosts) < len(c.Spec.Hosts) {
+			log.Warnf("resetting only the controllers, the workers will lose their connection to the cluster")
+		}
+	}
+	return hosts, nil
+}
+
+// confirmReset lists the hosts that are going to be reset and asks the user to type in the cluster name
+// or "yes" to confirm
+func confirmReset(c *config.Cluster, hosts cluster.Hosts) error {
+	if !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return fmt.Errorf("not running in an interactive terminal, use --confirm to reset without confirmation")
+	}
+
+	fmt.Printf("Going to reset the following hosts of cluster %s, which will destroy all configuration and data:\n", c.Metadata.Name)
+	for _, h := range hosts {
+		fmt.Printf("  - %s (%s)\n", h, h.Role)
+	}
+
+	var answer string
+	prompt := &survey.Input{
+		Message: fmt.Sprintf("Type the cluster name %q or \"yes\" to proceed:", c.Metadata.Name),
+	}
+	if err := survey.AskOne(prompt, &answer); err != nil {
+		return fmt.Errorf("confirmation failed: %w", err)
+	}
+
+	if !resetConfirmed(answer, c.Metadata.Name) {
+		return fmt.Errorf("reset not confirmed, use --confirm to reset without confirmation")
+	}
+
+	return nil
+}
+
+func resetConfirmed(answer, clusterName string) bool {
+	answer = strings.TrimSpace(answer)
+	return answer == "yes" || (clusterName != "" && answer == clusterName)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetConfirmed(t *testing.T) {
+	require.True(t, resetConfirmed("yes", "k0s-cluster"))
+	require.True(t, resetConfirmed(" k0s-cluster\n", "k0s-cluster"))
+	require.False(t, resetConfirmed("y", "k0s-cluster"))
+	require.False(t, resetConfirmed("", ""))
+	require.False(t, resetConfirmed("other-cluster", "k0s-cluster"))
+}
+
+func TestResetHosts(t *testing.T) {
+	host := func(role, addr string) *cluster.Host {
+		return &cluster.Host{Role: role, Connection: rig.Connection{SSH: &rig.SSH{Address: addr, Port: 22}}}
+	}
+	controller := host("controller", "10.0.0.1")
+	controllerWorker := host("controller+worker", "10.0.0.2")
+	worker := host("worker", "10.0.0.3")
+	c := &config.Cluster{Spec: &cluster.Spec{Hosts: cluster.Hosts{controller, controllerWorker, worker}}}
+
+	hosts, err := resetHosts(c, "")
+	require.NoError(t, err)
+	require.Equal(t, c.Spec.Hosts, hosts)
+
+	hosts, err = resetHosts(c, "controller")
+	require.NoError(t, err)
+	require.Equal(t, cluster.Hosts{controller, controllerWorker}, hosts)
+
+	hosts, err = resetHosts(c, "worker")
+	require.NoError(t, err)
+	require.Equal(t, cluster.Hosts{worker}, hosts)
+
+	c.Spec.Hosts = cluster.Hosts{controller, controllerWorker}
+	_, err = resetHosts(c, "worker")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no hosts with the worker role")
+}
@@ -8,17 +8,42 @@ import (
 var App = &cli.App{
 	Name:  "k0sctl",
 	Usage: "k0s cluster management tool",
+	// needed for the shell completion scripts, see the completion command
+	EnableBashCompletion: true,
 	Flags: []cli.Flag{
 		debugFlag,
 		traceFlag,
 		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
 	},
 	Commands: []*cli.Command{
 		versionCommand,
 		applyCommand,
 		kubeconfigCommand,
+		tokenCommand,
 		initCommand,
 		resetCommand,
 		backupCommand,
+		describeCommand,
+		statusCommand,
+		connectCommand,
+		configCommand,
+		schemaCommand,
+		listPhasesCommand,
+		completionCommand,
+		logsCommand,
+	},
+	After: func(ctx *cli.Context) error {
+		stopSignals()
+		stopKeyAgent()
+		stopKeyFifos()
+		return nil
 	},
 }
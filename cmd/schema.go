@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/urfave/cli/v2"
+)
+
+var schemaCommand = &cli.Command{
+	Name:  "schema",
+	Usage: "Output the JSON Schema of the k0sctl configuration for editor integration",
+	Action: func(ctx *cli.Context) error {
+		out, err := json.MarshalIndent(config.Schema(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(ctx.App.Writer, string(out))
+		return nil
+	},
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// interruptedExitCode is the exit code when k0sctl is forced to exit by a second signal
+const interruptedExitCode = 130
+
+// signalStop is closed by stopSignals to stop the signal handler started by handleSignals
+var signalStop chan struct{}
+
+// handleSignals cancels the context of the command on SIGINT or SIGTERM. The running phase stops at a
+// safe point and the connections to the hosts are closed. A second signal exits immediately.
+func handleSignals(ctx *cli.Context) error {
+	sigctx, cancel := context.WithCancel(ctx.Context)
+	ctx.Context = sigctx
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	stop := make(chan struct{})
+	signalStop = stop
+
+	go func() {
+		defer signal.Stop(sigs)
+		var sig os.Signal
+		select {
+		case sig = <-sigs:
+		case <-stop:
+			return
+		}
+		log.Warnf("received %s, interrupting - send the signal again to exit immediately", sig)
+		cancel()
+
+		select {
+		case sig = <-sigs:
+		case <-stop:
+			return
+		}
+		log.Errorf("received %s again, exiting", sig)
+		stopKeyAgent()
+		stopKeyFifos()
+		os.Exit(interruptedExitCode)
+	}()
+
+	return nil
+}
+
+// stopSignals stops the signal handler started by handleSignals
+func stopSignals() {
+	if signalStop == nil {
+		return
+	}
+	close(signalStop)
+	signalStop = nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+func TestHandleSignals(t *testing.T) {
+	ctx := cli.NewContext(&cli.App{}, flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	ctx.Context = context.Background()
+	require.NoError(t, handleSignals(ctx))
+	defer stopSignals()
+	require.NoError(t, ctx.Context.Err())
+
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skipf("sending signals is not supported: %s", err.Error())
+	}
+
+	select {
+	case <-ctx.Context.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context was not cancelled")
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var keyAgentDir string
+
+// startKeyAgent serves the private keys through an in-process ssh agent. The ssh client only reads keys
+// from files or from the agent in SSH_AUTH_SOCK, this way the inline keys never touch the disk. An
+// existing agent is chained after the inline keys so that its keys can still be used.
+func startKeyAgent(keys []string) error {
+	keyring := agent.NewKeyring()
+	for _, key := range keys {
+		pk, err := ssh.ParseRawPrivateKey([]byte(key))
+		if err != nil {
+			return fmt.Errorf("failed to parse ssh keyData: %w", err)
+		}
+		if err := keyring.Add(agent.AddedKey{PrivateKey: pk, Comment: "k0sctl keyData"}); err != nil {
+			return fmt.Errorf("failed to add ssh keyData to the agent: %w", err)
+		}
+	}
+
+	ka := &keyAgent{ExtendedAgent: keyring.(agent.ExtendedAgent)}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			log.Warnf("can't connect to SSH agent auth socket %s: %s", sock, err.Error())
+		} else {
+			ka.upstream = agent.NewClient(conn)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "k0sctl-agent")
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return err
+	}
+	keyAgentDir = dir
+	sock := filepath.Join(dir, "agent.sock")
+
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		stopKeyAgent()
+		return fmt.Errorf("failed to start the ssh agent for keyData: %w", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(ka, conn)
+			}()
+		}
+	}()
+
+	log.Tracef("serving %d ssh keyData key(s) through agent socket %s", len(keys), sock)
+	// the ssh connections read the auth socket from the environment
+	return os.Setenv("SSH_AUTH_SOCK", sock)
+}
+
+// stopKeyAgent removes the agent socket created by startKeyAgent
+func stopKeyAgent() {
+	if keyAgentDir == "" {
+		return
+	}
+	if err := os.RemoveAll(keyAgentDir); err != nil {
+		log.Debugf("failed to remove ssh agent directory %s: %s", keyAgentDir, err.Error())
+	}
+	keyAgentDir = ""
+}
+
+// keyAgent serves the keyring keys first and falls back to the upstream agent
+type keyAgent struct {
+	agent.ExtendedAgent
+	upstream agent.ExtendedAgent
+}
+
+func (a *keyAgent) List() ([]*agent.Key, error) {
+	keys, err := a.ExtendedAgent.List()
+	if err != nil || a.upstream == nil {
+		return keys, err
+	}
+	upstream, err := a.upstream.List()
+	if err != nil {
+		log.Debugf("failed to list the keys of the ssh agent: %s", err.Error())
+		return keys, nil
+	}
+	return append(keys, upstream...), nil
+}
+
+func (a *keyAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+func (a *keyAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	sig, err := a.ExtendedAgent.SignWithFlags(key, data, flags)
+	if err != nil && a.upstream != nil {
+		return a.upstream.SignWithFlags(key, data, flags)
+	}
+	return sig, err
+}
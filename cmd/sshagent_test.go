@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestKeyAgent(t *testing.T) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(pk)
+	require.NoError(t, err)
+	key := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	require.NoError(t, startKeyAgent([]string{key}))
+	dir := keyAgentDir
+	defer stopKeyAgent()
+
+	conn, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	signers, err := agent.NewClient(conn).Signers()
+	require.NoError(t, err)
+	require.Len(t, signers, 1)
+
+	sig, err := signers[0].Sign(rand.Reader, []byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, signers[0].PublicKey().Verify([]byte("data"), sig))
+
+	stopKeyAgent()
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,114 @@
+// +build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// keyFifosSupported is true when the inline keys can be served through named pipes
+const keyFifosSupported = true
+
+var (
+	keyFifoDir string
+	keyFifos   []*keyFifo
+)
+
+// keyFifo is a named pipe that gives the key to each reader
+type keyFifo struct {
+	path    string
+	key     string
+	stopped int32
+}
+
+// keyFifoPath creates a named pipe serving the key and returns its path. The pipe is used as the key file
+// of a single ssh connection, so that each host is only offered its own key. The key is passed through
+// the pipe and never touches the disk.
+func keyFifoPath(key string) (string, error) {
+	if keyFifoDir == "" {
+		dir, err := os.MkdirTemp("", "k0sctl-keys")
+		if err != nil {
+			return "", err
+		}
+		if err := os.Chmod(dir, 0700); err != nil {
+			return "", err
+		}
+		keyFifoDir = dir
+	}
+
+	path := filepath.Join(keyFifoDir, fmt.Sprintf("key-%d", len(keyFifos)))
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		return "", fmt.Errorf("failed to create a pipe for ssh keyData: %w", err)
+	}
+
+	f := &keyFifo{path: path, key: key}
+	keyFifos = append(keyFifos, f)
+	go f.serve()
+
+	log.Tracef("serving ssh keyData through pipe %s", path)
+	return path, nil
+}
+
+func (f *keyFifo) isStopped() bool {
+	return atomic.LoadInt32(&f.stopped) == 1
+}
+
+func (f *keyFifo) serve() {
+	for !f.isStopped() {
+		// blocks until a reader opens the pipe
+		w, err := os.OpenFile(f.path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		if f.isStopped() {
+			w.Close()
+			return
+		}
+		// the next reader gets a new pipe, otherwise the next write could end up to the current reader
+		if err := f.replace(); err != nil {
+			log.Debugf("failed to replace ssh key pipe %s: %s", f.path, err.Error())
+			w.Close()
+			return
+		}
+		_, _ = w.WriteString(f.key)
+		w.Close()
+	}
+}
+
+// replace atomically replaces the pipe with a new one
+func (f *keyFifo) replace() error {
+	tmp := f.path + ".new"
+	if err := syscall.Mkfifo(tmp, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+func (f *keyFifo) stop() {
+	atomic.StoreInt32(&f.stopped, 1)
+	// opening the pipe for reading releases the writer waiting for a reader
+	if r, err := os.OpenFile(f.path, os.O_RDONLY|syscall.O_NONBLOCK, 0); err == nil {
+		r.Close()
+	}
+}
+
+// stopKeyFifos removes the named pipes created by keyFifoPath
+func stopKeyFifos() {
+	for _, f := range keyFifos {
+		f.stop()
+	}
+	keyFifos = nil
+	if keyFifoDir == "" {
+		return
+	}
+	if err := os.RemoveAll(keyFifoDir); err != nil {
+		log.Debugf("failed to remove ssh key directory %s: %s", keyFifoDir, err.Error())
+	}
+	keyFifoDir = ""
+}
@@ -0,0 +1,32 @@
+// +build !windows
+
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFifo(t *testing.T) {
+	path, err := keyFifoPath("key one")
+	require.NoError(t, err)
+	path2, err := keyFifoPath("key two")
+	require.NoError(t, err)
+	dir := keyFifoDir
+	defer stopKeyFifos()
+
+	for i := 0; i < 3; i++ {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "key one", string(data))
+	}
+	data, err := os.ReadFile(path2)
+	require.NoError(t, err)
+	require.Equal(t, "key two", string(data))
+
+	stopKeyFifos()
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+}
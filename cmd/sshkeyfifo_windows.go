@@ -0,0 +1,13 @@
+// +build windows
+
+package cmd
+
+// keyFifosSupported is false, the inline keys are served through the ssh agent
+const keyFifosSupported = false
+
+// keyFifoPath returns an empty path, named pipes can't be used as key files on windows
+func keyFifoPath(_ string) (string, error) {
+	return "", nil
+}
+
+func stopKeyFifos() {}
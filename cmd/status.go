@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/k0sproject/k0sctl/analytics"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+var statusCommand = &cli.Command{
+	Name:  "status",
+	Usage: "Check the health of the cluster nodes, exits with an error when a node is not healthy",
+	Flags: []cli.Flag{
+		outputFlag,
+		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		sudoPasswordFlag,
+		sshProxyFlag,
+		sshKeepAliveFlag,
+		connectTimeoutFlag,
+		concurrencyFlag,
+		debugFlag,
+		traceFlag,
+		verboseCommandsFlag,
+		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
+		analyticsFlag,
+	},
+	Before: actions(validateConcurrencyFlag, validateSSHFlags, validateOutputFlag, initSilentLogging, initConfig, handleSignals, initAnalytics),
+	After: func(ctx *cli.Context) error {
+		analytics.Client.Close()
+		return nil
+	},
+	Action: func(ctx *cli.Context) error {
+		content := configContent(ctx)
+		c := config.Cluster{}
+		if err := yaml.UnmarshalStrict([]byte(content), &c); err != nil {
+			return err
+		}
+
+		if err := c.Validate(); err != nil {
+			return err
+		}
+
+		status := &phase.ClusterStatus{}
+		manager := phase.Manager{Config: &c, Concurrency: ctx.Int("concurrency")}
+		manager.AddPhase(
+			status,
+			&phase.Disconnect{},
+		)
+
+		if err := manager.RunContext(ctx.Context); err != nil {
+			return err
+		}
+
+		if ctx.String("output") == "json" {
+			if err := printJSON(status.Nodes); err != nil {
+				return err
+			}
+		} else if err := writeStatusTable(os.Stdout, status.Nodes); err != nil {
+			return err
+		}
+
+		var unhealthy int
+		for _, n := range status.Nodes {
+			if !n.Healthy {
+				unhealthy++
+			}
+		}
+		if unhealthy > 0 {
+			return fmt.Errorf("%d of %d nodes are not healthy", unhealthy, len(status.Nodes))
+		}
+
+		return nil
+	},
+}
+
+// writeStatusTable writes the node statuses as a table
+func writeStatusTable(w io.Writer, nodes []*phase.NodeStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tHOSTNAME\tROLE\tK0S\tREADY\tHEALTHY")
+	for _, n := range nodes {
+		healthy := "yes"
+		if !n.Healthy {
+			healthy = "no"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", dash(n.Address), dash(n.Hostname), n.Role, dash(n.K0s), dash(n.Ready), healthy)
+	}
+	return tw.Flush()
+}
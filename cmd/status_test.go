@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/phase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteStatusTable(t *testing.T) {
+	nodes := []*phase.NodeStatus{
+		{Address: "10.0.0.1", Hostname: "ctrl", Role: "controller", K0s: phase.K0sRunning, Healthy: true},
+		{Address: "10.0.0.2", Hostname: "worker", Role: "worker", K0s: phase.K0sRunning, Ready: "False"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeStatusTable(&buf, nodes))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"ADDRESS", "HOSTNAME", "ROLE", "K0S", "READY", "HEALTHY"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{"10.0.0.1", "ctrl", "controller", "running", "-", "yes"}, strings.Fields(lines[1]))
+	require.Equal(t, []string{"10.0.0.2", "worker", "worker", "running", "False", "no"}, strings.Fields(lines[2]))
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/k0sproject/k0sctl/analytics"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/config/sshconfig"
+	"github.com/k0sproject/k0sctl/phase"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+var tokenCommand = &cli.Command{
+	Name:  "token",
+	Usage: "Join token related subcommands",
+	Subcommands: []*cli.Command{
+		tokenRotateCommand,
+	},
+}
+
+var tokenRotateCommand = &cli.Command{
+	Name:  "rotate",
+	Usage: "Create a new join token on a controller and output it",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "role",
+			Usage: "Role of the nodes that can join with the token (controller, worker)",
+			Value: "worker",
+		},
+		&cli.DurationFlag{
+			Name:  "expiry",
+			Usage: "Time until the token expires, such as 1h. Use 0 for a token that does not expire",
+			Value: 24 * time.Hour,
+		},
+		&cli.StringFlag{
+			Name:      "output",
+			Aliases:   []string{"o"},
+			Usage:     "Write the token to a file instead of stdout",
+			TakesFile: true,
+		},
+		configFlag,
+		configTimeoutFlag,
+		noEnvSubstitutionFlag,
+		sshConfigFlag,
+		sshPortFlag,
+		sshPassphraseFlag,
+		sudoPasswordFlag,
+		sshProxyFlag,
+		sshKeepAliveFlag,
+		connectTimeoutFlag,
+		connectRetriesFlag,
+		connectRetryIntervalFlag,
+		auditLogFlag,
+		debugFlag,
+		traceFlag,
+		verboseCommandsFlag,
+		redactFlag,
+		redactPatternFlag,
+		logFormatFlag,
+		fileLogFormatFlag,
+		colorFlag,
+		logFileFlag,
+		logMaxSizeFlag,
+		logMaxBackupsFlag,
+		noFileLogFlag,
+		analyticsFlag,
+	},
+	Before: actions(validateTokenFlags, validateSSHFlags, initSilentLogging, initConfig, handleSignals, initAnalytics),
+	After: func(ctx *cli.Context) error {
+		analytics.Client.Close()
+		return nil
+	},
+	Action: func(ctx *cli.Context) error {
+		content := configContent(ctx)
+		c := config.Cluster{}
+		if err := yaml.UnmarshalStrict([]byte(content), &c); err != nil {
+			return err
+		}
+
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		// only the controller creating the token needs to be connected
+		c.Spec.Hosts = cluster.Hosts{c.Spec.K0sLeader()}
+		manager := phase.Manager{Config: &c}
+
+		token := &phase.CreateToken{Role: ctx.String("role"), Expiry: ctx.Duration("expiry")}
+		manager.AddPhase(
+			connectPhase(ctx),
+			&phase.DetectOS{},
+			token,
+			&phase.Disconnect{},
+		)
+
+		stopAudit, err := startAuditLog(ctx, &manager)
+		if err != nil {
+			return err
+		}
+		defer stopAudit()
+
+		err = manager.RunContext(ctx.Context)
+		if token.Token != "" {
+			addRedactSecret(token.Token)
+		}
+		if err != nil {
+			return err
+		}
+
+		if ctx.String("output") == "" {
+			fmt.Println(token.Token)
+			return nil
+		}
+
+		return writeToken(ctx.String("output"), token.Token)
+	},
+}
+
+func validateTokenFlags(ctx *cli.Context) error {
+	switch ctx.String("role") {
+	case "controller", "worker":
+	default:
+		return fmt.Errorf("invalid --role %q, must be controller or worker", ctx.String("role"))
+	}
+	if ctx.Duration("expiry") < 0 {
+		return fmt.Errorf("invalid --expiry %s, must not be negative", ctx.Duration("expiry"))
+	}
+	return nil
+}
+
+// writeToken writes the token to a file with 0600 permissions
+func writeToken(fn, token string) error {
+	fn, err := sshconfig.ExpandHome(fn)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for the token: %w", err)
+	}
+
+	if err := os.WriteFile(fn, []byte(token+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write the token: %w", err)
+	}
+	log.Infof("join token written to %s", fn)
+	return nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+func TestValidateTokenFlags(t *testing.T) {
+	set := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	set.String("role", "worker", "")
+	set.Duration("expiry", time.Hour, "")
+	ctx := cli.NewContext(App, set, nil)
+	require.NoError(t, validateTokenFlags(ctx))
+
+	require.NoError(t, set.Set("role", "controller+worker"))
+	require.EqualError(t, validateTokenFlags(ctx), `invalid --role "controller+worker", must be controller or worker`)
+
+	require.NoError(t, set.Set("role", "controller"))
+	require.NoError(t, set.Set("expiry", "-1h"))
+	require.EqualError(t, validateTokenFlags(ctx), "invalid --expiry -1h0m0s, must not be negative")
+}
+
+func TestWriteToken(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "tokens", "worker.token")
+	require.NoError(t, writeToken(fn, "secret-token"))
+
+	content, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.Equal(t, "secret-token\n", string(content))
+
+	stat, err := os.Stat(fn)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+}
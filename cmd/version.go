@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	goversion "github.com/hashicorp/go-version"
 	"github.com/k0sproject/k0sctl/analytics"
+	"github.com/k0sproject/k0sctl/cache"
 	"github.com/k0sproject/k0sctl/integration/github"
 	"github.com/k0sproject/k0sctl/version"
 	"github.com/urfave/cli/v2"
@@ -26,6 +32,11 @@ var versionCommand = &cli.Command{
 			Name:  "pre",
 			Usage: "When used in conjunction with --k0s, a pre release is accepted as the latest version",
 		},
+		&cli.BoolFlag{
+			Name:  "check",
+			Usage: "Check if a newer version of k0sctl is available",
+		},
+		analyticsFlag,
 	},
 	Before: func(ctx *cli.Context) error {
 		if ctx.Bool("k0s") {
@@ -52,6 +63,76 @@ var versionCommand = &cli.Command{
 	Action: func(ctx *cli.Context) error {
 		fmt.Printf("version: %s\n", version.Version)
 		fmt.Printf("commit: %s\n", version.GitCommit)
+
+		if !ctx.Bool("check") {
+			return nil
+		}
+		if ctx.Bool("disable-telemetry") {
+			fmt.Println("update check skipped because telemetry is disabled")
+			return nil
+		}
+
+		latest, err := latestK0sctlRelease(cache.File("k0sctl", "latest-release.json"), time.Now())
+		if err != nil {
+			// the local version is all that can be shown when offline
+			return nil
+		}
+
+		if updateAvailable(version.Version, latest.TagName) {
+			fmt.Printf("an update is available: %s (%s)\n", latest.TagName, latest.URL)
+		} else {
+			fmt.Println("k0sctl is up to date")
+		}
 		return nil
 	},
 }
+
+// updateCheckTTL is how long the result of the update check is cached
+const updateCheckTTL = 6 * time.Hour
+
+type cachedRelease struct {
+	Release github.Release `json:"release"`
+	Checked time.Time      `json:"checked"`
+}
+
+var fetchLatestK0sctlRelease = func() (github.Release, error) {
+	return github.LatestK0sctlRelease(3 * time.Second)
+}
+
+// latestK0sctlRelease returns the latest k0sctl release from the cache file when it was checked less
+// than updateCheckTTL ago, otherwise it is fetched from github and the cache is updated
+func latestK0sctlRelease(fn string, now time.Time) (github.Release, error) {
+	if data, err := os.ReadFile(fn); err == nil {
+		var cached cachedRelease
+		if err := json.Unmarshal(data, &cached); err == nil && now.Sub(cached.Checked) < updateCheckTTL && cached.Release.TagName != "" {
+			return cached.Release, nil
+		}
+	}
+
+	release, err := fetchLatestK0sctlRelease()
+	if err != nil {
+		return github.Release{}, err
+	}
+
+	if data, err := json.Marshal(cachedRelease{Release: github.Release{TagName: release.TagName, URL: release.URL}, Checked: now}); err == nil {
+		if err := cache.EnsureDir(filepath.Dir(fn)); err == nil {
+			_ = os.WriteFile(fn, data, 0644)
+		}
+	}
+
+	return release, nil
+}
+
+// updateAvailable is true when the latest version is newer than the current version. Versions that
+// can't be parsed, such as development builds, are never considered outdated.
+func updateAvailable(current, latest string) bool {
+	cv, err := goversion.NewVersion(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return false
+	}
+	lv, err := goversion.NewVersion(strings.TrimPrefix(latest, "v"))
+	if err != nil {
+		return false
+	}
+	return lv.GreaterThan(cv)
+}
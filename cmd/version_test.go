@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k0sproject/k0sctl/integration/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateAvailable(t *testing.T) {
+	require.True(t, updateAvailable("0.10.0", "v0.11.0"))
+	require.False(t, updateAvailable("v0.11.0", "v0.11.0"))
+	require.False(t, updateAvailable("0.12.0-rc.1", "v0.11.0"))
+	require.False(t, updateAvailable("dev", "v0.11.0"))
+}
+
+func TestLatestK0sctlReleaseCache(t *testing.T) {
+	defer func(f func() (github.Release, error)) { fetchLatestK0sctlRelease = f }(fetchLatestK0sctlRelease)
+
+	var fetches int
+	fetchLatestK0sctlRelease = func() (github.Release, error) {
+		fetches++
+		return github.Release{TagName: fmt.Sprintf("v0.1%d.0", fetches)}, nil
+	}
+
+	fn := filepath.Join(t.TempDir(), "k0sctl", "latest-release.json")
+	now := time.Now()
+
+	r, err := latestK0sctlRelease(fn, now)
+	require.NoError(t, err)
+	require.Equal(t, "v0.11.0", r.TagName)
+
+	r, err = latestK0sctlRelease(fn, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, "v0.11.0", r.TagName)
+	require.Equal(t, 1, fetches)
+
+	r, err = latestK0sctlRelease(fn, now.Add(updateCheckTTL+time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, "v0.12.0", r.TagName)
+
+	fetchLatestK0sctlRelease = func() (github.Release, error) {
+		return github.Release{}, fmt.Errorf("offline")
+	}
+	_, err = latestK0sctlRelease(filepath.Join(t.TempDir(), "missing.json"), now)
+	require.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// anchorConfig uses anchors, aliases and merge keys the way hand-maintained configurations do
+var anchorConfig = []byte(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: anchors
+spec:
+  hosts:
+    - &controller
+      role: controller
+      ssh: &ssh
+        address: 10.0.0.1
+        user: admin
+        port: 2222
+        keyPath: /keys/cluster
+      installFlags: &flags
+        - --debug
+      environment: &env
+        HTTP_PROXY: http://proxy:3128
+    - <<: *controller
+      ssh:
+        <<: *ssh
+        address: 10.0.0.2
+    - role: worker
+      ssh:
+        <<: *ssh
+        address: 10.0.0.3
+      installFlags: *flags
+      environment: *env
+  groups:
+    - role: worker
+      ssh:
+        <<: *ssh
+      hosts:
+        - 10.0.0.[10-11]
+  k0s:
+    version: 1.23.3+k0s.0
+`)
+
+func loadAnchorConfig(t *testing.T, content []byte) *Cluster {
+	t.Helper()
+	content, err := ExpandHostGroups(content)
+	require.NoError(t, err)
+	content, err = ApplyK0sConfigPath(content, "", nil)
+	require.NoError(t, err)
+
+	c := &Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(content, c))
+	require.NoError(t, c.Validate())
+	return c
+}
+
+func TestAnchors(t *testing.T) {
+	c := loadAnchorConfig(t, anchorConfig)
+	require.Len(t, c.Spec.Hosts, 5)
+
+	roles := []string{"controller", "controller", "worker", "worker", "worker"}
+	addresses := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.10", "10.0.0.11"}
+	for i, h := range c.Spec.Hosts {
+		require.Equal(t, roles[i], h.Role, "host %d", i)
+		require.Equal(t, addresses[i], h.SSH.Address, "host %d", i)
+		require.Equal(t, "admin", h.SSH.User, "host %d", i)
+		require.Equal(t, 2222, h.SSH.Port, "host %d", i)
+		require.Equal(t, "/keys/cluster", h.SSH.KeyPath, "host %d", i)
+	}
+
+	for _, h := range c.Spec.Hosts[:3] {
+		require.Equal(t, cluster.Flags{"--debug"}, h.InstallFlags)
+		require.Equal(t, map[string]string{"HTTP_PROXY": "http://proxy:3128"}, h.Environment)
+	}
+}
+
+func TestAnchorsMerge(t *testing.T) {
+	overlay := []byte(`
+spec:
+  hosts:
+    - ssh: &ssh
+        address: 10.0.0.2
+        port: 2222
+        user: ops
+    - role: worker
+      ssh:
+        <<: *ssh
+        address: 10.0.0.4
+`)
+
+	merged, err := MergeYAML([]string{"base.yaml", "overlay.yaml"}, [][]byte{anchorConfig, overlay})
+	require.NoError(t, err)
+
+	c := loadAnchorConfig(t, merged)
+	require.Len(t, c.Spec.Hosts, 6)
+	require.Equal(t, "admin", c.Spec.Hosts[0].SSH.User)
+	require.Equal(t, "ops", c.Spec.Hosts[1].SSH.User)
+	require.Equal(t, "/keys/cluster", c.Spec.Hosts[1].SSH.KeyPath)
+	require.Equal(t, "10.0.0.4", c.Spec.Hosts[3].SSH.Address)
+	require.Equal(t, "ops", c.Spec.Hosts[3].SSH.User)
+}
+
+func TestAnchorsMarshal(t *testing.T) {
+	c := loadAnchorConfig(t, anchorConfig)
+	out, err := yaml.Marshal(c)
+	require.NoError(t, err)
+	require.NotContains(t, string(out), "<<")
+	require.NotContains(t, string(out), "*ssh")
+
+	// the expanded output loads to the same configuration
+	require.Equal(t, c.Spec.Hosts, loadAnchorConfig(t, out).Spec.Hosts)
+}
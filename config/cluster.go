@@ -2,10 +2,17 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
 
 	validator "github.com/go-playground/validator/v10"
 	"github.com/hashicorp/go-version"
 	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
 )
 
 // APIVersion is the current api version
@@ -13,7 +20,8 @@ const APIVersion = "k0sctl.k0sproject.io/v1beta1"
 
 // ClusterMetadata defines cluster metadata
 type ClusterMetadata struct {
-	Name string `yaml:"name" validate:"required" default:"k0s-cluster"`
+	Name       string `yaml:"name" validate:"required" default:"k0s-cluster"`
+	Kubeconfig string `yaml:"-"`
 }
 
 // Cluster describes launchpad.yaml configuration
@@ -44,10 +52,17 @@ func (c *Cluster) UnmarshalYAML(unmarshal func(interface{}) error) error {
 // Validate performs a configuration sanity check
 func (c *Cluster) Validate() error {
 	validator := validator.New()
-	validator.RegisterStructValidation(validateMinK0sVersion, cluster.K0s{})
+	validator.RegisterStructValidation(validateK0s, cluster.K0s{})
+	validator.RegisterStructValidation(validateSpec, cluster.Spec{})
+	validator.RegisterStructValidation(validateHost, cluster.Host{})
+	validator.RegisterStructValidation(validateWinRM, rig.WinRM{})
 	if err := validator.RegisterValidation("apiversionmatch", validateAPIVersion); err != nil {
 		return err
 	}
+	// the built-in ip validation does not accept zone-scoped IPv6 addresses such as fe80::1%eth0
+	if err := validator.RegisterValidation("ip", validateIP); err != nil {
+		return err
+	}
 	return validator.Struct(c)
 }
 
@@ -55,19 +70,418 @@ func validateAPIVersion(fl validator.FieldLevel) bool {
 	return fl.Field().String() == APIVersion
 }
 
-func validateMinK0sVersion(sl validator.StructLevel) {
+func validateIP(fl validator.FieldLevel) bool {
+	addr := fl.Field().String()
+	return net.ParseIP(addr) != nil || cluster.IsIPv6(addr)
+}
+
+func validateK0s(sl validator.StructLevel) {
 	if k0s, ok := sl.Current().Interface().(cluster.K0s); ok {
-		v, err := version.NewVersion(k0s.Version)
-		if err != nil {
-			sl.ReportError(k0s.Version, "version", "", "invalid version", "")
+		if k0s.VersionChannel != "" {
+			if err := cluster.ValidateVersionChannel(k0s.VersionChannel); err != nil {
+				sl.ReportError(k0s.VersionChannel, "versionChannel", "", err.Error(), "")
+			}
+			if k0s.Version != "" {
+				sl.ReportError(k0s.VersionChannel, "versionChannel", "", "spec.k0s.version can not be given with spec.k0s.versionChannel", "")
+			}
+		}
+		if k0s.Version != "" || k0s.VersionChannel == "" {
+			validateK0sVersion(sl, k0s.Version, "version")
+		}
+		validateInstallFlags(sl, k0s.InstallFlags)
+		validateDataDir(sl, k0s.DataDir, "dataDir")
+		validateEnvironment(sl, k0s.Environment)
+		validateExternalEtcd(sl, k0s)
+		if err := k0s.ServiceOverrides.Validate(); err != nil {
+			sl.ReportError(k0s.ServiceOverrides, "serviceOverrides", "", err.Error(), "")
+		}
+		if k0s.DownloadURLBase != "" {
+			if err := cluster.ValidateDownloadURLBase(k0s.DownloadURLBase); err != nil {
+				sl.ReportError(k0s.DownloadURLBase, "downloadURLBase", "", err.Error(), "")
+			}
+		}
+	}
+}
+
+// validateExternalEtcd makes sure an external etcd cluster in the k0s configuration has endpoints and that
+// the client certificates are given when the endpoints use https
+func validateExternalEtcd(sl validator.StructLevel, k0s cluster.K0s) {
+	ext := k0s.ExternalEtcd()
+	if ext == nil {
+		return
+	}
+	if t := k0s.Config.DigString("spec", "storage", "type"); t != "" && t != "etcd" {
+		sl.ReportError(k0s.Config, "config", "", fmt.Sprintf("spec.storage.etcd.externalCluster can not be used with the storage type %q", t), "")
+		return
+	}
+
+	endpoints, _ := ext.Dig("endpoints").([]interface{})
+	if len(endpoints) == 0 {
+		sl.ReportError(k0s.Config, "config", "", "spec.storage.etcd.externalCluster.endpoints must list the endpoints of the etcd cluster", "")
+		return
+	}
+	var https bool
+	for _, e := range endpoints {
+		endpoint, _ := e.(string)
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			sl.ReportError(k0s.Config, "config", "", fmt.Sprintf("invalid external etcd endpoint %v, must be an url such as https://etcd.example.com:2379", e), "")
+			return
+		}
+		if u.Scheme == "https" {
+			https = true
+		}
+	}
+	if !https {
+		return
+	}
+	for _, field := range []string{"caFile", "clientCertFile", "clientKeyFile"} {
+		if ext.DigString(field) == "" {
+			sl.ReportError(k0s.Config, "config", "", fmt.Sprintf("spec.storage.etcd.externalCluster.%s is required for https endpoints", field), "")
+			return
+		}
+	}
+}
+
+// validateInstallFlags makes sure the install flag names are plain flags, the values are quoted for the shell
+// when the install command is built
+func validateInstallFlags(sl validator.StructLevel, flags cluster.Flags) {
+	if flag := flags.Invalid(); flag != "" {
+		sl.ReportError(flags, "installFlags", "", fmt.Sprintf("install flag %q is not a valid flag", flag), "")
+	}
+}
+
+func validateK0sVersion(sl validator.StructLevel, k0sVersion, field string) {
+	v, err := version.NewVersion(k0sVersion)
+	if err != nil {
+		sl.ReportError(k0sVersion, field, "", "invalid version", "")
+		return
+	}
+	min, err := version.NewVersion(cluster.K0sMinVersion)
+	if err != nil {
+		panic("invalid k0s minversion")
+	}
+	if v.LessThan(min) {
+		sl.ReportError(k0sVersion, field, "", fmt.Sprintf("minimum k0s version is %s", cluster.K0sMinVersion), "")
+	}
+}
+
+func validateHost(sl validator.StructLevel) {
+	validateBastion(sl)
+	validateLocalhost(sl)
+
+	if h, ok := sl.Current().Interface().(cluster.Host); ok {
+		if h.K0sVersion != "" {
+			validateK0sVersion(sl, h.K0sVersion, "k0sVersion")
+		}
+		validateInstallFlags(sl, h.InstallFlags)
+		validateDataDir(sl, h.K0sDataDir(), "installFlags")
+		if h.K0sInstallPath != "" && !path.IsAbs(h.K0sInstallPath) {
+			sl.ReportError(h.K0sInstallPath, "k0sInstallPath", "", fmt.Sprintf("the k0s binary path %q must be an absolute path", h.K0sInstallPath), "")
+		}
+		if h.Shell != "" && !path.IsAbs(h.Shell) {
+			sl.ReportError(h.Shell, "shell", "", fmt.Sprintf("the shell %q must be an absolute path", h.Shell), "")
+		}
+		validateEnvironment(sl, h.Environment)
+		validateAddresses(sl, h)
+		if err := h.Prerequisites.Validate(); err != nil {
+			sl.ReportError(h.Prerequisites, "prerequisites", "", err.Error(), "")
+		}
+		validateNodeLabels(sl, h)
+		if err := h.SSHAuth.Validate(); err != nil {
+			sl.ReportError(h.SSHAuth, "ssh", "", err.Error(), "")
+		}
+	}
+}
+
+// validateNodeLabels checks the labels and taints of the host, they can only be set on hosts that have
+// a kubernetes node
+func validateNodeLabels(sl validator.StructLevel, h cluster.Host) {
+	if len(h.Labels) == 0 && len(h.Taints) == 0 {
+		return
+	}
+	if !h.HasKubelet() {
+		sl.ReportError(h.Labels, "labels", "", fmt.Sprintf("host %s with role %s does not run a kubelet, labels and taints can only be set on worker nodes", &h, h.Role), "")
+		return
+	}
+	if err := h.Labels.Validate(); err != nil {
+		sl.ReportError(h.Labels, "labels", "", err.Error(), "")
+		return
+	}
+	seen := make(map[string]struct{}, len(h.Taints))
+	for _, t := range h.Taints {
+		if _, ok := seen[t.ID()]; ok {
+			sl.ReportError(h.Taints, "taints", "", fmt.Sprintf("taint %s is given more than once", t.ID()), "")
 			return
 		}
-		min, err := version.NewVersion(cluster.K0sMinVersion)
-		if err != nil {
-			panic("invalid k0s minversion")
+		seen[t.ID()] = struct{}{}
+	}
+}
+
+// envNameRe matches valid environment variable names
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnvironment makes sure the environment variable names can be used in the shell
+func validateEnvironment(sl validator.StructLevel, env map[string]string) {
+	for k := range env {
+		if !envNameRe.MatchString(k) {
+			sl.ReportError(env, "environment", "", fmt.Sprintf("invalid environment variable name %q", k), "")
+			return
+		}
+	}
+}
+
+// windowsAbsPathRe matches absolute windows paths such as C:\k0s
+var windowsAbsPathRe = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// validateDataDir makes sure the k0s data directory is an absolute path
+func validateDataDir(sl validator.StructLevel, dir, field string) {
+	if dir == "" || path.IsAbs(dir) || windowsAbsPathRe.MatchString(dir) {
+		return
+	}
+	sl.ReportError(dir, field, "", fmt.Sprintf("the k0s data directory %q must be an absolute path", dir), "")
+}
+
+// validateAddresses gives a clear error for addresses that include a port and for zone-scoped private addresses
+func validateAddresses(sl validator.StructLevel, h cluster.Host) {
+	var addrs []string
+	if h.SSH != nil {
+		addrs = append(addrs, h.SSH.Address)
+		if h.SSH.Bastion != nil {
+			addrs = append(addrs, h.SSH.Bastion.Address)
+		}
+	}
+	if h.WinRM != nil {
+		addrs = append(addrs, h.WinRM.Address)
+		if h.WinRM.Bastion != nil {
+			addrs = append(addrs, h.WinRM.Bastion.Address)
+		}
+	}
+	for _, addr := range addrs {
+		if strings.Contains(addr, ":") && !cluster.IsIPv6(addr) {
+			sl.ReportError(addr, "address", "", fmt.Sprintf("invalid address %q, the address must not include a port", addr), "")
+		}
+	}
+
+	if strings.Contains(h.PrivateAddress, "%") {
+		sl.ReportError(h.PrivateAddress, "privateAddress", "", "a zone-scoped address can not be used as the private address", "")
+	}
+}
+
+func validateSpec(sl validator.StructLevel) {
+	validateUniqueHosts(sl)
+	validateLeader(sl)
+	validateWorkerProfiles(sl)
+	validateLocalHooks(sl)
+	validateKubeconfig(sl)
+	validateAPIServerAddress(sl)
+}
+
+// validateAPIServerAddress makes sure the spec.k0s.apiServerAddress is a host[:port] or an url
+func validateAPIServerAddress(sl validator.StructLevel) {
+	spec, ok := sl.Current().Interface().(cluster.Spec)
+	if !ok || spec.K0s.APIServerAddress == "" {
+		return
+	}
+	if _, err := cluster.KubeconfigAPIURL(spec.K0s.APIServerAddress, 6443); err != nil {
+		sl.ReportError(spec.K0s.APIServerAddress, "apiServerAddress", "", err.Error(), "")
+	}
+}
+
+// validateKubeconfig makes sure the spec.kubeconfig.apiAddress is a host[:port] or an url
+func validateKubeconfig(sl validator.StructLevel) {
+	spec, ok := sl.Current().Interface().(cluster.Spec)
+	if !ok || spec.Kubeconfig.APIAddress == "" {
+		return
+	}
+	if _, err := cluster.KubeconfigAPIURL(spec.Kubeconfig.APIAddress, 6443); err != nil {
+		sl.ReportError(spec.Kubeconfig.APIAddress, "kubeconfig", "", err.Error(), "")
+	}
+}
+
+// builtinWorkerProfiles are the worker profiles k0s provides without them being in the k0s config
+var builtinWorkerProfiles = []string{"default", "default-windows"}
+
+// validateWorkerProfiles makes sure the worker profiles of the hosts are defined in the k0s config
+func validateWorkerProfiles(sl validator.StructLevel) {
+	spec, ok := sl.Current().Interface().(cluster.Spec)
+	if !ok {
+		return
+	}
+	defined := append(append([]string{}, builtinWorkerProfiles...), spec.K0s.WorkerProfiles()...)
+	for _, h := range spec.Hosts {
+		if h == nil {
+			continue
+		}
+		profile := h.Profile
+		if profile == "" {
+			profile = h.InstallFlags.GetValue("--profile")
+		}
+		if profile == "" {
+			profile = spec.K0s.InstallFlags.GetValue("--profile")
+		}
+		if profile == "" {
+			continue
+		}
+		if h.Profile != "" && !strings.HasSuffix(h.Role, "worker") {
+			sl.ReportError(h.Profile, "profile", "", fmt.Sprintf("host %s with role %s can not have a worker profile", h, h.Role), "")
+			return
+		}
+		if !strings.HasSuffix(h.Role, "worker") {
+			continue
+		}
+		var found bool
+		for _, name := range defined {
+			if name == profile {
+				found = true
+				break
+			}
+		}
+		if !found {
+			sl.ReportError(h.Profile, "profile", "", fmt.Sprintf("host %s uses the worker profile %q which is not defined in spec.k0s.config spec.workerProfiles", h, profile), "")
+			return
+		}
+	}
+}
+
+// validateLeader makes sure only one controller is marked as the leader
+func validateLeader(sl validator.StructLevel) {
+	if spec, ok := sl.Current().Interface().(cluster.Spec); ok {
+		var leader *cluster.Host
+		for _, h := range spec.Hosts {
+			if h == nil || !h.Leader {
+				continue
+			}
+			if !h.IsController() {
+				sl.ReportError(spec.Hosts, "leader", "", fmt.Sprintf("host %s with role %s can not be the leader, only controllers can", h, h.Role), "")
+				return
+			}
+			if leader != nil {
+				sl.ReportError(spec.Hosts, "leader", "", fmt.Sprintf("only one host can be the leader, both %s and %s are", leader, h), "")
+				return
+			}
+			leader = h
+		}
+	}
+}
+
+// validateLocalHooks makes sure the local hooks are given for the before and after stages of the phases
+func validateLocalHooks(sl validator.StructLevel) {
+	if spec, ok := sl.Current().Interface().(cluster.Spec); ok {
+		for title, stages := range spec.LocalHooks {
+			for stage, hooks := range stages {
+				if stage != "before" && stage != "after" {
+					sl.ReportError(spec.LocalHooks, "localHooks", "", fmt.Sprintf("invalid stage %q for the local hooks of phase %q, must be before or after", stage, title), "")
+					return
+				}
+				for _, hook := range hooks {
+					if strings.TrimSpace(hook.Cmd) == "" {
+						sl.ReportError(spec.LocalHooks, "localHooks", "", fmt.Sprintf("empty command in the %s local hooks of phase %q", stage, title), "")
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func validateUniqueHosts(sl validator.StructLevel) {
+	if spec, ok := sl.Current().Interface().(cluster.Spec); ok {
+		seen := make(map[string]struct{}, len(spec.Hosts))
+		var localhosts int
+		for _, h := range spec.Hosts {
+			if h == nil {
+				continue
+			}
+			if h.Localhost != nil {
+				localhosts++
+				if localhosts > 1 {
+					sl.ReportError(spec.Hosts, "hosts", "", "only one localhost host can be defined", "")
+					return
+				}
+			}
+			key := h.String()
+			if _, ok := seen[key]; ok {
+				sl.ReportError(spec.Hosts, "hosts", "", fmt.Sprintf("duplicate host %s", key), "")
+				return
+			}
+			seen[key] = struct{}{}
+		}
+	}
+}
+
+// validateLocalhost makes sure a localhost connection is not combined with the settings of other connection types,
+// they would be silently ignored
+func validateLocalhost(sl validator.StructLevel) {
+	h, ok := sl.Current().Interface().(cluster.Host)
+	if !ok || h.Localhost == nil {
+		return
+	}
+
+	if h.SSH != nil && h.SSH.KeyPath != "" {
+		sl.ReportError(h.SSH.KeyPath, "keyPath", "", "a localhost connection can not be used with an ssh keyPath", "")
+		return
+	}
+
+	if h.SSH != nil || h.WinRM != nil {
+		sl.ReportError(h.Localhost, "localhost", "", "a localhost connection can not be combined with ssh or winRM", "")
+	}
+}
+
+// validateBastion makes sure a bastion host is not nested and that there are credentials available for connecting to it
+func validateBastion(sl validator.StructLevel) {
+	h, ok := sl.Current().Interface().(cluster.Host)
+	if !ok {
+		return
+	}
+
+	var bastion *rig.SSH
+	switch {
+	case h.SSH != nil && h.SSH.Bastion != nil:
+		bastion = h.SSH.Bastion
+	case h.WinRM != nil && h.WinRM.Bastion != nil:
+		bastion = h.WinRM.Bastion
+	default:
+		return
+	}
+
+	if bastion.Bastion != nil {
+		sl.ReportError(bastion.Bastion, "bastion", "", "nested bastion hosts are not supported", "")
+		return
+	}
+
+	if bastion.User == "" {
+		sl.ReportError(bastion.User, "user", "", "bastion user is required", "")
+	}
+
+	if bastion.KeyPath != "" {
+		if _, err := os.Stat(bastion.KeyPath); err == nil {
+			return
+		}
+	}
+
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		sl.ReportError(bastion.KeyPath, "keyPath", "", fmt.Sprintf("bastion keyPath %s does not exist and no ssh agent is available (SSH_AUTH_SOCK)", bastion.KeyPath), "")
+	}
+}
+
+// validateWinRM checks that the TLS options of a WinRM connection are not conflicting
+func validateWinRM(sl validator.StructLevel) {
+	w, ok := sl.Current().Interface().(rig.WinRM)
+	if !ok {
+		return
+	}
+
+	if w.Insecure && w.CACertPath != "" {
+		sl.ReportError(w.CACertPath, "caCertPath", "", "caCertPath and insecure can not be used together", "")
+	}
+
+	if !w.UseHTTPS {
+		if w.Insecure {
+			sl.ReportError(w.Insecure, "insecure", "", "insecure requires useHTTPS", "")
 		}
-		if v.LessThan(min) {
-			sl.ReportError(k0s.Version, "version", "", fmt.Sprintf("minimum k0s version is %s", cluster.K0sMinVersion), "")
+		if w.CACertPath != "" {
+			sl.ReportError(w.CACertPath, "caCertPath", "", "caCertPath requires useHTTPS", "")
 		}
 	}
 }
@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/k0sproject/rig"
+)
+
+// UnbracketAddress removes the brackets around an IPv6 address such as "[2001:db8::1]"
+func UnbracketAddress(addr string) string {
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		return addr[1 : len(addr)-1]
+	}
+	return addr
+}
+
+// IsIPv6 returns true when the address is an IPv6 address, optionally with a zone such as "fe80::1%eth0"
+func IsIPv6(addr string) bool {
+	if idx := strings.LastIndex(addr, "%"); idx > 0 {
+		if idx == len(addr)-1 {
+			return false
+		}
+		addr = addr[:idx]
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.To4() == nil
+}
+
+// JoinHostPort combines the address and the port, IPv6 addresses are bracketed like "[2001:db8::1]:22"
+func JoinHostPort(addr string, port int) string {
+	return net.JoinHostPort(UnbracketAddress(addr), strconv.Itoa(port))
+}
+
+func bracketAddress(addr string) string {
+	if IsIPv6(addr) {
+		return "[" + addr + "]"
+	}
+	return addr
+}
+
+// normalizeAddresses removes the brackets from the IPv6 addresses of the connection
+func normalizeAddresses(c *rig.Connection) {
+	if c.SSH != nil {
+		c.SSH.Address = UnbracketAddress(c.SSH.Address)
+		if c.SSH.Bastion != nil {
+			c.SSH.Bastion.Address = UnbracketAddress(c.SSH.Bastion.Address)
+		}
+	}
+	if c.WinRM != nil {
+		c.WinRM.Address = UnbracketAddress(c.WinRM.Address)
+		if c.WinRM.Bastion != nil {
+			c.WinRM.Bastion.Address = UnbracketAddress(c.WinRM.Bastion.Address)
+		}
+	}
+}
+
+// bracketAddresses brackets the IPv6 addresses of the connection and returns a function for
+// restoring them. The connections join the address and the port without brackets when dialing.
+func bracketAddresses(c *rig.Connection) func() {
+	var restore []func()
+	set := func(addr *string) {
+		orig := *addr
+		*addr = bracketAddress(orig)
+		restore = append(restore, func() { *addr = orig })
+	}
+
+	if c.SSH != nil {
+		set(&c.SSH.Address)
+		if c.SSH.Bastion != nil {
+			set(&c.SSH.Bastion.Address)
+		}
+	}
+	if c.WinRM != nil {
+		set(&c.WinRM.Address)
+		if c.WinRM.Bastion != nil {
+			set(&c.WinRM.Bastion.Address)
+		}
+	}
+
+	return func() {
+		for _, fn := range restore {
+			fn()
+		}
+	}
+}
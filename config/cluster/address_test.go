@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestJoinHostPort(t *testing.T) {
+	require.Equal(t, "10.0.0.1:22", JoinHostPort("10.0.0.1", 22))
+	require.Equal(t, "example.com:6443", JoinHostPort("example.com", 6443))
+	require.Equal(t, "[2001:db8::1]:22", JoinHostPort("2001:db8::1", 22))
+	require.Equal(t, "[2001:db8::1]:22", JoinHostPort("[2001:db8::1]", 22))
+	require.Equal(t, "[fe80::1%eth0]:22", JoinHostPort("fe80::1%eth0", 22))
+}
+
+func TestIsIPv6(t *testing.T) {
+	require.True(t, IsIPv6("2001:db8::1"))
+	require.True(t, IsIPv6("fe80::1%eth0"))
+	require.False(t, IsIPv6("fe80::1%"))
+	require.False(t, IsIPv6("[2001:db8::1]"))
+	require.False(t, IsIPv6("10.0.0.1"))
+	require.False(t, IsIPv6("example.com"))
+	require.False(t, IsIPv6("10.0.0.1:22"))
+}
+
+func TestHostAddressUnbracketed(t *testing.T) {
+	data := `
+role: controller
+privateAddress: "[2001:db8::2]"
+ssh:
+  address: "[2001:db8::1]"
+  bastion:
+    address: "[2001:db8::3]"
+`
+	h := &Host{}
+	require.NoError(t, yaml.Unmarshal([]byte(data), h))
+	require.Equal(t, "2001:db8::1", h.Address())
+	require.Equal(t, "2001:db8::2", h.PrivateAddress)
+	require.Equal(t, "2001:db8::3", h.SSH.Bastion.Address)
+}
+
+func TestBracketAddresses(t *testing.T) {
+	c := &rig.Connection{SSH: &rig.SSH{Address: "fe80::1%eth0", Bastion: &rig.SSH{Address: "10.0.0.1"}}}
+	restore := bracketAddresses(c)
+	require.Equal(t, "[fe80::1%eth0]", c.SSH.Address)
+	require.Equal(t, "10.0.0.1", c.SSH.Bastion.Address)
+	restore()
+	require.Equal(t, "fe80::1%eth0", c.SSH.Address)
+}
@@ -0,0 +1,25 @@
+package cluster
+
+import "fmt"
+
+// extractAgentForwarding removes the agentForwarding field from the ssh connection of the host yaml.
+// Returns true when the field was found.
+func extractAgentForwarding(host map[interface{}]interface{}, forward *bool) (bool, error) {
+	conn, ok := host["ssh"].(map[interface{}]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	v, ok := conn["agentForwarding"]
+	if !ok {
+		return false, nil
+	}
+	delete(conn, "agentForwarding")
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("ssh.agentForwarding: must be true or false")
+	}
+	*forward = b
+
+	return true, nil
+}
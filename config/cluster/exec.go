@@ -0,0 +1,289 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alessio/shellescape"
+	"github.com/k0sproject/rig"
+	"github.com/k0sproject/rig/exec"
+	log "github.com/sirupsen/logrus"
+)
+
+// VerboseCommands makes the hosts log every command they run at info level, the commands are redacted
+// the same way as in the debug log
+var VerboseCommands bool
+
+// DefaultShell is the shell the commands are run with on the linux hosts that do not set shell
+const DefaultShell = "/bin/sh"
+
+// SudoPassword is the sudo password of the hosts that do not set sudoPassword
+var SudoPassword string
+
+// ExecObserver is called after each command run with Exec and each file uploaded with Upload when set,
+// such as for writing the audit log. The command is redacted the same way as in the debug log.
+var ExecObserver func(h *Host, cmd string, err error)
+
+// CommandError is returned from the Exec functions of a host when running a command fails. The
+// error message is the one of the underlying error, the command has been redacted.
+type CommandError struct {
+	Host     string
+	Command  string
+	ExitCode int
+	Err      error
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the original error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// exitCode returns the exit code from the error returned by the ssh, winrm or local clients
+// or -1 when the command did not get to run or the client does not report one
+func exitCode(err error) int {
+	switch e := err.(type) {
+	case interface{ ExitStatus() int }:
+		return e.ExitStatus()
+	case interface{ ExitCode() int }:
+		return e.ExitCode()
+	}
+	if u, ok := err.(interface{ Unwrap() error }); ok && u.Unwrap() != nil {
+		return exitCode(u.Unwrap())
+	}
+	return -1
+}
+
+// loggedCommand returns the command redacted for logging, commands that are hidden from the log are replaced entirely
+func loggedCommand(cmd string, o *exec.Options) string {
+	if !o.LogCommand {
+		return "[REDACTED]"
+	}
+	return o.Redact(cmd)
+}
+
+func newCommandError(h *Host, cmd string, opts []exec.Option, err error) error {
+	return &CommandError{Host: h.String(), Command: loggedCommand(cmd, exec.Build(opts...)), ExitCode: exitCode(err), Err: err}
+}
+
+// logCommand logs the command at info level when VerboseCommands is set
+func (h *Host) logCommand(cmd string, opts []exec.Option) {
+	if !VerboseCommands {
+		return
+	}
+	o := exec.Build(opts...)
+	if o.Sudo {
+		log.WithField("host", h).Infof("running with sudo: %s", loggedCommand(cmd, o))
+		return
+	}
+	log.WithField("host", h).Infof("running: %s", loggedCommand(cmd, o))
+}
+
+// secretEnvRe matches the names of environment variables that are likely to hold secrets
+var secretEnvRe = regexp.MustCompile(`(?i)(pass|secret|token|key|credential|auth)`)
+
+// isSecretEnv returns true when the environment variable looks like it holds a secret, such as a password
+// or a proxy url with credentials
+func isSecretEnv(name, value string) bool {
+	return secretEnvRe.MatchString(name) || strings.Contains(value, "@")
+}
+
+// withEnvironment sets the environment variables of the host for the command on linux hosts. The values of
+// the variables that look like secrets are redacted from the logs.
+func (h *Host) withEnvironment(cmd string, opts []exec.Option) (string, []exec.Option) {
+	if h.Configurer == nil || h.Configurer.Kind() == "windows" {
+		return cmd, opts
+	}
+	env := h.EnvironmentVars()
+	if len(env) == 0 {
+		return cmd, opts
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	vars := make([]string, len(keys))
+	var secrets []string
+	for i, k := range keys {
+		vars[i] = k + "=" + shellescape.Quote(env[k])
+		if env[k] != "" && isSecretEnv(k, env[k]) {
+			secrets = append(secrets, shellescape.Quote(env[k]), env[k])
+		}
+	}
+
+	// the command is run by the shell of the host also with sudo, so the variables reach every command of
+	// a compound command such as a && b
+	cmd = fmt.Sprintf("export %s; %s", strings.Join(vars, " "), cmd)
+
+	if len(secrets) > 0 {
+		opts = append(opts, func(o *exec.Options) {
+			redact := o.RedactFunc
+			o.RedactFunc = func(s string) string {
+				if redact != nil {
+					s = redact(s)
+				}
+				for _, secret := range secrets {
+					s = strings.ReplaceAll(s, secret, "[REDACTED]")
+				}
+				return s
+			}
+		})
+	}
+	return cmd, opts
+}
+
+// ExecShell returns the shell the commands are run with on the host
+func (h *Host) ExecShell() string {
+	if h.Shell != "" {
+		return h.Shell
+	}
+	return DefaultShell
+}
+
+// withShell wraps the command to be run by the shell of the host on linux hosts, so that the quoting and the
+// pipes in the commands work the same regardless of the login shell of the user
+func (h *Host) withShell(cmd string) string {
+	if h.Configurer == nil || h.Configurer.Kind() == "windows" {
+		return cmd
+	}
+	return h.ExecShell() + " -c " + shellescape.Quote(cmd)
+}
+
+// sudoPassword returns the password sudo needs on the host, empty when the connection found a way to elevate
+// without a password
+func (h *Host) sudoPassword() string {
+	if _, err := h.Connection.Sudo(""); err == nil {
+		return ""
+	}
+	if h.SudoPassword != "" {
+		return h.SudoPassword
+	}
+	return SudoPassword
+}
+
+// Sudo returns the command for running cmd with elevated permissions. When the user is not root and has no
+// passwordless sudo, sudo reads the password from stdin, the password is never on the command line.
+func (h *Host) Sudo(cmd string) (string, error) {
+	cmd = h.withShell(cmd)
+	sudo, err := h.Connection.Sudo(cmd)
+	if err == nil || h.sudoPassword() == "" {
+		return sudo, err
+	}
+	// the password is only used on linux hosts, the shell is added also before the os is known
+	if h.Configurer == nil {
+		cmd = h.ExecShell() + " -c " + shellescape.Quote(cmd)
+	}
+	// -k makes sudo always prompt, so that the password is not left in the input of the command. The command
+	// is run without -s, which would put the login shell of the target user in front of the shell.
+	return "sudo -k -S -p '' -- " + cmd, nil
+}
+
+// withSudoPassword writes the password to stdin ahead of any input of the command, sudo reads the first line
+// and leaves the rest to the command. The password is redacted from the logged stdin.
+func withSudoPassword(password string, opts []exec.Option) []exec.Option {
+	return append(opts, func(o *exec.Options) {
+		o.Stdin = password + "\n" + o.Stdin
+		redact := o.RedactFunc
+		o.RedactFunc = func(s string) string {
+			if redact != nil {
+				s = redact(s)
+			}
+			return strings.ReplaceAll(s, password, "[REDACTED]")
+		}
+	})
+}
+
+// CheckSudoPassword makes sure sudo accepts the sudo password of the host when the host needs one
+func (h *Host) CheckSudoPassword() error {
+	password := h.sudoPassword()
+	if password == "" {
+		return nil
+	}
+	if err := h.Connection.Exec("sudo -k -S -p '' true", withSudoPassword(password, []exec.Option{exec.HideOutput()})...); err != nil {
+		return fmt.Errorf("sudo password rejected on host %s", h)
+	}
+	return nil
+}
+
+// Exec runs a command on the host with the environment variables of the host set, failures are returned
+// as a *CommandError
+func (h *Host) Exec(cmd string, opts ...exec.Option) error {
+	envCmd, envOpts := h.withEnvironment(cmd, opts)
+	if password := h.sudoPassword(); password != "" && exec.Build(envOpts...).Sudo {
+		envOpts = withSudoPassword(password, envOpts)
+	}
+	h.logCommand(envCmd, envOpts)
+	// with sudo the shell is added by Sudo so that it runs as the elevated user
+	if !exec.Build(envOpts...).Sudo {
+		envCmd = h.withShell(envCmd)
+	}
+	err := h.Connection.Exec(envCmd, envOpts...)
+	if err != nil {
+		err = newCommandError(h, cmd, envOpts, err)
+	}
+	if observe := ExecObserver; observe != nil {
+		observe(h, loggedCommand(cmd, exec.Build(envOpts...)), err)
+	}
+	return err
+}
+
+// Upload uploads a file to the host, the upload is reported to the ExecObserver
+func (h *Host) Upload(src, dst string, opts ...exec.Option) error {
+	var err error
+	if password := h.sudoPassword(); password != "" && exec.Build(opts...).Sudo {
+		err = h.uploadWithSudoPassword(src, dst, opts)
+	} else {
+		err = h.Connection.Upload(src, dst, opts...)
+	}
+	if observe := ExecObserver; observe != nil {
+		observe(h, fmt.Sprintf("upload %s to %s", src, dst), err)
+	}
+	return err
+}
+
+// uploadWithSudoPassword uploads the file to a temporary file as the connection user and copies it in place with
+// sudo, the upload streams the file to stdin and the sudo password can't be written ahead of it
+func (h *Host) uploadWithSudoPassword(src, dst string, opts []exec.Option) error {
+	tmp, err := h.ExecOutput("mktemp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = h.Exec(fmt.Sprintf("rm -f %s", shellescape.Quote(tmp)))
+	}()
+
+	noSudo := append(append([]exec.Option{}, opts...), func(o *exec.Options) { o.Sudo = false })
+	if err := h.Connection.Upload(src, tmp, noSudo...); err != nil {
+		return err
+	}
+	return h.Exec(fmt.Sprintf("cp %s %s", shellescape.Quote(tmp), shellescape.Quote(dst)), exec.Sudo(h))
+}
+
+// ExecOutput runs a command on the host and returns the output as a string
+func (h *Host) ExecOutput(cmd string, opts ...exec.Option) (string, error) {
+	var output string
+	opts = append(opts, exec.Output(&output))
+	err := h.Exec(cmd, opts...)
+	return strings.TrimSpace(output), err
+}
+
+// Execf is like Exec but the command can be templated like with Sprintf
+func (h *Host) Execf(s string, params ...interface{}) error {
+	opts, args := rig.GroupParams(params...)
+	return h.Exec(fmt.Sprintf(s, args...), opts...)
+}
+
+// ExecOutputf is like ExecOutput but the command can be templated like with Sprintf
+func (h *Host) ExecOutputf(s string, params ...interface{}) (string, error) {
+	opts, args := rig.GroupParams(params...)
+	return h.ExecOutput(fmt.Sprintf(s, args...), opts...)
+}
@@ -0,0 +1,152 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/k0sproject/rig"
+	"github.com/k0sproject/rig/exec"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+type exitStatusError int
+
+func (e exitStatusError) Error() string   { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitStatusError) ExitStatus() int { return int(e) }
+
+type exitCodeError int
+
+func (e exitCodeError) Error() string { return fmt.Sprintf("exit code %d", int(e)) }
+func (e exitCodeError) ExitCode() int { return int(e) }
+
+func TestExitCode(t *testing.T) {
+	require.Equal(t, 2, exitCode(exitStatusError(2)))
+	require.Equal(t, 127, exitCode(exitCodeError(127)))
+	require.Equal(t, 3, exitCode(fmt.Errorf("command failed: %w", exitStatusError(3))))
+	require.Equal(t, -1, exitCode(errors.New("connection lost")))
+}
+
+func TestHostExecCommandError(t *testing.T) {
+	h := &Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+
+	err := h.Execf("echo %s", "secret-value", exec.RedactString("secret-value"))
+	require.Error(t, err)
+	var cerr *CommandError
+	require.True(t, errors.As(err, &cerr))
+	require.Equal(t, "[ssh] 10.0.0.1:22", cerr.Host)
+	require.Equal(t, "echo [REDACTED]", cerr.Command)
+	require.Equal(t, -1, cerr.ExitCode)
+	var nerr *rig.NotConnectedError
+	require.True(t, errors.As(err, &nerr))
+	require.Equal(t, nerr.Error(), err.Error())
+
+	_, err = h.ExecOutput("cat /etc/secret", exec.HideCommand())
+	require.True(t, errors.As(err, &cerr))
+	require.Equal(t, "[REDACTED]", cerr.Command)
+}
+
+func TestHostWithEnvironment(t *testing.T) {
+	k0s := &K0s{Environment: map[string]string{"HTTPS_PROXY": "http://user:pw@proxy:3128", "LANG": "C"}}
+	h := &Host{k0s: k0s, Environment: map[string]string{"LANG": "en_US.UTF-8", "API_TOKEN": "t0ps3cr3t"}}
+
+	cmd, opts := h.withEnvironment("k0s status", nil)
+	require.Equal(t, "k0s status", cmd, "no environment before the os is known")
+	require.Empty(t, opts)
+
+	h.Configurer = &mockconfigurer{}
+	cmd, opts = h.withEnvironment("k0s status", nil)
+	require.Equal(t, "export API_TOKEN=t0ps3cr3t HTTPS_PROXY=http://user:pw@proxy:3128 LANG=en_US.UTF-8; k0s status", cmd)
+	o := exec.Build(opts...)
+	require.Equal(t, "export API_TOKEN=[REDACTED] HTTPS_PROXY=[REDACTED] LANG=en_US.UTF-8; k0s status", o.Redact(cmd))
+
+	cmd, opts = h.withEnvironment("k0s status", []exec.Option{exec.Sudo(h), exec.RedactString("status")})
+	require.Equal(t, "export API_TOKEN=t0ps3cr3t HTTPS_PROXY=http://user:pw@proxy:3128 LANG=en_US.UTF-8; k0s status", cmd)
+	require.Equal(t, "export API_TOKEN=[REDACTED] HTTPS_PROXY=[REDACTED] LANG=en_US.UTF-8; k0s [REDACTED]", exec.Build(opts...).Redact(cmd))
+}
+
+func TestHostWithEnvironmentCompoundCommand(t *testing.T) {
+	defer func(p string) { SudoPassword = p }(SudoPassword)
+	SudoPassword = "hunter2"
+
+	h := &Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}, Environment: map[string]string{"HTTPS_PROXY": "http://proxy:3128"}}
+	h.Configurer = &mockconfigurer{}
+
+	cmd, _ := h.withEnvironment("apt-get update && apt-get install -y curl | tee /tmp/log", nil)
+	require.Equal(t, `/bin/sh -c 'export HTTPS_PROXY=http://proxy:3128; apt-get update && apt-get install -y curl | tee /tmp/log'`, h.withShell(cmd))
+
+	cmd, _ = h.withEnvironment("apt-get update && apt-get install -y curl", []exec.Option{exec.Sudo(h)})
+	sudo, err := h.Sudo(cmd)
+	require.NoError(t, err)
+	require.Equal(t, `sudo -k -S -p '' -- /bin/sh -c 'export HTTPS_PROXY=http://proxy:3128; apt-get update && apt-get install -y curl'`, sudo)
+}
+
+func TestHostExecVerboseCommands(t *testing.T) {
+	defer func(v bool) { VerboseCommands = v }(VerboseCommands)
+	hook := logtest.NewGlobal()
+	defer log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+
+	h := &Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+
+	VerboseCommands = false
+	_ = h.Exec("echo hello")
+	require.Empty(t, hook.AllEntries())
+
+	VerboseCommands = true
+	_ = h.Execf("echo %s", "secret-value", exec.RedactString("secret-value"))
+	_ = h.Exec("cat /etc/secret", exec.HideCommand())
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	require.Equal(t, log.InfoLevel, entries[0].Level)
+	require.Equal(t, h, entries[0].Data["host"])
+	require.Equal(t, "running: echo [REDACTED]", entries[0].Message)
+	require.Equal(t, "running: [REDACTED]", entries[1].Message)
+}
+
+func TestHostSudoPassword(t *testing.T) {
+	defer func(p string) { SudoPassword = p }(SudoPassword)
+	SudoPassword = ""
+
+	h := &Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+	_, err := h.Sudo("true")
+	require.Error(t, err, "no passwordless sudo and no password")
+	require.NoError(t, h.CheckSudoPassword())
+
+	SudoPassword = "fallback"
+	cmd, err := h.Sudo("true")
+	require.NoError(t, err)
+	require.Equal(t, "sudo -k -S -p '' -- /bin/sh -c true", cmd)
+	require.Equal(t, "fallback", h.sudoPassword())
+
+	h.SudoPassword = "hunter2"
+	require.Equal(t, "hunter2", h.sudoPassword())
+
+	o := exec.Build(withSudoPassword(h.sudoPassword(), []exec.Option{exec.Stdin("file content"), exec.RedactString("content")})...)
+	require.Equal(t, "hunter2\nfile content", o.Stdin)
+	require.Equal(t, "[REDACTED]\nfile [REDACTED]", o.Redact(o.Stdin))
+
+	err = h.CheckSudoPassword()
+	require.Error(t, err)
+	require.Equal(t, "sudo password rejected on host [ssh] 10.0.0.1:22", err.Error())
+}
+
+func TestHostWithShell(t *testing.T) {
+	defer func(p string) { SudoPassword = p }(SudoPassword)
+	SudoPassword = "hunter2"
+
+	h := &Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+	require.Equal(t, "k0s status", h.withShell("k0s status"), "no shell before the os is known")
+
+	h.Configurer = &mockconfigurer{}
+	require.Equal(t, "/bin/sh", h.ExecShell())
+	require.Equal(t, `/bin/sh -c 'echo "$HOME" | cat'`, h.withShell(`echo "$HOME" | cat`))
+
+	h.Shell = "/usr/bin/bash"
+	require.Equal(t, `/usr/bin/bash -c 'echo '"'"'hello'"'"''`, h.withShell("echo 'hello'"))
+
+	cmd, err := h.Sudo("k0s status")
+	require.NoError(t, err)
+	require.Equal(t, "sudo -k -S -p '' -- /usr/bin/bash -c 'k0s status'", cmd)
+}
@@ -1,10 +1,15 @@
 package cluster
 
 import (
-	"strconv"
+	"regexp"
 	"strings"
+
+	"github.com/alessio/shellescape"
 )
 
+// flagNameRe matches a valid flag name such as --enable-worker or -c
+var flagNameRe = regexp.MustCompile(`^--?[A-Za-z0-9][A-Za-z0-9-]*$`)
+
 // Flags is a slice of strings with added functions to ease manipulating lists of command-line flags
 type Flags []string
 
@@ -74,13 +79,7 @@ func (f Flags) GetValue(s string) string {
 		return ""
 	}
 
-	val := fl[idx+1:]
-	s, err := strconv.Unquote(val)
-	if err == nil {
-		return s
-	}
-
-	return val
+	return unquote(fl[idx+1:])
 }
 
 // Delete removes a matching flag from the list
@@ -117,3 +116,65 @@ func (f *Flags) MergeAdd(b Flags) {
 func (f *Flags) Join() string {
 	return strings.Join(*f, " ")
 }
+
+// Invalid returns the first flag with a name that does not look like a command-line flag or an empty string if there are none
+func (f Flags) Invalid() string {
+	for _, flag := range f {
+		name := flag
+		if idx := strings.IndexAny(flag, "= "); idx >= 0 {
+			name = flag[:idx]
+		}
+		if !flagNameRe.MatchString(name) {
+			return flag
+		}
+	}
+	return ""
+}
+
+// Quoted returns the flags with the values quoted for the shell. A value that is already wrapped in quotes
+// is unquoted first, so every value ends up quoted exactly once.
+func (f Flags) Quoted() Flags {
+	res := make(Flags, 0, len(f))
+	for _, flag := range f {
+		idx := strings.IndexAny(flag, "= ")
+		if idx < 0 {
+			res = append(res, shellescape.Quote(flag))
+			continue
+		}
+		res = append(res, flag[:idx+1]+shellescape.Quote(unquote(flag[idx+1:])))
+	}
+	return res
+}
+
+// unquote removes the surrounding single or double quotes from a value
+func unquote(s string) string {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return s
+	}
+	switch s[0] {
+	case '\'':
+		return s[1 : len(s)-1]
+	case '"':
+		return unQE(s)
+	}
+	return s
+}
+
+// Redacted returns the flags with the values of token flags such as --token replaced for logging
+func (f Flags) Redacted() Flags {
+	res := make(Flags, 0, len(f))
+	for _, flag := range f {
+		idx := strings.IndexAny(flag, "= ")
+		if idx < 0 {
+			res = append(res, flag)
+			continue
+		}
+		name := flag[:idx]
+		if strings.Contains(name, "token") && !strings.HasSuffix(name, "-file") {
+			res = append(res, flag[:idx+1]+"[REDACTED]")
+			continue
+		}
+		res = append(res, flag)
+	}
+	return res
+}
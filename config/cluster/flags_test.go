@@ -49,3 +49,23 @@ func TestString(t *testing.T) {
 	flags := Flags{"--help", "--setting=false"}
 	require.Equal(t, "--help --setting=false", flags.Join())
 }
+
+func TestFlagsInvalid(t *testing.T) {
+	require.Equal(t, "", Flags{"--enable-worker", `--kubelet-extra-args="--foo bar"`, "--labels=a;reboot", "-c /etc/k0s.yaml"}.Invalid())
+	require.Equal(t, "--debug;reboot", Flags{"--enable-worker", "--debug;reboot"}.Invalid())
+	require.Equal(t, "$(id)", Flags{"$(id)"}.Invalid())
+	require.Equal(t, "--", Flags{"--"}.Invalid())
+}
+
+func TestFlagsQuoted(t *testing.T) {
+	flags := Flags{"--enable-worker", "--labels=a=b", `--token-file "/etc/k0s/token"`, "--kubelet-extra-args=--foo bar", "--data-dir="}
+	require.Equal(t, Flags{"--enable-worker", "--labels=a=b", "--token-file /etc/k0s/token", "--kubelet-extra-args='--foo bar'", "--data-dir=''"}, flags.Quoted())
+
+	flags = Flags{"--labels=a;reboot", `--data-dir="$(id)"`, "--debug='`id`'"}
+	require.Equal(t, Flags{`--labels='a;reboot'`, `--data-dir='$(id)'`, "--debug='`id`'"}, flags.Quoted())
+}
+
+func TestFlagsRedacted(t *testing.T) {
+	flags := Flags{"--token=secret", "--token-file /etc/k0s/token", "--enable-worker"}
+	require.Equal(t, Flags{"--token=[REDACTED]", "--token-file /etc/k0s/token", "--enable-worker"}, flags.Redacted())
+}
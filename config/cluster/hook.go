@@ -1,10 +1,44 @@
 package cluster
 
 // Hooks define a list of hooks such as hooks["apply"]["before"] = ["ls -al", "rm foo.txt"]
-type Hooks map[string]map[string][]string
+type Hooks map[string]map[string][]Hook
+
+// Hook is a command to run on a host. In the configuration it can be given as a plain command string or as
+// a mapping with the command in "cmd" and options.
+type Hook struct {
+	Cmd string `yaml:"cmd"`
+	// IgnoreErrors makes a failure of the command not fail the operation
+	IgnoreErrors bool `yaml:"ignoreErrors,omitempty"`
+}
+
+// UnmarshalYAML accepts both the string and the mapping form
+func (h *Hook) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var cmd string
+	if err := unmarshal(&cmd); err == nil {
+		h.Cmd = cmd
+		return nil
+	}
+
+	type hook Hook
+	return unmarshal((*hook)(h))
+}
+
+// MarshalYAML uses the string form when there are no options set
+func (h Hook) MarshalYAML() (interface{}, error) {
+	if !h.IgnoreErrors {
+		return h.Cmd, nil
+	}
+	type hook Hook
+	return hook(h), nil
+}
+
+// String returns the command of the hook
+func (h Hook) String() string {
+	return h.Cmd
+}
 
 // ForActionAndStage return hooks for given action and stage
-func (h Hooks) ForActionAndStage(action, stage string) []string {
+func (h Hooks) ForActionAndStage(action, stage string) []Hook {
 	if len(h[action]) > 0 {
 		return h[action][stage]
 	}
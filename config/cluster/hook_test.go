@@ -0,0 +1,27 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestHooksUnmarshal(t *testing.T) {
+	var hooks Hooks
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+apply:
+  after:
+    - kubectl label node foo bar=baz
+    - cmd: systemctl restart monitoring-agent
+      ignoreErrors: true
+`), &hooks))
+
+	after := hooks.ForActionAndStage("apply", "after")
+	require.Equal(t, []Hook{{Cmd: "kubectl label node foo bar=baz"}, {Cmd: "systemctl restart monitoring-agent", IgnoreErrors: true}}, after)
+	require.Empty(t, hooks.ForActionAndStage("reset", "before"))
+
+	out, err := yaml.Marshal(hooks)
+	require.NoError(t, err)
+	require.Equal(t, "apply:\n  after:\n  - kubectl label node foo bar=baz\n  - cmd: systemctl restart monitoring-agent\n    ignoreErrors: true\n", string(out))
+}
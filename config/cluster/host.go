@@ -3,11 +3,13 @@ package cluster
 import (
 	"encoding/json"
 	"fmt"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/alessio/shellescape"
 	"github.com/avast/retry-go"
 	"github.com/creasty/defaults"
 	"github.com/k0sproject/rig"
@@ -21,21 +23,39 @@ import (
 type Host struct {
 	rig.Connection `yaml:",inline"`
 
+	Name             string            `yaml:"name,omitempty"`
 	Role             string            `yaml:"role" validate:"oneof=controller worker controller+worker"`
+	Leader           bool              `yaml:"leader,omitempty"`
+	Arch             string            `yaml:"arch,omitempty" validate:"omitempty,oneof=amd64 arm64 arm"`
+	Profile          string            `yaml:"profile,omitempty"`
 	PrivateInterface string            `yaml:"privateInterface,omitempty"`
 	PrivateAddress   string            `yaml:"privateAddress,omitempty" validate:"omitempty,ip"`
 	Environment      map[string]string `yaml:"environment,flow,omitempty" default:"{}"`
 	UploadBinary     bool              `yaml:"uploadBinary,omitempty"`
 	K0sBinaryPath    string            `yaml:"k0sBinaryPath,omitempty"`
+	K0sInstallPath   string            `yaml:"k0sInstallPath,omitempty"`
+	K0sVersion       string            `yaml:"k0sVersion,omitempty"`
 	InstallFlags     Flags             `yaml:"installFlags,omitempty"`
 	Files            []UploadFile      `yaml:"files,omitempty"`
 	OSIDOverride     string            `yaml:"os,omitempty"`
 	HostnameOverride string            `yaml:"hostname,omitempty"`
 	Hooks            Hooks             `yaml:"hooks,omitempty"`
+	Prerequisites    Prerequisites     `yaml:"prerequisites,omitempty"`
+	Labels           NodeLabels        `yaml:"labels,omitempty"`
+	Taints           []Taint           `yaml:"taints,omitempty"`
+	ConnectTimeout   time.Duration     `yaml:"connectTimeout,omitempty" validate:"gte=0"`
+	SudoPassword     string            `yaml:"sudoPassword,omitempty"`
+	Shell            string            `yaml:"shell,omitempty"`
+	SSHKeepAlive     KeepAlive         `yaml:"-"`
+	SSHAuth          SSHAuth           `yaml:"-"`
+	// SSHAgentForwarding is set from the agentForwarding field of the ssh connection configuration
+	SSHAgentForwarding bool `yaml:"-"`
 
 	UploadBinaryPath string       `yaml:"-"`
 	Metadata         HostMetadata `yaml:"-"`
 	Configurer       configurer   `yaml:"-"`
+
+	k0s *K0s
 }
 
 type configurer interface {
@@ -58,11 +78,14 @@ type configurer interface {
 	ReadFile(os.Host, string) (string, error)
 	FileExist(os.Host, string) bool
 	Chmod(os.Host, string, string) error
-	DownloadK0s(os.Host, string, string) error
+	DownloadK0s(os.Host, string, map[string]string) error
 	InstallPackage(os.Host, ...string) error
 	FileContains(os.Host, string, string) bool
 	MoveFile(os.Host, string, string) error
 	DeleteFile(os.Host, string) error
+	Sha256sum(os.Host, string) (string, error)
+	FileSize(os.Host, string) (int64, error)
+	AppendFile(os.Host, string, string) error
 	CommandExist(os.Host, string) bool
 	Hostname(os.Host) string
 	KubectlCmdf(string, ...interface{}) string
@@ -70,6 +93,7 @@ type configurer interface {
 	IsContainer(os.Host) bool
 	FixContainer(os.Host) error
 	HTTPStatus(os.Host, string) (int, error)
+	TCPConnect(os.Host, string, int) error
 	PrivateInterface(os.Host) (string, error)
 	PrivateAddress(os.Host, string, string) (string, error)
 	TempDir(os.Host) (string, error)
@@ -86,6 +110,8 @@ type HostMetadata struct {
 	Hostname          string
 	Ready             bool
 	NeedsUpgrade      bool
+	// Cordoned is true while the node has been cordoned by k0sctl during the run
+	Cordoned bool
 }
 
 // UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
@@ -93,13 +119,32 @@ func (h *Host) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type host Host
 	yh := (*host)(h)
 
-	if err := unmarshal(yh); err != nil {
+	if err := unmarshalHost(unmarshal, yh, &h.SSHKeepAlive, &h.SSHAuth, &h.SSHAgentForwarding); err != nil {
 		return err
 	}
 
+	normalizeAddresses(&h.Connection)
+	h.PrivateAddress = UnbracketAddress(h.PrivateAddress)
+
 	return defaults.Set(h)
 }
 
+// MarshalYAML puts the keepalive, authentication and agent forwarding settings back into the ssh
+// connection so that the output can be read back in
+func (h Host) MarshalYAML() (interface{}, error) {
+	type host Host
+	if h.SSH == nil || (h.SSHKeepAlive.Interval == nil && h.SSHKeepAlive.CountMax == nil && len(h.SSHAuth.Methods) == 0 && h.SSHAuth.Password == "" && !h.SSHAgentForwarding) {
+		return host(h), nil
+	}
+	return marshalHost(host(h), h.SSHKeepAlive, h.SSHAuth, h.SSHAgentForwarding)
+}
+
+// Connect to the host
+func (h *Host) Connect() error {
+	defer bracketAddresses(&h.Connection)()
+	return h.Connection.Connect()
+}
+
 // Address returns an address for the host
 func (h *Host) Address() string {
 	if h.SSH != nil {
@@ -138,6 +183,13 @@ func (h *Host) ResolveConfigurer() error {
 	}
 
 	if c, ok := bf().(configurer); ok {
+		if h.K0sInstallPath != "" {
+			setter, ok := c.(interface{ SetK0sBinaryPath(string) })
+			if !ok {
+				return fmt.Errorf("k0sInstallPath is not supported on %s", h.OSVersion)
+			}
+			setter.SetK0sBinaryPath(h.K0sInstallPath)
+		}
 		h.Configurer = c
 
 		return nil
@@ -146,9 +198,51 @@ func (h *Host) ResolveConfigurer() error {
 	return fmt.Errorf("unsupported OS")
 }
 
+// K0sInstallFlags returns the install flags of the host merged with the cluster-wide spec.k0s installFlags
+// and dataDir, a flag set on the host takes precedence
+func (h *Host) K0sInstallFlags() Flags {
+	flags := make(Flags, len(h.InstallFlags))
+	copy(flags, h.InstallFlags)
+	if h.k0s != nil {
+		if h.k0s.DataDir != "" {
+			flags.AddUnlessExist("--data-dir=" + h.k0s.DataDir)
+		}
+		flags.Merge(h.k0s.InstallFlags)
+	}
+	return flags
+}
+
+// EnvironmentVars returns the spec.k0s.environment variables merged with the environment of the host,
+// the values of the host take precedence
+func (h *Host) EnvironmentVars() map[string]string {
+	env := make(map[string]string)
+	if h.k0s != nil {
+		for k, v := range h.k0s.Environment {
+			env[k] = v
+		}
+	}
+	for k, v := range h.Environment {
+		env[k] = v
+	}
+	return env
+}
+
+// ServiceOverrides returns the spec.k0s.serviceOverrides systemd drop-in
+func (h *Host) ServiceOverrides() ServiceOverrides {
+	if h.k0s == nil {
+		return ServiceOverrides{}
+	}
+	return h.k0s.ServiceOverrides
+}
+
+// K0sServiceOverridePath returns the path of the systemd drop-in file for the k0s service
+func (h *Host) K0sServiceOverridePath() string {
+	return path.Join("/etc/systemd/system", h.K0sServiceName()+".service.d", "override.conf")
+}
+
 // K0sJoinTokenPath returns the token file path from install flags or configurer
 func (h *Host) K0sJoinTokenPath() string {
-	if path := h.InstallFlags.GetValue("--token-file"); path != "" {
+	if path := h.K0sInstallFlags().GetValue("--token-file"); path != "" {
 		return path
 	}
 
@@ -157,11 +251,12 @@ func (h *Host) K0sJoinTokenPath() string {
 
 // K0sConfigPath returns the config file path from install flags or configurer
 func (h *Host) K0sConfigPath() string {
-	if path := h.InstallFlags.GetValue("--config"); path != "" {
+	flags := h.K0sInstallFlags()
+	if path := flags.GetValue("--config"); path != "" {
 		return path
 	}
 
-	if path := h.InstallFlags.GetValue("-c"); path != "" {
+	if path := flags.GetValue("-c"); path != "" {
 		return path
 	}
 
@@ -183,7 +278,7 @@ func unQE(s string) string {
 // K0sInstallCommand returns a full command that will install k0s service with necessary flags
 func (h *Host) K0sInstallCommand() string {
 	role := h.Role
-	flags := h.InstallFlags
+	flags := h.K0sInstallFlags()
 
 	if role == "controller+worker" {
 		role = "controller"
@@ -191,30 +286,38 @@ func (h *Host) K0sInstallCommand() string {
 	}
 
 	if !h.Metadata.IsK0sLeader {
-		flags.AddUnlessExist(fmt.Sprintf(`--token-file "%s"`, h.K0sJoinTokenPath()))
+		flags.AddUnlessExist("--token-file=" + h.K0sJoinTokenPath())
 	}
 
 	if h.IsController() {
-		flags.AddUnlessExist(fmt.Sprintf(`--config "%s"`, h.K0sConfigPath()))
+		flags.AddUnlessExist("--config=" + h.K0sConfigPath())
+	}
+
+	if h.Profile != "" && strings.HasSuffix(h.Role, "worker") {
+		flags.AddOrReplace("--profile=" + h.Profile)
 	}
 
 	if strings.HasSuffix(h.Role, "worker") && h.PrivateAddress != "" {
 		// set worker's private address to --node-ip in --extra-kubelet-args
 		var extra Flags
 		if old := flags.GetValue("--kubelet-extra-args"); old != "" {
-			extra = Flags{unQE(old)}
+			extra = Flags{old}
 		}
 		extra.AddUnlessExist(fmt.Sprintf("--node-ip=%s", h.PrivateAddress))
 		if h.HostnameOverride != "" {
 			extra.AddOrReplace(fmt.Sprintf("--hostname-override=%s", h.HostnameOverride))
 		}
-		flags.AddOrReplace(fmt.Sprintf("--kubelet-extra-args=%s", strconv.Quote(extra.Join())))
+		flags.AddOrReplace("--kubelet-extra-args=" + extra.Join())
 	}
 
-	cmd := h.Configurer.K0sCmdf("install %s %s", role, flags.Join())
+	quoted := flags.Quoted()
+	redacted := quoted.Redacted()
+	log.WithField("host", h).Debugf("k0s install command: %s", h.Configurer.K0sCmdf("install %s %s", role, redacted.Join()))
+
+	cmd := h.Configurer.K0sCmdf("install %s %s", role, quoted.Join())
 	sudocmd, err := h.Sudo(cmd)
 	if err != nil {
-		log.Warnf("%s: %s", h, err.Error())
+		log.WithField("host", h).Warnf("%s", err.Error())
 		return cmd
 	}
 	return sudocmd
@@ -222,12 +325,50 @@ func (h *Host) K0sInstallCommand() string {
 
 // K0sBackupCommand returns a full command to be used as run k0s backup
 func (h *Host) K0sBackupCommand(targetDir string) string {
-	return h.Configurer.K0sCmdf("backup --save-path %s", targetDir)
+	return h.Configurer.K0sCmdf("backup --save-path %s%s", targetDir, h.k0sDataDirFlag())
 }
 
 // K0sRestoreCommand returns a full command to restore cluster state from a backup
 func (h *Host) K0sRestoreCommand(backupfile string) string {
-	return h.Configurer.K0sCmdf("restore %s", backupfile)
+	return h.Configurer.K0sCmdf("restore %s%s", backupfile, h.k0sDataDirFlag())
+}
+
+// K0sResetCommand returns a full command to uninstall k0s and remove its data directory
+func (h *Host) K0sResetCommand() string {
+	return h.Configurer.K0sCmdf("reset%s", h.k0sDataDirFlag())
+}
+
+// K0sStatusCommand returns a full command to get the status of k0s in json
+func (h *Host) K0sStatusCommand() string {
+	return h.Configurer.K0sCmdf("status -o json%s", h.k0sDataDirFlag())
+}
+
+// K0sDataDir returns the k0s data directory from install flags or an empty string when the k0s default is used
+func (h *Host) K0sDataDir() string {
+	return h.K0sInstallFlags().GetValue("--data-dir")
+}
+
+func (h *Host) k0sDataDirFlag() string {
+	if dir := h.K0sDataDir(); dir != "" {
+		return " --data-dir=" + shellescape.Quote(dir)
+	}
+	return ""
+}
+
+// KubeconfigPath returns the path to the admin kubeconfig in the k0s data directory
+func (h *Host) KubeconfigPath() string {
+	if dir := h.K0sDataDir(); dir != "" {
+		return path.Join(dir, "pki", "admin.conf")
+	}
+	return h.Configurer.KubeconfigPath()
+}
+
+// KubectlCmdf returns a command line in sprintf manner for running kubectl on the host using the admin kubeconfig
+func (h *Host) KubectlCmdf(s string, args ...interface{}) string {
+	if h.K0sDataDir() == "" {
+		return h.Configurer.KubectlCmdf(s, args...)
+	}
+	return h.Configurer.K0sCmdf(`kubectl --kubeconfig "%s" %s`, h.KubeconfigPath(), fmt.Sprintf(s, args...))
 }
 
 // IsController returns true for controller and controller+worker roles
@@ -235,6 +376,19 @@ func (h *Host) IsController() bool {
 	return h.Role == "controller" || h.Role == "controller+worker"
 }
 
+// ConnectTimeoutOr returns the connect timeout of the host or the given default when the host does not set one
+func (h *Host) ConnectTimeoutOr(def time.Duration) time.Duration {
+	if h.ConnectTimeout > 0 {
+		return h.ConnectTimeout
+	}
+	return def
+}
+
+// HasKubelet returns true when k0s runs a kubelet on the host, so that the host has a kubernetes node
+func (h *Host) HasKubelet() bool {
+	return h.Role != "controller" || h.K0sInstallFlags().Include("--enable-worker")
+}
+
 // K0sServiceName returns correct service name
 func (h *Host) K0sServiceName() string {
 	if h.Role == "controller+worker" {
@@ -243,8 +397,18 @@ func (h *Host) K0sServiceName() string {
 	return "k0s" + h.Role
 }
 
-// UpdateK0sBinary updates the binary on the host either by downloading or uploading, based on the config
-func (h *Host) UpdateK0sBinary(version string) error {
+// K0sDownloadURL returns the url for downloading the k0s binary of the version for the host
+func (h *Host) K0sDownloadURL(version string) string {
+	var k0s K0s
+	if h.k0s != nil {
+		k0s = *h.k0s
+	}
+	return k0s.DownloadURL(version, fmt.Sprintf("k0s-v%s-%s", strings.TrimPrefix(version, "v"), h.Metadata.Arch))
+}
+
+// UpdateK0sBinary updates the binary on the host either by downloading or uploading, based on the config.
+// The env is passed to the download command, it is used for the proxy settings.
+func (h *Host) UpdateK0sBinary(version string, env map[string]string) error {
 	if h.UploadBinaryPath != "" {
 		if err := h.Upload(h.UploadBinaryPath, h.Configurer.K0sBinaryPath(), exec.Sudo(h)); err != nil {
 			return err
@@ -253,7 +417,7 @@ func (h *Host) UpdateK0sBinary(version string) error {
 			return err
 		}
 	} else {
-		if err := h.Configurer.DownloadK0s(h, version, h.Metadata.Arch); err != nil {
+		if err := h.Configurer.DownloadK0s(h, h.K0sDownloadURL(version), env); err != nil {
 			return err
 		}
 
@@ -270,70 +434,174 @@ func (h *Host) UpdateK0sBinary(version string) error {
 	return nil
 }
 
+type kubeNodeCondition struct {
+	Status  string `json:"status"`
+	Type    string `json:"type"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// healthy is true for the Ready condition when the node is ready and for the other conditions, such as
+// MemoryPressure, when they are not in effect
+func (c kubeNodeCondition) healthy() bool {
+	if c.Type == "Ready" {
+		return c.Status == "True"
+	}
+	return c.Status == "False"
+}
+
+func (c kubeNodeCondition) String() string {
+	s := c.Type + "=" + c.Status
+	if c.healthy() || (c.Reason == "" && c.Message == "") {
+		return s
+	}
+	if c.Message == "" {
+		return fmt.Sprintf("%s (%s)", s, c.Reason)
+	}
+	return fmt.Sprintf("%s (%s: %s)", s, c.Reason, c.Message)
+}
+
 type kubeNodeStatus struct {
 	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
 		Status struct {
-			Conditions []struct {
-				Status string `json:"status"`
-				Type   string `json:"type"`
-			} `json:"conditions"`
+			Conditions []kubeNodeCondition `json:"conditions"`
 		} `json:"status"`
 	} `json:"items"`
 }
 
 // KubeNodeReady runs kubectl on the host and returns true if the given node is marked as ready
 func (h *Host) KubeNodeReady(node *Host) (bool, error) {
-	output, err := h.ExecOutput(h.Configurer.KubectlCmdf("get node -l kubernetes.io/hostname=%s -o json", node.Metadata.Hostname), exec.HideOutput(), exec.Sudo(h))
+	ready, _, err := h.kubeNodeReady(node)
+	return ready, err
+}
+
+// kubeNodeReady returns true if the given node is marked as ready and the status conditions of the node,
+// the conditions are empty when the node is not registered
+func (h *Host) kubeNodeReady(node *Host) (bool, []kubeNodeCondition, error) {
+	output, err := h.ExecOutput(h.KubectlCmdf("get node -l kubernetes.io/hostname=%s -o json", node.Metadata.Hostname), exec.HideOutput(), exec.Sudo(h))
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 	log.Tracef("node status output:\n%s\n", output)
 	status := kubeNodeStatus{}
 	if err := json.Unmarshal([]byte(output), &status); err != nil {
-		return false, fmt.Errorf("failed to decode kubectl output: %s", err.Error())
+		return false, nil, fmt.Errorf("failed to decode kubectl output: %s", err.Error())
 	}
+	var conditions []kubeNodeCondition
 	for _, i := range status.Items {
-		for _, c := range i.Status.Conditions {
-			log.Debugf("%s: node status condition %s = %s", node, c.Type, c.Status)
+		conditions = i.Status.Conditions
+		for _, c := range conditions {
+			log.WithField("host", node).Debugf("node status condition %s = %s", c.Type, c.Status)
 			if c.Type == "Ready" {
-				return c.Status == "True", nil
+				return c.Status == "True", conditions, nil
 			}
 		}
 	}
 
-	log.Debugf("%s: failed to find Ready=True state in kubectl output", node)
-	return false, nil
+	log.WithField("host", node).Debug("failed to find Ready=True state in kubectl output")
+	return false, conditions, nil
+}
+
+// KubeNodeConditions runs kubectl on the host and returns the status of the Ready condition ("True", "False"
+// or "Unknown") of each of the nodes in the cluster by node name
+func (h *Host) KubeNodeConditions() (map[string]string, error) {
+	output, err := h.ExecOutput(h.KubectlCmdf("get nodes -o json"), exec.HideOutput(), exec.Sudo(h))
+	if err != nil {
+		return nil, err
+	}
+	status := kubeNodeStatus{}
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return nil, fmt.Errorf("failed to decode kubectl output: %s", err.Error())
+	}
+
+	nodes := make(map[string]string, len(status.Items))
+	for _, i := range status.Items {
+		nodes[i.Metadata.Name] = "Unknown"
+		for _, c := range i.Status.Conditions {
+			if c.Type == "Ready" {
+				nodes[i.Metadata.Name] = c.Status
+			}
+		}
+	}
+	return nodes, nil
 }
 
-// WaitKubeNodeReady blocks until node becomes ready. TODO should probably use Context
+// NodeReadyTimeout is how long to wait for a node to become ready after installing or upgrading k0s on it
+var NodeReadyTimeout = 5 * time.Minute
+
+// nodeReadyDelay is the delay between the node readiness checks
+var nodeReadyDelay = 3 * time.Second
+
+// WaitKubeNodeReady blocks until node becomes ready or NodeReadyTimeout is reached. When the node does not become
+// ready in time, the error includes the last reported status conditions of the node.
 func (h *Host) WaitKubeNodeReady(node *Host) error {
-	return retry.Do(
-		func() error {
-			status, err := h.KubeNodeReady(node)
-			if err != nil {
-				return err
+	return waitNodeReady(node.Metadata.Hostname, NodeReadyTimeout, func() (bool, []kubeNodeCondition, error) {
+		return h.kubeNodeReady(node)
+	})
+}
+
+// waitNodeReady runs check until it reports the node ready or the timeout is reached
+func waitNodeReady(name string, timeout time.Duration, check func() (bool, []kubeNodeCondition, error)) error {
+	deadline := time.Now().Add(timeout)
+	var conditions []kubeNodeCondition
+	var registered bool
+	for {
+		ready, c, err := check()
+		if err == nil {
+			if ready {
+				return nil
 			}
-			if !status {
-				return fmt.Errorf("%s: node %s status not reported as ready", h, node.Metadata.Hostname)
+			conditions = c
+			registered = len(c) > 0
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			switch {
+			case registered:
+				list := make([]string, len(conditions))
+				for i, c := range conditions {
+					list[i] = c.String()
+				}
+				return fmt.Errorf("node %s did not become ready in %s, the node conditions are: %s", name, timeout, strings.Join(list, ", "))
+			case err != nil:
+				return fmt.Errorf("node %s did not become ready in %s: %w", name, timeout, err)
+			default:
+				return fmt.Errorf("node %s did not become ready in %s, the node has not registered to the cluster", name, timeout)
 			}
-			return nil
-		},
-		retry.DelayType(retry.CombineDelay(retry.FixedDelay, retry.RandomDelay)),
-		retry.MaxJitter(time.Second*2),
-		retry.Delay(time.Second*3),
-		retry.Attempts(120),
-		retry.LastErrorOnly(true),
-	)
+		}
+		if err != nil {
+			log.Debugf("failed to check the status of node %s: %s", name, err.Error())
+		}
+
+		delay := nodeReadyDelay
+		if delay > remaining {
+			delay = remaining
+		}
+		time.Sleep(delay)
+	}
 }
 
-// DrainNode drains the given node
-func (h *Host) DrainNode(node *Host) error {
-	return h.Exec(h.Configurer.KubectlCmdf("drain --grace-period=120 --force --timeout=5m --ignore-daemonsets --delete-local-data %s", node.Metadata.Hostname), exec.Sudo(h))
+// DrainNode drains the given node. Pods are evicted through the eviction API, so the PodDisruptionBudgets
+// are respected.
+func (h *Host) DrainNode(node *Host, gracePeriod, timeout time.Duration) error {
+	if err := h.Exec(h.KubectlCmdf("drain --grace-period=%d --force --timeout=%s --ignore-daemonsets --delete-local-data %s", int(gracePeriod.Seconds()), timeout, node.Metadata.Hostname), exec.Sudo(h)); err != nil {
+		return fmt.Errorf("failed to drain node %s within %s: %w", node.Metadata.Hostname, timeout, err)
+	}
+	return nil
 }
 
 // UncordonNode marks the node schedulable again
 func (h *Host) UncordonNode(node *Host) error {
-	return h.Exec(h.Configurer.KubectlCmdf("uncordon %s", node.Metadata.Hostname), exec.Sudo(h))
+	return h.Exec(h.KubectlCmdf("uncordon %s", node.Metadata.Hostname), exec.Sudo(h))
+}
+
+// DeleteNode removes the node object from the cluster
+func (h *Host) DeleteNode(node *Host) error {
+	return h.Exec(h.KubectlCmdf("delete node %s", node.Metadata.Hostname), exec.Sudo(h))
 }
 
 // CheckHTTPStatus will perform a web request to the url and return an error if the http status is not the expected
@@ -367,23 +635,79 @@ func (h *Host) WaitHTTPStatus(url string, expected ...int) error {
 	)
 }
 
-// WaitK0sServiceRunning blocks until the k0s service is running on the host
+// K0sStartTimeout is how long to wait for the k0s service to become ready after it has been started
+var K0sStartTimeout = 5 * time.Minute
+
+// the k0s status is first checked again after k0sStartDelay, the delay doubles after each check up to k0sStartMaxDelay
+var (
+	k0sStartDelay    = time.Second
+	k0sStartMaxDelay = 15 * time.Second
+)
+
+// WaitK0sServiceRunning blocks until the k0s service is running on the host and k0s status succeeds. The status is
+// checked with an exponential backoff until K0sStartTimeout, waiting stops early when the service has failed.
 func (h *Host) WaitK0sServiceRunning() error {
-	return retry.Do(
-		func() error {
+	return waitK0sReady(h.String(), K0sStartTimeout,
+		func() (string, error) {
 			if !h.Configurer.ServiceIsRunning(h, h.K0sServiceName()) {
-				return fmt.Errorf("not running")
+				return "", fmt.Errorf("the k0s service is not running")
 			}
-			return h.Exec(h.Configurer.K0sCmdf("status"), exec.Sudo(h))
+			return h.ExecOutput(h.K0sStatusCommand(), exec.Sudo(h))
 		},
-		retry.DelayType(retry.CombineDelay(retry.FixedDelay, retry.RandomDelay)),
-		retry.MaxJitter(time.Second*2),
-		retry.Delay(time.Second*3),
-		retry.Attempts(60),
-		retry.LastErrorOnly(true),
+		h.K0sServiceFailed,
 	)
 }
 
+// waitK0sReady runs check until it succeeds, the timeout is reached or failed returns true. The error includes
+// the output of the last check.
+func waitK0sReady(host string, timeout time.Duration, check func() (string, error), failed func() bool) error {
+	deadline := time.Now().Add(timeout)
+	delay := k0sStartDelay
+	for {
+		output, err := check()
+		if err == nil {
+			return nil
+		}
+		if output != "" {
+			err = fmt.Errorf("%w, last k0s status output: %s", err, output)
+		}
+		if failed() {
+			return fmt.Errorf("the k0s service failed to start: %w", err)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("k0s did not become ready in %s: %w", timeout, err)
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		log.WithField("host", host).Debugf("k0s is not ready yet, checking again in %s: %s", delay, err.Error())
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > k0sStartMaxDelay {
+			delay = k0sStartMaxDelay
+		}
+	}
+}
+
+// K0sServiceFailed returns true when the init system reports that the k0s service has failed and is not
+// going to come up without intervention
+func (h *Host) K0sServiceFailed() bool {
+	if h.Configurer.Kind() == "windows" {
+		return false
+	}
+	if h.Configurer.CommandExist(h, "systemctl") {
+		return h.Execf("systemctl is-failed --quiet %s 2> /dev/null", h.K0sServiceName(), exec.Sudo(h)) == nil
+	}
+	if h.Configurer.CommandExist(h, "rc-service") {
+		output, _ := h.ExecOutputf("rc-service %s status 2> /dev/null", h.K0sServiceName(), exec.Sudo(h))
+		return strings.Contains(output, "crashed")
+	}
+	return false
+}
+
 // WaitK0sServiceStopped blocks until the k0s service is no longer running on the host
 func (h *Host) WaitK0sServiceStopped() error {
 	return retry.Do(
@@ -391,7 +715,7 @@ func (h *Host) WaitK0sServiceStopped() error {
 			if h.Configurer.ServiceIsRunning(h, h.K0sServiceName()) {
 				return fmt.Errorf("k0s still running")
 			}
-			if h.Exec(h.Configurer.K0sCmdf("status"), exec.Sudo(h)) == nil {
+			if h.Exec(h.K0sStatusCommand(), exec.Sudo(h)) == nil {
 				return fmt.Errorf("k0s still running")
 			}
 			return nil
@@ -3,10 +3,12 @@ package cluster
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	cfg "github.com/k0sproject/k0sctl/configurer"
 	"github.com/k0sproject/k0sctl/configurer/linux"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 func TestHostK0sServiceName(t *testing.T) {
@@ -68,23 +70,282 @@ func TestK0sInstallCommand(t *testing.T) {
 	h := Host{Role: "worker"}
 	h.Configurer = &mockconfigurer{}
 
-	require.Equal(t, `k0s install worker --token-file "from-configurer"`, h.K0sInstallCommand())
+	require.Equal(t, `k0s install worker --token-file=from-configurer`, h.K0sInstallCommand())
 
 	h.Role = "controller"
 	h.Metadata.IsK0sLeader = true
-	require.Equal(t, `k0s install controller --config "from-configurer"`, h.K0sInstallCommand())
+	require.Equal(t, `k0s install controller --config=from-configurer`, h.K0sInstallCommand())
 	h.Metadata.IsK0sLeader = false
-	require.Equal(t, `k0s install controller --token-file "from-configurer" --config "from-configurer"`, h.K0sInstallCommand())
+	require.Equal(t, `k0s install controller --token-file=from-configurer --config=from-configurer`, h.K0sInstallCommand())
 
 	h.Role = "controller+worker"
 	h.Metadata.IsK0sLeader = true
-	require.Equal(t, `k0s install controller --enable-worker --config "from-configurer"`, h.K0sInstallCommand())
+	require.Equal(t, `k0s install controller --enable-worker --config=from-configurer`, h.K0sInstallCommand())
 	h.Metadata.IsK0sLeader = false
-	require.Equal(t, `k0s install controller --enable-worker --token-file "from-configurer" --config "from-configurer"`, h.K0sInstallCommand())
+	require.Equal(t, `k0s install controller --enable-worker --token-file=from-configurer --config=from-configurer`, h.K0sInstallCommand())
 
 	h.Role = "worker"
+	h.Profile = "gpu"
+	require.Equal(t, `k0s install worker --token-file=from-configurer --profile=gpu`, h.K0sInstallCommand())
+	h.Profile = ""
 	h.PrivateAddress = "10.0.0.9"
-	require.Equal(t, `k0s install worker --token-file "from-configurer" --kubelet-extra-args="--node-ip=10.0.0.9"`, h.K0sInstallCommand())
+	require.Equal(t, `k0s install worker --token-file=from-configurer --kubelet-extra-args=--node-ip=10.0.0.9`, h.K0sInstallCommand())
 	h.InstallFlags = []string{`--kubelet-extra-args="--foo bar"`}
-	require.Equal(t, `k0s install worker --kubelet-extra-args="--foo bar --node-ip=10.0.0.9" --token-file "from-configurer"`, h.K0sInstallCommand())
+	require.Equal(t, `k0s install worker --kubelet-extra-args='--foo bar --node-ip=10.0.0.9' --token-file=from-configurer`, h.K0sInstallCommand())
+}
+
+func TestK0sInstallCommandQuotesFlags(t *testing.T) {
+	h := Host{Role: "controller", InstallFlags: Flags{"--kubelet-extra-args=--foo bar"}}
+	h.Configurer = &mockconfigurer{}
+	h.Metadata.IsK0sLeader = true
+
+	require.Equal(t, `k0s install controller --kubelet-extra-args='--foo bar' --config=from-configurer`, h.K0sInstallCommand())
+
+	h.InstallFlags = Flags{"--labels=a=b && reboot", `--data-dir="/data/$(id)"`}
+	require.Equal(t, `k0s install controller --labels='a=b && reboot' --data-dir='/data/$(id)' --config=from-configurer`, h.K0sInstallCommand())
+}
+
+func TestK0sDataDir(t *testing.T) {
+	h := Host{Role: "controller"}
+	h.Configurer = &mockconfigurer{}
+
+	require.Equal(t, "", h.K0sDataDir())
+	require.Equal(t, "k0s reset", h.K0sResetCommand())
+	require.Equal(t, "k0s status -o json", h.K0sStatusCommand())
+	require.Equal(t, "k0s backup --save-path /tmp/backup", h.K0sBackupCommand("/tmp/backup"))
+	require.Equal(t, "/var/lib/k0s/pki/admin.conf", h.KubeconfigPath())
+
+	h.InstallFlags = Flags{"--data-dir=/data/k0s"}
+	require.Equal(t, "/data/k0s", h.K0sDataDir())
+	require.Equal(t, "k0s reset --data-dir=/data/k0s", h.K0sResetCommand())
+	require.Equal(t, "k0s status -o json --data-dir=/data/k0s", h.K0sStatusCommand())
+	require.Equal(t, "k0s backup --save-path /tmp/backup --data-dir=/data/k0s", h.K0sBackupCommand("/tmp/backup"))
+	require.Equal(t, "k0s restore /tmp/backup.tar.gz --data-dir=/data/k0s", h.K0sRestoreCommand("/tmp/backup.tar.gz"))
+	require.Equal(t, "/data/k0s/pki/admin.conf", h.KubeconfigPath())
+	require.Equal(t, `k0s kubectl --kubeconfig "/data/k0s/pki/admin.conf" get nodes`, h.KubectlCmdf("get nodes"))
+}
+
+func TestHostSSHKeepAlive(t *testing.T) {
+	h := Host{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+role: worker
+ssh:
+  address: 10.0.0.1
+  keepAliveInterval: 10s
+  keepAliveCountMax: 5
+`), &h))
+	require.Equal(t, "10.0.0.1", h.SSH.Address)
+	require.Equal(t, 10*time.Second, h.SSHKeepAlive.IntervalOr(time.Minute))
+	require.Equal(t, 5, h.SSHKeepAlive.Count())
+
+	h = Host{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte("role: worker\nssh:\n  address: 10.0.0.1\n"), &h))
+	require.Equal(t, time.Minute, h.SSHKeepAlive.IntervalOr(time.Minute))
+	require.Equal(t, DefaultKeepAliveCountMax, h.SSHKeepAlive.Count())
+
+	h = Host{}
+	err := yaml.UnmarshalStrict([]byte("role: worker\nssh:\n  address: 10.0.0.1\n  keepAliveInterval: soon\n"), &h)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ssh.keepAliveInterval")
+
+	h = Host{}
+	require.Error(t, yaml.UnmarshalStrict([]byte("role: worker\nssh:\n  address: 10.0.0.1\n  keepAliveInterval: 10s\n  unknown: true\n"), &h))
+}
+
+func TestHostMarshalSSHKeepAlive(t *testing.T) {
+	h := Host{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte("role: worker\nssh:\n  address: 10.0.0.1\n  keepAliveInterval: 10s\n  keepAliveCountMax: 5\n"), &h))
+
+	out, err := yaml.Marshal(h)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "keepAliveInterval: 10s")
+	require.Contains(t, string(out), "keepAliveCountMax: 5")
+
+	h2 := Host{}
+	require.NoError(t, yaml.UnmarshalStrict(out, &h2))
+	require.Equal(t, h.SSHKeepAlive, h2.SSHKeepAlive)
+	require.Equal(t, "10.0.0.1", h2.SSH.Address)
+}
+
+func TestHostSSHAuth(t *testing.T) {
+	h := Host{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+role: worker
+ssh:
+  address: 10.0.0.1
+  authMethods: [key, password]
+  password: secret
+`), &h))
+	require.Equal(t, []string{SSHAuthKey, SSHAuthPassword}, h.SSHAuth.Methods)
+	require.Equal(t, "secret", h.SSHAuth.Password)
+	require.NoError(t, h.SSHAuth.Validate())
+
+	out, err := yaml.Marshal(h)
+	require.NoError(t, err)
+	h2 := Host{}
+	require.NoError(t, yaml.UnmarshalStrict(out, &h2))
+	require.Equal(t, h.SSHAuth, h2.SSHAuth)
+
+	h = Host{}
+	err = yaml.UnmarshalStrict([]byte("role: worker\nssh:\n  address: 10.0.0.1\n  authMethods: key\n"), &h)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ssh.authMethods")
+}
+
+func TestHostSSHAgentForwarding(t *testing.T) {
+	h := Host{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+role: worker
+ssh:
+  address: 10.0.0.1
+  agentForwarding: true
+`), &h))
+	require.True(t, h.SSHAgentForwarding)
+	require.Equal(t, "10.0.0.1", h.SSH.Address)
+
+	out, err := yaml.Marshal(h)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "agentForwarding: true")
+	h2 := Host{}
+	require.NoError(t, yaml.UnmarshalStrict(out, &h2))
+	require.True(t, h2.SSHAgentForwarding)
+
+	h = Host{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte("role: worker\nssh:\n  address: 10.0.0.1\n"), &h))
+	require.False(t, h.SSHAgentForwarding)
+
+	err = yaml.UnmarshalStrict([]byte("role: worker\nssh:\n  address: 10.0.0.1\n  agentForwarding: yes please\n"), &h)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ssh.agentForwarding")
+}
+
+func TestSSHAuthValidate(t *testing.T) {
+	require.NoError(t, SSHAuth{}.Validate())
+	require.NoError(t, SSHAuth{Methods: []string{SSHAuthAgent, SSHAuthKey}}.Validate())
+	require.NoError(t, SSHAuth{Methods: []string{SSHAuthKeyboardInteractive}, Password: "secret"}.Validate())
+
+	err := SSHAuth{Methods: []string{"kerberos"}}.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "supported methods are key, agent, password, keyboard-interactive")
+
+	err = SSHAuth{Methods: []string{SSHAuthKey, SSHAuthKey}}.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than once")
+
+	err = SSHAuth{Methods: []string{SSHAuthKey, SSHAuthPassword}}.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ssh.password is required")
+
+	err = SSHAuth{Password: "secret"}.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only used with")
+}
+
+func TestWaitK0sReady(t *testing.T) {
+	defer func(d, m time.Duration) { k0sStartDelay, k0sStartMaxDelay = d, m }(k0sStartDelay, k0sStartMaxDelay)
+	k0sStartDelay = time.Millisecond
+	k0sStartMaxDelay = 4 * time.Millisecond
+
+	t.Run("becomes ready", func(t *testing.T) {
+		var checks int
+		err := waitK0sReady("test", time.Second, func() (string, error) {
+			checks++
+			if checks < 4 {
+				return "", fmt.Errorf("not running")
+			}
+			return "Version: v1.23.1+k0s.0", nil
+		}, func() bool { return false })
+		require.NoError(t, err)
+		require.Equal(t, 4, checks)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		var checks int
+		err := waitK0sReady("test", 20*time.Millisecond, func() (string, error) {
+			checks++
+			return fmt.Sprintf("check %d", checks), fmt.Errorf("connection refused")
+		}, func() bool { return false })
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "k0s did not become ready in 20ms: connection refused")
+		require.Contains(t, err.Error(), fmt.Sprintf("last k0s status output: check %d", checks))
+		require.Greater(t, checks, 2)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		var checks int
+		err := waitK0sReady("test", time.Second, func() (string, error) {
+			checks++
+			return "", fmt.Errorf("the k0s service is not running")
+		}, func() bool { return true })
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the k0s service failed to start")
+		require.Equal(t, 1, checks)
+	})
+}
+
+func TestWaitNodeReady(t *testing.T) {
+	defer func(d time.Duration) { nodeReadyDelay = d }(nodeReadyDelay)
+	nodeReadyDelay = time.Millisecond
+
+	notReady := []kubeNodeCondition{
+		{Type: "MemoryPressure", Status: "False", Reason: "KubeletHasSufficientMemory", Message: "kubelet has sufficient memory available"},
+		{Type: "Ready", Status: "False", Reason: "KubeletNotReady", Message: "container runtime network not ready: NetworkReady=false"},
+	}
+
+	t.Run("becomes ready", func(t *testing.T) {
+		var checks int
+		err := waitNodeReady("worker-1", time.Second, func() (bool, []kubeNodeCondition, error) {
+			checks++
+			return checks == 3, notReady, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, checks)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		err := waitNodeReady("worker-1", 10*time.Millisecond, func() (bool, []kubeNodeCondition, error) {
+			return false, notReady, nil
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "node worker-1 did not become ready in 10ms, the node conditions are: MemoryPressure=False, Ready=False (KubeletNotReady: container runtime network not ready: NetworkReady=false)")
+	})
+
+	t.Run("last known conditions", func(t *testing.T) {
+		var checks int
+		err := waitNodeReady("worker-1", 10*time.Millisecond, func() (bool, []kubeNodeCondition, error) {
+			checks++
+			if checks == 1 {
+				return false, notReady, nil
+			}
+			return false, nil, fmt.Errorf("connection refused")
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Ready=False (KubeletNotReady")
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		err := waitNodeReady("worker-1", 10*time.Millisecond, func() (bool, []kubeNodeCondition, error) {
+			return false, nil, nil
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the node has not registered to the cluster")
+	})
+
+	t.Run("kubectl fails", func(t *testing.T) {
+		err := waitNodeReady("worker-1", 10*time.Millisecond, func() (bool, []kubeNodeCondition, error) {
+			return false, nil, fmt.Errorf("connection refused")
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "node worker-1 did not become ready in 10ms: connection refused")
+	})
+}
+
+func TestHostHasKubelet(t *testing.T) {
+	require.True(t, (&Host{Role: "worker"}).HasKubelet())
+	require.True(t, (&Host{Role: "controller+worker"}).HasKubelet())
+	require.False(t, (&Host{Role: "controller"}).HasKubelet())
+	require.True(t, (&Host{Role: "controller", InstallFlags: Flags{"--enable-worker"}}).HasKubelet())
+
+	h := &Host{Role: "controller", k0s: &K0s{InstallFlags: Flags{"--enable-worker"}}}
+	require.True(t, h.HasKubelet(), "--enable-worker from spec.k0s.installFlags")
 }
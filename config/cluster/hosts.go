@@ -68,40 +68,78 @@ func (hosts *Hosts) Workers() Hosts {
 	return hosts.WithRole("worker")
 }
 
+// HostError is an error that occurred on a specific host
+type HostError struct {
+	Host string
+	Err  error
+}
+
+// Error implements the error interface
+func (e HostError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Host, e.Err.Error())
+}
+
+// Unwrap returns the original error
+func (e HostError) Unwrap() error {
+	return e.Err
+}
+
+// ParallelError is returned from ParallelEach when the function fails on one or more of the hosts
+type ParallelError struct {
+	Errors []HostError
+}
+
+// Error implements the error interface
+func (e *ParallelError) Error() string {
+	errors := make([]string, len(e.Errors))
+	for i, he := range e.Errors {
+		errors[i] = he.Error()
+	}
+	return fmt.Sprintf("failed on %d hosts:\n - %s", len(errors), strings.Join(errors, "\n - "))
+}
+
 // ParallelEach runs a function (or multiple functions chained) on every Host parallelly.
-// Any errors will be concatenated and returned.
+// Any errors will be concatenated and returned as a *ParallelError.
 func (hosts *Hosts) ParallelEach(filter ...func(h *Host) error) error {
-	var wg sync.WaitGroup
-	var errors []string
-	type erritem struct {
-		address string
-		err     error
+	return hosts.BatchedParallelEach(0, filter...)
+}
+
+// BatchedParallelEach is like ParallelEach but runs the function on at most concurrency hosts at a time.
+// The hosts are started in order, so a concurrency of 1 processes them serially. A concurrency of
+// 0 or less means no limit.
+func (hosts *Hosts) BatchedParallelEach(concurrency int, filter ...func(h *Host) error) error {
+	if concurrency <= 0 || concurrency > len(*hosts) {
+		concurrency = len(*hosts)
 	}
-	ec := make(chan erritem, 1)
+
+	var errors []HostError
+	var mu sync.Mutex
 
 	for _, f := range filter {
-		wg.Add(len(*hosts))
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
 
 		for _, h := range *hosts {
+			sem <- struct{}{}
+			wg.Add(1)
 			go func(h *Host) {
-				ec <- erritem{h.String(), f(h)}
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				if err := f(h); err != nil {
+					mu.Lock()
+					errors = append(errors, HostError{Host: h.String(), Err: err})
+					mu.Unlock()
+				}
 			}(h)
 		}
 
-		go func() {
-			for e := range ec {
-				if e.err != nil {
-					errors = append(errors, fmt.Sprintf("%s: %s", e.address, e.err.Error()))
-				}
-				wg.Done()
-			}
-		}()
-
 		wg.Wait()
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("failed on %d hosts:\n - %s", len(errors), strings.Join(errors, "\n - "))
+		return &ParallelError{Errors: errors}
 	}
 
 	return nil
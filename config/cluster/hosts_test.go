@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func testHosts(n int) Hosts {
+	hosts := make(Hosts, n)
+	for i := range hosts {
+		hosts[i] = &Host{Connection: rig.Connection{SSH: &rig.SSH{Address: fmt.Sprintf("10.0.0.%d", i+1), Port: 22}}}
+	}
+	return hosts
+}
+
+func TestBatchedParallelEach(t *testing.T) {
+	hosts := testHosts(10)
+
+	var running, max int32
+	err := hosts.BatchedParallelEach(3, func(h *Host) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		defer atomic.AddInt32(&running, -1)
+		return nil
+	})
+	require.NoError(t, err)
+	require.LessOrEqual(t, max, int32(3))
+}
+
+func TestBatchedParallelEachSerial(t *testing.T) {
+	hosts := testHosts(5)
+
+	var mu sync.Mutex
+	var order []string
+	err := hosts.BatchedParallelEach(1, func(h *Host) error {
+		mu.Lock()
+		order = append(order, h.Address())
+		mu.Unlock()
+		if h.Address() == "10.0.0.2" {
+			return fmt.Errorf("failed")
+		}
+		return nil
+	})
+	require.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}, order)
+
+	var perr *ParallelError
+	require.ErrorAs(t, err, &perr)
+	require.Len(t, perr.Errors, 1)
+	require.Equal(t, "[ssh] 10.0.0.2:22: failed", perr.Errors[0].Error())
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 	"time"
 
@@ -20,17 +21,140 @@ import (
 // K0sMinVersion is the minimum k0s version supported
 const K0sMinVersion = "0.11.0-rc1"
 
+// DefaultK0sDownloadURLBase is the location of the k0s release binaries
+const DefaultK0sDownloadURLBase = "https://github.com/k0sproject/k0s/releases/download"
+
+// The version channels that can be given in spec.k0s.versionChannel
+const (
+	VersionChannelStable = "stable"
+	VersionChannelLatest = "latest"
+)
+
+// latestK0sVersion looks up the version of the latest k0s release, it can be replaced in tests
+var latestK0sVersion = github.LatestK0sVersion
+
 // K0s holds configuration for bootstraping a k0s cluster
 type K0s struct {
-	Version  string      `yaml:"version" validate:"required"`
-	Config   dig.Mapping `yaml:"config,omitempty"`
-	Metadata K0sMetadata `yaml:"-"`
+	Version          string                       `yaml:"version" validate:"required_without=VersionChannel"`
+	VersionChannel   string                       `yaml:"versionChannel,omitempty"`
+	Config           dig.Mapping                  `yaml:"config,omitempty"`
+	ConfigPath       string                       `yaml:"configPath,omitempty"`
+	Upgrade          K0sUpgrade                   `yaml:"upgrade,omitempty"`
+	BinaryDir        string                       `yaml:"binaryDir,omitempty"`
+	DownloadURLBase  string                       `yaml:"downloadURLBase,omitempty"`
+	SHA256           map[string]map[string]string `yaml:"sha256,omitempty"`
+	DataDir          string                       `yaml:"dataDir,omitempty"`
+	InstallFlags     Flags                        `yaml:"installFlags,omitempty"`
+	Environment      map[string]string            `yaml:"environment,flow,omitempty"`
+	ServiceOverrides ServiceOverrides             `yaml:"serviceOverrides,omitempty"`
+	APIServerAddress string                       `yaml:"apiServerAddress,omitempty"`
+	Metadata         K0sMetadata                  `yaml:"-"`
+}
+
+// SHA256For returns the configured checksum for the k0s binary of the version and architecture or an
+// empty string if there is none
+func (k K0s) SHA256For(version, arch string) string {
+	if sums, ok := k.SHA256[version]; ok {
+		return sums[arch]
+	}
+	if sums, ok := k.SHA256["v"+version]; ok {
+		return sums[arch]
+	}
+	return ""
+}
+
+// WorkerProfiles returns the names of the worker profiles defined in spec.workerProfiles of the k0s config
+func (k K0s) WorkerProfiles() []string {
+	profiles, ok := k.Config.Dig("spec", "workerProfiles").([]interface{})
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, p := range profiles {
+		if m, ok := p.(dig.Mapping); ok {
+			if name := m.DigString("name"); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// APIServerURL returns the url of the spec.k0s.apiServerAddress load balancer the workers join through
+// or an empty string when it is not set. The port defaults to the spec.api.port of the k0s config.
+func (k K0s) APIServerURL() string {
+	if k.APIServerAddress == "" {
+		return ""
+	}
+	port := 6443
+	if p, ok := k.Config.Dig("spec", "api", "port").(int); ok {
+		port = p
+	}
+	u, err := KubeconfigAPIURL(k.APIServerAddress, port)
+	if err != nil {
+		return ""
+	}
+	return u
+}
+
+// ExternalEtcd returns the spec.storage.etcd.externalCluster of the k0s configuration or nil when the
+// etcd cluster is managed by k0s
+func (k K0s) ExternalEtcd() dig.Mapping {
+	if ext, ok := k.Config.Dig("spec", "storage", "etcd", "externalCluster").(dig.Mapping); ok {
+		return ext
+	}
+	return nil
+}
+
+// DownloadURL returns the url of a k0s release binary, the binaries are expected to be found under
+// <base>/v<version>/<filename> like in the github releases
+func (k K0s) DownloadURL(version, filename string) string {
+	base := k.DownloadURLBase
+	if base == "" {
+		base = DefaultK0sDownloadURLBase
+	}
+	return fmt.Sprintf("%s/v%s/%s", strings.TrimSuffix(base, "/"), strings.TrimPrefix(version, "v"), filename)
+}
+
+// ValidateDownloadURLBase checks that the k0s download url base is an absolute http or https url
+func ValidateDownloadURLBase(base string) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("invalid k0s download url base %q: %w", base, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid k0s download url base %q: must be an http or https url such as https://mirror.example.com/k0s/releases", base)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid k0s download url base %q: must not have a query or a fragment", base)
+	}
+	return nil
+}
+
+// K0sUpgrade holds configuration for upgrading the k0s cluster
+type K0sUpgrade struct {
+	// Drain the worker nodes before upgrading them, defaults to false
+	Drain bool `yaml:"drain,omitempty"`
+	// DrainTimeout is the time to wait for a node drain to complete before giving up
+	DrainTimeout time.Duration `yaml:"drainTimeout,omitempty" default:"5m"`
+	// DrainGracePeriod is the time given to each pod to terminate gracefully
+	DrainGracePeriod time.Duration `yaml:"drainGracePeriod,omitempty" default:"120s"`
+	// BatchSize is the number of workers upgraded at a time, the next batch is started once the nodes
+	// of the previous batch are ready
+	BatchSize int `yaml:"batchSize,omitempty" default:"1" validate:"gte=0"`
+}
+
+// DrainEnabled returns true when draining has been enabled
+func (u K0sUpgrade) DrainEnabled() bool {
+	return u.Drain
 }
 
 // K0sMetadata contains gathered information about k0s cluster
 type K0sMetadata struct {
 	ClusterID        string
 	VersionDefaulted bool
+	// VersionChannel is the version channel the version was resolved from
+	VersionChannel string
 }
 
 // UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
@@ -47,7 +171,7 @@ func (k *K0s) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // SetDefaults (implements defaults Setter interface) defaults the version to latest k0s version
 func (k *K0s) SetDefaults() {
-	if defaults.CanUpdate(k.Version) {
+	if defaults.CanUpdate(k.Version) && k.VersionChannel == "" {
 		preok := version.IsPre() || version.Version == "0.0.0"
 		if latest, err := github.LatestK0sVersion(preok); err == nil {
 			k.Version = latest
@@ -58,6 +182,37 @@ func (k *K0s) SetDefaults() {
 	k.Version = strings.TrimPrefix(k.Version, "v")
 }
 
+// ValidateVersionChannel checks that the version channel is one of the supported channels
+func ValidateVersionChannel(channel string) error {
+	if channel != VersionChannelStable && channel != VersionChannelLatest {
+		return fmt.Errorf("invalid k0s version channel %q, must be %s or %s", channel, VersionChannelStable, VersionChannelLatest)
+	}
+	return nil
+}
+
+// ResolveVersionChannel sets the version to the latest k0s release in the spec.k0s.versionChannel, the stable
+// channel leaves out the pre-releases. The channel is cleared so that the resolved configuration pins the
+// version.
+func (k *K0s) ResolveVersionChannel() error {
+	if k.VersionChannel == "" {
+		return nil
+	}
+	if k.Version != "" {
+		return fmt.Errorf("spec.k0s.version can not be given with spec.k0s.versionChannel")
+	}
+	if err := ValidateVersionChannel(k.VersionChannel); err != nil {
+		return err
+	}
+	latest, err := latestK0sVersion(k.VersionChannel == VersionChannelLatest)
+	if err != nil {
+		return fmt.Errorf("failed to look up the latest k0s release in the %s version channel from github, set spec.k0s.version when the k0s releases can not be reached: %w", k.VersionChannel, err)
+	}
+	k.Version = strings.TrimPrefix(latest, "v")
+	k.Metadata.VersionChannel = k.VersionChannel
+	k.VersionChannel = ""
+	return nil
+}
+
 // GenerateToken runs the k0s token create command
 func (k K0s) GenerateToken(h *Host, role string, expiry time.Duration) (token string, err error) {
 	err = retry.Do(
@@ -80,33 +235,42 @@ func (k K0s) GenerateToken(h *Host, role string, expiry time.Duration) (token st
 
 // GetClusterID uses kubectl to fetch the kube-system namespace uid
 func (k K0s) GetClusterID(h *Host) (string, error) {
-	return h.ExecOutput(h.Configurer.KubectlCmdf("get -n kube-system namespace kube-system -o template={{.metadata.uid}}"), exec.Sudo(h))
+	return h.ExecOutput(h.KubectlCmdf("get -n kube-system namespace kube-system -o template={{.metadata.uid}}"), exec.Sudo(h))
 }
 
-// TokenID returns a token id from a token string that can be used to invalidate the token
-func TokenID(s string) (string, error) {
+// decodeToken returns the kubeconfig inside a join token
+func decodeToken(s string) (dig.Mapping, error) {
 	b64 := make([]byte, base64.StdEncoding.DecodedLen(len(s)))
 	_, err := base64.StdEncoding.Decode(b64, []byte(s))
 	if err != nil {
-		return "", fmt.Errorf("failed to decode token: %w", err)
+		return nil, fmt.Errorf("failed to decode token: %w", err)
 	}
 
 	sr := strings.NewReader(s)
 	b64r := base64.NewDecoder(base64.StdEncoding, sr)
 	gzr, err := gzip.NewReader(b64r)
 	if err != nil {
-		return "", fmt.Errorf("failed to create a reader for token: %w", err)
+		return nil, fmt.Errorf("failed to create a reader for token: %w", err)
 	}
 	defer gzr.Close()
 
 	c, err := io.ReadAll(gzr)
 	if err != nil {
-		return "", fmt.Errorf("failed to uncompress token: %w", err)
+		return nil, fmt.Errorf("failed to uncompress token: %w", err)
 	}
 	cfg := dig.Mapping{}
 	err = yaml.Unmarshal(c, &cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to unmarshal token: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal token: %w", err)
+	}
+	return cfg, nil
+}
+
+// TokenID returns a token id from a token string that can be used to invalidate the token
+func TokenID(s string) (string, error) {
+	cfg, err := decodeToken(s)
+	if err != nil {
+		return "", err
 	}
 
 	users, ok := cfg.Dig("users").([]interface{})
@@ -130,3 +294,42 @@ func TokenID(s string) (string, error) {
 	}
 	return token[0:idx], nil
 }
+
+// TokenWithServer returns the join token with the kubernetes api server address of its kubeconfig
+// replaced with the given url
+func TokenWithServer(s, server string) (string, error) {
+	cfg, err := decodeToken(s)
+	if err != nil {
+		return "", err
+	}
+
+	clusters, ok := cfg.Dig("clusters").([]interface{})
+	if !ok || len(clusters) < 1 {
+		return "", fmt.Errorf("failed to find clusters in token")
+	}
+	for _, c := range clusters {
+		c, ok := c.(dig.Mapping)
+		if !ok {
+			return "", fmt.Errorf("failed to find cluster in token")
+		}
+		c.DigMapping("cluster")["server"] = server
+	}
+
+	c, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token: %w", err)
+	}
+	var buf strings.Builder
+	b64w := base64.NewEncoder(base64.StdEncoding, &buf)
+	gzw := gzip.NewWriter(b64w)
+	if _, err := gzw.Write(c); err != nil {
+		return "", fmt.Errorf("failed to compress token: %w", err)
+	}
+	if err := gzw.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress token: %w", err)
+	}
+	if err := b64w.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+	return buf.String(), nil
+}
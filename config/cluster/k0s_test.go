@@ -1,9 +1,13 @@
 package cluster
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/k0sproject/dig"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 func TestTokenID(t *testing.T) {
@@ -13,3 +17,142 @@ func TestTokenID(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "i6i3yg", id)
 }
+
+func TestTokenWithServer(t *testing.T) {
+	token := "H4sIAAAAAAAC/2xVXY/iOBZ9r1/BH6geO4GeAWkfKiEmGGLKjn1N/BbidAFOgjuk+Frtf18V3SPtSvN2fc/ROdaVfc9L6Q9Q9+fDqZuNLvilaj7PQ92fZy+vo9/17GU0Go3OdX+p+9loPwz+PPvjD/xn8A3/+Q19C2bfx+Pwyanqfjj8OFTlUL+Wn8P+1B+G+6sth3I2WudoWOc4FspSeYjmAqjKlaEcESWeGBpih2muRCQSNucavEEkzBWNDGoApDV1t19W6uNSbJsyRzS1mPc7TVdiDknV0qNFQmjl1zvsaZmao3RECHVd8YZEFtlEgGW8ISmXBIQiY6km+wwbr5v9yoIvVHs71pL81CAio0yYpQ2DJMFSe1InWHEZMZHQveiqa/3hf2Eg+v/FpKJdnZifHCA2aKK5IwwSsbVzYnZgJkWLdUZ8IbfCZA5CE1hSKhxliZ2rkKRxw2hxZIlSEHMgwFWCckUTi8iTmyNy+ZqJUtktO2Y9C8Wpuk8DsTUT7ehnjt9uBTQ0T7yDB9nyw+A4Tlb5wt2NbHgB5LSJpwvR2Ytpp6oKm/lG2ZvUZoDERjs9vubzamxJcZEaX6vDwLKWFeUWIoOqi7z/hWx7c2q77DfcJ5BkQQFAyxYw6xix8BZILAar8Ha3GM7l420ssZ/UZE/rrQtUytSus4ssXGKOissKkdgiOskw1fowPKRqxnFLPy0hj1pPvV6IC0t4AOhGgZDlZjFdGYdXLBVZBozKrUccW6Ra2mQNm5sF9bsHXRVqv8lB7E3XmNyZjKHTSm7Jp82HyxoJDom56HY8zgFa6/xCoOtdIL8qF8t71rDUYBZAI247ZHnpiluZn+9WNu8GsvEusFuOpvNS20J/+GUN1aN2U2kfpFQouVaBj3PsW6VgXwXVeJfSd4DlLdN2JR+gqoAed8hEBcB7OXc4J3Dl2jLuSCQCL0pHo9jhiCU2ygCcSC3hh2moFEQWNTFvfaQS2snGLJXDMdfFWCiquBKRUh8XqZZXgZIbaJEYTLbcUQnBtLDkY8VbWuzmMAhH97ka1tWWKN1lvQFLICEb3tq+0vu+VNXEPqKvN/gQjkQSsejLv3BsUjTRNk8mpNbMF46d1Ju/SURPRWihBOJtS5eVwp9ZQhvIB8+UCo1ksSXg7IPcS2wNc35cphHKVKNE4rebbSR2ODpxd5uYAA/VfH+JW9Jt1GRv231eJ9mj1uao2+Z7pRrB2ulP4+xF5kOxDtUF3PLKJXmXCb4XgQmzuRFVmmGZnCaA/nrIBdCvuRduvMpVs8lcNi7UcDVhRG0A93JLYpP66yqYgJoLoZumlQ9x2xFD8znIkux77oacdWqSdZSVyjCWnkKmb+9WDz/Nh5+b9O1SIDIUHaC6bW5V4qFsYSnSRmUIloXCuV1MaE7IsQAxBkR5ndqASRZtFDVGm7VszHGzwEfhJqzUzTV2tMi1iG369dfsmjVvkxKKfhMPgjsccEUPLMmCTcJCsTDrfGHGdXsOJcBpo4ezQd7sQroC3EQrdLtVD+Z16lZCY58rEO8SrX7vZiId/+AIckiaRa5YBIl67uU1P/3rZTTqyraejRw6v1Snbqhvw6+U+FX/Som/I+PJ+mp8np+nz13d1MPr7nQazkNf+v9X++z7uhte/1Z6Nt2hs7NRfOp+HD5efF//qPu6q+rzbPTv/7x8qT7Nf4v8g/zT+HmF4eTqbjY6fD+E949vVzeZ7vHx8mM6uPCATi//DQAA//+MVAsnAgcAAA=="
+
+	lb, err := TokenWithServer(token, "https://lb.example.com:6443")
+	require.NoError(t, err)
+
+	cfg, err := decodeToken(lb)
+	require.NoError(t, err)
+	clusters, ok := cfg.Dig("clusters").([]interface{})
+	require.True(t, ok)
+	require.Len(t, clusters, 1)
+	c, ok := clusters[0].(dig.Mapping)
+	require.True(t, ok)
+	require.Equal(t, "https://lb.example.com:6443", c.DigString("cluster", "server"))
+
+	id, err := TokenID(lb)
+	require.NoError(t, err)
+	require.Equal(t, "i6i3yg", id)
+
+	_, err = TokenWithServer("foo", "https://lb.example.com:6443")
+	require.Error(t, err)
+}
+
+func TestK0sAPIServerURL(t *testing.T) {
+	k := K0s{}
+	require.Empty(t, k.APIServerURL())
+
+	k.APIServerAddress = "lb.example.com"
+	require.Equal(t, "https://lb.example.com:6443", k.APIServerURL())
+
+	k.Config = dig.Mapping{"spec": dig.Mapping{"api": dig.Mapping{"port": 7443}}}
+	require.Equal(t, "https://lb.example.com:7443", k.APIServerURL())
+
+	k.APIServerAddress = "10.0.0.100:8443"
+	require.Equal(t, "https://10.0.0.100:8443", k.APIServerURL())
+
+	k.APIServerAddress = "https://lb.example.com"
+	require.Equal(t, "https://lb.example.com", k.APIServerURL())
+}
+
+func TestK0sUpgradeDefaults(t *testing.T) {
+	k := &K0s{}
+	require.NoError(t, yaml.Unmarshal([]byte("version: 1.21.2+k0s.0\n"), k))
+	require.False(t, k.Upgrade.DrainEnabled())
+	require.Equal(t, 5*time.Minute, k.Upgrade.DrainTimeout)
+	require.Equal(t, 120*time.Second, k.Upgrade.DrainGracePeriod)
+	require.Equal(t, 1, k.Upgrade.BatchSize)
+
+	k = &K0s{}
+	require.NoError(t, yaml.Unmarshal([]byte("version: 1.21.2+k0s.0\nupgrade:\n  drain: true\n  drainTimeout: 10m\n  drainGracePeriod: 30s\n  batchSize: 3\n"), k))
+	require.True(t, k.Upgrade.DrainEnabled())
+	require.Equal(t, 10*time.Minute, k.Upgrade.DrainTimeout)
+	require.Equal(t, 30*time.Second, k.Upgrade.DrainGracePeriod)
+	require.Equal(t, 3, k.Upgrade.BatchSize)
+}
+
+func TestK0sSHA256For(t *testing.T) {
+	k := K0s{SHA256: map[string]map[string]string{
+		"1.21.2+k0s.0":  {"amd64": "aaaa"},
+		"v1.21.3+k0s.0": {"arm64": "bbbb"},
+	}}
+	require.Equal(t, "aaaa", k.SHA256For("1.21.2+k0s.0", "amd64"))
+	require.Equal(t, "bbbb", k.SHA256For("1.21.3+k0s.0", "arm64"))
+	require.Empty(t, k.SHA256For("1.21.2+k0s.0", "arm64"))
+	require.Empty(t, k.SHA256For("1.21.4+k0s.0", "amd64"))
+}
+
+func TestK0sDownloadURL(t *testing.T) {
+	k := K0s{}
+	require.Equal(t, "https://github.com/k0sproject/k0s/releases/download/v1.21.2+k0s.0/k0s-v1.21.2+k0s.0-amd64", k.DownloadURL("1.21.2+k0s.0", "k0s-v1.21.2+k0s.0-amd64"))
+
+	k.DownloadURLBase = "https://artifactory.example.com/k0s/releases/"
+	require.Equal(t, "https://artifactory.example.com/k0s/releases/v1.21.2+k0s.0/k0s-v1.21.2+k0s.0-arm64", k.DownloadURL("v1.21.2+k0s.0", "k0s-v1.21.2+k0s.0-arm64"))
+
+	h := &Host{k0s: &k, Metadata: HostMetadata{Arch: "arm"}}
+	require.Equal(t, "https://artifactory.example.com/k0s/releases/v1.21.2+k0s.0/k0s-v1.21.2+k0s.0-arm", h.K0sDownloadURL("1.21.2+k0s.0"))
+}
+
+func TestValidateDownloadURLBase(t *testing.T) {
+	require.NoError(t, ValidateDownloadURLBase("https://artifactory.example.com/k0s/releases"))
+	require.NoError(t, ValidateDownloadURLBase("http://10.0.0.1:8080"))
+	for _, base := range []string{"artifactory.example.com/k0s", "ftp://example.com/k0s", "https://", "https://example.com/k0s?x=1", "://foo"} {
+		require.Error(t, ValidateDownloadURLBase(base), base)
+	}
+}
+
+func TestK0sResolveVersionChannel(t *testing.T) {
+	defer func(f func(bool) (string, error)) { latestK0sVersion = f }(latestK0sVersion)
+	var gotPre bool
+	latestK0sVersion = func(preok bool) (string, error) {
+		gotPre = preok
+		if preok {
+			return "v1.22.0-rc.1+k0s.0", nil
+		}
+		return "v1.21.3+k0s.0", nil
+	}
+
+	k := &K0s{Version: "1.21.2+k0s.0"}
+	require.NoError(t, k.ResolveVersionChannel())
+	require.Equal(t, "1.21.2+k0s.0", k.Version, "no channel, the version is kept")
+
+	k = &K0s{VersionChannel: "stable"}
+	require.NoError(t, k.ResolveVersionChannel())
+	require.False(t, gotPre)
+	require.Equal(t, "1.21.3+k0s.0", k.Version)
+	require.Empty(t, k.VersionChannel)
+	require.Equal(t, "stable", k.Metadata.VersionChannel)
+
+	k = &K0s{VersionChannel: "latest"}
+	require.NoError(t, k.ResolveVersionChannel())
+	require.True(t, gotPre)
+	require.Equal(t, "1.22.0-rc.1+k0s.0", k.Version)
+
+	k = &K0s{VersionChannel: "edge"}
+	err := k.ResolveVersionChannel()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid k0s version channel "edge"`)
+
+	k = &K0s{VersionChannel: "stable", Version: "1.21.2+k0s.0"}
+	err = k.ResolveVersionChannel()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can not be given with spec.k0s.versionChannel")
+
+	latestK0sVersion = func(bool) (string, error) { return "", fmt.Errorf("dial tcp: lookup api.github.com: no such host") }
+	k = &K0s{VersionChannel: "stable"}
+	err = k.ResolveVersionChannel()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "set spec.k0s.version when the k0s releases can not be reached")
+	require.Contains(t, err.Error(), "no such host")
+	require.Empty(t, k.Version)
+}
+
+func TestK0sVersionChannelNoDefault(t *testing.T) {
+	k := &K0s{}
+	require.NoError(t, yaml.Unmarshal([]byte("versionChannel: stable\n"), k))
+	require.Empty(t, k.Version, "the version is resolved from the channel at apply time")
+	require.False(t, k.Metadata.VersionDefaulted)
+}
@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultKeepAliveCountMax is the number of unanswered keepalive probes after which an ssh connection is considered dead
+const DefaultKeepAliveCountMax = 3
+
+// KeepAlive holds the keepalive settings of the host ssh connection. The fields are set from the
+// keepAliveInterval and keepAliveCountMax fields of the ssh connection configuration.
+type KeepAlive struct {
+	Interval *time.Duration `yaml:"-" validate:"omitempty,gte=0"`
+	CountMax *int           `yaml:"-" validate:"omitempty,gte=1"`
+}
+
+// IntervalOr returns the keepalive interval or the given default when it has not been set
+func (k KeepAlive) IntervalOr(def time.Duration) time.Duration {
+	if k.Interval == nil {
+		return def
+	}
+	return *k.Interval
+}
+
+// Count returns the number of unanswered keepalive probes after which the connection is considered dead
+func (k KeepAlive) Count() int {
+	if k.CountMax == nil {
+		return DefaultKeepAliveCountMax
+	}
+	return *k.CountMax
+}
+
+var keepAliveKeys = map[string]func(*KeepAlive, interface{}) error{
+	"keepAliveInterval": func(k *KeepAlive, v interface{}) error {
+		var d time.Duration
+		switch val := v.(type) {
+		case string:
+			parsed, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			d = parsed
+		case int:
+			d = time.Duration(val) * time.Second
+		default:
+			return fmt.Errorf("must be a duration such as 30s")
+		}
+		k.Interval = &d
+		return nil
+	},
+	"keepAliveCountMax": func(k *KeepAlive, v interface{}) error {
+		count, ok := v.(int)
+		if !ok {
+			return fmt.Errorf("must be a number")
+		}
+		k.CountMax = &count
+		return nil
+	},
+}
+
+// extractKeepAlive removes the keepalive fields from the ssh connection of the host yaml, the ssh
+// connection configuration does not know about them. Returns true when the fields were found.
+func extractKeepAlive(host map[interface{}]interface{}, k *KeepAlive) (bool, error) {
+	conn, ok := host["ssh"].(map[interface{}]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	var found bool
+	for key, set := range keepAliveKeys {
+		v, ok := conn[key]
+		if !ok {
+			continue
+		}
+		found = true
+		delete(conn, key)
+		if err := set(k, v); err != nil {
+			return false, fmt.Errorf("ssh.%s: %w", key, err)
+		}
+	}
+
+	return found, nil
+}
+
+// unmarshalHost unmarshals the host yaml into the target, the keepalive, authentication and agent
+// forwarding fields are extracted first
+func unmarshalHost(unmarshal func(interface{}) error, target interface{}, k *KeepAlive, a *SSHAuth, forward *bool) error {
+	var raw map[interface{}]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	foundKeepAlive, err := extractKeepAlive(raw, k)
+	if err != nil {
+		return err
+	}
+	foundAuth, err := extractSSHAuth(raw, a)
+	if err != nil {
+		return err
+	}
+	foundForward, err := extractAgentForwarding(raw, forward)
+	if err != nil {
+		return err
+	}
+	if !foundKeepAlive && !foundAuth && !foundForward {
+		return unmarshal(target)
+	}
+
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalStrict(data, target)
+}
+
+// marshalHost returns the host as a yaml mapping with the keepalive, authentication and agent forwarding
+// fields added to the ssh connection
+func marshalHost(h interface{}, k KeepAlive, a SSHAuth, forward bool) (interface{}, error) {
+	data, err := yaml.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	var raw yaml.MapSlice
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for i, item := range raw {
+		if item.Key != "ssh" {
+			continue
+		}
+		conn, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			break
+		}
+		if k.Interval != nil {
+			conn = append(conn, yaml.MapItem{Key: "keepAliveInterval", Value: k.Interval.String()})
+		}
+		if k.CountMax != nil {
+			conn = append(conn, yaml.MapItem{Key: "keepAliveCountMax", Value: *k.CountMax})
+		}
+		if len(a.Methods) > 0 {
+			conn = append(conn, yaml.MapItem{Key: "authMethods", Value: a.Methods})
+		}
+		if a.Password != "" {
+			conn = append(conn, yaml.MapItem{Key: "password", Value: a.Password})
+		}
+		if forward {
+			conn = append(conn, yaml.MapItem{Key: "agentForwarding", Value: true})
+		}
+		raw[i].Value = conn
+	}
+	return raw, nil
+}
@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kubeconfig holds the settings of the admin kubeconfig output by k0sctl
+type Kubeconfig struct {
+	// APIAddress is the address of the kubernetes API in the kubeconfig as host[:port] or as an URL
+	APIAddress string `yaml:"apiAddress,omitempty"`
+}
+
+// hostnameRe matches dns names such as lb.example.com
+var hostnameRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+// KubeconfigAPIURL returns the kubernetes API server URL for an address given as host[:port] or as an URL. The
+// default port is used when a host is given without a port, URLs are returned as they are.
+func KubeconfigAPIURL(address string, defaultPort int) (string, error) {
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+			return "", fmt.Errorf("invalid kubernetes api address %q, must be host[:port] or an url such as https://lb.example.com:6443", address)
+		}
+		return address, nil
+	}
+
+	host, port := address, defaultPort
+	if h, p, err := net.SplitHostPort(address); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("invalid kubernetes api address %q, the port must be between 1 and 65535", address)
+		}
+		host, port = h, n
+	}
+	host = UnbracketAddress(host)
+	if net.ParseIP(host) == nil && !hostnameRe.MatchString(host) {
+		return "", fmt.Errorf("invalid kubernetes api address %q, must be host[:port] or an url such as https://lb.example.com:6443", address)
+	}
+
+	return "https://" + JoinHostPort(host, port), nil
+}
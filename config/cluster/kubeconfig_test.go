@@ -0,0 +1,29 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubeconfigAPIURL(t *testing.T) {
+	for address, expected := range map[string]string{
+		"lb.example.com":              "https://lb.example.com:6443",
+		"lb.example.com:443":          "https://lb.example.com:443",
+		"10.0.0.1":                    "https://10.0.0.1:6443",
+		"::1":                         "https://[::1]:6443",
+		"[fd00::1]:8443":              "https://[fd00::1]:8443",
+		"https://lb.example.com":      "https://lb.example.com",
+		"https://lb.example.com:6443": "https://lb.example.com:6443",
+	} {
+		url, err := KubeconfigAPIURL(address, 6443)
+		require.NoError(t, err, address)
+		require.Equal(t, expected, url)
+	}
+
+	for _, address := range []string{"lb.example.com:0", "lb.example.com:http", "lb example.com", "ftp://lb.example.com", "https://", "lb.example.com/k8s"} {
+		_, err := KubeconfigAPIURL(address, 6443)
+		require.Error(t, err, address)
+		require.Contains(t, err.Error(), "invalid kubernetes api address")
+	}
+}
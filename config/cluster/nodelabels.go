@@ -0,0 +1,109 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// NodeLabels are the labels to set on the kubernetes node of the host
+type NodeLabels map[string]string
+
+// Validate checks that the label keys and values are accepted by kubernetes
+func (l NodeLabels) Validate() error {
+	for _, k := range l.Keys() {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(l[k]); len(errs) > 0 {
+			return fmt.Errorf("invalid value %q for label %s: %s", l[k], k, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// Keys returns the sorted label keys
+func (l NodeLabels) Keys() []string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// taintEffects are the taint effects supported by kubernetes
+var taintEffects = []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+
+// Taint is a kubernetes node taint, given in the configuration as key=value:effect or key:effect
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// ParseTaint parses a taint in the key=value:effect or key:effect form
+func ParseTaint(s string) (Taint, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return Taint{}, fmt.Errorf("invalid taint %q, the taint must be given as key=value:effect or key:effect", s)
+	}
+	t := Taint{Key: s[:i], Effect: s[i+1:]}
+	if j := strings.Index(t.Key, "="); j >= 0 {
+		t.Key, t.Value = t.Key[:j], t.Key[j+1:]
+	}
+	if err := t.Validate(); err != nil {
+		return Taint{}, err
+	}
+	return t, nil
+}
+
+// Validate checks the key, value and effect of the taint
+func (t Taint) Validate() error {
+	if errs := validation.IsQualifiedName(t.Key); len(errs) > 0 {
+		return fmt.Errorf("invalid taint key %q: %s", t.Key, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(t.Value); len(errs) > 0 {
+		return fmt.Errorf("invalid value %q for taint %s: %s", t.Value, t.Key, strings.Join(errs, "; "))
+	}
+	for _, e := range taintEffects {
+		if t.Effect == e {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid effect %q for taint %s, supported effects are %s", t.Effect, t.Key, strings.Join(taintEffects, ", "))
+}
+
+// ID returns the key and the effect of the taint, which together identify the taint on a node
+func (t Taint) ID() string {
+	return t.Key + ":" + t.Effect
+}
+
+// String returns the taint in the key=value:effect form used by kubectl
+func (t Taint) String() string {
+	if t.Value == "" {
+		return t.ID()
+	}
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
+}
+
+// UnmarshalYAML parses the taint from a string
+func (t *Taint) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	taint, err := ParseTaint(s)
+	if err != nil {
+		return err
+	}
+	*t = taint
+	return nil
+}
+
+// MarshalYAML returns the taint as a string
+func (t Taint) MarshalYAML() (interface{}, error) {
+	return t.String(), nil
+}
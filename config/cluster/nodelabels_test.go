@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseTaint(t *testing.T) {
+	taint, err := ParseTaint("dedicated=db:NoSchedule")
+	require.NoError(t, err)
+	require.Equal(t, Taint{Key: "dedicated", Value: "db", Effect: "NoSchedule"}, taint)
+	require.Equal(t, "dedicated:NoSchedule", taint.ID())
+
+	taint, err = ParseTaint("example.com/gpu:NoExecute")
+	require.NoError(t, err)
+	require.Equal(t, Taint{Key: "example.com/gpu", Effect: "NoExecute"}, taint)
+	require.Equal(t, "example.com/gpu:NoExecute", taint.String())
+
+	for _, s := range []string{"dedicated=db", "dedicated=db:Never", "bad key:NoSchedule", "key=bad value:NoSchedule"} {
+		_, err := ParseTaint(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestTaintYAML(t *testing.T) {
+	var taints []Taint
+	require.NoError(t, yaml.Unmarshal([]byte("- dedicated=db:NoSchedule\n- gpu:PreferNoSchedule\n"), &taints))
+	require.Equal(t, []Taint{{Key: "dedicated", Value: "db", Effect: "NoSchedule"}, {Key: "gpu", Effect: "PreferNoSchedule"}}, taints)
+
+	out, err := yaml.Marshal(taints)
+	require.NoError(t, err)
+	require.Equal(t, "- dedicated=db:NoSchedule\n- gpu:PreferNoSchedule\n", string(out))
+
+	err = yaml.Unmarshal([]byte("- dedicated=db\n"), &taints)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "key=value:effect")
+}
+
+func TestNodeLabelsValidate(t *testing.T) {
+	require.NoError(t, NodeLabels{"example.com/zone": "a", "tier": ""}.Validate())
+
+	err := NodeLabels{"tier": "has spaces"}.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid value")
+
+	require.Error(t, NodeLabels{"-tier": "db"}.Validate())
+}
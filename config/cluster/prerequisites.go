@@ -0,0 +1,25 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Prerequisites are the packages to install on the host before installing k0s
+type Prerequisites struct {
+	// Packages are installed with the package manager of the host operating system
+	Packages []string `yaml:"packages,omitempty"`
+}
+
+// packageNameRe matches package names, optionally with a version such as socat=1.7.4.1-3ubuntu4
+var packageNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.+_:=~-]*$`)
+
+// Validate checks that the package names can be given to the package manager
+func (p Prerequisites) Validate() error {
+	for _, pkg := range p.Packages {
+		if !packageNameRe.MatchString(pkg) {
+			return fmt.Errorf("invalid package name %q", pkg)
+		}
+	}
+	return nil
+}
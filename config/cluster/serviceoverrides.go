@@ -0,0 +1,92 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ServiceOverrides is a systemd drop-in for the k0s service. It is given either as a mapping of
+// [Service] section directives or as the raw content of the drop-in file.
+type ServiceOverrides struct {
+	Directives map[string]string
+	Raw        string
+}
+
+// UnmarshalYAML accepts a string with the drop-in content or a mapping of directives
+func (s *ServiceOverrides) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err == nil {
+		s.Raw = raw
+		return nil
+	}
+	var directives map[string]string
+	if err := unmarshal(&directives); err != nil {
+		return fmt.Errorf("serviceOverrides must be a mapping of systemd directives or the content of a drop-in file")
+	}
+	s.Directives = directives
+	return nil
+}
+
+// MarshalYAML returns the overrides in the form they were given in
+func (s ServiceOverrides) MarshalYAML() (interface{}, error) {
+	if s.Raw != "" {
+		return s.Raw, nil
+	}
+	return s.Directives, nil
+}
+
+// IsZero returns true when no overrides are set
+func (s ServiceOverrides) IsZero() bool {
+	return strings.TrimSpace(s.Raw) == "" && len(s.Directives) == 0
+}
+
+// Content returns the content of the drop-in file
+func (s ServiceOverrides) Content() string {
+	if s.Raw != "" {
+		return strings.TrimRight(s.Raw, "\n") + "\n"
+	}
+
+	keys := make([]string, 0, len(s.Directives))
+	for k := range s.Directives {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("# generated-by-k0sctl\n[Service]\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, s.Directives[k])
+	}
+	return b.String()
+}
+
+var systemdDirectiveRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// Validate checks that the directive names are valid and that a raw drop-in starts with a section
+func (s ServiceOverrides) Validate() error {
+	if s.Raw != "" {
+		for _, line := range strings.Split(s.Raw, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+				continue
+			}
+			if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("the drop-in content must start with a section such as [Service], found %q", line)
+			}
+			return nil
+		}
+		return nil
+	}
+
+	for k, v := range s.Directives {
+		if !systemdDirectiveRe.MatchString(k) {
+			return fmt.Errorf("invalid systemd directive %q", k)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("the value of the systemd directive %s can not contain line breaks", k)
+		}
+	}
+	return nil
+}
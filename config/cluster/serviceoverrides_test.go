@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestServiceOverridesDirectives(t *testing.T) {
+	var k0s K0s
+	require.NoError(t, yaml.Unmarshal([]byte("version: v1.23.1+k0s.0\nserviceOverrides:\n  TimeoutStartSec: \"300\"\n  LimitNOFILE: \"1048576\"\n"), &k0s))
+	require.False(t, k0s.ServiceOverrides.IsZero())
+	require.NoError(t, k0s.ServiceOverrides.Validate())
+	require.Equal(t, "# generated-by-k0sctl\n[Service]\nLimitNOFILE=1048576\nTimeoutStartSec=300\n", k0s.ServiceOverrides.Content())
+
+	out, err := yaml.Marshal(k0s)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "serviceOverrides:\n  LimitNOFILE: \"1048576\"\n  TimeoutStartSec: \"300\"\n")
+
+	k0s.ServiceOverrides.Directives["Limit-NOFILE"] = "1"
+	err = k0s.ServiceOverrides.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid systemd directive "Limit-NOFILE"`)
+
+	k0s.ServiceOverrides.Directives = map[string]string{"ExecStartPre": "/bin/true\nExecStart=/bin/false"}
+	err = k0s.ServiceOverrides.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can not contain line breaks")
+}
+
+func TestServiceOverridesRaw(t *testing.T) {
+	var k0s K0s
+	require.NoError(t, yaml.Unmarshal([]byte("version: v1.23.1+k0s.0\nserviceOverrides: |\n  # limits\n  [Service]\n  CPUQuota=200%\n"), &k0s))
+	require.NoError(t, k0s.ServiceOverrides.Validate())
+	require.Equal(t, "# limits\n[Service]\nCPUQuota=200%\n", k0s.ServiceOverrides.Content())
+
+	k0s.ServiceOverrides.Raw = "CPUQuota=200%"
+	err := k0s.ServiceOverrides.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must start with a section")
+
+	require.True(t, ServiceOverrides{}.IsZero())
+	out, err := yaml.Marshal(K0s{Version: "v1.23.1+k0s.0"})
+	require.NoError(t, err)
+	require.NotContains(t, string(out), "serviceOverrides")
+}
+
+func TestK0sServiceOverridePath(t *testing.T) {
+	require.Equal(t, "/etc/systemd/system/k0scontroller.service.d/override.conf", (&Host{Role: "controller+worker"}).K0sServiceOverridePath())
+	require.Equal(t, "/etc/systemd/system/k0sworker.service.d/override.conf", (&Host{Role: "worker"}).K0sServiceOverridePath())
+}
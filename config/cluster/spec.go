@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/creasty/defaults"
 )
@@ -10,8 +11,20 @@ import (
 type Spec struct {
 	Hosts Hosts `yaml:"hosts" validate:"required,dive,min=1"`
 	K0s   K0s   `yaml:"k0s"`
+	// Hooks are run on all of the hosts before the hooks of the host
+	Hooks Hooks `yaml:"hooks,omitempty"`
+	// LocalHooks are run on the machine running k0sctl before and after the phases, by phase title and stage
+	LocalHooks Hooks `yaml:"localHooks,omitempty"`
+	// Telemetry selects the analytics events that are sent
+	Telemetry Telemetry `yaml:"telemetry,omitempty"`
+	// Kubeconfig sets the address of the kubernetes API in the admin kubeconfig
+	Kubeconfig Kubeconfig `yaml:"kubeconfig,omitempty"`
 
 	k0sLeader *Host
+	// unselected are the hosts left out by SelectHosts
+	unselected Hosts
+	// tokenLeader is the controller kept by SelectHosts for creating the join tokens and running kubectl
+	tokenLeader *Host
 }
 
 // UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
@@ -23,25 +36,48 @@ func (s *Spec) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	for _, h := range s.Hosts {
+		if h != nil {
+			h.k0s = &s.K0s
+		}
+	}
+
 	return defaults.Set(s)
 }
 
 // K0sLeader returns a controller host that is selected to be a "leader",
 // or an initial node, a node that creates join tokens for other controllers.
+// A controller that is already running is preferred, the host marked with leader: true
+// is picked over the other running controllers and used to initialize a new cluster.
 func (s *Spec) K0sLeader() *Host {
+	if s.tokenLeader != nil && len(s.Hosts.Controllers()) == 0 {
+		return s.tokenLeader
+	}
 	if s.k0sLeader == nil {
 		controllers := s.Hosts.Controllers()
+		preferred := controllers.Find(func(h *Host) bool { return h.Leader })
 
-		// Pick the first controller that reports to be running and persist the choice
-		for _, h := range controllers {
-			if h.Metadata.K0sBinaryVersion != "" && h.Metadata.K0sRunningVersion != "" {
-				s.k0sLeader = h
-				break
+		running := func(h *Host) bool {
+			return h.Metadata.K0sBinaryVersion != "" && h.Metadata.K0sRunningVersion != ""
+		}
+
+		// Pick the preferred leader or the first controller that reports to be running and persist the choice
+		if preferred != nil && running(preferred) {
+			s.k0sLeader = preferred
+		} else {
+			for _, h := range controllers {
+				if running(h) {
+					s.k0sLeader = h
+					break
+				}
 			}
 		}
 
-		// Still nil?  Fall back to first "controller" host, do not persist selection.
+		// Still nil?  Fall back to the preferred leader or the first "controller" host, do not persist selection.
 		if s.k0sLeader == nil {
+			if preferred != nil {
+				return preferred
+			}
 			return controllers.First()
 		}
 	}
@@ -49,6 +85,64 @@ func (s *Spec) K0sLeader() *Host {
 	return s.k0sLeader
 }
 
+// SelectHosts restricts the hosts to the ones the function returns true for. When no controller is
+// selected, the controller that would be the leader is kept for creating the join tokens and returned.
+// It is not one of the hosts, it is only returned from K0sLeader and ConnectHosts. The hosts that are
+// left out are still included in AllHosts.
+func (s *Spec) SelectHosts(selected func(*Host) bool) (*Host, error) {
+	var extra *Host
+	if s.Hosts.Find(func(h *Host) bool { return h.IsController() && selected(h) }) == nil {
+		extra = s.K0sLeader()
+	}
+
+	var hosts, unselected Hosts
+	for _, h := range s.Hosts {
+		if selected(h) {
+			hosts = append(hosts, h)
+		} else {
+			unselected = append(unselected, h)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no hosts selected")
+	}
+
+	s.Hosts = hosts
+	s.unselected = append(s.unselected, unselected...)
+	s.tokenLeader = extra
+	s.k0sLeader = nil
+	return extra, nil
+}
+
+// TokenLeader returns the controller kept by SelectHosts for creating the join tokens or nil when a
+// controller was selected
+func (s *Spec) TokenLeader() *Host {
+	return s.tokenLeader
+}
+
+// ConnectHosts returns the hosts k0sctl connects to and gathers the facts of, the hosts and the controller
+// kept by SelectHosts for creating the join tokens
+func (s *Spec) ConnectHosts() Hosts {
+	if s.tokenLeader == nil {
+		return s.Hosts
+	}
+	return append(append(Hosts{}, s.Hosts...), s.tokenLeader)
+}
+
+// AllHosts returns the hosts including the ones left out by SelectHosts
+func (s *Spec) AllHosts() Hosts {
+	return append(append(Hosts{}, s.Hosts...), s.unselected...)
+}
+
+// K0sVersionFor returns the k0s version to install on the host, the host's k0sVersion takes precedence
+// over the cluster-wide spec.k0s.version
+func (s *Spec) K0sVersionFor(h *Host) string {
+	if h != nil && h.K0sVersion != "" {
+		return strings.TrimPrefix(h.K0sVersion, "v")
+	}
+	return s.K0s.Version
+}
+
 // KubeAPIURL returns an url to the cluster's kube api
 func (s *Spec) KubeAPIURL() string {
 	var caddr string
@@ -68,5 +162,5 @@ func (s *Spec) KubeAPIURL() string {
 		cport = p
 	}
 
-	return fmt.Sprintf("https://%s:%d", caddr, cport)
+	return "https://" + JoinHostPort(caddr, cport)
 }
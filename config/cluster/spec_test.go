@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSpecInstallFlags(t *testing.T) {
+	data := `
+hosts:
+  - role: controller
+    ssh:
+      address: 10.0.0.1
+    installFlags:
+      - --debug=false
+  - role: worker
+    ssh:
+      address: 10.0.0.2
+k0s:
+  version: 1.23.3+k0s.0
+  installFlags:
+    - --debug=true
+    - --labels=zone=a
+`
+	spec := &Spec{}
+	require.NoError(t, yaml.Unmarshal([]byte(data), spec))
+	require.Equal(t, Flags{"--debug=false"}, spec.Hosts[0].InstallFlags)
+	require.Equal(t, Flags{"--debug=false", "--labels=zone=a"}, spec.Hosts[0].K0sInstallFlags())
+	require.Nil(t, spec.Hosts[1].InstallFlags)
+	require.Equal(t, Flags{"--debug=true", "--labels=zone=a"}, spec.Hosts[1].K0sInstallFlags())
+
+	out, err := yaml.Marshal(spec)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(out), "--labels=zone=a"))
+	require.Equal(t, 1, strings.Count(string(out), "--debug=false"))
+}
+
+func TestKubeAPIURLIPv6(t *testing.T) {
+	spec := &Spec{Hosts: Hosts{{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "2001:db8::1"}}}}}
+	require.Equal(t, "https://[2001:db8::1]:6443", spec.KubeAPIURL())
+}
+
+func TestSpecDataDir(t *testing.T) {
+	data := `
+hosts:
+  - role: controller
+    ssh:
+      address: 10.0.0.1
+  - role: worker
+    ssh:
+      address: 10.0.0.2
+    installFlags:
+      - --data-dir=/mnt/k0s
+k0s:
+  version: 1.23.3+k0s.0
+  dataDir: /data/k0s
+`
+	spec := &Spec{}
+	require.NoError(t, yaml.Unmarshal([]byte(data), spec))
+	require.Equal(t, "/data/k0s", spec.Hosts[0].K0sDataDir())
+	require.Equal(t, "/mnt/k0s", spec.Hosts[1].K0sDataDir())
+	require.Nil(t, spec.Hosts[0].InstallFlags)
+}
+
+func TestK0sLeader(t *testing.T) {
+	newSpec := func() *Spec {
+		return &Spec{Hosts: Hosts{
+			{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1"}}},
+			{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2"}}},
+			{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.3"}}},
+		}}
+	}
+	running := func(h *Host) {
+		h.Metadata.K0sBinaryVersion = "1.23.3+k0s.0"
+		h.Metadata.K0sRunningVersion = "1.23.3+k0s.0"
+	}
+
+	spec := newSpec()
+	require.Equal(t, spec.Hosts[0], spec.K0sLeader())
+
+	t.Run("preferred leader initializes the cluster", func(t *testing.T) {
+		spec := newSpec()
+		spec.Hosts[1].Leader = true
+		require.Equal(t, spec.Hosts[1], spec.K0sLeader())
+	})
+
+	t.Run("running controller is preferred over a new leader", func(t *testing.T) {
+		spec := newSpec()
+		spec.Hosts[1].Leader = true
+		running(spec.Hosts[0])
+		require.Equal(t, spec.Hosts[0], spec.K0sLeader())
+	})
+
+	t.Run("running preferred leader", func(t *testing.T) {
+		spec := newSpec()
+		spec.Hosts[1].Leader = true
+		running(spec.Hosts[0])
+		running(spec.Hosts[1])
+		require.Equal(t, spec.Hosts[1], spec.K0sLeader())
+	})
+}
+
+func TestSelectHosts(t *testing.T) {
+	spec := &Spec{Hosts: Hosts{
+		{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1"}}},
+		{Role: "controller", Leader: true, Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2"}}},
+		{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.3"}}},
+	}}
+	all := append(Hosts{}, spec.Hosts...)
+
+	extra, err := spec.SelectHosts(func(h *Host) bool { return h.Role == "worker" })
+	require.NoError(t, err)
+	require.Equal(t, all[1], extra)
+	require.Equal(t, Hosts{all[2]}, spec.Hosts, "the controller for the join tokens is not one of the hosts")
+	require.Equal(t, all[1], spec.TokenLeader())
+	require.Equal(t, Hosts{all[2], all[1]}, spec.ConnectHosts())
+	require.ElementsMatch(t, all, spec.AllHosts())
+	require.Equal(t, all[1], spec.K0sLeader())
+
+	_, err = spec.SelectHosts(func(h *Host) bool { return false })
+	require.Error(t, err)
+	require.Equal(t, Hosts{all[2]}, spec.Hosts)
+
+	spec = &Spec{Hosts: append(Hosts{}, all...)}
+	extra, err = spec.SelectHosts(func(h *Host) bool { return h.Role == "controller" })
+	require.NoError(t, err)
+	require.Nil(t, extra)
+	require.Nil(t, spec.TokenLeader())
+	require.Equal(t, spec.Hosts, spec.ConnectHosts())
+}
@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SSH authentication methods that can be listed in ssh.authMethods
+const (
+	SSHAuthKey                 = "key"
+	SSHAuthAgent               = "agent"
+	SSHAuthPassword            = "password"
+	SSHAuthKeyboardInteractive = "keyboard-interactive"
+)
+
+var sshAuthMethods = []string{SSHAuthKey, SSHAuthAgent, SSHAuthPassword, SSHAuthKeyboardInteractive}
+
+// SSHAuth holds the authentication settings of the host ssh connection. The fields are set from the
+// authMethods and password fields of the ssh connection configuration.
+type SSHAuth struct {
+	Methods  []string `yaml:"-"`
+	Password string   `yaml:"-"`
+}
+
+// Validate checks the authentication methods and that a password is given for the methods that need one
+func (a SSHAuth) Validate() error {
+	if len(a.Methods) == 0 {
+		if a.Password != "" {
+			return fmt.Errorf("ssh.password is only used with the password and keyboard-interactive ssh.authMethods")
+		}
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(a.Methods))
+	var needsPassword bool
+	for _, m := range a.Methods {
+		if !isSSHAuthMethod(m) {
+			return fmt.Errorf("invalid ssh.authMethods method %q, supported methods are %s", m, strings.Join(sshAuthMethods, ", "))
+		}
+		if _, ok := seen[m]; ok {
+			return fmt.Errorf("ssh.authMethods method %q is listed more than once", m)
+		}
+		seen[m] = struct{}{}
+		if m == SSHAuthPassword || m == SSHAuthKeyboardInteractive {
+			needsPassword = true
+		}
+	}
+	if needsPassword && a.Password == "" {
+		return fmt.Errorf("ssh.password is required for the password and keyboard-interactive ssh.authMethods")
+	}
+	return nil
+}
+
+func isSSHAuthMethod(m string) bool {
+	for _, s := range sshAuthMethods {
+		if m == s {
+			return true
+		}
+	}
+	return false
+}
+
+// extractSSHAuth removes the authentication fields from the ssh connection of the host yaml. Returns
+// true when the fields were found.
+func extractSSHAuth(host map[interface{}]interface{}, a *SSHAuth) (bool, error) {
+	conn, ok := host["ssh"].(map[interface{}]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	var found bool
+	if v, ok := conn["authMethods"]; ok {
+		found = true
+		delete(conn, "authMethods")
+		list, ok := v.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("ssh.authMethods: must be a list of methods")
+		}
+		a.Methods = make([]string, len(list))
+		for i, item := range list {
+			m, ok := item.(string)
+			if !ok {
+				return false, fmt.Errorf("ssh.authMethods: must be a list of methods")
+			}
+			a.Methods[i] = m
+		}
+	}
+	if v, ok := conn["password"]; ok {
+		found = true
+		delete(conn, "password")
+		pass, ok := v.(string)
+		if !ok {
+			return false, fmt.Errorf("ssh.password: must be a string")
+		}
+		a.Password = pass
+	}
+
+	return found, nil
+}
@@ -0,0 +1,19 @@
+package cluster
+
+// Telemetry selects the categories of anonymous analytics events that are sent
+type Telemetry struct {
+	// Usage enables the events about the commands and phases that are run, defaults to true
+	Usage *bool `yaml:"usage,omitempty"`
+	// Errors enables the events about failures and crashes, defaults to true
+	Errors *bool `yaml:"errors,omitempty"`
+}
+
+// UsageEnabled returns true unless the usage events have been disabled
+func (t Telemetry) UsageEnabled() bool {
+	return t.Usage == nil || *t.Usage
+}
+
+// ErrorsEnabled returns true unless the error events have been disabled
+func (t Telemetry) ErrorsEnabled() bool {
+	return t.Errors == nil || *t.Errors
+}
@@ -2,6 +2,8 @@ package cluster
 
 import (
 	"fmt"
+	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 )
@@ -10,9 +12,19 @@ import (
 type UploadFile struct {
 	Name           string      `yaml:"name,omitempty"`
 	Source         string      `yaml:"src" validate:"required"`
-	DestinationDir string      `yaml:"dstDir" validate:"required"`
+	DestinationDir string      `yaml:"dstDir,omitempty" validate:"required_without=Destination"`
+	Destination    string      `yaml:"dst,omitempty"`
 	PermMode       interface{} `yaml:"perm" default:"0755"`
 	PermString     string      `yaml:"-"`
+	User           string      `yaml:"user,omitempty"`
+	Group          string      `yaml:"group,omitempty"`
+}
+
+// UploadItem is a single local file to be uploaded and its remote destination path
+type UploadItem struct {
+	Source      string
+	Destination string
+	Size        int64
 }
 
 // UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
@@ -24,7 +36,20 @@ func (u *UploadFile) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 
+	if u.Source == "" {
+		return fmt.Errorf("uploadFile src is required")
+	}
+	if u.DestinationDir == "" && u.Destination == "" {
+		return fmt.Errorf("uploadFile %s: one of dst or dstDir is required", u.Source)
+	}
+	if u.DestinationDir != "" && u.Destination != "" {
+		return fmt.Errorf("uploadFile %s: dst and dstDir can not be used together", u.Source)
+	}
+
 	switch t := u.PermMode.(type) {
+	case nil:
+		u.PermMode = "0755"
+		u.PermString = "0755"
 	case int:
 		if t < 0 {
 			return fmt.Errorf("invalid uploadFile permission: %d: must be a positive value", t)
@@ -57,6 +82,7 @@ func (u *UploadFile) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// Resolve returns the local paths matching the source glob pattern
 func (u *UploadFile) Resolve() ([]string, error) {
 	sources, err := filepath.Glob(u.Source)
 	if err != nil {
@@ -64,3 +90,66 @@ func (u *UploadFile) Resolve() ([]string, error) {
 	}
 	return sources, nil
 }
+
+// Items resolves the source into the files to upload. Matched directories are walked recursively and their
+// structure is recreated under the destination directory. The dst path can only be used when the source
+// matches a single file.
+func (u *UploadFile) Items() ([]UploadItem, error) {
+	sources, err := u.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no files found matching %s", u.Source)
+	}
+
+	var items []UploadItem
+	for _, src := range sources {
+		err := filepath.Walk(src, func(fn string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsPermission(err) {
+					return fmt.Errorf("permission denied while reading %s for upload", fn)
+				}
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(filepath.Dir(src), fn)
+			if err != nil {
+				return err
+			}
+			items = append(items, UploadItem{
+				Source:      fn,
+				Destination: path.Join(u.DestinationDir, filepath.ToSlash(rel)),
+				Size:        info.Size(),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if u.Destination != "" {
+		if len(items) != 1 || items[0].Source != sources[0] {
+			return nil, fmt.Errorf("uploadFile dst can only be used when src matches a single file, use dstDir for %s", u.Source)
+		}
+		items[0].Destination = u.Destination
+	}
+
+	return items, nil
+}
+
+// Owner returns the owner for chown in the user:group format or an empty string when neither is set
+func (u *UploadFile) Owner() string {
+	switch {
+	case u.User != "" && u.Group != "":
+		return u.User + ":" + u.Group
+	case u.Group != "":
+		return ":" + u.Group
+	default:
+		return u.User
+	}
+}
@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -64,3 +66,42 @@ perm: 0
 
 	require.Error(t, yaml.Unmarshal(yml, &u))
 }
+
+func TestUploadFileItems(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "certs", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "certs", "ca.pem"), []byte("ca"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "certs", "sub", "tls.key"), []byte("key"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "auth.json"), []byte("{}"), 0600))
+
+	u := UploadFile{Source: filepath.Join(dir, "certs"), DestinationDir: "/etc/certs"}
+	items, err := u.Items()
+	require.NoError(t, err)
+	require.Equal(t, []UploadItem{
+		{Source: filepath.Join(dir, "certs", "ca.pem"), Destination: "/etc/certs/certs/ca.pem", Size: 2},
+		{Source: filepath.Join(dir, "certs", "sub", "tls.key"), Destination: "/etc/certs/certs/sub/tls.key", Size: 3},
+	}, items)
+
+	u = UploadFile{Source: filepath.Join(dir, "*.json"), Destination: "/root/.docker/config.json"}
+	items, err = u.Items()
+	require.NoError(t, err)
+	require.Equal(t, []UploadItem{{Source: filepath.Join(dir, "auth.json"), Destination: "/root/.docker/config.json", Size: 2}}, items)
+
+	u = UploadFile{Source: filepath.Join(dir, "certs"), Destination: "/etc/ca.pem"}
+	_, err = u.Items()
+	require.Error(t, err)
+
+	u = UploadFile{Source: filepath.Join(dir, "*.missing"), DestinationDir: "/tmp"}
+	_, err = u.Items()
+	require.Error(t, err)
+}
+
+func TestUploadFileUnmarshal(t *testing.T) {
+	u := UploadFile{}
+	require.NoError(t, yaml.Unmarshal([]byte("src: auth.json\ndst: /root/.docker/config.json\nuser: root\ngroup: docker\n"), &u))
+	require.Equal(t, "0755", u.PermString)
+	require.Equal(t, "root:docker", u.Owner())
+
+	require.Error(t, yaml.Unmarshal([]byte("src: auth.json\n"), &UploadFile{}))
+	require.Error(t, yaml.Unmarshal([]byte("src: auth.json\ndst: /a\ndstDir: /b\n"), &UploadFile{}))
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 func TestAPIVersionValidation(t *testing.T) {
@@ -36,3 +41,588 @@ func TestK0sVersionValidation(t *testing.T) {
 	cfg.Spec.K0s.Version = cluster.K0sMinVersion
 	require.NoError(t, cfg.Validate())
 }
+
+func TestHostK0sVersionValidation(t *testing.T) {
+	h := &cluster.Host{Role: "worker", K0sVersion: "0.1.0", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "minimum k0s version")
+	h.K0sVersion = "v1.21.3+k0s.0"
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, "1.21.3+k0s.0", cfg.Spec.K0sVersionFor(h))
+	h.K0sVersion = ""
+	require.Equal(t, cluster.K0sMinVersion, cfg.Spec.K0sVersionFor(h))
+}
+
+func TestUniqueHostsValidation(t *testing.T) {
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{
+				Version: cluster.K0sMinVersion,
+			},
+			Hosts: cluster.Hosts{
+				&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}},
+				&cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}},
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "duplicate host")
+	cfg.Spec.Hosts[1] = &cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 2222, User: "root"}}}
+	require.NoError(t, cfg.Validate())
+}
+
+func TestLeaderValidation(t *testing.T) {
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{
+				Version: cluster.K0sMinVersion,
+			},
+			Hosts: cluster.Hosts{
+				&cluster.Host{Role: "controller", Leader: true, Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}},
+				&cluster.Host{Role: "controller+worker", Leader: true, Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2", Port: 22, User: "root"}}},
+				&cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.3", Port: 22, User: "root"}}},
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only one host can be the leader")
+
+	cfg.Spec.Hosts[1].Leader = false
+	cfg.Spec.Hosts[2].Leader = true
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only controllers can")
+
+	cfg.Spec.Hosts[2].Leader = false
+	require.NoError(t, cfg.Validate())
+}
+
+func TestArchValidation(t *testing.T) {
+	h := &cluster.Host{Role: "worker", Arch: "x86_64", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Arch")
+
+	for _, arch := range []string{"amd64", "arm64", "arm", ""} {
+		h.Arch = arch
+		require.NoError(t, cfg.Validate())
+	}
+}
+
+func TestWorkerProfileValidation(t *testing.T) {
+	h := &cluster.Host{Role: "worker", Profile: "gpu", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{
+				&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}},
+				h,
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `worker profile "gpu" which is not defined`)
+
+	require.NoError(t, yaml.Unmarshal([]byte("spec:\n  workerProfiles:\n  - name: gpu\n    values:\n      maxPods: 50\n"), &cfg.Spec.K0s.Config))
+	require.Equal(t, []string{"gpu"}, cfg.Spec.K0s.WorkerProfiles())
+	require.NoError(t, cfg.Validate())
+
+	h.Profile = ""
+	h.InstallFlags = cluster.Flags{"--profile=fast"}
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `worker profile "fast"`)
+
+	h.InstallFlags = cluster.Flags{"--profile=default"}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Spec.Hosts[0].Profile = "gpu"
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can not have a worker profile")
+}
+
+func TestExternalEtcdValidation(t *testing.T) {
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{
+				&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}},
+			},
+		},
+	}
+	require.Nil(t, cfg.Spec.K0s.ExternalEtcd())
+
+	setConfig := func(config string) {
+		cfg.Spec.K0s.Config = nil
+		require.NoError(t, yaml.Unmarshal([]byte(config), &cfg.Spec.K0s.Config))
+	}
+
+	setConfig("spec:\n  storage:\n    etcd:\n      externalCluster:\n        etcdPrefix: k0s\n")
+	require.NotNil(t, cfg.Spec.K0s.ExternalEtcd())
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "externalCluster.endpoints must list the endpoints")
+
+	setConfig("spec:\n  storage:\n    etcd:\n      externalCluster:\n        endpoints:\n        - etcd1:2379\n")
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid external etcd endpoint etcd1:2379")
+
+	setConfig("spec:\n  storage:\n    etcd:\n      externalCluster:\n        endpoints:\n        - http://etcd1:2379\n")
+	require.NoError(t, cfg.Validate())
+
+	setConfig("spec:\n  storage:\n    etcd:\n      externalCluster:\n        endpoints:\n        - https://etcd1:2379\n        caFile: /etc/ca.crt\n")
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "externalCluster.clientCertFile is required for https endpoints")
+
+	setConfig("spec:\n  storage:\n    etcd:\n      externalCluster:\n        endpoints:\n        - https://etcd1:2379\n        caFile: /etc/ca.crt\n        clientCertFile: /etc/client.crt\n        clientKeyFile: /etc/client.key\n")
+	require.NoError(t, cfg.Validate())
+
+	cfg.Spec.K0s.Config.DigMapping("spec", "storage")["type"] = "kine"
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `can not be used with the storage type "kine"`)
+}
+
+func TestLocalHooksValidation(t *testing.T) {
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{
+				Version: cluster.K0sMinVersion,
+			},
+			Hosts: cluster.Hosts{
+				&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}},
+			},
+			LocalHooks: cluster.Hooks{"Install controllers": {"during": {{Cmd: "./update-lb.sh"}}}},
+		},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid stage "during"`)
+
+	cfg.Spec.LocalHooks = cluster.Hooks{"Install controllers": {"after": {{Cmd: " "}}}}
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "empty command")
+
+	cfg.Spec.LocalHooks = cluster.Hooks{"Install controllers": {"after": {{Cmd: "./update-lb.sh"}}}}
+	require.NoError(t, cfg.Validate())
+}
+
+func TestEnvironmentValidation(t *testing.T) {
+	h := &cluster.Host{Role: "controller", Environment: map[string]string{"HTTPS_PROXY": "http://proxy:3128"}, Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{
+				Version:     cluster.K0sMinVersion,
+				Environment: map[string]string{"NO-PROXY": "10.0.0.0/8"},
+			},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid environment variable name "NO-PROXY"`)
+
+	cfg.Spec.K0s.Environment = map[string]string{"NO_PROXY": "10.0.0.0/8"}
+	require.NoError(t, cfg.Validate())
+
+	h.Environment["1FOO"] = "bar"
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid environment variable name "1FOO"`)
+}
+
+func TestPrerequisitesValidation(t *testing.T) {
+	h := &cluster.Host{Role: "controller", Prerequisites: cluster.Prerequisites{Packages: []string{"socat", "conntrack=1:1.4.6-2"}}, Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	h.Prerequisites.Packages = append(h.Prerequisites.Packages, "socat; reboot")
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid package name "socat; reboot"`)
+}
+
+func TestNodeLabelsValidation(t *testing.T) {
+	h := &cluster.Host{Role: "controller", Labels: cluster.NodeLabels{"zone": "a"}, Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not run a kubelet")
+
+	h.Role = "controller+worker"
+	h.Taints = []cluster.Taint{{Key: "dedicated", Effect: "NoSchedule"}}
+	require.NoError(t, cfg.Validate())
+
+	h.Taints = append(h.Taints, cluster.Taint{Key: "dedicated", Value: "db", Effect: "NoSchedule"})
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "taint dedicated:NoSchedule is given more than once")
+}
+
+func TestKubeconfigValidation(t *testing.T) {
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:        cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts:      cluster.Hosts{&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}},
+			Kubeconfig: cluster.Kubeconfig{APIAddress: "lb.example.com:6443"},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Spec.Kubeconfig.APIAddress = "lb.example.com:99999"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "the port must be between 1 and 65535")
+}
+
+func TestAPIServerAddressValidation(t *testing.T) {
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion, APIServerAddress: "https://lb.example.com:6443"},
+			Hosts: cluster.Hosts{&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Spec.K0s.APIServerAddress = "lb_example.com"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid kubernetes api address")
+}
+
+func TestLocalhostValidation(t *testing.T) {
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{
+				Version: cluster.K0sMinVersion,
+			},
+			Hosts: cluster.Hosts{
+				&cluster.Host{Role: "controller+worker", Connection: rig.Connection{Localhost: &rig.Localhost{Enabled: true}}},
+			},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Spec.Hosts[0].SSH = &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root", KeyPath: "/keys/id_rsa"}
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can not be used with an ssh keyPath")
+
+	cfg.Spec.Hosts[0].SSH = nil
+	cfg.Spec.Hosts = append(cfg.Spec.Hosts, &cluster.Host{Role: "worker", Connection: rig.Connection{Localhost: &rig.Localhost{Enabled: true}}})
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "only one localhost host can be defined")
+}
+
+func TestBastionValidation(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "id_rsa")
+	require.NoError(t, os.WriteFile(keyPath, []byte("key"), 0600))
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	bastion := &rig.SSH{Address: "10.0.0.2", Port: 22, User: "root", KeyPath: keyPath}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{
+				Version: cluster.K0sMinVersion,
+			},
+			Hosts: cluster.Hosts{
+				&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root", Bastion: bastion}}},
+			},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	bastion.KeyPath = filepath.Join(t.TempDir(), "missing")
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no ssh agent is available")
+
+	bastion.KeyPath = keyPath
+	bastion.Bastion = &rig.SSH{Address: "10.0.0.3", Port: 22, User: "root"}
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "nested bastion hosts are not supported")
+}
+
+func TestWinRMValidation(t *testing.T) {
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caCert, []byte("cert"), 0600))
+
+	winrm := &rig.WinRM{Address: "10.0.0.1", Port: 5986, User: "Administrator", UseHTTPS: true, CACertPath: caCert}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s: cluster.K0s{
+				Version: cluster.K0sMinVersion,
+			},
+			Hosts: cluster.Hosts{
+				&cluster.Host{Role: "worker", Connection: rig.Connection{WinRM: winrm}},
+			},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	winrm.Insecure = true
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "caCertPath and insecure can not be used together")
+
+	winrm.Insecure = false
+	winrm.UseHTTPS = false
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "caCertPath requires useHTTPS")
+}
+
+func TestInstallFlagsValidation(t *testing.T) {
+	h := &cluster.Host{Role: "worker", InstallFlags: cluster.Flags{"--labels=a=b"}, Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	h.InstallFlags = cluster.Flags{"--labels=a=b && reboot"}
+	require.NoError(t, cfg.Validate())
+
+	h.InstallFlags = cluster.Flags{"--labels;reboot"}
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not a valid flag")
+
+	h.InstallFlags = nil
+	cfg.Spec.K0s.InstallFlags = cluster.Flags{"$(id)"}
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not a valid flag")
+}
+
+func TestAddressValidation(t *testing.T) {
+	h := &cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "2001:db8::1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	h.SSH.Address = "fe80::1%eth0"
+	require.NoError(t, cfg.Validate())
+
+	h.SSH.Address = "[2001:db8::1]:22"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must not include a port")
+
+	h.SSH.Address = "10.0.0.1:22"
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must not include a port")
+
+	h.SSH.Address = "2001:db8::1"
+	h.PrivateAddress = "fe80::2%eth0"
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "zone-scoped")
+}
+
+func TestDataDirValidation(t *testing.T) {
+	h := &cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion, DataDir: "/data/k0s"},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Spec.K0s.DataDir = "data/k0s"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must be an absolute path")
+
+	cfg.Spec.K0s.DataDir = ""
+	h.InstallFlags = cluster.Flags{"--data-dir=k0s"}
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must be an absolute path")
+}
+
+func TestKeepAliveValidation(t *testing.T) {
+	h := &cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	count := 0
+	h.SSHKeepAlive.CountMax = &count
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "CountMax")
+
+	count = 1
+	require.NoError(t, cfg.Validate())
+}
+
+func TestConnectTimeoutValidation(t *testing.T) {
+	h := &cluster.Host{Role: "controller", ConnectTimeout: 10 * time.Second, Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, 10*time.Second, h.ConnectTimeoutOr(time.Minute))
+
+	h.ConnectTimeout = -time.Second
+	require.Error(t, cfg.Validate())
+
+	h.ConnectTimeout = 0
+	require.Equal(t, time.Minute, h.ConnectTimeoutOr(time.Minute))
+}
+
+func TestK0sInstallPathValidation(t *testing.T) {
+	h := &cluster.Host{Role: "controller", K0sInstallPath: "/opt/k0s/bin/k0s", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	h.K0sInstallPath = "bin/k0s"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must be an absolute path")
+}
+
+func TestShellValidation(t *testing.T) {
+	h := &cluster.Host{Role: "controller", Shell: "/bin/bash", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{Version: cluster.K0sMinVersion},
+			Hosts: cluster.Hosts{h},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	h.Shell = "bash"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `the shell "bash" must be an absolute path`)
+}
+
+func TestVersionChannelValidation(t *testing.T) {
+	cfg := Cluster{
+		APIVersion: APIVersion,
+		Kind:       "cluster",
+		Spec: &cluster.Spec{
+			K0s:   cluster.K0s{VersionChannel: "stable"},
+			Hosts: cluster.Hosts{&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22, User: "root"}}}},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Spec.K0s.VersionChannel = "edge"
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid k0s version channel "edge"`)
+
+	cfg.Spec.K0s.VersionChannel = "latest"
+	cfg.Spec.K0s.Version = cluster.K0sMinVersion
+	err = cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "spec.k0s.version can not be given with spec.k0s.versionChannel")
+
+	cfg.Spec.K0s.VersionChannel = ""
+	cfg.Spec.K0s.Version = ""
+	require.Error(t, cfg.Validate(), "a version or a version channel is required")
+}
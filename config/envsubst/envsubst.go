@@ -0,0 +1,92 @@
+// Package envsubst expands environment variable references in configuration content
+package envsubst
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	varRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+	// matches the beginning of a line up to a mapping value, such as "  - key: "
+	mappingValueRe = regexp.MustCompile(`^(\s*(?:- )*)[^\s#'"][^#'"]*:\s+$`)
+)
+
+// Expand replaces ${VAR} and ${VAR:-default} references in the input using the process environment.
+// The default value is used when the variable is unset or empty. An error listing the variable names is
+// returned when variables without a default value are not set.
+//
+// Multi-line values are inserted so that the yaml stays valid: when the reference is the whole value
+// of a mapping key it is replaced with a literal block scalar, otherwise the continuation lines are
+// indented like the line of the reference, which works for references inside block scalars.
+func Expand(input string) (string, error) {
+	return ExpandFunc(input, os.LookupEnv)
+}
+
+// ExpandFunc is like Expand but uses the lookup function for resolving the variables
+func ExpandFunc(input string, lookup func(string) (string, bool)) (string, error) {
+	var missing []string
+	var res strings.Builder
+	var last int
+
+	for _, loc := range varRe.FindAllStringSubmatchIndex(input, -1) {
+		start, end := loc[0], loc[1]
+		ref := input[start:end]
+		res.WriteString(input[last:start])
+		last = end
+
+		name := input[loc[2]:loc[3]]
+		hasDefault := loc[4] != -1
+
+		var val string
+		if v, ok := lookup(name); ok && (v != "" || !hasDefault) {
+			val = v
+		} else if hasDefault {
+			val = input[loc[6]:loc[7]]
+		} else {
+			missing = append(missing, name)
+			res.WriteString(ref)
+			continue
+		}
+
+		if strings.Contains(val, "\n") {
+			val = multiline(input, start, end, val)
+		}
+		res.WriteString(val)
+	}
+	res.WriteString(input[last:])
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined environment variable(s) in configuration: %s", strings.Join(missing, ", "))
+	}
+
+	return res.String(), nil
+}
+
+// multiline formats a multi-line value for the reference at input[start:end]
+func multiline(input string, start, end int, val string) string {
+	lineStart := strings.LastIndex(input[:start], "\n") + 1
+	prefix := input[lineStart:start]
+
+	lineEnd := strings.Index(input[end:], "\n")
+	if lineEnd == -1 {
+		lineEnd = len(input)
+	} else {
+		lineEnd += end
+	}
+
+	if match := mappingValueRe.FindStringSubmatch(prefix); match != nil && strings.TrimSpace(input[end:lineEnd]) == "" {
+		indent := strings.Repeat(" ", len(match[1])+2)
+		indicator := "|"
+		if !strings.HasSuffix(val, "\n") {
+			indicator = "|-"
+		}
+		lines := strings.Split(strings.TrimSuffix(val, "\n"), "\n")
+		return indicator + "\n" + indent + strings.Join(lines, "\n"+indent)
+	}
+
+	indent := prefix[:len(prefix)-len(strings.TrimLeft(prefix, " \t"))]
+	return strings.ReplaceAll(strings.TrimSuffix(val, "\n"), "\n", "\n"+indent)
+}
@@ -0,0 +1,47 @@
+package envsubst
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
+}
+
+func TestExpand(t *testing.T) {
+	env := lookup(map[string]string{"HOST": "10.0.0.1", "EMPTY": ""})
+
+	res, err := ExpandFunc("address: ${HOST}\nuser: ${USER_NAME:-root}\nkey: ${EMPTY:-~/.ssh/id_rsa}\nport: $PORT\n", env)
+	require.NoError(t, err)
+	require.Equal(t, "address: 10.0.0.1\nuser: root\nkey: ~/.ssh/id_rsa\nport: $PORT\n", res)
+
+	res, err = ExpandFunc("value: '${EMPTY}'", env)
+	require.NoError(t, err)
+	require.Equal(t, "value: ''", res)
+}
+
+func TestExpandUndefined(t *testing.T) {
+	_, err := ExpandFunc("address: ${HOST}\nkey: ${KEY_PATH}\n", lookup(map[string]string{}))
+	require.EqualError(t, err, "undefined environment variable(s) in configuration: HOST, KEY_PATH")
+}
+
+func TestExpandMultiline(t *testing.T) {
+	env := lookup(map[string]string{"KEY": "line1\nline2\n", "TEXT": "a\nb"})
+
+	res, err := ExpandFunc("hosts:\n  - ssh:\n      keyData: ${KEY}\n  - role: worker\n", env)
+	require.NoError(t, err)
+	require.Equal(t, "hosts:\n  - ssh:\n      keyData: |\n        line1\n        line2\n  - role: worker\n", res)
+
+	res, err = ExpandFunc("  - keyData: ${TEXT}", env)
+	require.NoError(t, err)
+	require.Equal(t, "  - keyData: |-\n      a\n      b", res)
+
+	res, err = ExpandFunc("data: |\n    ${KEY}\n", env)
+	require.NoError(t, err)
+	require.Equal(t, "data: |\n    line1\n    line2\n", res)
+}
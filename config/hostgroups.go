@@ -0,0 +1,297 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+// addressRangeRe matches a numeric range such as [1-40] in a group member address
+var addressRangeRe = regexp.MustCompile(`\[(\d+)-(\d+)\]`)
+
+// maxGroupRange is the maximum number of addresses a single range can expand to
+const maxGroupRange = 1024
+
+// ExpandHostGroups replaces the spec.groups of a cluster config yaml with concrete hosts appended to spec.hosts.
+// A group is a host mapping with the shared fields such as the role and the connection user, port and keyPath,
+// and a list of member hosts. A member is an address or a host mapping with an optional address field, the
+// fields of a member override the ones in the group. A numeric range such as 10.0.0.[10-49] in a member
+// address expands to a host for each number. A range in the address of a host in spec.hosts is expanded in
+// place the same way, each of the hosts getting the other fields of the host. The content is returned
+// unmodified when there are no groups or ranges.
+func ExpandHostGroups(content []byte) ([]byte, error) {
+	var data map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+
+	spec, ok := data["spec"].(map[interface{}]interface{})
+	if !ok {
+		return content, nil
+	}
+
+	var hosts []interface{}
+	if value, ok := spec["hosts"]; ok && value != nil {
+		hosts, ok = value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("spec.hosts must be a list")
+		}
+	}
+	hosts, ranged, err := expandHostRanges(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := spec["groups"]
+	if !ok {
+		if !ranged {
+			return content, nil
+		}
+		spec["hosts"] = hosts
+		return yaml.Marshal(data)
+	}
+	delete(spec, "groups")
+
+	groups, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec.groups must be a list")
+	}
+
+	for i, g := range groups {
+		group, ok := g.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("spec.groups[%d] must be a mapping", i)
+		}
+		members, err := expandGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", groupPath(i, group), err)
+		}
+		hosts = append(hosts, members...)
+	}
+	spec["hosts"] = hosts
+
+	return yaml.Marshal(data)
+}
+
+// expandHostRanges replaces the hosts with a range in the connection address with a host for each address
+// of the range, returns true when there were ranges. The expanded addresses may not overlap with the other
+// hosts.
+func expandHostRanges(hosts []interface{}) ([]interface{}, bool, error) {
+	var ranged bool
+	res := make([]interface{}, 0, len(hosts))
+	// source is the index of the ranged host in spec.hosts a host was expanded from, -1 for the declared hosts
+	source := make([]int, 0, len(hosts))
+	for i, h := range hosts {
+		host, ok := h.(map[interface{}]interface{})
+		address := connAddress(host)
+		if !ok || !addressRangeRe.MatchString(address) {
+			res = append(res, h)
+			source = append(source, -1)
+			continue
+		}
+		ranged = true
+		if _, ok := host["name"]; ok {
+			return nil, false, fmt.Errorf("spec.hosts[%d]: a host with the address range %s can not have a name", i, address)
+		}
+		addresses, err := expandAddressRange(address)
+		if err != nil {
+			return nil, false, fmt.Errorf("spec.hosts[%d]: %w", i, err)
+		}
+		for _, addr := range addresses {
+			h := copyValue(host).(map[interface{}]interface{})
+			if err := setGroupAddress(h, addr); err != nil {
+				return nil, false, fmt.Errorf("spec.hosts[%d]: %w", i, err)
+			}
+			res = append(res, h)
+			source = append(source, i)
+		}
+	}
+	if !ranged {
+		return hosts, false, nil
+	}
+
+	seen := make(map[string]int, len(res))
+	for i, h := range res {
+		key := hostKey(h).address
+		if key == "" {
+			continue
+		}
+		if j, ok := seen[key]; ok && (source[i] >= 0 || source[j] >= 0) {
+			ranges := source[i]
+			if ranges < 0 {
+				ranges = source[j]
+			}
+			return nil, false, fmt.Errorf("spec.hosts[%d]: the address range %s overlaps with another host at %s", ranges, connAddress(hosts[ranges].(map[interface{}]interface{})), key)
+		}
+		seen[key] = i
+	}
+
+	return res, true, nil
+}
+
+// connAddress returns the address of the winRM connection of the host or the ssh connection otherwise
+func connAddress(h map[interface{}]interface{}) string {
+	proto := "ssh"
+	if _, ok := h["winRM"]; ok {
+		proto = "winRM"
+	}
+	if conn, ok := h[proto].(map[interface{}]interface{}); ok {
+		if a, ok := conn["address"].(string); ok {
+			return a
+		}
+	}
+	return ""
+}
+
+func groupPath(i int, group map[interface{}]interface{}) string {
+	if name, ok := group["name"].(string); ok && name != "" {
+		return fmt.Sprintf("spec.groups[%d] (%s)", i, name)
+	}
+	return fmt.Sprintf("spec.groups[%d]", i)
+}
+
+// expandGroup returns the hosts of a group with the group fields merged in
+func expandGroup(group map[interface{}]interface{}) ([]interface{}, error) {
+	members, ok := group["hosts"].([]interface{})
+	if !ok || len(members) == 0 {
+		return nil, fmt.Errorf("a group must have a list of hosts")
+	}
+
+	defaults := make(map[interface{}]interface{}, len(group))
+	for k, v := range group {
+		if k == "name" || k == "hosts" {
+			continue
+		}
+		defaults[k] = v
+	}
+	if _, ok := defaults["localhost"]; ok {
+		return nil, fmt.Errorf("localhost connections can not be used in a group")
+	}
+
+	var res []interface{}
+	for j, m := range members {
+		var member map[interface{}]interface{}
+		var address string
+		switch v := m.(type) {
+		case string:
+			member = map[interface{}]interface{}{}
+			address = v
+		case map[interface{}]interface{}:
+			member = copyValue(v).(map[interface{}]interface{})
+			if a, ok := member["address"]; ok {
+				address, ok = a.(string)
+				if !ok {
+					return nil, fmt.Errorf("hosts[%d].address must be a string", j)
+				}
+				delete(member, "address")
+			}
+		default:
+			return nil, fmt.Errorf("hosts[%d] must be an address or a mapping", j)
+		}
+
+		addresses, err := expandAddressRange(address)
+		if err != nil {
+			return nil, fmt.Errorf("hosts[%d]: %w", j, err)
+		}
+
+		for _, addr := range addresses {
+			host, err := mergeValue(copyValue(defaults), copyValue(member), nil)
+			if err != nil {
+				return nil, fmt.Errorf("hosts[%d]: %w", j, err)
+			}
+			h := host.(map[interface{}]interface{})
+			if err := setGroupAddress(h, addr); err != nil {
+				return nil, fmt.Errorf("hosts[%d]: %w", j, err)
+			}
+			if role, ok := h["role"].(string); !ok || role == "" {
+				return nil, fmt.Errorf("host %s does not have a role, set the role on the group or the host", hostKey(h))
+			}
+			res = append(res, h)
+		}
+	}
+
+	return res, nil
+}
+
+// setGroupAddress sets the address of the winRM connection of the host or the ssh connection otherwise
+func setGroupAddress(h map[interface{}]interface{}, address string) error {
+	proto := "ssh"
+	if _, ok := h["winRM"]; ok {
+		proto = "winRM"
+	}
+	conn, ok := h[proto].(map[interface{}]interface{})
+	if !ok {
+		if h[proto] != nil {
+			return fmt.Errorf("%s must be a mapping", proto)
+		}
+		conn = map[interface{}]interface{}{}
+		h[proto] = conn
+	}
+	if address != "" {
+		conn["address"] = address
+	}
+	if a, ok := conn["address"].(string); !ok || a == "" {
+		return fmt.Errorf("no address given")
+	}
+	return nil
+}
+
+// expandAddressRange expands a numeric range such as [1-40] in the address, leading zeros in the start of the
+// range are kept, so that [01-10] gives 01, 02 .. 10
+func expandAddressRange(address string) ([]string, error) {
+	loc := addressRangeRe.FindStringSubmatchIndex(address)
+	if loc == nil {
+		return []string{address}, nil
+	}
+	startStr := address[loc[2]:loc[3]]
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return nil, err
+	}
+	end, err := strconv.Atoi(address[loc[4]:loc[5]])
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("invalid address range in %s: the end is before the start", address)
+	}
+	if end-start >= maxGroupRange {
+		return nil, fmt.Errorf("invalid address range in %s: a range can expand to at most %d addresses", address, maxGroupRange)
+	}
+	if addressRangeRe.MatchString(address[loc[1]:]) {
+		return nil, fmt.Errorf("invalid address %s: only one range can be used", address)
+	}
+
+	width := 0
+	if len(startStr) > 1 && startStr[0] == '0' {
+		width = len(startStr)
+	}
+
+	res := make([]string, 0, end-start+1)
+	for n := start; n <= end; n++ {
+		res = append(res, fmt.Sprintf("%s%0*d%s", address[:loc[0]], width, n, address[loc[1]:]))
+	}
+	return res, nil
+}
+
+// copyValue returns a deep copy of a value decoded from yaml
+func copyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(val))
+		for k, item := range val {
+			res[k] = copyValue(item)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = copyValue(item)
+		}
+		return res
+	default:
+		return v
+	}
+}
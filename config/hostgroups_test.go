@@ -0,0 +1,226 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExpandHostGroups(t *testing.T) {
+	content := []byte(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+  groups:
+    - name: workers
+      role: worker
+      ssh:
+        user: ubuntu
+        port: 2222
+        keyPath: /keys/workers
+      hosts:
+        - worker-[08-10].example.com
+        - address: 10.0.2.1
+          ssh:
+            port: 22
+        - address: 10.0.2.2
+          role: controller+worker
+  k0s:
+    version: 1.23.3+k0s.0
+`)
+	res, err := ExpandHostGroups(content)
+	require.NoError(t, err)
+
+	c := Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(res, &c))
+	require.NoError(t, c.Validate())
+
+	hosts := c.Spec.Hosts
+	require.Len(t, hosts, 6)
+	require.Equal(t, "10.0.0.1", hosts[0].SSH.Address)
+	require.Equal(t, "root", hosts[0].SSH.User)
+
+	for i, addr := range []string{"worker-08.example.com", "worker-09.example.com", "worker-10.example.com"} {
+		h := hosts[i+1]
+		require.Equal(t, addr, h.SSH.Address)
+		require.Equal(t, "worker", h.Role)
+		require.Equal(t, "ubuntu", h.SSH.User)
+		require.Equal(t, 2222, h.SSH.Port)
+		require.Equal(t, "/keys/workers", h.SSH.KeyPath)
+	}
+
+	require.Equal(t, "10.0.2.1", hosts[4].SSH.Address)
+	require.Equal(t, 22, hosts[4].SSH.Port)
+	require.Equal(t, "ubuntu", hosts[4].SSH.User)
+	require.Equal(t, "controller+worker", hosts[5].Role)
+	require.Equal(t, 2222, hosts[5].SSH.Port)
+}
+
+func TestExpandHostGroupsNoGroups(t *testing.T) {
+	content := []byte("spec:\n  hosts:\n    - role: worker\n")
+	res, err := ExpandHostGroups(content)
+	require.NoError(t, err)
+	require.Equal(t, content, res)
+}
+
+func TestExpandHostGroupsErrors(t *testing.T) {
+	_, err := ExpandHostGroups([]byte(`
+spec:
+  groups:
+    - name: workers
+      ssh:
+        user: ubuntu
+      hosts:
+        - 10.0.1.1
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "spec.groups[0] (workers)")
+	require.Contains(t, err.Error(), "host 10.0.1.1:22 does not have a role")
+
+	_, err = ExpandHostGroups([]byte(`
+spec:
+  groups:
+    - role: worker
+      hosts:
+        - role: worker
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no address given")
+
+	_, err = ExpandHostGroups([]byte(`
+spec:
+  groups:
+    - role: worker
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "a group must have a list of hosts")
+}
+
+func TestExpandAddressRange(t *testing.T) {
+	res, err := expandAddressRange("worker-[1-3].example.com")
+	require.NoError(t, err)
+	require.Equal(t, []string{"worker-1.example.com", "worker-2.example.com", "worker-3.example.com"}, res)
+
+	res, err = expandAddressRange("10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.1"}, res)
+
+	_, err = expandAddressRange("10.0.0.[9-1]")
+	require.Error(t, err)
+	_, err = expandAddressRange("10.0.[0-1].[1-2]")
+	require.Error(t, err)
+	_, err = expandAddressRange("10.0.[0-5000].1")
+	require.Error(t, err)
+}
+
+func TestExpandHostRanges(t *testing.T) {
+	content := []byte(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+    - role: worker
+      ssh:
+        address: worker-[01-03].internal
+        user: ubuntu
+        keyPath: /keys/workers
+    - role: worker
+      winRM:
+        address: 10.0.1.[8-10]
+        user: Administrator
+  k0s:
+    version: 1.23.3+k0s.0
+`)
+	res, err := ExpandHostGroups(content)
+	require.NoError(t, err)
+
+	c := Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(res, &c))
+	require.NoError(t, c.Validate())
+
+	hosts := c.Spec.Hosts
+	require.Len(t, hosts, 7)
+	require.Equal(t, "10.0.0.1", hosts[0].SSH.Address)
+	for i, addr := range []string{"worker-01.internal", "worker-02.internal", "worker-03.internal"} {
+		h := hosts[i+1]
+		require.Equal(t, addr, h.SSH.Address)
+		require.Equal(t, "worker", h.Role)
+		require.Equal(t, "ubuntu", h.SSH.User)
+		require.Equal(t, "/keys/workers", h.SSH.KeyPath)
+	}
+	for i, addr := range []string{"10.0.1.8", "10.0.1.9", "10.0.1.10"} {
+		h := hosts[i+4]
+		require.Equal(t, addr, h.WinRM.Address)
+		require.Equal(t, "Administrator", h.WinRM.User)
+	}
+}
+
+func TestExpandHostRangesErrors(t *testing.T) {
+	_, err := ExpandHostGroups([]byte(`
+spec:
+  hosts:
+    - role: worker
+      ssh:
+        address: worker-05.internal
+    - role: worker
+      ssh:
+        address: worker-[01-10].internal
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "spec.hosts[1]: the address range worker-[01-10].internal overlaps with another host at worker-05.internal:22")
+
+	_, err = ExpandHostGroups([]byte(`
+spec:
+  hosts:
+    - role: worker
+      ssh:
+        address: 10.0.0.[1-5]
+    - role: worker
+      ssh:
+        address: 10.0.0.[5-9]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "overlaps with another host at 10.0.0.5:22")
+
+	_, err = ExpandHostGroups([]byte(`
+spec:
+  hosts:
+    - role: worker
+      ssh:
+        address: 10.0.0.[1-5]
+        port: 2222
+    - role: worker
+      ssh:
+        address: 10.0.0.3
+`))
+	require.NoError(t, err, "the same address with a different port is another host")
+
+	_, err = ExpandHostGroups([]byte(`
+spec:
+  hosts:
+    - role: worker
+      ssh:
+        address: 10.0.0.[9-1]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "spec.hosts[0]: invalid address range")
+
+	_, err = ExpandHostGroups([]byte(`
+spec:
+  hosts:
+    - name: worker
+      role: worker
+      ssh:
+        address: 10.0.0.[1-3]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can not have a name")
+}
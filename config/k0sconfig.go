@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/k0sproject/k0sctl/config/sshconfig"
+	"gopkg.in/yaml.v2"
+)
+
+// ApplyK0sConfigPath loads the k0s configuration file referenced in spec.k0s.configPath of the cluster
+// config yaml and deep-merges the embedded spec.k0s.config over it, so that the inline values win. A
+// relative path is resolved from dir. The expand function, when not nil, is used for the environment
+// variable substitution of the file contents. The content is returned unmodified when there is no
+// configPath.
+func ApplyK0sConfigPath(content []byte, dir string, expand func(string) (string, error)) ([]byte, error) {
+	var data map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+
+	spec, ok := data["spec"].(map[interface{}]interface{})
+	if !ok {
+		return content, nil
+	}
+	k0s, ok := spec["k0s"].(map[interface{}]interface{})
+	if !ok {
+		return content, nil
+	}
+	fn, ok := k0s["configPath"].(string)
+	if !ok || fn == "" {
+		return content, nil
+	}
+
+	fn, err := sshconfig.ExpandHome(fn)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(fn) && dir != "" {
+		fn = filepath.Join(dir, fn)
+	}
+
+	fileContent, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec.k0s.configPath: %w", err)
+	}
+
+	text := string(fileContent)
+	if expand != nil {
+		text, err = expand(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	var fileConfig interface{}
+	if err := yaml.Unmarshal([]byte(text), &fileConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse k0s config %s: %w", fn, err)
+	}
+	if _, ok := fileConfig.(map[interface{}]interface{}); !ok {
+		return nil, fmt.Errorf("k0s config %s is not a yaml mapping", fn)
+	}
+
+	merged, err := mergeValue(fileConfig, k0s["config"], []string{"spec", "k0s", "config"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge spec.k0s.config over %s: %w", fn, err)
+	}
+	k0s["config"] = merged
+
+	return yaml.Marshal(data)
+}
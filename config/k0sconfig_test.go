@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/envsubst"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestApplyK0sConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "k0s.yaml"), []byte(`
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: ClusterConfig
+spec:
+  api:
+    externalAddress: ${LB_ADDRESS}
+    port: 6443
+  telemetry:
+    enabled: true
+`), 0644))
+
+	content := []byte(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+  k0s:
+    version: 1.21.3+k0s.0
+    configPath: k0s.yaml
+    config:
+      spec:
+        telemetry:
+          enabled: false
+`)
+
+	expand := func(s string) (string, error) {
+		return envsubst.ExpandFunc(s, func(string) (string, bool) { return "lb.example.com", true })
+	}
+	res, err := ApplyK0sConfigPath(content, dir, expand)
+	require.NoError(t, err)
+
+	c := Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(res, &c))
+	require.Equal(t, "k0s.yaml", c.Spec.K0s.ConfigPath)
+	require.Equal(t, "ClusterConfig", c.Spec.K0s.Config.DigString("kind"))
+	require.Equal(t, "lb.example.com", c.Spec.K0s.Config.DigString("spec", "api", "externalAddress"))
+	require.Equal(t, 6443, c.Spec.K0s.Config.Dig("spec", "api", "port"))
+	require.Equal(t, false, c.Spec.K0s.Config.Dig("spec", "telemetry", "enabled"))
+
+	_, err = ApplyK0sConfigPath([]byte("spec:\n  k0s:\n    configPath: missing.yaml\n"), dir, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read spec.k0s.configPath")
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MergeYAML deep-merges the given cluster config yaml documents in order, values in the later documents override
+// the values in the earlier ones and an explicit null value removes the value. The spec.hosts lists are merged by
+// the host name or the connection address and port so that an overlay can modify a single host without repeating
+// the whole host list. Other lists are replaced. The names are only used in error messages.
+func MergeYAML(names []string, docs [][]byte) ([]byte, error) {
+	if len(names) != len(docs) {
+		return nil, fmt.Errorf("config merge: got %d names for %d documents", len(names), len(docs))
+	}
+
+	var merged interface{}
+	for i, doc := range docs {
+		var data interface{}
+		if err := yaml.Unmarshal(doc, &data); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s for merging: %w", names[i], err)
+		}
+		if i == 0 {
+			merged = data
+			continue
+		}
+		res, err := mergeValue(merged, data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge config %s over %s (later configs override earlier ones): %w", names[i], strings.Join(names[:i], ", "), err)
+		}
+		merged = res
+	}
+
+	return yaml.Marshal(merged)
+}
+
+func mergeValue(base, overlay interface{}, path []string) (interface{}, error) {
+	if overlay == nil || base == nil {
+		if overlay == nil {
+			return base, nil
+		}
+		return overlay, nil
+	}
+
+	switch ov := overlay.(type) {
+	case map[interface{}]interface{}:
+		bv, ok := base.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s is a mapping but an earlier config defines it as %s", pathString(path), typeName(base))
+		}
+		for k, v := range ov {
+			if v == nil {
+				delete(bv, k)
+				continue
+			}
+			res, err := mergeValue(bv[k], v, append(path, fmt.Sprint(k)))
+			if err != nil {
+				return nil, err
+			}
+			bv[k] = res
+		}
+		return bv, nil
+	case []interface{}:
+		if pathString(path) != "spec.hosts" {
+			return ov, nil
+		}
+		bv, ok := base.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s is a list but an earlier config defines it as %s", pathString(path), typeName(base))
+		}
+		return mergeHosts(bv, ov, path)
+	default:
+		if _, ok := base.(map[interface{}]interface{}); ok {
+			return nil, fmt.Errorf("%s is %s but an earlier config defines it as a mapping", pathString(path), typeName(overlay))
+		}
+		return overlay, nil
+	}
+}
+
+// mergeHosts merges the overlay hosts into the base hosts by host name or address, hosts not in the base are appended
+func mergeHosts(base, overlay []interface{}, path []string) ([]interface{}, error) {
+	for i, h := range overlay {
+		key := hostKey(h)
+		if key.name == "" && key.address == "" {
+			return nil, fmt.Errorf("%s[%d] does not have a name or an address that could be used for merging", pathString(path), i)
+		}
+		found := false
+		for j, bh := range base {
+			if !hostKey(bh).matches(key) {
+				continue
+			}
+			res, err := mergeValue(bh, h, append(path, fmt.Sprintf("[%s]", key)))
+			if err != nil {
+				return nil, err
+			}
+			base[j] = res
+			found = true
+			break
+		}
+		if !found {
+			base = append(base, h)
+		}
+	}
+	return base, nil
+}
+
+var defaultPorts = map[string]int{"ssh": 22, "winRM": 5985}
+
+// mergeKey identifies a host in a host list
+type mergeKey struct {
+	name    string
+	address string
+}
+
+// matches is true when both keys have the same name or when either of them has no name and the addresses match
+func (k mergeKey) matches(other mergeKey) bool {
+	if k.name != "" && other.name != "" {
+		return k.name == other.name
+	}
+	return k.address != "" && k.address == other.address
+}
+
+func (k mergeKey) String() string {
+	if k.name != "" {
+		return k.name
+	}
+	return k.address
+}
+
+// hostKey returns the name and the connection address and port of a host, the address is "localhost" for local connections
+func hostKey(h interface{}) mergeKey {
+	m, ok := h.(map[interface{}]interface{})
+	if !ok {
+		return mergeKey{}
+	}
+	key := mergeKey{}
+	if name, ok := m["name"].(string); ok {
+		key.name = name
+	}
+	for _, proto := range []string{"ssh", "winRM"} {
+		if conn, ok := m[proto].(map[interface{}]interface{}); ok {
+			if addr, ok := conn["address"].(string); ok && addr != "" {
+				port, ok := conn["port"].(int)
+				if !ok {
+					port = defaultPorts[proto]
+				}
+				key.address = fmt.Sprintf("%s:%d", addr, port)
+				return key
+			}
+		}
+	}
+	if _, ok := m["localhost"]; ok {
+		key.address = "localhost"
+	}
+	return key
+}
+
+func pathString(path []string) string {
+	if len(path) == 0 {
+		return "the document root"
+	}
+	return strings.Join(path, ".")
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[interface{}]interface{}:
+		return "a mapping"
+	case []interface{}:
+		return "a list"
+	default:
+		return fmt.Sprintf("a scalar (%v)", v)
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMergeYAML(t *testing.T) {
+	base := []byte(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+        user: root
+    - role: worker
+      ssh:
+        address: 10.0.0.2
+  k0s:
+    version: 1.21.2+k0s.1
+`)
+	overlay := []byte(`
+metadata:
+  name: prod
+spec:
+  hosts:
+    - ssh:
+        address: 10.0.0.1
+        keyPath: /keys/prod
+    - role: worker
+      ssh:
+        address: 10.0.0.3
+  k0s:
+    version: 1.21.3+k0s.0
+`)
+
+	merged, err := MergeYAML([]string{"base.yaml", "overlay.yaml"}, [][]byte{base, overlay})
+	require.NoError(t, err)
+
+	c := Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(merged, &c))
+	require.Equal(t, "prod", c.Metadata.Name)
+	require.Equal(t, "1.21.3+k0s.0", c.Spec.K0s.Version)
+	require.Len(t, c.Spec.Hosts, 3)
+	require.Equal(t, "controller", c.Spec.Hosts[0].Role)
+	require.Equal(t, "root", c.Spec.Hosts[0].SSH.User)
+	require.Equal(t, "/keys/prod", c.Spec.Hosts[0].SSH.KeyPath)
+	require.Equal(t, "10.0.0.3", c.Spec.Hosts[2].Address())
+}
+
+func TestMergeYAMLTypeMismatch(t *testing.T) {
+	_, err := MergeYAML([]string{"base.yaml", "overlay.yaml"}, [][]byte{[]byte("spec:\n  k0s:\n    version: 1.21.2+k0s.1\n"), []byte("spec:\n  k0s: foo\n")})
+	require.EqualError(t, err, "failed to merge config overlay.yaml over base.yaml (later configs override earlier ones): spec.k0s is a scalar (foo) but an earlier config defines it as a mapping")
+}
+
+func TestMergeYAMLHostKeys(t *testing.T) {
+	base := []byte(`
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 192.0.2.1
+        port: 2201
+    - role: worker
+      ssh:
+        address: 192.0.2.1
+        port: 2202
+    - name: worker-2
+      role: worker
+      ssh:
+        address: 192.0.2.1
+        port: 2203
+        keyPath: /keys/base
+`)
+	overlay := []byte(`
+spec:
+  hosts:
+    - ssh:
+        address: 192.0.2.1
+        port: 2202
+        user: admin
+    - name: worker-2
+      ssh:
+        port: 2204
+        keyPath: null
+`)
+
+	merged, err := MergeYAML([]string{"base.yaml", "overlay.yaml"}, [][]byte{base, overlay})
+	require.NoError(t, err)
+
+	c := Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(merged, &c))
+	require.Len(t, c.Spec.Hosts, 3)
+	require.Equal(t, "root", c.Spec.Hosts[0].SSH.User)
+	require.Equal(t, "admin", c.Spec.Hosts[1].SSH.User)
+	require.Equal(t, 2204, c.Spec.Hosts[2].SSH.Port)
+	require.Equal(t, "192.0.2.1", c.Spec.Hosts[2].SSH.Address)
+	require.NotEqual(t, "/keys/base", c.Spec.Hosts[2].SSH.KeyPath)
+}
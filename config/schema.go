@@ -0,0 +1,277 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k0sproject/dig"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+)
+
+// schemaDraft is the JSON Schema version of the generated schema, it is the one supported by most editors
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+type jsonSchema map[string]interface{}
+
+var (
+	durationType         = reflect.TypeOf(time.Duration(0))
+	hookType             = reflect.TypeOf(cluster.Hook{})
+	serviceOverridesType = reflect.TypeOf(cluster.ServiceOverrides{})
+	taintType            = reflect.TypeOf(cluster.Taint{})
+)
+
+// knownSchema returns the schemas of the types that are not given in the configuration in the form of their go type
+func (g *schemaGenerator) knownSchema(t reflect.Type) (jsonSchema, bool) {
+	switch t {
+	case reflect.TypeOf(dig.Mapping{}):
+		return jsonSchema{"type": "object"}, true
+	case durationType:
+		return jsonSchema{"type": "string"}, true
+	case serviceOverridesType:
+		return jsonSchema{"oneOf": []interface{}{
+			jsonSchema{"type": "string"},
+			jsonSchema{"type": "object", "additionalProperties": jsonSchema{"type": "string"}},
+		}}, true
+	case taintType:
+		return jsonSchema{"type": "string", "pattern": `^[^:=]+(=[^:]*)?:(NoSchedule|PreferNoSchedule|NoExecute)$`}, true
+	case hookType:
+		return jsonSchema{"oneOf": []interface{}{
+			jsonSchema{"type": "string"},
+			g.structSchema(hookType),
+		}}, true
+	}
+	return nil, false
+}
+
+// adjust modifies the generated schemas of the structs for the fields that are handled before the
+// configuration is unmarshaled or that are validated in code
+func (g *schemaGenerator) adjust(t reflect.Type, s jsonSchema) {
+	switch t {
+	case reflect.TypeOf(Cluster{}):
+		s.property("apiVersion")["enum"] = []string{APIVersion}
+	case reflect.TypeOf(cluster.K0s{}):
+		// the version defaults to the latest k0s release
+		delete(s, "required")
+	case reflect.TypeOf(cluster.Spec{}):
+		s.properties()["groups"] = jsonSchema{
+			"type": "array",
+			"items": jsonSchema{
+				"type":     "object",
+				"required": []string{"hosts"},
+				"properties": jsonSchema{
+					"name": jsonSchema{"type": "string"},
+					"hosts": jsonSchema{
+						"type":     "array",
+						"minItems": 1,
+						"items":    jsonSchema{"oneOf": []interface{}{jsonSchema{"type": "string"}, jsonSchema{"type": "object"}}},
+					},
+				},
+			},
+		}
+	case reflect.TypeOf(cluster.Host{}):
+		// the inline keys are accepted in the ssh connection of the host and its bastion and the keepalive,
+		// authentication and agent forwarding settings only in the ssh connection of the host
+		bastion := g.structSchema(reflect.TypeOf(rig.SSH{}))
+		bastion.properties()["keyData"] = jsonSchema{"type": "string"}
+		bastion.properties()["keyPassphrase"] = jsonSchema{"type": "string"}
+		ssh := g.structSchema(reflect.TypeOf(rig.SSH{}))
+		ssh.properties()["keyData"] = jsonSchema{"type": "string"}
+		ssh.properties()["keyPassphrase"] = jsonSchema{"type": "string"}
+		ssh.properties()["keepAliveInterval"] = jsonSchema{"type": []string{"string", "integer"}}
+		ssh.properties()["keepAliveCountMax"] = jsonSchema{"type": "integer", "minimum": 1}
+		ssh.properties()["authMethods"] = jsonSchema{"type": "array", "uniqueItems": true, "items": jsonSchema{"enum": []string{cluster.SSHAuthKey, cluster.SSHAuthAgent, cluster.SSHAuthPassword, cluster.SSHAuthKeyboardInteractive}}}
+		ssh.properties()["password"] = jsonSchema{"type": "string"}
+		ssh.properties()["agentForwarding"] = jsonSchema{"type": "boolean"}
+		ssh.properties()["bastion"] = bastion
+		s.properties()["ssh"] = ssh
+
+		s["oneOf"] = []interface{}{
+			jsonSchema{"required": []string{"ssh"}},
+			jsonSchema{"required": []string{"winRM"}},
+			jsonSchema{"required": []string{"localhost"}},
+		}
+	}
+}
+
+func (s jsonSchema) properties() jsonSchema {
+	return s["properties"].(jsonSchema)
+}
+
+func (s jsonSchema) property(name string) jsonSchema {
+	return s.properties()[name].(jsonSchema)
+}
+
+type schemaGenerator struct {
+	definitions jsonSchema
+}
+
+// Schema returns the JSON Schema of the k0sctl cluster configuration. It is generated from the configuration
+// structs, the yaml tags give the field names and the validate and default tags the constraints.
+func Schema() map[string]interface{} {
+	g := &schemaGenerator{definitions: jsonSchema{}}
+	s := g.structSchema(reflect.TypeOf(Cluster{}))
+	s["$schema"] = schemaDraft
+	s["title"] = "k0sctl cluster configuration"
+	s["definitions"] = g.definitions
+	return s
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) jsonSchema {
+	if s, ok := g.knownSchema(t); ok {
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.Struct:
+		return g.ref(t)
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return jsonSchema{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return jsonSchema{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	}
+	return jsonSchema{}
+}
+
+// ref returns a reference to the definition of the struct, the definition is generated on first use
+func (g *schemaGenerator) ref(t reflect.Type) jsonSchema {
+	if _, ok := g.definitions[t.Name()]; !ok {
+		// reserve the name first for the structs that refer to themselves
+		g.definitions[t.Name()] = jsonSchema{}
+		g.definitions[t.Name()] = g.structSchema(t)
+	}
+	return jsonSchema{"$ref": "#/definitions/" + t.Name()}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) jsonSchema {
+	s := jsonSchema{"type": "object", "additionalProperties": false}
+	props := jsonSchema{}
+	var required []string
+	g.addFields(t, props, &required)
+	s["properties"] = props
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	g.adjust(t, s)
+	return s
+}
+
+func (g *schemaGenerator) addFields(t reflect.Type, props jsonSchema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		opts := strings.Split(f.Tag.Get("yaml"), ",")
+		name := opts[0]
+		if name == "-" {
+			continue
+		}
+		if hasOption(opts[1:], "inline") {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			g.addFields(ft, props, required)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		fs := g.typeSchema(f.Type)
+		def, hasDefault := f.Tag.Lookup("default")
+		if hasDefault {
+			if v, ok := defaultValue(f.Type, def); ok {
+				fs["default"] = v
+			}
+		}
+
+		if validateFieldSchema(f, fs) && !hasDefault {
+			*required = append(*required, name)
+		}
+		props[name] = fs
+	}
+}
+
+// validateFieldSchema adds the constraints from the validate tag of the field to the schema and returns
+// true when the field is required
+func validateFieldSchema(f reflect.StructField, fs jsonSchema) bool {
+	var required, omitempty, enum bool
+	numeric := fs["type"] == "integer"
+rules:
+	for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "dive":
+			// the rest of the rules are for the items
+			break rules
+		case "required":
+			required = true
+		case "omitempty":
+			omitempty = true
+		case "oneof":
+			enum = true
+			fs["enum"] = strings.Fields(param)
+		case "gt", "gte", "lt", "lte":
+			if !numeric {
+				continue
+			}
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			fs[numericKeywords[name]] = n
+		}
+	}
+	// a field with a list of allowed values that can't be left empty is required as well
+	return required || (enum && !omitempty)
+}
+
+var numericKeywords = map[string]string{"gt": "exclusiveMinimum", "gte": "minimum", "lt": "exclusiveMaximum", "lte": "maximum"}
+
+// defaultValue converts the value of the default tag to the type of the field
+func defaultValue(t reflect.Type, def string) (interface{}, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return def, true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return def, true
+	case reflect.Bool:
+		v, err := strconv.ParseBool(def)
+		return v, err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.Atoi(def)
+		return v, err == nil
+	}
+	return nil, false
+}
+
+func hasOption(opts []string, option string) bool {
+	for _, o := range opts {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
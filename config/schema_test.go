@@ -0,0 +1,193 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// schemaConfig sets most of the configuration fields
+var schemaConfig = []byte(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: schema
+spec:
+  hosts:
+    - role: controller
+      leader: true
+      arch: arm64
+      ssh:
+        address: 10.0.0.1
+        keepAliveInterval: 30s
+        bastion:
+          address: 10.0.0.100
+      environment:
+        HTTP_PROXY: http://proxy:3128
+      files:
+        - src: images.tar
+          dstDir: /var/lib/k0s/images
+      hooks:
+        apply:
+          before:
+            - date
+            - cmd: hostname
+              ignoreErrors: true
+    - role: worker
+      profile: large
+      labels:
+        tier: db
+      taints:
+        - dedicated=db:NoSchedule
+      winRM:
+        address: 10.0.0.2
+    - role: controller+worker
+      localhost:
+        enabled: true
+  groups:
+    - name: workers
+      role: worker
+      hosts:
+        - 10.0.1.[1-3]
+  k0s:
+    version: 1.23.1+k0s.0
+    config:
+      spec:
+        workerProfiles:
+          - name: large
+    upgrade:
+      drain: true
+      drainTimeout: 5m
+    serviceOverrides:
+      LimitNOFILE: "1048576"
+    installFlags:
+      - --debug
+  localHooks:
+    apply:
+      after:
+        - ./notify.sh
+  telemetry:
+    usage: false
+`)
+
+func resolveSchema(t *testing.T, root, s map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	if ref, ok := s["$ref"].(string); ok {
+		def, ok := root["definitions"].(map[string]interface{})[strings.TrimPrefix(ref, "#/definitions/")]
+		require.True(t, ok, "definition for %s", ref)
+		return def.(map[string]interface{})
+	}
+	return s
+}
+
+// requireSchemaCovers fails when a key of the configuration is not known to the schema
+func requireSchemaCovers(t *testing.T, root, s map[string]interface{}, value interface{}, path string) {
+	t.Helper()
+	s = resolveSchema(t, root, s)
+
+	if alts, ok := s["oneOf"].([]interface{}); ok && s["type"] == nil {
+		for _, a := range alts {
+			alt := resolveSchema(t, root, a.(map[string]interface{}))
+			if _, isMap := value.(map[string]interface{}); isMap == (alt["type"] == "object") {
+				s = alt
+				break
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+		for k, child := range v {
+			var cs map[string]interface{}
+			if ps, ok := props[k]; ok {
+				cs = ps.(map[string]interface{})
+			} else if ap, ok := s["additionalProperties"].(map[string]interface{}); ok {
+				cs = ap
+			} else {
+				require.NotEqual(t, false, s["additionalProperties"], "%s.%s is not in the schema", path, k)
+				continue
+			}
+			requireSchemaCovers(t, root, cs, child, path+"."+k)
+		}
+	case []interface{}:
+		items, ok := s["items"].(map[string]interface{})
+		require.True(t, ok, "%s is not a list in the schema", path)
+		for _, child := range v {
+			requireSchemaCovers(t, root, items, child, path+"[*]")
+		}
+	}
+}
+
+func TestSchemaCoversConfig(t *testing.T) {
+	data, err := json.Marshal(Schema())
+	require.NoError(t, err)
+	var root map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &root))
+
+	var raw interface{}
+	require.NoError(t, yaml.Unmarshal(schemaConfig, &raw))
+	requireSchemaCovers(t, root, root, jsonValue(raw), "")
+
+	var c Cluster
+	require.NoError(t, yaml.UnmarshalStrict(mustExpand(t, schemaConfig), &c))
+	out, err := yaml.Marshal(c)
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(out, &raw))
+	requireSchemaCovers(t, root, root, jsonValue(raw), "")
+}
+
+func mustExpand(t *testing.T, content []byte) []byte {
+	t.Helper()
+	out, err := ExpandHostGroups(content)
+	require.NoError(t, err)
+	return out
+}
+
+// jsonValue converts the yaml mappings to the form they have in json
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			m[k.(string)] = jsonValue(child)
+		}
+		return m
+	case []interface{}:
+		for i, child := range val {
+			val[i] = jsonValue(child)
+		}
+	}
+	return v
+}
+
+func TestSchemaConstraints(t *testing.T) {
+	s := Schema()
+	require.Equal(t, schemaDraft, s["$schema"])
+	require.Equal(t, []string{"apiVersion", "kind"}, s["required"])
+	require.Equal(t, []string{APIVersion}, jsonSchema(s).property("apiVersion")["enum"])
+
+	defs := s["definitions"].(jsonSchema)
+	host := defs["Host"].(jsonSchema)
+	require.Equal(t, []string{"role"}, host["required"])
+	require.Equal(t, []string{"controller", "worker", "controller+worker"}, host.property("role")["enum"])
+	require.Len(t, host["oneOf"], 3)
+	require.Equal(t, []string{"address"}, host.property("ssh")["required"])
+	require.Contains(t, host.property("ssh").properties(), "keepAliveInterval")
+	require.NotContains(t, defs["SSH"].(jsonSchema).properties(), "keepAliveInterval")
+	require.Contains(t, host.property("ssh").properties(), "authMethods")
+	require.Contains(t, host.property("ssh").properties(), "agentForwarding")
+	require.Contains(t, host.property("ssh").properties(), "password")
+	require.NotContains(t, defs["SSH"].(jsonSchema).properties(), "password")
+	require.Contains(t, host.property("ssh").property("bastion").properties(), "keyData")
+	require.Equal(t, 22, defs["SSH"].(jsonSchema).property("port")["default"])
+
+	require.NotContains(t, defs["K0s"].(jsonSchema), "required")
+	require.Equal(t, []string{"src"}, defs["UploadFile"].(jsonSchema)["required"])
+	require.NotContains(t, defs["ClusterMetadata"].(jsonSchema), "required")
+	require.NotContains(t, host.properties(), "metadata")
+	require.Equal(t, "string", host.property("taints")["items"].(jsonSchema)["type"])
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/k0sproject/k0sctl/config/sshconfig"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// ApplySSHConfig fills in the unset ssh connection fields of the hosts in the cluster config yaml
+// from the OpenSSH client configuration. The host address is used as the ssh config host alias and
+// HostName, Port, User, IdentityFile and ProxyJump are supported. Fields set in the cluster config
+// take precedence. The content is returned unmodified when there was nothing to fill in.
+func ApplySSHConfig(content []byte, sc *sshconfig.Config) ([]byte, error) {
+	var data map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+
+	spec, ok := data["spec"].(map[interface{}]interface{})
+	if !ok {
+		return content, nil
+	}
+	hosts, ok := spec["hosts"].([]interface{})
+	if !ok {
+		return content, nil
+	}
+
+	var changed bool
+	for _, h := range hosts {
+		host, ok := h.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		conn, ok := host["ssh"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		c, err := applySSHConfigToConn(conn, sc, true)
+		if err != nil {
+			return nil, err
+		}
+		changed = changed || c
+	}
+
+	if !changed {
+		return content, nil
+	}
+
+	return yaml.Marshal(data)
+}
+
+func applySSHConfigToConn(conn map[interface{}]interface{}, sc *sshconfig.Config, withJump bool) (bool, error) {
+	alias, ok := conn["address"].(string)
+	if !ok || alias == "" {
+		return false, nil
+	}
+
+	var changed bool
+	set := func(key string, value interface{}) {
+		log.Debugf("host %s: using %s %v from ssh config", alias, key, value)
+		conn[key] = value
+		changed = true
+	}
+
+	if hostname := sc.Get(alias, "HostName"); hostname != "" {
+		set("address", strings.ReplaceAll(hostname, "%h", alias))
+	}
+
+	if _, ok := conn["port"]; !ok {
+		if port := sc.Get(alias, "Port"); port != "" {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return false, fmt.Errorf("invalid port %q for host %s in ssh config", port, alias)
+			}
+			set("port", p)
+		}
+	}
+
+	if _, ok := conn["user"]; !ok {
+		if user := sc.Get(alias, "User"); user != "" {
+			set("user", user)
+		}
+	}
+
+	if _, ok := conn["keyPath"]; !ok {
+		if identity := sc.Get(alias, "IdentityFile"); identity != "" {
+			fn, err := sshconfig.ExpandHome(identity)
+			if err != nil {
+				return false, err
+			}
+			set("keyPath", fn)
+		}
+	}
+
+	if !withJump {
+		return changed, nil
+	}
+
+	if _, ok := conn["bastion"]; ok {
+		return changed, nil
+	}
+
+	jump := sc.Get(alias, "ProxyJump")
+	if jump == "" || jump == "none" {
+		return changed, nil
+	}
+
+	if strings.Contains(jump, ",") {
+		return false, fmt.Errorf("host %s: multiple ProxyJump hops in ssh config are not supported", alias)
+	}
+
+	bastion, err := parseJump(jump)
+	if err != nil {
+		return false, fmt.Errorf("host %s: %w", alias, err)
+	}
+	if _, err := applySSHConfigToConn(bastion, sc, false); err != nil {
+		return false, err
+	}
+	set("bastion", bastion)
+
+	return changed, nil
+}
+
+// parseJump parses a ProxyJump value in the [user@]host[:port] format
+func parseJump(jump string) (map[interface{}]interface{}, error) {
+	bastion := make(map[interface{}]interface{})
+
+	if idx := strings.LastIndex(jump, "@"); idx != -1 {
+		bastion["user"] = jump[:idx]
+		jump = jump[idx+1:]
+	}
+
+	var port string
+	switch {
+	case strings.HasPrefix(jump, "["):
+		idx := strings.Index(jump, "]")
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid ProxyJump address %q", jump)
+		}
+		port = strings.TrimPrefix(jump[idx+1:], ":")
+		jump = jump[1:idx]
+	case strings.Count(jump, ":") == 1:
+		idx := strings.Index(jump, ":")
+		port = jump[idx+1:]
+		jump = jump[:idx]
+	}
+
+	if port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ProxyJump port %q", port)
+		}
+		bastion["port"] = p
+	}
+
+	bastion["address"] = jump
+
+	return bastion, nil
+}
@@ -0,0 +1,179 @@
+// Package sshconfig implements a parser for the OpenSSH client configuration file format
+package sshconfig
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxIncludeDepth limits the nesting of Include directives to avoid include loops
+const maxIncludeDepth = 16
+
+type entry struct {
+	patterns []string
+	key      string
+	value    string
+}
+
+// Config is a parsed ssh client configuration. Only the Host blocks are supported, the directives
+// inside Match blocks are ignored.
+type Config struct {
+	entries []entry
+	// IgnoredMatches lists the locations of the ignored Match blocks as file:line
+	IgnoredMatches []string
+}
+
+// ParseFile parses the ssh config file at the given path. Include directives are followed, relative include
+// paths are resolved against the ~/.ssh directory.
+func ParseFile(fn string) (*Config, error) {
+	c := &Config{}
+	if err := c.parseFile(fn, []string{"*"}, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Parse parses ssh config content. Relative include paths are resolved against the ~/.ssh directory.
+func Parse(r io.Reader) (*Config, error) {
+	c := &Config{}
+	if err := c.parse(r, "config", []string{"*"}, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) parseFile(fn string, patterns []string, depth int) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.parse(f, fn, patterns, depth)
+}
+
+func (c *Config) parse(r io.Reader, name string, patterns []string, depth int) error {
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value := splitLine(line)
+		if value == "" {
+			return fmt.Errorf("%s:%d: missing value for %s", name, lineno, key)
+		}
+
+		switch key {
+		case "host":
+			patterns = strings.Fields(value)
+		case "match":
+			// match blocks are not supported, ignore everything until the next Host line
+			patterns = nil
+			c.IgnoredMatches = append(c.IgnoredMatches, fmt.Sprintf("%s:%d", name, lineno))
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("%s:%d: too many nested includes", name, lineno)
+			}
+			for _, inc := range strings.Fields(value) {
+				if err := c.include(inc, patterns, depth+1); err != nil {
+					return fmt.Errorf("%s:%d: %w", name, lineno, err)
+				}
+			}
+		default:
+			if patterns != nil {
+				c.entries = append(c.entries, entry{patterns: patterns, key: key, value: unquote(value)})
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *Config) include(pattern string, patterns []string, depth int) error {
+	pattern, err := ExpandHome(pattern)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(pattern) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		pattern = filepath.Join(home, ".ssh", pattern)
+	}
+
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, fn := range files {
+		if err := c.parseFile(fn, patterns, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the first value obtained for the keyword for the host alias, following the
+// ssh_config(5) rule that the first obtained value is used. Keywords are case-insensitive.
+func (c *Config) Get(alias, key string) string {
+	key = strings.ToLower(key)
+	for _, e := range c.entries {
+		if e.key == key && matches(alias, e.patterns) {
+			return e.value
+		}
+	}
+	return ""
+}
+
+func matches(alias string, patterns []string) bool {
+	var matched bool
+	for _, p := range patterns {
+		negate := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if ok, _ := path.Match(p, alias); ok {
+			if negate {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// ExpandHome replaces a leading ~ in the path with the user's home directory
+func ExpandHome(fn string) (string, error) {
+	if fn != "~" && !strings.HasPrefix(fn, "~/") {
+		return fn, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fn[1:]), nil
+}
+
+func splitLine(line string) (string, string) {
+	idx := strings.IndexAny(line, " \t=")
+	if idx == -1 {
+		return strings.ToLower(line), ""
+	}
+	key := strings.ToLower(line[:idx])
+	value := strings.TrimSpace(line[idx:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return key, value
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(`
+# comment
+Host web* !web-legacy
+  HostName %h.example.com
+  User deploy
+
+Host web-legacy
+  User root
+
+Match exec "true"
+  User ignored
+
+Host *
+  Port=2222
+  User fallback
+  IdentityFile "~/.ssh/id ed25519"
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, "%h.example.com", c.Get("web1", "hostname"))
+	require.Equal(t, "deploy", c.Get("web1", "User"))
+	require.Equal(t, "2222", c.Get("web1", "Port"))
+	require.Equal(t, "root", c.Get("web-legacy", "User"))
+	require.Equal(t, "", c.Get("web-legacy", "HostName"))
+	require.Equal(t, "fallback", c.Get("db", "User"))
+	require.Equal(t, "~/.ssh/id ed25519", c.Get("db", "IdentityFile"))
+	require.Equal(t, []string{"config:10"}, c.IgnoredMatches)
+}
+
+func TestParseFileInclude(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "a.conf"), []byte("Host included\n  User included-user\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config"), []byte("Include "+filepath.Join(dir, "conf.d", "*.conf")+"\nHost *\n  User default\n"), 0600))
+
+	c, err := ParseFile(filepath.Join(dir, "config"))
+	require.NoError(t, err)
+	require.Equal(t, "included-user", c.Get("included", "User"))
+	require.Equal(t, "default", c.Get("other", "User"))
+}
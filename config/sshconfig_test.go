@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/sshconfig"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestApplySSHConfig(t *testing.T) {
+	sc, err := sshconfig.Parse(strings.NewReader(`
+Host ctrl
+  HostName 10.0.0.1
+  User admin
+  Port 2222
+  IdentityFile /keys/ctrl
+  ProxyJump jumper@bastion:2200
+
+Host bastion
+  HostName 10.0.0.254
+  IdentityFile /keys/bastion
+`))
+	require.NoError(t, err)
+
+	content := []byte(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: ctrl
+        user: root
+    - role: worker
+      ssh:
+        address: 10.0.0.2
+  k0s:
+    version: 1.21.3+k0s.0
+`)
+
+	res, err := ApplySSHConfig(content, sc)
+	require.NoError(t, err)
+
+	c := Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(res, &c))
+	h := c.Spec.Hosts[0]
+	require.Equal(t, "10.0.0.1", h.SSH.Address)
+	require.Equal(t, "root", h.SSH.User)
+	require.Equal(t, 2222, h.SSH.Port)
+	require.Equal(t, "/keys/ctrl", h.SSH.KeyPath)
+	require.NotNil(t, h.SSH.Bastion)
+	require.Equal(t, "10.0.0.254", h.SSH.Bastion.Address)
+	require.Equal(t, "jumper", h.SSH.Bastion.User)
+	require.Equal(t, 2200, h.SSH.Bastion.Port)
+	require.Equal(t, "/keys/bastion", h.SSH.Bastion.KeyPath)
+	require.Equal(t, "10.0.0.2", c.Spec.Hosts[1].SSH.Address)
+	require.Nil(t, c.Spec.Hosts[1].SSH.Bastion)
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/k0sproject/k0sctl/config/sshconfig"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultSSHKeyPath is the key file used for the ssh connections without a keyPath
+const defaultSSHKeyPath = "~/.ssh/id_rsa"
+
+// DecryptSSHKeys decrypts the passphrase protected private keys of the hosts and their bastions in the
+// cluster config yaml using the ssh.keyPassphrase of the connection or the passphrase when the
+// connection does not have one. The decrypted keys are put into the ssh.keyData fields in place of the
+// encrypted keyData or the key file, to be extracted with ExtractSSHKeyData. The keyPassphrase fields are
+// removed. The passphrases that were used are returned. The content is returned unmodified when there are
+// no passphrases.
+func DecryptSSHKeys(content []byte, passphrase string) ([]byte, []string, error) {
+	data, hosts, err := configHosts(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var used []string
+	var changed bool
+	for i, host := range hosts {
+		conn, ok := host["ssh"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		conns := map[string]map[interface{}]interface{}{fmt.Sprintf("spec.hosts[%d].ssh", i): conn}
+		if bastion, ok := conn["bastion"].(map[interface{}]interface{}); ok {
+			conns[fmt.Sprintf("spec.hosts[%d].ssh.bastion", i)] = bastion
+		}
+		for name, c := range conns {
+			pass, err := decryptKey(name, c, passphrase)
+			if err != nil {
+				return nil, nil, err
+			}
+			if pass != "" {
+				used = append(used, pass)
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return content, nil, nil
+	}
+
+	res, err := yaml.Marshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, used, nil
+}
+
+// decryptKey decrypts the key of the connection when it is passphrase protected and returns the
+// passphrase of the connection, which is empty when there is nothing to decrypt with
+func decryptKey(name string, conn map[interface{}]interface{}, passphrase string) (string, error) {
+	if value, ok := conn["keyPassphrase"]; ok {
+		delete(conn, "keyPassphrase")
+		pass, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("%s.keyPassphrase: must be a string", name)
+		}
+		passphrase = pass
+	}
+	if passphrase == "" {
+		return "", nil
+	}
+
+	if value, ok := conn["keyData"]; ok {
+		key, ok := value.(string)
+		if !ok || !isEncryptedKey([]byte(key)) {
+			// invalid keys are reported by ExtractSSHKeyData
+			return passphrase, nil
+		}
+		decrypted, err := decryptPrivateKey(name+".keyData", []byte(key), passphrase)
+		if err != nil {
+			return "", err
+		}
+		conn["keyData"] = decrypted
+		return passphrase, nil
+	}
+
+	path, explicit := conn["keyPath"].(string)
+	if !explicit || path == "" {
+		path = defaultSSHKeyPath
+	}
+	fn, err := sshconfig.ExpandHome(path)
+	if err != nil {
+		return "", err
+	}
+	key, err := os.ReadFile(fn)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return passphrase, nil
+		}
+		return "", fmt.Errorf("%s.keyPath: failed to read the private key: %w", name, err)
+	}
+	if !isEncryptedKey(key) {
+		return passphrase, nil
+	}
+	decrypted, err := decryptPrivateKey(path, key, passphrase)
+	if err != nil {
+		return "", err
+	}
+	conn["keyData"] = decrypted
+	return passphrase, nil
+}
+
+func isEncryptedKey(key []byte) bool {
+	_, err := ssh.ParseRawPrivateKey(key)
+	var perr *ssh.PassphraseMissingError
+	return errors.As(err, &perr)
+}
+
+// decryptPrivateKey decrypts the key and returns it PEM encoded in the PKCS#8 format
+func decryptPrivateKey(name string, key []byte, passphrase string) (string, error) {
+	raw, err := ssh.ParseRawPrivateKeyWithPassphrase(key, []byte(passphrase))
+	if err != nil {
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return "", fmt.Errorf("incorrect passphrase for key %s", name)
+		}
+		return "", fmt.Errorf("failed to decrypt key %s, the passphrase may be incorrect: %w", name, err)
+	}
+	if k, ok := raw.(*ed25519.PrivateKey); ok {
+		raw = *k
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(raw)
+	if err != nil {
+		return "", fmt.Errorf("unsupported key type in %s: %w", name, err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ExtractSSHKeyData removes the inline ssh.keyData private keys of the hosts and their bastions from the
+// cluster config yaml and returns them. The ssh connection configuration does not know about the field,
+// the keys are made available to the connections by other means. The keys are validated to be parseable.
+// When keyPath is not nil, it is called for each key and the connection keyPath is set to the returned
+// path, so that each connection only uses its own key. Otherwise or when the returned path is empty,
+// the keyPath is dropped so that the inline key is used instead of the key file. The content is
+// returned unmodified when there are no inline keys.
+func ExtractSSHKeyData(content []byte, keyPath func(key string) (string, error)) ([]byte, []string, error) {
+	var data map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, nil, err
+	}
+
+	spec, ok := data["spec"].(map[interface{}]interface{})
+	if !ok {
+		return content, nil, nil
+	}
+	hosts, ok := spec["hosts"].([]interface{})
+	if !ok {
+		return content, nil, nil
+	}
+
+	var keys []string
+	for i, h := range hosts {
+		host, ok := h.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		conn, ok := host["ssh"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		k, err := extractKeyData(conn, keyPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("spec.hosts[%d].ssh.keyData: %w", i, err)
+		}
+		keys = append(keys, k...)
+
+		bastion, ok := conn["bastion"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		k, err = extractKeyData(bastion, keyPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("spec.hosts[%d].ssh.bastion.keyData: %w", i, err)
+		}
+		keys = append(keys, k...)
+	}
+
+	if len(keys) == 0 {
+		return content, nil, nil
+	}
+
+	res, err := yaml.Marshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, keys, nil
+}
+
+func extractKeyData(conn map[interface{}]interface{}, keyPath func(key string) (string, error)) ([]string, error) {
+	value, ok := conn["keyData"]
+	if !ok {
+		return nil, nil
+	}
+	delete(conn, "keyData")
+
+	key, ok := value.(string)
+	if !ok || key == "" {
+		return nil, fmt.Errorf("must be a PEM encoded private key")
+	}
+
+	if _, err := ssh.ParseRawPrivateKey([]byte(key)); err != nil {
+		if _, ok := err.(*ssh.PassphraseMissingError); ok {
+			return nil, fmt.Errorf("the key is passphrase protected, set the passphrase in keyPassphrase or give it with --ssh-passphrase")
+		}
+		return nil, fmt.Errorf("failed to parse the private key, make sure it is a complete PEM encoded key including the BEGIN and END lines: %w", err)
+	}
+
+	delete(conn, "keyPath")
+	if keyPath != nil {
+		path, err := keyPath(key)
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			conn["keyPath"] = path
+		}
+	}
+
+	return []string{key}, nil
+}
+
+// InsertSSHKeyData puts the inline ssh.keyData keys and the ssh.keyPassphrase passphrases of the hosts and
+// their bastions in the original cluster config yaml back into the content, in place of the keyPath set by
+// ExtractSSHKeyData. The keys and passphrases are passed through the replace function, such as for
+// redacting them, when it is not nil.
+func InsertSSHKeyData(content, original []byte, replace func(key string) string) ([]byte, error) {
+	_, orig, err := configHosts(original)
+	if err != nil || len(orig) == 0 {
+		return content, err
+	}
+	data, hosts, err := configHosts(content)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, host := range hosts {
+		if i >= len(orig) {
+			break
+		}
+		conn, ok := host["ssh"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		origConn, ok := orig[i]["ssh"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		insertKeyData(conn, origConn, replace)
+
+		bastion, ok := conn["bastion"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		if origBastion, ok := origConn["bastion"].(map[interface{}]interface{}); ok {
+			insertKeyData(bastion, origBastion, replace)
+		}
+	}
+
+	return yaml.Marshal(data)
+}
+
+func insertKeyData(conn, orig map[interface{}]interface{}, replace func(key string) string) {
+	if pass, ok := orig["keyPassphrase"].(string); ok {
+		if replace != nil {
+			pass = replace(pass)
+		}
+		conn["keyPassphrase"] = pass
+	}
+
+	key, ok := orig["keyData"].(string)
+	if !ok {
+		// the key file may have been decrypted into keyData
+		if path, ok := orig["keyPath"]; ok {
+			conn["keyPath"] = path
+		} else {
+			delete(conn, "keyPath")
+		}
+		return
+	}
+	if replace != nil {
+		key = replace(key)
+	}
+	delete(conn, "keyPath")
+	conn["keyData"] = key
+}
+
+// configHosts parses a cluster config yaml and returns it along with the spec.hosts mappings
+func configHosts(content []byte) (map[interface{}]interface{}, []map[interface{}]interface{}, error) {
+	var data map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, nil, err
+	}
+	spec, ok := data["spec"].(map[interface{}]interface{})
+	if !ok {
+		return data, nil, nil
+	}
+	hosts, ok := spec["hosts"].([]interface{})
+	if !ok {
+		return data, nil, nil
+	}
+	res := make([]map[interface{}]interface{}, len(hosts))
+	for i, h := range hosts {
+		if host, ok := h.(map[interface{}]interface{}); ok {
+			res[i] = host
+		}
+	}
+	return data, res, nil
+}
+
+// SSHPasswords returns the ssh.password passwords of the hosts in the cluster config yaml
+func SSHPasswords(content []byte) ([]string, error) {
+	_, hosts, err := configHosts(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var passwords []string
+	for _, host := range hosts {
+		conn, ok := host["ssh"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		if pass, ok := conn["password"].(string); ok && pass != "" {
+			passwords = append(passwords, pass)
+		}
+	}
+	return passwords, nil
+}
+
+// SudoPasswords returns the sudoPassword passwords of the hosts in the cluster config yaml
+func SudoPasswords(content []byte) ([]string, error) {
+	_, hosts, err := configHosts(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var passwords []string
+	for _, host := range hosts {
+		if pass, ok := host["sudoPassword"].(string); ok && pass != "" {
+			passwords = append(passwords, pass)
+		}
+	}
+	return passwords, nil
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/envsubst"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func testKey(t *testing.T) string {
+	t.Helper()
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(pk)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+func TestExtractSSHKeyData(t *testing.T) {
+	key := testKey(t)
+	content, err := envsubst.ExpandFunc(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+        keyPath: /keys/ctrl
+        keyData: ${SSH_KEY}
+        bastion:
+          address: 10.0.0.254
+          keyData: ${SSH_KEY}
+    - role: worker
+      ssh:
+        address: 10.0.0.2
+        keyPath: /keys/worker
+`, func(string) (string, bool) { return key, true })
+	require.NoError(t, err)
+
+	res, keys, err := ExtractSSHKeyData([]byte(content), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{key, key}, keys)
+	require.NotContains(t, string(res), "keyData")
+	require.NotContains(t, string(res), "PRIVATE KEY")
+
+	c := Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(res, &c))
+	require.Equal(t, "10.0.0.254", c.Spec.Hosts[0].SSH.Bastion.Address)
+	require.NotEqual(t, "/keys/ctrl", c.Spec.Hosts[0].SSH.KeyPath)
+	require.Equal(t, "/keys/worker", c.Spec.Hosts[1].SSH.KeyPath)
+
+	unchanged := []byte("spec:\n  hosts:\n    - ssh:\n        address: 10.0.0.1\n")
+	res, keys, err = ExtractSSHKeyData(unchanged, nil)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+	require.Equal(t, unchanged, res)
+}
+
+func TestExtractSSHKeyDataInvalid(t *testing.T) {
+	_, _, err := ExtractSSHKeyData([]byte("spec:\n  hosts:\n    - ssh:\n        address: 10.0.0.1\n    - ssh:\n        address: 10.0.0.2\n        keyData: not a key\n"), nil)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "spec.hosts[1].ssh.keyData: failed to parse the private key"))
+}
+
+func TestExtractSSHKeyDataKeyPath(t *testing.T) {
+	key := testKey(t)
+	content := "spec:\n  hosts:\n    - role: worker\n      ssh:\n        address: 10.0.0.1\n        keyData: |\n          " + strings.ReplaceAll(strings.TrimSpace(key), "\n", "\n          ") + "\n"
+
+	var paths int
+	res, keys, err := ExtractSSHKeyData([]byte(content), func(k string) (string, error) {
+		require.Equal(t, strings.TrimSpace(key), strings.TrimSpace(k))
+		paths++
+		return fmt.Sprintf("/run/k0sctl/key-%d", paths), nil
+	})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	c := Cluster{}
+	require.NoError(t, yaml.UnmarshalStrict(res, &c))
+	require.Equal(t, "/run/k0sctl/key-1", c.Spec.Hosts[0].SSH.KeyPath)
+}
+
+func TestInsertSSHKeyData(t *testing.T) {
+	key := testKey(t)
+	original, err := envsubst.ExpandFunc(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+        keyData: ${SSH_KEY}
+        bastion:
+          address: 10.0.0.254
+          keyData: ${SSH_KEY}
+    - role: worker
+      ssh:
+        address: 10.0.0.2
+        keyPath: /keys/worker
+`, func(string) (string, bool) { return fmt.Sprintf("%q", key), true })
+	require.NoError(t, err)
+
+	content, _, err := ExtractSSHKeyData([]byte(original), func(string) (string, error) { return "/tmp/pipe", nil })
+	require.NoError(t, err)
+
+	res, err := InsertSSHKeyData(content, []byte(original), func(string) string { return "[REDACTED]" })
+	require.NoError(t, err)
+	require.NotContains(t, string(res), "/tmp/pipe")
+	require.NotContains(t, string(res), "PRIVATE KEY")
+	require.Equal(t, 2, strings.Count(string(res), "keyData: '[REDACTED]'"))
+	require.Contains(t, string(res), "keyPath: /keys/worker")
+
+	res, err = InsertSSHKeyData(content, []byte(original), nil)
+	require.NoError(t, err)
+	_, keys, err := ExtractSSHKeyData(res, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{key, key}, keys)
+}
+
+func testEncryptedKey(t *testing.T, passphrase string) string {
+	t.Helper()
+	block, _ := pem.Decode([]byte(testKey(t)))
+	enc, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, []byte(passphrase), x509.PEMCipherAES256)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(enc))
+}
+
+func TestDecryptSSHKeys(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "id_ecdsa")
+	require.NoError(t, os.WriteFile(keyFile, []byte(testEncryptedKey(t, "correct horse")), 0600))
+	plainFile := filepath.Join(dir, "id_plain")
+	require.NoError(t, os.WriteFile(plainFile, []byte(testKey(t)), 0600))
+
+	original := fmt.Sprintf(`
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+        keyPath: %s
+        keyPassphrase: correct horse
+    - role: worker
+      ssh:
+        address: 10.0.0.2
+        keyData: %q
+        bastion:
+          address: 10.0.0.254
+          keyPath: %s
+`, keyFile, testEncryptedKey(t, "battery staple"), plainFile)
+
+	content, used, err := DecryptSSHKeys([]byte(original), "battery staple")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"correct horse", "battery staple", "battery staple"}, used)
+	require.NotContains(t, string(content), "keyPassphrase")
+	require.NotContains(t, string(content), "ENCRYPTED")
+	require.Contains(t, string(content), "keyPath: "+plainFile)
+
+	content, keys, err := ExtractSSHKeyData(content, func(string) (string, error) { return "/tmp/pipe", nil })
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.NotContains(t, string(content), keyFile)
+
+	saved, err := InsertSSHKeyData(content, []byte(original), func(string) string { return "[REDACTED]" })
+	require.NoError(t, err)
+	require.NotContains(t, string(saved), "/tmp/pipe")
+	require.NotContains(t, string(saved), "correct horse")
+	require.Contains(t, string(saved), "keyPath: "+keyFile)
+	require.Contains(t, string(saved), "keyPassphrase: '[REDACTED]'")
+	require.Contains(t, string(saved), "keyPath: "+plainFile)
+
+	t.Run("incorrect passphrase", func(t *testing.T) {
+		_, _, err := DecryptSSHKeys([]byte(fmt.Sprintf("spec:\n  hosts:\n    - ssh:\n        address: 10.0.0.1\n        keyPath: %s\n", keyFile)), "wrong")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "passphrase")
+		require.Contains(t, err.Error(), "key "+keyFile)
+	})
+
+	t.Run("no passphrase", func(t *testing.T) {
+		unchanged := []byte(fmt.Sprintf("spec:\n  hosts:\n    - ssh:\n        address: 10.0.0.1\n        keyPath: %s\n", keyFile))
+		content, used, err := DecryptSSHKeys(unchanged, "")
+		require.NoError(t, err)
+		require.Empty(t, used)
+		require.Equal(t, unchanged, content)
+	})
+}
+
+func TestSSHPasswords(t *testing.T) {
+	content := []byte(`apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+        authMethods: [password]
+        password: secret
+    - role: worker
+      ssh:
+        address: 10.0.0.2
+`)
+	passwords, err := SSHPasswords(content)
+	require.NoError(t, err)
+	require.Equal(t, []string{"secret"}, passwords)
+}
+
+func TestSudoPasswords(t *testing.T) {
+	content := []byte(`apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      sudoPassword: secret
+      ssh:
+        address: 10.0.0.1
+    - role: worker
+      ssh:
+        address: 10.0.0.2
+`)
+	passwords, err := SudoPasswords(content)
+	require.NoError(t, err)
+	require.Equal(t, []string{"secret"}, passwords)
+}
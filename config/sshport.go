@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ApplySSHPort sets the ssh port of the hosts in the cluster config yaml that use a ssh connection without
+// a port. The bastions are left unchanged. The content is returned unmodified when there was nothing to set.
+func ApplySSHPort(content []byte, port int) ([]byte, error) {
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid ssh port %d, the port must be between 1 and 65535", port)
+	}
+
+	var data map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+
+	spec, ok := data["spec"].(map[interface{}]interface{})
+	if !ok {
+		return content, nil
+	}
+	hosts, ok := spec["hosts"].([]interface{})
+	if !ok {
+		return content, nil
+	}
+
+	var changed bool
+	for _, h := range hosts {
+		host, ok := h.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		conn, ok := host["ssh"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := conn["port"]; ok {
+			continue
+		}
+		conn["port"] = port
+		changed = true
+	}
+
+	if !changed {
+		return content, nil
+	}
+
+	return yaml.Marshal(data)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestApplySSHPort(t *testing.T) {
+	content := []byte(`
+apiVersion: k0sctl.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  hosts:
+    - role: controller
+      ssh:
+        address: 10.0.0.1
+        bastion:
+          address: bastion.example.com
+    - role: worker
+      ssh:
+        address: 10.0.0.2
+        port: 2200
+    - role: worker
+      winRM:
+        address: 10.0.0.3
+`)
+
+	out, err := ApplySSHPort(content, 2222)
+	require.NoError(t, err)
+
+	var c Cluster
+	require.NoError(t, yaml.UnmarshalStrict(out, &c))
+	require.Equal(t, 2222, c.Spec.Hosts[0].SSH.Port)
+	require.Equal(t, 22, c.Spec.Hosts[0].SSH.Bastion.Port)
+	require.Equal(t, 2200, c.Spec.Hosts[1].SSH.Port)
+	require.Equal(t, 5985, c.Spec.Hosts[2].WinRM.Port)
+
+	unchanged := []byte("spec:\n  hosts:\n    - ssh:\n        address: 10.0.0.1\n        port: 22\n")
+	out, err = ApplySSHPort(unchanged, 2222)
+	require.NoError(t, err)
+	require.Equal(t, unchanged, out)
+
+	for _, port := range []int{0, -1, 65536} {
+		_, err = ApplySSHPort(content, port)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the port must be between 1 and 65535")
+	}
+}
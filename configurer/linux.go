@@ -2,11 +2,14 @@ package configurer
 
 import (
 	"fmt"
+	"net"
 	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/alessio/shellescape"
 	"github.com/k0sproject/rig/exec"
 	"github.com/k0sproject/rig/os"
 )
@@ -22,6 +25,9 @@ type PathFuncs interface {
 // Linux is a base module for various linux OS support packages
 type Linux struct {
 	PathFuncs
+
+	// k0sBinaryPath overrides the default location of the k0s binary of the distro
+	k0sBinaryPath string
 }
 
 // NOTE The Linux struct does not embed rig/os.Linux because it will confuse
@@ -62,9 +68,22 @@ func (l Linux) K0sCmdf(template string, args ...interface{}) string {
 
 // K0sBinaryPath returns the location of k0s binary
 func (l Linux) K0sBinaryPath() string {
+	if l.k0sBinaryPath != "" {
+		return l.k0sBinaryPath
+	}
 	return "/usr/local/bin/k0s"
 }
 
+// SetK0sBinaryPath makes the k0s commands use the binary at the given path instead of the default location
+func (l *Linux) SetK0sBinaryPath(path string) {
+	l.k0sBinaryPath = path
+}
+
+// K0sBinaryPathOverride returns the k0s binary location set with SetK0sBinaryPath or an empty string
+func (l Linux) K0sBinaryPathOverride() string {
+	return l.k0sBinaryPath
+}
+
 // K0sConfigPath returns the location of k0s configuration file
 func (l Linux) K0sConfigPath() string {
 	return "/etc/k0s/k0s.yaml"
@@ -85,16 +104,16 @@ func (l Linux) TempDir(h os.Host) (string, error) {
 	return h.ExecOutput("mktemp -d")
 }
 
-// DownloadK0s performs k0s binary download from github on the host
-func (l Linux) DownloadK0s(h os.Host, version, arch string) error {
+// DownloadK0s performs k0s binary download from the url on the host, the env is set for the download command
+func (l Linux) DownloadK0s(h os.Host, url string, env map[string]string) error {
 	tmp, err := l.TempFile(h)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = h.Execf(`rm -f "%s"`, tmp) }()
 
-	url := fmt.Sprintf("https://github.com/k0sproject/k0s/releases/download/v%s/k0s-v%s-%s", version, version, arch)
-	if err := h.Execf(`curl -sSLf -o "%s" "%s"`, tmp, url); err != nil {
+	prefix, redact := envPrefix(env)
+	if err := h.Execf(`%scurl -sSLf -o "%s" "%s"`, prefix, tmp, url, exec.RedactString(redact...)); err != nil {
 		return err
 	}
 
@@ -125,6 +144,37 @@ func (l Linux) DeleteFile(h os.Host, path string) error {
 	return h.Execf(`rm -f "%s"`, path, exec.Sudo(h))
 }
 
+// FileSize returns the size of a file on the host in bytes
+func (l Linux) FileSize(h os.Host, path string) (int64, error) {
+	output, err := h.ExecOutputf(`stat -c "%%s" "%s"`, path, exec.Sudo(h))
+	if err != nil {
+		return -1, err
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return -1, fmt.Errorf("unexpected stat output: %s", output)
+	}
+	return size, nil
+}
+
+// AppendFile appends the contents of the src file to the dst file on the host, dst is created when it does not exist
+func (l Linux) AppendFile(h os.Host, src, dst string) error {
+	return h.Execf(`sh -c 'cat "%s" >> "%s"'`, src, dst, exec.Sudo(h))
+}
+
+// Sha256sum returns the hex encoded sha256 checksum of a file on the host
+func (l Linux) Sha256sum(h os.Host, path string) (string, error) {
+	output, err := h.ExecOutputf(`sha256sum "%s"`, path, exec.Sudo(h))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected sha256sum output: %s", output)
+	}
+	return fields[0], nil
+}
+
 // KubeconfigPath returns the path to a kubeconfig on the host
 func (l Linux) KubeconfigPath() string {
 	return "/var/lib/k0s/pki/admin.conf"
@@ -149,6 +199,23 @@ func (l Linux) HTTPStatus(h os.Host, url string) (int, error) {
 	return status, nil
 }
 
+// TCPConnect opens a TCP connection from the host to the port of the address. The connect time reported by
+// curl is zero when the connection could not be made, whatever the protocol spoken on the port is.
+func (l Linux) TCPConnect(h os.Host, address string, port int) error {
+	output, err := h.ExecOutput(fmt.Sprintf(`curl -kso /dev/null --connect-timeout 5 --max-time 10 -w "%%{time_connect}" "https://%s/" || true`, net.JoinHostPort(address, strconv.Itoa(port))))
+	if err != nil {
+		return err
+	}
+	connect, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+	if err != nil {
+		return fmt.Errorf("invalid response: %s", err.Error())
+	}
+	if connect == 0 {
+		return fmt.Errorf("connection failed")
+	}
+	return nil
+}
+
 const sbinPath = `PATH=/usr/local/sbin:/usr/sbin:/sbin:$PATH`
 
 // PrivateInterface tries to find a private network interface
@@ -189,3 +256,29 @@ func (l Linux) PrivateAddress(h os.Host, iface, publicip string) (string, error)
 
 	return "", fmt.Errorf("not found")
 }
+
+// envPrefix returns an "env" command prefix for running a command with the environment variables and the
+// values that contain credentials and should be redacted from the logs
+func envPrefix(env map[string]string) (string, []string) {
+	if len(env) == 0 {
+		return "", nil
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var prefix strings.Builder
+	var redact []string
+	prefix.WriteString("env ")
+	for _, k := range keys {
+		prefix.WriteString(fmt.Sprintf("%s=%s ", k, shellescape.Quote(env[k])))
+		if strings.Contains(env[k], "@") {
+			redact = append(redact, env[k])
+		}
+	}
+
+	return prefix.String(), redact
+}
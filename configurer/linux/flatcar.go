@@ -32,5 +32,8 @@ func (l Flatcar) InstallPackage(h os.Host, pkg ...string) error {
 }
 
 func (l Flatcar) K0sBinaryPath() string {
+	if path := l.K0sBinaryPathOverride(); path != "" {
+		return path
+	}
 	return "/opt/bin/k0s"
 }
@@ -24,3 +24,20 @@ func TestPaths(t *testing.T) {
 	require.Equal(t, "/var/lib/k0s/pki/admin.conf", fc.KubeconfigPath())
 	require.Equal(t, "/var/lib/k0s/pki/admin.conf", ubuntu.KubeconfigPath())
 }
+
+func TestSetK0sBinaryPath(t *testing.T) {
+	fc := &Flatcar{}
+	fc.PathFuncs = interface{}(fc).(configurer.PathFuncs)
+	fc.SetK0sBinaryPath("/opt/k0s/k0s-hardened")
+
+	ubuntu := &Ubuntu{}
+	ubuntu.PathFuncs = interface{}(ubuntu).(configurer.PathFuncs)
+	ubuntu.SetK0sBinaryPath("/opt/k0s/k0s-hardened")
+
+	require.Equal(t, "/opt/k0s/k0s-hardened", fc.K0sBinaryPath())
+	require.Equal(t, "/opt/k0s/k0s-hardened", ubuntu.K0sBinaryPath())
+
+	require.Equal(t, "/opt/k0s/k0s-hardened --help", fc.K0sCmdf("--help"))
+	require.Equal(t, "/opt/k0s/k0s-hardened --help", ubuntu.K0sCmdf("--help"))
+	require.Equal(t, `/opt/k0s/k0s-hardened kubectl --kubeconfig "/var/lib/k0s/pki/admin.conf" get nodes`, ubuntu.KubectlCmdf("get nodes"))
+}
@@ -100,13 +100,22 @@ func vCompare(a, b *version.Version) int {
 	return 1
 }
 
+// LatestK0sctlRelease returns the latest k0sctl release, the timeout limits the duration of the api request
+func LatestK0sctlRelease(timeout time.Duration) (Release, error) {
+	return latestRelease("k0sproject/k0sctl", false, timeout)
+}
+
 // LatestRelease returns the semantically sorted latest version from github releases page for a repo.
 // Set preok true to allow returning pre-release versions.  Assumes the repository has release tags with
 // semantic version numbers (optionally v-prefixed).
 func LatestRelease(repo string, preok bool) (Release, error) {
+	return latestRelease(repo, preok, timeOut)
+}
+
+func latestRelease(repo string, preok bool, timeout time.Duration) (Release, error) {
 	var gotV bool
 	var releases []Release
-	if err := unmarshalURLBody(fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=20&page=1", repo), &releases); err != nil {
+	if err := unmarshalURLBody(fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=20&page=1", repo), &releases, timeout); err != nil {
 		return Release{}, err
 	}
 
@@ -120,6 +129,9 @@ func LatestRelease(repo string, preok bool) (Release, error) {
 			versions = append(versions, version)
 		}
 	}
+	if len(versions) == 0 {
+		return Release{}, fmt.Errorf("no releases found for %s", repo)
+	}
 	vc := versionCollection(versions)
 	sort.Sort(vc)
 
@@ -137,9 +149,9 @@ func LatestRelease(repo string, preok bool) (Release, error) {
 	return Release{}, fmt.Errorf("failed to get the latest version information")
 }
 
-func unmarshalURLBody(url string, o interface{}) error {
+func unmarshalURLBody(url string, o interface{}, timeout time.Duration) error {
 	client := &http.Client{
-		Timeout: timeOut,
+		Timeout: timeout,
 	}
 
 	resp, err := client.Get(url)
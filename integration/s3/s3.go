@@ -0,0 +1,218 @@
+// Package s3 implements uploading and downloading files to Amazon S3 or a S3 compatible object storage
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	timeOut       = time.Minute * 30
+	defaultRegion = "us-east-1"
+)
+
+// uploadPartSize is the size of the parts of multipart uploads, files smaller than it are uploaded in a single request
+var uploadPartSize int64 = manager.DefaultUploadPartSize
+
+// Options for an upload or a download
+type Options struct {
+	// SSE is the server-side encryption algorithm, "AES256" or "aws:kms"
+	SSE string
+	// SSEKMSKeyID is the KMS key to use when SSE is "aws:kms", the default key is used when empty
+	SSEKMSKeyID string
+	// Region of the bucket, defaults to the region from the environment or the shared config
+	Region string
+	// Endpoint overrides the S3 endpoint, for example for a S3 compatible storage. Path style
+	// addressing is used when an endpoint is set.
+	Endpoint string
+}
+
+// Location is a parsed s3:// URL
+type Location struct {
+	Bucket string
+	Key    string
+}
+
+// IsS3URL returns true if the string looks like a s3:// URL
+func IsS3URL(s string) bool {
+	return strings.HasPrefix(s, "s3://")
+}
+
+// ParseURL parses a s3://bucket/prefix URL. When the path is empty or ends with a slash, the
+// given file name is appended to it to form the object key.
+func ParseURL(s, filename string) (*Location, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 url %q: %w", s, err)
+	}
+	if u.Scheme != "s3" {
+		return nil, fmt.Errorf("invalid s3 url %q: scheme must be s3://", s)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 url %q: bucket name missing", s)
+	}
+
+	key := strings.TrimPrefix(u.Path, "/")
+	if key == "" || strings.HasSuffix(key, "/") {
+		key += filename
+	}
+
+	return &Location{Bucket: u.Host, Key: key}, nil
+}
+
+// ValidateSSE checks that the server-side encryption algorithm is supported
+func ValidateSSE(sse string) error {
+	switch sse {
+	case "", "AES256", "aws:kms":
+		return nil
+	default:
+		return fmt.Errorf("unsupported s3 server-side encryption %q, must be AES256 or aws:kms", sse)
+	}
+}
+
+// IsNotFound returns true when the error is a "not found" response from S3
+func IsNotFound(err error) bool {
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
+}
+
+// Upload uploads a local file to the s3:// URL. Files larger than the part size are sent as a
+// multipart upload, which is aborted if any of the parts fails, so a failed upload does not leave a
+// partial object behind. Returns the s3:// URL of the uploaded object.
+func Upload(s3url, file string, opts Options) (string, error) {
+	loc, err := ParseURL(s3url, filepath.Base(file))
+	if err != nil {
+		return "", err
+	}
+
+	if err := ValidateSSE(opts.SSE); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(loc.Bucket),
+		Key:         aws.String(loc.Key),
+		Body:        f,
+		ContentType: aws.String("application/gzip"),
+	}
+	if opts.SSE != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(opts.SSE)
+		if opts.SSE == "aws:kms" && opts.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(opts.SSEKMSKeyID)
+		}
+	}
+
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) { u.PartSize = uploadPartSize })
+	if _, err := uploader.Upload(ctx, input); err != nil {
+		return "", fmt.Errorf("failed to upload to s3://%s/%s: %w", loc.Bucket, loc.Key, err)
+	}
+
+	return fmt.Sprintf("s3://%s/%s", loc.Bucket, loc.Key), nil
+}
+
+// Download downloads the object at the s3:// URL to a local file. The file is only created when the
+// download succeeds.
+func Download(s3url, file string, opts Options) error {
+	loc, err := ParseURL(s3url, "")
+	if err != nil {
+		return err
+	}
+	if loc.Key == "" || strings.HasSuffix(loc.Key, "/") {
+		return fmt.Errorf("invalid s3 url %q: object key missing", s3url)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+	defer cancel()
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	obj, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(loc.Bucket), Key: aws.String(loc.Key)})
+	if err != nil {
+		return fmt.Errorf("failed to download s3://%s/%s: %w", loc.Bucket, loc.Key, err)
+	}
+	defer obj.Body.Close()
+
+	tmp := file + ".partial"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if _, err := io.Copy(f, obj.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to download s3://%s/%s: %w", loc.Bucket, loc.Key, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+// client returns a s3 client configured from the options and the standard AWS credential chain
+func (o Options) client(ctx context.Context) (*s3.Client, error) {
+	var cfgOpts []func(*config.LoadOptions) error
+	if o.Region != "" {
+		cfgOpts = append(cfgOpts, config.WithRegion(o.Region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, cfgOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the aws configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = defaultRegion
+	}
+
+	endpoint := o.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL_S3")
+	}
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint != "" {
+		if u, err := url.Parse(endpoint); err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+		}
+	}
+
+	return s3.NewFromConfig(cfg, func(so *s3.Options) {
+		if endpoint != "" {
+			so.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+			so.UsePathStyle = true
+		}
+	}), nil
+}
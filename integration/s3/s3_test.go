@@ -0,0 +1,182 @@
+package s3
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	loc, err := ParseURL("s3://bucket/prefix/", "backup.tar.gz")
+	require.NoError(t, err)
+	require.Equal(t, "bucket", loc.Bucket)
+	require.Equal(t, "prefix/backup.tar.gz", loc.Key)
+
+	loc, err = ParseURL("s3://bucket", "backup.tar.gz")
+	require.NoError(t, err)
+	require.Equal(t, "backup.tar.gz", loc.Key)
+
+	loc, err = ParseURL("s3://bucket/backups/latest.tar.gz", "backup.tar.gz")
+	require.NoError(t, err)
+	require.Equal(t, "backups/latest.tar.gz", loc.Key)
+
+	_, err = ParseURL("https://bucket/prefix/", "backup.tar.gz")
+	require.Error(t, err)
+
+	_, err = ParseURL("s3:///prefix/", "backup.tar.gz")
+	require.Error(t, err)
+}
+
+// testCredentials makes the aws sdk read static credentials from the environment
+func testCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "token")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+}
+
+func TestUpload(t *testing.T) {
+	testCredentials(t)
+
+	var gotPath, gotBody string
+	var gotHeader http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotHeader = r.Header
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer server.Close()
+
+	fn := filepath.Join(t.TempDir(), "k0s_backup_1.tar.gz")
+	require.NoError(t, os.WriteFile(fn, []byte("backup data"), 0600))
+
+	opts := Options{
+		SSE:         "aws:kms",
+		SSEKMSKeyID: "my-key",
+		Region:      "eu-west-1",
+		Endpoint:    server.URL,
+	}
+	dest, err := Upload("s3://bucket/backups/", fn, opts)
+	require.NoError(t, err)
+	require.Equal(t, "s3://bucket/backups/k0s_backup_1.tar.gz", dest)
+	require.Equal(t, "/bucket/backups/k0s_backup_1.tar.gz", gotPath)
+	require.Equal(t, "backup data", gotBody)
+	require.Equal(t, "aws:kms", gotHeader.Get("X-Amz-Server-Side-Encryption"))
+	require.Equal(t, "my-key", gotHeader.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+	require.Equal(t, "token", gotHeader.Get("X-Amz-Security-Token"))
+	require.True(t, strings.HasPrefix(gotHeader.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/"))
+	require.Contains(t, gotHeader.Get("Authorization"), "/eu-west-1/s3/aws4_request")
+}
+
+func TestUploadMultipart(t *testing.T) {
+	testCredentials(t)
+	defer func(size int64) { uploadPartSize = size }(uploadPartSize)
+	uploadPartSize = 5 * 1024 * 1024
+
+	var mu sync.Mutex
+	parts := make(map[string]int)
+	var completed, aborted bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		q := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && q.Has("uploads"):
+			fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>k0s_backup_1.tar.gz</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == http.MethodPut && q.Get("uploadId") == "upload-1":
+			body, _ := io.ReadAll(r.Body)
+			parts[q.Get("partNumber")] = len(body)
+			w.Header().Set("ETag", fmt.Sprintf(`"etag-%s"`, q.Get("partNumber")))
+		case r.Method == http.MethodPost && q.Get("uploadId") == "upload-1":
+			completed = true
+			fmt.Fprint(w, `<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>k0s_backup_1.tar.gz</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`)
+		case r.Method == http.MethodDelete:
+			aborted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	fn := filepath.Join(t.TempDir(), "k0s_backup_1.tar.gz")
+	require.NoError(t, os.WriteFile(fn, make([]byte, uploadPartSize+1024), 0600))
+
+	_, err := Upload("s3://bucket/", fn, Options{Endpoint: server.URL})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"1": int(uploadPartSize), "2": 1024}, parts)
+	require.True(t, completed)
+	require.False(t, aborted)
+}
+
+func TestUploadError(t *testing.T) {
+	testCredentials(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>Access Denied</Message><RequestId>ABC123</RequestId></Error>`))
+	}))
+	defer server.Close()
+
+	fn := filepath.Join(t.TempDir(), "k0s_backup_1.tar.gz")
+	require.NoError(t, os.WriteFile(fn, []byte("backup data"), 0600))
+
+	_, err := Upload("s3://bucket/", fn, Options{Endpoint: server.URL})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to upload to s3://bucket/k0s_backup_1.tar.gz")
+	require.Contains(t, err.Error(), "AccessDenied")
+	require.False(t, IsNotFound(err))
+
+	_, err = Upload("s3://bucket/", fn, Options{SSE: "rot13"})
+	require.Error(t, err)
+}
+
+func TestDownload(t *testing.T) {
+	testCredentials(t)
+
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if strings.HasSuffix(r.URL.Path, "missing.tar.gz") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		_, _ = w.Write([]byte("backup data"))
+	}))
+	defer server.Close()
+
+	opts := Options{Endpoint: server.URL}
+	fn := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, Download("s3://bucket/backups/k0s_backup_1.tar.gz", fn, opts))
+	require.Equal(t, "/bucket/backups/k0s_backup_1.tar.gz", gotPath)
+	content, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.Equal(t, "backup data", string(content))
+
+	missing := filepath.Join(t.TempDir(), "missing.tar.gz")
+	err = Download("s3://bucket/missing.tar.gz", missing, opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to download s3://bucket/missing.tar.gz")
+	require.True(t, IsNotFound(err))
+	_, err = os.Stat(missing)
+	require.True(t, os.IsNotExist(err))
+
+	require.Error(t, Download("s3://bucket/prefix/", fn, opts))
+}
@@ -32,6 +32,7 @@ var ctx = &segment.Context{
 type Client struct {
 	client    segment.Client
 	machineID string
+	disabled  map[string]bool
 }
 
 // NewClient returns a new segment analytics client
@@ -50,8 +51,20 @@ func NewClient() (*Client, error) {
 	}, nil
 }
 
+// Disable stops the sending of the events of an analytics category, such as analytics.CategoryUsage
+func (c *Client) Disable(category string) {
+	if c.disabled == nil {
+		c.disabled = make(map[string]bool)
+	}
+	c.disabled[category] = true
+}
+
 // Publish enqueues the sending of a tracking event
 func (c Client) Publish(event string, props map[string]interface{}) error {
+	if c.disabled[analytics.Category(event)] {
+		log.Tracef("segment event %s not sent, %s telemetry is disabled", event, analytics.Category(event))
+		return nil
+	}
 	log.Tracef("segment event %s - properties: %+v", event, props)
 	return c.client.Enqueue(segment.Track{
 		Context:     ctx,
@@ -0,0 +1,15 @@
+package segment
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/analytics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishDisabledCategory(t *testing.T) {
+	// the segment client is not initialized, a disabled event must not reach it
+	c := &Client{}
+	c.Disable(analytics.CategoryUsage)
+	require.NoError(t, c.Publish("apply-start", map[string]interface{}{}))
+}
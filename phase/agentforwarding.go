@@ -0,0 +1,191 @@
+package phase
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/acarl005/stripansi"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/k0sproject/rig/exec"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// forwardingClient is a rig ssh connection that forwards the local ssh agent to the host. The agent
+// forwarding has to be requested for each session and the rig connection creates its sessions
+// internally, so the commands are run here instead.
+type forwardingClient struct {
+	*rig.SSH
+	host *cluster.Host
+	// connect makes the ssh connection, the rig connection or the authClient when ssh.authMethods are set
+	connect func() error
+
+	agentConn net.Conn
+}
+
+// Connect makes the ssh connection and starts serving the agent requests of the host from the local agent
+func (c *forwardingClient) Connect() error {
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		c.SSH.Disconnect()
+		return fmt.Errorf("ssh.agentForwarding is enabled but SSH_AUTH_SOCK is not set")
+	}
+	client := sshClient(c.SSH)
+	if client == nil {
+		c.SSH.Disconnect()
+		return fmt.Errorf("failed to forward the ssh agent to %s: ssh client not available", c.host)
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		c.SSH.Disconnect()
+		return fmt.Errorf("failed to connect to the ssh agent: %w", err)
+	}
+	if err := agent.ForwardToAgent(client, agent.NewClient(conn)); err != nil {
+		conn.Close()
+		c.SSH.Disconnect()
+		return fmt.Errorf("failed to forward the ssh agent to %s: %w", c.host, err)
+	}
+	c.agentConn = conn
+	log.WithField("host", c.host).Tracef("forwarding the ssh agent %s to the host", sock)
+
+	return nil
+}
+
+// Disconnect closes the ssh connection, which ends the forwarding, and the local agent connection
+func (c *forwardingClient) Disconnect() {
+	c.SSH.Disconnect()
+	if c.agentConn != nil {
+		c.agentConn.Close()
+		c.agentConn = nil
+	}
+}
+
+// Exec runs the command in a session that has the agent forwarding enabled. The output is handled
+// like in the rig connection.
+func (c *forwardingClient) Exec(cmd string, opts ...exec.Option) error {
+	client := sshClient(c.SSH)
+	if client == nil {
+		return fmt.Errorf("%s: not connected", c)
+	}
+	isWindows := c.SSH.IsWindows()
+
+	o := exec.Build(opts...)
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		return fmt.Errorf("failed to request ssh agent forwarding: %w", err)
+	}
+
+	cmd, err = o.Command(cmd)
+	if err != nil {
+		return err
+	}
+
+	if len(o.Stdin) == 0 && !isWindows {
+		// a pty is only requested without stdin data, otherwise the end of the input would need a ctrl-d
+		if err := session.RequestPty("xterm", 80, 40, ssh.TerminalModes{ssh.ECHO: 0}); err != nil {
+			return err
+		}
+	}
+
+	o.LogCmd(c.String(), cmd)
+
+	stdin, _ := session.StdinPipe()
+	stdout, _ := session.StdoutPipe()
+	stderr, _ := session.StderrPipe()
+
+	if err := session.Start(cmd); err != nil {
+		return err
+	}
+
+	if len(o.Stdin) > 0 {
+		o.LogStdin(c.String())
+		if _, err := io.WriteString(stdin, o.Stdin); err != nil {
+			return err
+		}
+	}
+	stdin.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if o.Writer != nil {
+			if _, err := io.Copy(o.Writer, stdout); err != nil {
+				o.LogErrorf("%s: failed to stream stdout: %s", c, err.Error())
+			}
+			return
+		}
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			o.AddOutput(c.String(), stripansi.Strip(scanner.Text())+"\n", "")
+		}
+		if err := scanner.Err(); err != nil {
+			o.LogErrorf("%s: %s", c, err.Error())
+		}
+	}()
+
+	var gotErrors bool
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			gotErrors = true
+			o.AddOutput(c.String(), "", scanner.Text()+"\n")
+		}
+		if err := scanner.Err(); err != nil {
+			gotErrors = true
+			o.LogErrorf("%s: %s", c, err.Error())
+		}
+	}()
+
+	err = session.Wait()
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	if isWindows && !o.AllowWinStderr && gotErrors {
+		return fmt.Errorf("command failed (received output to stderr on windows)")
+	}
+
+	return nil
+}
+
+// useAgentForwarding makes the host connection forward the local ssh agent when ssh.agentForwarding
+// is set. Like with useSSHAuth, the client is set to the unexported field of the rig connection.
+func useAgentForwarding(h *cluster.Host) error {
+	if h.SSH == nil || !h.SSHAgentForwarding {
+		return nil
+	}
+	h.SSH.SetDefaults()
+
+	c := &forwardingClient{SSH: h.SSH, host: h, connect: h.SSH.Connect}
+	if len(h.SSHAuth.Methods) > 0 {
+		c.connect = (&authClient{SSH: h.SSH, host: h}).Connect
+	}
+
+	f := reflect.ValueOf(&h.Connection).Elem().FieldByName("client")
+	v := reflect.ValueOf(c)
+	if !f.IsValid() || !v.Type().AssignableTo(f.Type()) {
+		return fmt.Errorf("ssh.agentForwarding is not supported by the ssh client")
+	}
+	reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(v)
+	return nil
+}
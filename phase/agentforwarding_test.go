@@ -0,0 +1,197 @@
+package phase
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/k0sproject/rig/exec"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// startTestAgent serves an ssh agent holding a key with the comment on a unix socket and returns the socket path
+func startTestAgent(t *testing.T, comment string) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: key, Comment: comment}))
+
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_ = agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	return sock
+}
+
+// startAgentSSHServer starts an ssh server that only accepts the password. The "ssh-add -l" command lists
+// the keys of the forwarded agent when the session requested the agent forwarding.
+func startAgentSSHServer(t *testing.T, password string) int {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if string(pass) == password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("wrong password")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	session := func(conn *ssh.ServerConn, ch ssh.Channel, reqs <-chan *ssh.Request) {
+		defer ch.Close()
+		var forwarded bool
+		for req := range reqs {
+			switch req.Type {
+			case "auth-agent-req@openssh.com":
+				forwarded = true
+				_ = req.Reply(true, nil)
+			case "pty-req":
+				_ = req.Reply(true, nil)
+			case "exec":
+				var payload struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &payload)
+				_ = req.Reply(true, nil)
+				status := 1
+				if payload.Command == "ssh-add -l" {
+					status = listForwardedKeys(conn, ch, forwarded)
+				}
+				_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+				return
+			default:
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newCh := range chans {
+					if newCh.ChannelType() != "session" {
+						_ = newCh.Reject(ssh.UnknownChannelType, "only sessions")
+						continue
+					}
+					ch, chReqs, err := newCh.Accept()
+					if err != nil {
+						continue
+					}
+					go session(sconn, ch, chReqs)
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// listForwardedKeys writes the comments of the keys in the forwarded agent to the session like ssh-add -l
+func listForwardedKeys(conn *ssh.ServerConn, ch ssh.Channel, forwarded bool) int {
+	if !forwarded {
+		fmt.Fprintln(ch.Stderr(), "Could not open a connection to your authentication agent.")
+		return 2
+	}
+	agentCh, reqs, err := conn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		fmt.Fprintln(ch.Stderr(), err.Error())
+		return 2
+	}
+	defer agentCh.Close()
+	go ssh.DiscardRequests(reqs)
+
+	keys, err := agent.NewClient(agentCh).List()
+	if err != nil {
+		fmt.Fprintln(ch.Stderr(), err.Error())
+		return 2
+	}
+	for _, k := range keys {
+		fmt.Fprintln(ch, k.Comment)
+	}
+	return 0
+}
+
+func TestForwardingClient(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", startTestAgent(t, "operator@example.com"))
+	port := startAgentSSHServer(t, "secret")
+
+	s := &rig.SSH{Address: "127.0.0.1", Port: port, User: "root"}
+	h := &cluster.Host{Connection: rig.Connection{SSH: s}, SSHAuth: cluster.SSHAuth{Methods: []string{cluster.SSHAuthPassword}, Password: "secret"}, SSHAgentForwarding: true}
+	c := &forwardingClient{SSH: s, host: h, connect: (&authClient{SSH: s, host: h}).Connect}
+	require.NoError(t, c.Connect())
+	require.NotNil(t, c.agentConn)
+
+	var out string
+	require.NoError(t, c.Exec("ssh-add -l", exec.Output(&out)))
+	require.Equal(t, "operator@example.com", strings.TrimSpace(out))
+
+	agentConn := c.agentConn
+	c.Disconnect()
+	require.Nil(t, c.agentConn)
+	_, err := agentConn.Write([]byte{0})
+	require.Error(t, err, "the local agent connection is closed on disconnect")
+	require.Error(t, c.Exec("ssh-add -l"))
+}
+
+func TestForwardingClientNoAgent(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	port := startAgentSSHServer(t, "secret")
+
+	s := &rig.SSH{Address: "127.0.0.1", Port: port, User: "root"}
+	h := &cluster.Host{Connection: rig.Connection{SSH: s}, SSHAuth: cluster.SSHAuth{Methods: []string{cluster.SSHAuthPassword}, Password: "secret"}, SSHAgentForwarding: true}
+	c := &forwardingClient{SSH: s, host: h, connect: (&authClient{SSH: s, host: h}).Connect}
+	err := c.Connect()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "SSH_AUTH_SOCK is not set")
+}
+
+func TestUseAgentForwarding(t *testing.T) {
+	h := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+	require.NoError(t, useAgentForwarding(h))
+	f := reflect.ValueOf(&h.Connection).Elem().FieldByName("client")
+	require.True(t, f.IsNil(), "the agent is not forwarded without ssh.agentForwarding")
+
+	h.SSHAgentForwarding = true
+	require.NoError(t, useAgentForwarding(h))
+	require.False(t, f.IsNil())
+	require.Equal(t, "[ssh] 10.0.0.1:22", h.String())
+}
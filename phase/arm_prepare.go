@@ -25,6 +25,11 @@ func (p *PrepareArm) Title() string {
 func (p *PrepareArm) Prepare(config *config.Cluster) error {
 	p.Config = config
 
+	// etcd is not run on the controllers when the cluster uses an external etcd
+	if p.Config.Spec.K0s.ExternalEtcd() != nil {
+		return nil
+	}
+
 	p.hosts = p.Config.Spec.Hosts.Filter(func(h *cluster.Host) bool {
 		arch := h.Metadata.Arch
 		return h.Role != "worker" && (strings.HasPrefix(arch, "arm") || strings.HasPrefix(arch, "aarch"))
@@ -40,11 +45,11 @@ func (p *PrepareArm) ShouldRun() bool {
 
 // Run the phase
 func (p *PrepareArm) Run() error {
-	return p.hosts.ParallelEach(p.etcdUnsupportedArch)
+	return p.parallelDo(p.hosts, p.etcdUnsupportedArch)
 }
 
 func (p *PrepareArm) etcdUnsupportedArch(h *cluster.Host) error {
-	log.Warnf("%s: enabling ETCD_UNSUPPORTED_ARCH=%s override - you may encounter problems with etcd", h, h.Metadata.Arch)
+	log.WithField("host", h).Warnf("enabling ETCD_UNSUPPORTED_ARCH=%s override - you may encounter problems with etcd", h.Metadata.Arch)
 	h.Environment["ETCD_UNSUPPORTED_ARCH"] = h.Metadata.Arch
 
 	return nil
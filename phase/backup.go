@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/k0sproject/k0sctl/config"
 	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/integration/s3"
 	"github.com/k0sproject/rig/exec"
 	log "github.com/sirupsen/logrus"
 )
@@ -18,6 +22,15 @@ var _ phase = &Backup{}
 type Backup struct {
 	GenericPhase
 
+	// UploadURL is a s3://bucket/prefix/ URL the backup archive is uploaded to
+	UploadURL string
+	// S3Options are the options for the s3 upload
+	S3Options s3.Options
+	// KeepLocal keeps the local archive after a successful upload
+	KeepLocal bool
+	// FileName is the template for the name of the backup archive, see BackupFileName
+	FileName string
+
 	leader *cluster.Host
 }
 
@@ -26,6 +39,11 @@ func (p *Backup) Title() string {
 	return "Take backup"
 }
 
+// Sequential is true, the backup is taken on the leader
+func (p *Backup) Sequential() bool {
+	return true
+}
+
 // Prepare the phase
 func (p *Backup) Prepare(config *config.Cluster) error {
 	p.Config = config
@@ -52,7 +70,10 @@ func (p *Backup) Run() error {
 	h := p.leader
 	h.Metadata.IsK0sLeader = true
 
-	log.Infof("%s: backing up", h)
+	log.WithField("host", h).Info("backing up")
+	if p.Config.Spec.K0s.ExternalEtcd() != nil {
+		log.WithField("host", h).Warn("the cluster uses an external etcd, the backup does not include the etcd data, back up the etcd cluster separately")
+	}
 	backupDir, err := h.Configurer.TempDir(h)
 	if err != nil {
 		return err
@@ -68,13 +89,28 @@ func (p *Backup) Run() error {
 		return err
 	}
 
-	localFile, err := filepath.Abs(fmt.Sprintf("k0s_backup_%d.tar.gz", time.Now().Unix()))
+	template := p.FileName
+	if template == "" {
+		template = DefaultBackupFileName
+	}
+	var clusterName string
+	if p.Config.Metadata != nil {
+		clusterName = p.Config.Metadata.Name
+	}
+	localFile := BackupFileName(template, clusterName, time.Now())
+	if p.UploadURL != "" && !p.KeepLocal {
+		localFile = filepath.Join(os.TempDir(), filepath.Base(localFile))
+	}
+	localFile, err = filepath.Abs(localFile)
 	if err != nil {
 		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(localFile), 0700); err != nil {
+		return err
+	}
 
 	// Download the file
-	f, err := os.OpenFile(localFile, os.O_RDWR|os.O_CREATE|os.O_SYNC, 0600)
+	f, err := os.OpenFile(localFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_SYNC, 0600)
 	if err != nil {
 		return err
 	}
@@ -85,6 +121,95 @@ func (p *Backup) Run() error {
 		return err
 	}
 
-	log.Infof("backup file written to %s", localFile)
+	if err := writeBackupVersion(localFile, h.Metadata.K0sRunningVersion); err != nil {
+		return err
+	}
+
+	if p.UploadURL == "" {
+		log.Infof("backup file written to %s", localFile)
+		return nil
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return p.upload(localFile)
+}
+
+func (p *Backup) upload(localFile string) error {
+	log.Infof("uploading backup to %s", p.UploadURL)
+	dest, err := s3.Upload(p.UploadURL, localFile, p.S3Options)
+	if err != nil {
+		log.Errorf("backup upload failed, the backup file is kept at %s", localFile)
+		return err
+	}
+	log.Infof("backup uploaded to %s", dest)
+
+	if _, err := s3.Upload(dest+backupVersionSuffix, localFile+backupVersionSuffix, p.S3Options); err != nil {
+		log.Warnf("failed to upload the k0s version file of the backup: %s", err.Error())
+	}
+
+	if p.KeepLocal {
+		log.Infof("backup file written to %s", localFile)
+		return nil
+	}
+
+	for _, fn := range []string{localFile, localFile + backupVersionSuffix} {
+		if err := os.Remove(fn); err != nil {
+			log.Warnf("failed to remove the local backup file %s: %s", fn, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// DefaultBackupFileName is the template for the name of the backup archive when none is given
+const DefaultBackupFileName = "k0s_backup_{unix}.tar.gz"
+
+// backupFileTimestamp is the format of the {timestamp} placeholder, it does not use colons to keep
+// the name valid on windows
+const backupFileTimestamp = "20060102T150405Z"
+
+var backupFilePlaceholderRe = regexp.MustCompile(`\{[^{}]*\}`)
+
+// BackupFileName returns the name of the backup archive from the template. The placeholders {cluster},
+// {timestamp} (UTC, such as 20220314T153000Z), {date} (UTC, such as 2022-03-14) and {unix} (seconds
+// since the epoch) are replaced.
+func BackupFileName(template, clusterName string, t time.Time) string {
+	t = t.UTC()
+	return strings.NewReplacer(
+		"{cluster}", clusterName,
+		"{timestamp}", t.Format(backupFileTimestamp),
+		"{date}", t.Format("2006-01-02"),
+		"{unix}", strconv.FormatInt(t.Unix(), 10),
+	).Replace(template)
+}
+
+// ValidateBackupFileName makes sure the backup file name template does not use unknown placeholders
+func ValidateBackupFileName(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("the backup file name can not be empty")
+	}
+	for _, ph := range backupFilePlaceholderRe.FindAllString(template, -1) {
+		switch ph {
+		case "{cluster}", "{timestamp}", "{date}", "{unix}":
+		default:
+			return fmt.Errorf("unknown placeholder %s in the backup file name %q, supported placeholders are {cluster}, {timestamp}, {date} and {unix}", ph, template)
+		}
+	}
+	if strings.HasSuffix(template, "/") || strings.HasSuffix(template, string(filepath.Separator)) {
+		return fmt.Errorf("the backup file name %q must not be a directory", template)
+	}
 	return nil
 }
+
+// backupVersionSuffix is appended to the name of a backup archive to get the name of the file that
+// records the k0s version the backup was taken with
+const backupVersionSuffix = ".version"
+
+// writeBackupVersion writes the k0s version into a file next to the backup archive. The archive itself
+// is kept as produced by k0s.
+func writeBackupVersion(archive, version string) error {
+	return os.WriteFile(archive+backupVersionSuffix, []byte(version+"\n"), 0600)
+}
@@ -0,0 +1,29 @@
+package phase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupFileName(t *testing.T) {
+	ts := time.Date(2022, 3, 14, 17, 30, 5, 0, time.FixedZone("EET", 2*60*60))
+
+	require.Equal(t, "k0s_backup_1647271805.tar.gz", BackupFileName(DefaultBackupFileName, "prod", ts))
+	require.Equal(t, "backup-prod-20220314T153005Z.tar.gz", BackupFileName("backup-{cluster}-{timestamp}.tar.gz", "prod", ts))
+	require.Equal(t, "backups/2022-03-14/prod.tar.gz", BackupFileName("backups/{date}/{cluster}.tar.gz", "prod", ts))
+	require.NotContains(t, BackupFileName("{timestamp}", "prod", ts), ":")
+}
+
+func TestValidateBackupFileName(t *testing.T) {
+	require.NoError(t, ValidateBackupFileName(DefaultBackupFileName))
+	require.NoError(t, ValidateBackupFileName("backup-{cluster}-{timestamp}-{date}.tar.gz"))
+
+	err := ValidateBackupFileName("backup-{time}.tar.gz")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown placeholder {time}")
+
+	require.Error(t, ValidateBackupFileName(""))
+	require.Error(t, ValidateBackupFileName("backups/"))
+}
@@ -0,0 +1,102 @@
+package phase
+
+import (
+	"strings"
+	"time"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// HostAuthFailed is the status of a host that was reached but the authentication failed
+	HostAuthFailed = "auth-failed"
+	// HostCommandFailed is the status of a host that was connected to but running a command on it failed
+	HostCommandFailed = "command-failed"
+)
+
+// HostConnection is the result of a connection check of a host
+type HostConnection struct {
+	Address  string  `json:"address"`
+	Protocol string  `json:"protocol"`
+	Role     string  `json:"role"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// OK returns true when the host could be connected to and a command could be run on it
+func (c *HostConnection) OK() bool {
+	return c.Status == HostReachable
+}
+
+// CheckConnections opens a connection to each of the hosts and runs a trivial command on it. Unlike
+// the Connect phase it does not fail when a host can't be reached, the problem is recorded in the
+// result of the host instead.
+type CheckConnections struct {
+	GenericPhase
+
+	// Hosts are the results of the hosts in the order of the configuration
+	Hosts []*HostConnection
+}
+
+// Title for the phase
+func (p *CheckConnections) Title() string {
+	return "Check connections"
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *CheckConnections) ReadOnly() bool {
+	return true
+}
+
+// Run the phase
+func (p *CheckConnections) Run() error {
+	results := make(map[*cluster.Host]*HostConnection, len(p.Config.Spec.Hosts))
+	p.Hosts = make([]*HostConnection, 0, len(p.Config.Spec.Hosts))
+	for _, h := range p.Config.Spec.Hosts {
+		c := &HostConnection{Address: h.Address(), Protocol: h.Protocol(), Role: h.Role}
+		results[h] = c
+		p.Hosts = append(p.Hosts, c)
+	}
+
+	return p.parallelDo(p.Config.Spec.Hosts, func(h *cluster.Host) error {
+		c := results[h]
+		start := time.Now()
+		defer func() { c.Duration = time.Since(start).Seconds() }()
+
+		if err := connectHost(h); err != nil {
+			log.WithField("host", h).Warnf("failed to connect: %s", err.Error())
+			c.Status = HostUnreachable
+			if isAuthError(err) {
+				c.Status = HostAuthFailed
+			}
+			c.Error = err.Error()
+			return nil
+		}
+
+		if err := h.Exec("echo k0sctl"); err != nil {
+			log.WithField("host", h).Warnf("failed to run a command: %s", err.Error())
+			c.Status = HostCommandFailed
+			c.Error = err.Error()
+			return nil
+		}
+
+		log.WithField("host", h).Info("connected")
+		c.Status = HostReachable
+		return nil
+	})
+}
+
+// isAuthError returns true for connection errors that are caused by failed authentication
+// rather than the host being unreachable
+func isAuthError(err error) bool {
+	// rig does not wrap the underlying errors
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"unable to authenticate", "permission denied", "no supported methods remain", "401", "unauthorized", "incorrect passphrase"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package phase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAuthError(t *testing.T) {
+	require.True(t, isAuthError(fmt.Errorf("client connect: ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain")))
+	require.True(t, isAuthError(fmt.Errorf("http response error: 401 - invalid content type")))
+	require.False(t, isAuthError(fmt.Errorf("client connect: dial tcp 10.0.0.1:22: connect: connection refused")))
+	require.False(t, isAuthError(fmt.Errorf("client connect: dial tcp 10.0.0.1:22: i/o timeout")))
+}
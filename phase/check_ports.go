@@ -0,0 +1,219 @@
+package phase
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	retry "github.com/avast/retry-go"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	log "github.com/sirupsen/logrus"
+)
+
+// portCheckTimeout is the time a port check may take when the host has no connect timeout
+const portCheckTimeout = 30 * time.Second
+
+// CheckConnectionPorts makes sure the ssh or WinRM port of each host can be connected to before logging
+// in. When the host is reached through a bastion, the port of the bastion is checked instead. The hosts
+// reached through the --ssh-proxy and the localhost are not checked.
+type CheckConnectionPorts struct {
+	GenericPhase
+
+	// Retries is the number of times a failed check is retried, like the connection attempts
+	Retries uint
+	// RetryInterval is the delay before the first retry, the delay is doubled for each following retry
+	RetryInterval time.Duration
+}
+
+// Title for the phase
+func (p *CheckConnectionPorts) Title() string {
+	return "Check connection ports"
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *CheckConnectionPorts) ReadOnly() bool {
+	return true
+}
+
+// connectionPort returns the address and the port of the first hop of the host connection and the
+// protocol used on it. The address is empty when the port can't be checked from here.
+func connectionPort(h *cluster.Host) (string, int, string) {
+	var bastion *rig.SSH
+	switch {
+	case h.SSH != nil:
+		if SSHProxy != "" {
+			return "", 0, ""
+		}
+		if h.SSH.Bastion == nil {
+			return h.SSH.Address, h.SSH.Port, "ssh"
+		}
+		bastion = h.SSH.Bastion
+	case h.WinRM != nil:
+		if h.WinRM.Bastion == nil {
+			return h.WinRM.Address, h.WinRM.Port, "winrm"
+		}
+		bastion = h.WinRM.Bastion
+	default:
+		return "", 0, ""
+	}
+	return bastion.Address, bastion.Port, "ssh bastion"
+}
+
+// Run the phase
+func (p *CheckConnectionPorts) Run() error {
+	interval := p.RetryInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	return p.parallelDo(p.Config.Spec.ConnectHosts(), func(h *cluster.Host) error {
+		address, port, proto := connectionPort(h)
+		if address == "" {
+			return nil
+		}
+		timeout := h.ConnectTimeoutOr(ConnectTimeout)
+		if timeout <= 0 {
+			timeout = portCheckTimeout
+		}
+
+		err := retry.Do(
+			func() error {
+				return dialPort(address, port, timeout)
+			},
+			retry.OnRetry(
+				func(n uint, err error) {
+					log.WithField("host", h).Debugf("port check %d failed, %d retries remaining: %s", n+1, p.Retries-n, err.Error())
+				},
+			),
+			retry.RetryIf(isRetryableConnectError),
+			retry.DelayType(retry.BackOffDelay),
+			retry.Delay(interval),
+			retry.Attempts(p.Retries+1),
+			retry.LastErrorOnly(true),
+			retry.Context(p.Context()),
+		)
+		if err != nil {
+			return fmt.Errorf("%s port %d on %s %s is unreachable - check the firewall and the address and port of the host: %w", proto, port, h.Role, address, err)
+		}
+		log.WithField("host", h).Debugf("%s port %d is reachable", proto, port)
+		return nil
+	})
+}
+
+func dialPort(address string, port int, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", cluster.JoinHostPort(address, port), timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// portCheck is a port a joining host needs to reach on the controllers
+type portCheck struct {
+	address string
+	port    int
+	service string
+}
+
+// CheckK0sPorts makes sure the hosts that are going to join the cluster can reach the kubernetes api, the
+// k0s api and the konnectivity ports through the address they join to. Nothing is listening on the ports
+// before a controller is running k0s, so the check is only made when adding hosts to a running cluster.
+type CheckK0sPorts struct {
+	GenericPhase
+
+	leader *cluster.Host
+	hosts  cluster.Hosts
+}
+
+// Title for the phase
+func (p *CheckK0sPorts) Title() string {
+	return "Check k0s ports"
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *CheckK0sPorts) ReadOnly() bool {
+	return true
+}
+
+// Prepare the phase
+func (p *CheckK0sPorts) Prepare(config *config.Cluster) error {
+	p.Config = config
+	p.leader = config.Spec.K0sLeader()
+	if p.leader.Metadata.K0sRunningVersion == "" {
+		return nil
+	}
+	p.hosts = config.Spec.Hosts.Filter(func(h *cluster.Host) bool {
+		return h != p.leader && h.Metadata.K0sRunningVersion == ""
+	})
+	return nil
+}
+
+// ShouldRun is true when there are hosts joining a running cluster
+func (p *CheckK0sPorts) ShouldRun() bool {
+	return len(p.hosts) > 0
+}
+
+// portChecks returns the ports the host connects to when joining the cluster
+func (p *CheckK0sPorts) portChecks(h *cluster.Host) []portCheck {
+	address := p.Config.Spec.K0s.Config.DigString("spec", "api", "externalAddress")
+	if address == "" {
+		address = p.leader.PrivateAddress
+		if address == "" {
+			address = p.leader.Address()
+		}
+	}
+	port := func(def int, path ...string) int {
+		if p, ok := p.Config.Spec.K0s.Config.Dig(path...).(int); ok {
+			return p
+		}
+		return def
+	}
+
+	api := portCheck{address: address, port: port(6443, "spec", "api", "port"), service: "kubernetes api"}
+	if h.IsController() {
+		return []portCheck{api, {address: address, port: port(9443, "spec", "api", "k0sApiPort"), service: "k0s api"}}
+	}
+	// the workers join through the spec.k0s.apiServerAddress load balancer when there is one
+	if a, p, ok := urlHostPort(p.Config.Spec.K0s.APIServerURL()); ok {
+		api.address, api.port = a, p
+	}
+	return []portCheck{api, {address: address, port: port(8132, "spec", "konnectivity", "agentPort"), service: "konnectivity"}}
+}
+
+// urlHostPort returns the host and the port of an http or https url, the port defaults to the one of
+// the scheme
+func urlHostPort(s string) (string, int, bool) {
+	u, err := url.Parse(s)
+	if err != nil || u.Hostname() == "" {
+		return "", 0, false
+	}
+	if u.Port() == "" {
+		if u.Scheme == "http" {
+			return u.Hostname(), 80, true
+		}
+		return u.Hostname(), 443, true
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return "", 0, false
+	}
+	return u.Hostname(), port, true
+}
+
+// Run the phase
+func (p *CheckK0sPorts) Run() error {
+	return p.parallelDo(p.hosts, func(h *cluster.Host) error {
+		for _, c := range p.portChecks(h) {
+			log.WithField("host", h).Debugf("checking %s port %d on %s", c.service, c.port, c.address)
+			if err := h.Configurer.TCPConnect(h, c.address, c.port); err != nil {
+				return fmt.Errorf("%s port %d on %s is unreachable from %s %s - check the firewall: %w", c.service, c.port, c.address, h.Role, h.Address(), err)
+			}
+		}
+		log.WithField("host", h).Info("k0s ports are reachable")
+		return nil
+	})
+}
@@ -0,0 +1,118 @@
+package phase
+
+import (
+	"net"
+	"testing"
+
+	"github.com/k0sproject/dig"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPort(t *testing.T) {
+	h := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 2222}}}
+	address, port, proto := connectionPort(h)
+	require.Equal(t, "10.0.0.1", address)
+	require.Equal(t, 2222, port)
+	require.Equal(t, "ssh", proto)
+
+	h.SSH.Bastion = &rig.SSH{Address: "10.0.0.10", Port: 22}
+	address, port, proto = connectionPort(h)
+	require.Equal(t, "10.0.0.10", address)
+	require.Equal(t, 22, port)
+	require.Equal(t, "ssh bastion", proto)
+
+	h = &cluster.Host{Connection: rig.Connection{WinRM: &rig.WinRM{Address: "10.0.0.2", Port: 5985}}}
+	address, port, proto = connectionPort(h)
+	require.Equal(t, "10.0.0.2", address)
+	require.Equal(t, 5985, port)
+	require.Equal(t, "winrm", proto)
+
+	h = &cluster.Host{Connection: rig.Connection{Localhost: &rig.Localhost{Enabled: true}}}
+	address, _, _ = connectionPort(h)
+	require.Empty(t, address)
+
+	defer func() { SSHProxy = "" }()
+	SSHProxy = "socks5://proxy.example.com:1080"
+	h = &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+	address, _, _ = connectionPort(h)
+	require.Empty(t, address)
+}
+
+func TestCheckConnectionPorts(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	open := l.Addr().(*net.TCPAddr).Port
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	p := &CheckConnectionPorts{}
+	p.Config = &config.Cluster{Spec: &cluster.Spec{Hosts: cluster.Hosts{
+		&cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "127.0.0.1", Port: open}}},
+	}}}
+	require.NoError(t, p.Run())
+
+	p.Config.Spec.Hosts = append(p.Config.Spec.Hosts, &cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "127.0.0.1", Port: closedPort}}})
+	err = p.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "port")
+	require.Contains(t, err.Error(), "on worker 127.0.0.1 is unreachable - check the firewall")
+}
+
+func TestCheckK0sPortsChecks(t *testing.T) {
+	leader := &cluster.Host{Role: "controller", PrivateAddress: "10.0.0.1", Metadata: cluster.HostMetadata{K0sBinaryVersion: "1.21.2+k0s.0", K0sRunningVersion: "1.21.2+k0s.0"}}
+	controller := &cluster.Host{Role: "controller", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2"}}}
+	worker := &cluster.Host{Role: "worker", Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.3"}}}
+	joined := &cluster.Host{Role: "worker", Metadata: cluster.HostMetadata{K0sRunningVersion: "1.21.2+k0s.0"}}
+
+	p := &CheckK0sPorts{}
+	require.NoError(t, p.Prepare(&config.Cluster{Spec: &cluster.Spec{Hosts: cluster.Hosts{leader, controller, worker, joined}}}))
+	require.True(t, p.ShouldRun())
+	require.Equal(t, cluster.Hosts{controller, worker}, p.hosts)
+
+	require.Equal(t, []portCheck{
+		{address: "10.0.0.1", port: 6443, service: "kubernetes api"},
+		{address: "10.0.0.1", port: 9443, service: "k0s api"},
+	}, p.portChecks(controller))
+	require.Equal(t, []portCheck{
+		{address: "10.0.0.1", port: 6443, service: "kubernetes api"},
+		{address: "10.0.0.1", port: 8132, service: "konnectivity"},
+	}, p.portChecks(worker))
+
+	p.Config.Spec.K0s.Config = dig.Mapping{"spec": dig.Mapping{
+		"api":          dig.Mapping{"externalAddress": "lb.example.com", "port": 7443},
+		"konnectivity": dig.Mapping{"agentPort": 8133},
+	}}
+	require.Equal(t, []portCheck{
+		{address: "lb.example.com", port: 7443, service: "kubernetes api"},
+		{address: "lb.example.com", port: 8133, service: "konnectivity"},
+	}, p.portChecks(worker))
+
+	p.Config.Spec.K0s.APIServerAddress = "10.0.0.100"
+	require.Equal(t, []portCheck{
+		{address: "10.0.0.100", port: 7443, service: "kubernetes api"},
+		{address: "lb.example.com", port: 8133, service: "konnectivity"},
+	}, p.portChecks(worker))
+	require.Equal(t, []portCheck{
+		{address: "lb.example.com", port: 7443, service: "kubernetes api"},
+		{address: "lb.example.com", port: 9443, service: "k0s api"},
+	}, p.portChecks(controller))
+
+	p.Config.Spec.K0s.APIServerAddress = "https://[2001:db8::100]"
+	require.Equal(t, portCheck{address: "2001:db8::100", port: 443, service: "kubernetes api"}, p.portChecks(worker)[0])
+}
+
+func TestCheckK0sPortsNewCluster(t *testing.T) {
+	p := &CheckK0sPorts{}
+	require.NoError(t, p.Prepare(&config.Cluster{Spec: &cluster.Spec{Hosts: cluster.Hosts{
+		&cluster.Host{Role: "controller"},
+		&cluster.Host{Role: "worker"},
+	}}}))
+	require.False(t, p.ShouldRun())
+}
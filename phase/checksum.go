@@ -0,0 +1,95 @@
+package phase
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	log "github.com/sirupsen/logrus"
+)
+
+// K0sSHA256 is the expected sha256 checksum of the k0s binary, it takes precedence over the checksums in spec.k0s.sha256
+var K0sSHA256 string
+
+// FetchChecksum is used to fetch the .sha256 file published next to the k0s release binary when no checksum is configured
+var FetchChecksum bool
+
+// expectedChecksum returns the expected sha256 checksum of the k0s binary for the host or an empty string when
+// there is nothing to verify against
+func expectedChecksum(c *config.Cluster, h *cluster.Host) (string, error) {
+	if K0sSHA256 != "" {
+		return K0sSHA256, nil
+	}
+
+	version := c.Spec.K0sVersionFor(h)
+	if sum := c.Spec.K0s.SHA256For(version, h.Metadata.Arch); sum != "" {
+		return sum, nil
+	}
+
+	// a custom binary can't be verified against the official checksum
+	if !FetchChecksum || h.K0sBinaryPath != "" {
+		return "", nil
+	}
+
+	bin := binary{arch: h.Metadata.Arch, os: h.Configurer.Kind(), version: version, urlBase: c.Spec.K0s.DownloadURLBase}
+	return fetchChecksum(bin.url() + ".sha256")
+}
+
+func fetchChecksum(url string) (string, error) {
+	log.Debugf("fetching checksum from %s", url)
+	resp, err := downloadClient(30 * time.Second).Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch checksum from %s (http %d)", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+
+	// the file is in the sha256sum output format "<checksum>  <filename>"
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file %s is empty", url)
+	}
+
+	return fields[0], nil
+}
+
+// verifyK0sBinary compares the checksum of the k0s binary on the host to the expected one. The binary is
+// removed from the host when the checksum does not match.
+func verifyK0sBinary(c *config.Cluster, h *cluster.Host) error {
+	expected, err := expectedChecksum(c, h)
+	if err != nil {
+		return err
+	}
+	if expected == "" {
+		log.WithField("host", h).Debug("no checksum available for the k0s binary, skipping verification")
+		return nil
+	}
+
+	path := h.Configurer.K0sBinaryPath()
+	actual, err := h.Configurer.Sha256sum(h, path)
+	if err != nil {
+		return fmt.Errorf("failed to calculate the checksum of %s: %w", path, err)
+	}
+
+	if !strings.EqualFold(actual, expected) {
+		if err := h.Configurer.DeleteFile(h, path); err != nil {
+			log.WithField("host", h).Warnf("failed to remove %s: %s", path, err.Error())
+		}
+		return fmt.Errorf("k0s binary checksum mismatch: expected sha256 %s, got %s", expected, actual)
+	}
+
+	log.WithField("host", h).Info("verified k0s binary checksum")
+	return nil
+}
@@ -0,0 +1,29 @@
+package phase
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/k0s-v1.21.2+k0s.0-amd64.sha256" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, "f00dfeed  k0s-v1.21.2+k0s.0-amd64")
+	}))
+	defer server.Close()
+
+	sum, err := fetchChecksum(server.URL + "/k0s-v1.21.2+k0s.0-amd64.sha256")
+	require.NoError(t, err)
+	require.Equal(t, "f00dfeed", sum)
+
+	_, err = fetchChecksum(server.URL + "/k0s-v1.21.2+k0s.0-arm64.sha256")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "http 404")
+}
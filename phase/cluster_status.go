@@ -0,0 +1,171 @@
+package phase
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig/exec"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// K0sRunning is the k0s service state of a host where k0s is running
+	K0sRunning = "running"
+	// K0sStopped is the k0s service state of a host where k0s is not running
+	K0sStopped = "stopped"
+	// K0sUnknown is the k0s service state of a host that could not be inspected
+	K0sUnknown = "unknown"
+)
+
+// NodeStatus is the health of a node
+type NodeStatus struct {
+	Address  string `json:"address,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Role     string `json:"role"`
+	// K0s is the state of the k0s service: running, stopped, unreachable or unknown
+	K0s string `json:"k0s,omitempty"`
+	// Ready is the status of the kubernetes Ready condition of the node, empty for controllers
+	// that do not run workloads
+	Ready   string `json:"ready,omitempty"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ClusterStatus checks the state of the k0s service on the hosts and the readiness of the kubernetes
+// nodes using kubectl on a controller. Hosts that can't be reached are reported as unhealthy instead of
+// failing the phase.
+type ClusterStatus struct {
+	GenericPhase
+
+	// Nodes are the statuses of the hosts in the order of the configuration followed by the nodes in
+	// the cluster that are not in the configuration
+	Nodes []*NodeStatus
+}
+
+// Title for the phase
+func (p *ClusterStatus) Title() string {
+	return "Check cluster status"
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *ClusterStatus) ReadOnly() bool {
+	return true
+}
+
+// Run the phase
+func (p *ClusterStatus) Run() error {
+	statuses := make(map[*cluster.Host]*NodeStatus, len(p.Config.Spec.Hosts))
+	for _, h := range p.Config.Spec.Hosts {
+		statuses[h] = &NodeStatus{Address: h.Address(), Role: h.Role, K0s: K0sUnknown}
+	}
+
+	if err := p.parallelDo(p.Config.Spec.Hosts, func(h *cluster.Host) error {
+		s := statuses[h]
+		if err := connectHost(h); err != nil {
+			log.WithField("host", h).Warnf("failed to connect: %s", err.Error())
+			s.K0s = HostUnreachable
+			s.Error = err.Error()
+			return nil
+		}
+		if err := p.k0sState(h, s); err != nil {
+			log.WithField("host", h).Warnf("failed to check k0s status: %s", err.Error())
+			s.Error = err.Error()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	p.Nodes = nodeStatuses(p.Config.Spec.Hosts, statuses, p.nodeConditions(statuses))
+
+	return nil
+}
+
+// nodeStatuses combines the k0s states of the hosts with the Ready conditions of the nodes by node name
+func nodeStatuses(hosts cluster.Hosts, statuses map[*cluster.Host]*NodeStatus, conditions map[string]string) []*NodeStatus {
+	remaining := make(map[string]string, len(conditions))
+	for name, ready := range conditions {
+		remaining[name] = ready
+	}
+
+	nodes := make([]*NodeStatus, 0, len(hosts)+len(conditions))
+	for _, h := range hosts {
+		s := statuses[h]
+		if strings.HasSuffix(h.Role, "worker") {
+			// the node names are the lowercase hostnames
+			name := strings.ToLower(s.Hostname)
+			if ready, ok := remaining[name]; ok {
+				s.Ready = ready
+				delete(remaining, name)
+			} else {
+				s.Ready = "Unknown"
+			}
+		}
+		s.Healthy = s.K0s == K0sRunning && (s.Ready == "" || s.Ready == "True")
+		nodes = append(nodes, s)
+	}
+
+	// nodes that are in the cluster but not in the configuration
+	names := make([]string, 0, len(remaining))
+	for name := range remaining {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		nodes = append(nodes, &NodeStatus{Hostname: name, Role: "worker", Ready: remaining[name], Healthy: remaining[name] == "True"})
+	}
+
+	return nodes
+}
+
+func (p *ClusterStatus) k0sState(h *cluster.Host, s *NodeStatus) error {
+	if h.OSIDOverride != "" {
+		h.OSVersion.ID = h.OSIDOverride
+	}
+	if err := h.ResolveConfigurer(); err != nil {
+		return err
+	}
+
+	if h.HostnameOverride != "" {
+		s.Hostname = h.HostnameOverride
+	} else {
+		s.Hostname = h.Configurer.Hostname(h)
+	}
+	h.Metadata.Hostname = s.Hostname
+
+	s.K0s = K0sStopped
+	output, err := h.ExecOutput(h.K0sStatusCommand(), exec.Sudo(h))
+	if err != nil {
+		log.WithField("host", h).Debugf("k0s status failed: %s", err.Error())
+		return nil
+	}
+	status := k0sstatus{}
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		s.K0s = K0sUnknown
+		return err
+	}
+	if status.Pid != 0 {
+		s.K0s = K0sRunning
+		h.Metadata.K0sRunningVersion = strings.TrimPrefix(status.Version, "v")
+	}
+	return nil
+}
+
+// nodeConditions returns the Ready conditions of the nodes from the first controller that is running k0s
+func (p *ClusterStatus) nodeConditions(statuses map[*cluster.Host]*NodeStatus) map[string]string {
+	for _, h := range p.Config.Spec.Hosts.Controllers() {
+		if statuses[h].K0s != K0sRunning {
+			continue
+		}
+		conditions, err := h.KubeNodeConditions()
+		if err != nil {
+			log.WithField("host", h).Warnf("failed to get the kubernetes node status: %s", err.Error())
+			continue
+		}
+		return conditions
+	}
+	log.Warn("no controller available for checking the kubernetes node status")
+	return map[string]string{}
+}
@@ -0,0 +1,36 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeStatuses(t *testing.T) {
+	ctrl := &cluster.Host{Role: "controller"}
+	worker := &cluster.Host{Role: "worker"}
+	down := &cluster.Host{Role: "worker"}
+	statuses := map[*cluster.Host]*NodeStatus{
+		ctrl:   {Hostname: "ctrl", Role: "controller", K0s: K0sRunning},
+		worker: {Hostname: "Worker-1", Role: "worker", K0s: K0sRunning},
+		down:   {Role: "worker", K0s: HostUnreachable},
+	}
+	conditions := map[string]string{"worker-1": "True", "worker-2": "False"}
+
+	nodes := nodeStatuses(cluster.Hosts{ctrl, worker, down}, statuses, conditions)
+	require.Len(t, nodes, 4)
+
+	require.Equal(t, "", nodes[0].Ready)
+	require.True(t, nodes[0].Healthy)
+
+	require.Equal(t, "True", nodes[1].Ready)
+	require.True(t, nodes[1].Healthy)
+
+	require.Equal(t, "Unknown", nodes[2].Ready)
+	require.False(t, nodes[2].Healthy)
+
+	require.Equal(t, "worker-2", nodes[3].Hostname)
+	require.Equal(t, "False", nodes[3].Ready)
+	require.False(t, nodes[3].Healthy)
+}
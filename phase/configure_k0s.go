@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -23,30 +24,81 @@ func (p *ConfigureK0s) Title() string {
 	return "Configure k0s"
 }
 
+// Uninterruptible is true, the k0s configuration files are not left half written when the run is interrupted
+func (p *ConfigureK0s) Uninterruptible() bool {
+	return true
+}
+
 // Run the phase
 func (p *ConfigureK0s) Run() error {
 	if len(p.Config.Spec.K0s.Config) == 0 {
 		p.SetProp("default-config", true)
-		leader := p.Config.Spec.K0sLeader()
-		log.Warnf("%s: generating default configuration", leader)
-		cfg, err := leader.ExecOutput(leader.Configurer.K0sCmdf("default-config"), exec.Sudo(leader))
-		if err != nil {
+		if err := p.generateDefaultConfig(); err != nil {
 			return err
 		}
+	} else {
+		p.SetProp("default-config", false)
+	}
 
-		if err := yaml.Unmarshal([]byte(cfg), &p.Config.Spec.K0s.Config); err != nil {
+	controllers := p.Config.Spec.Hosts.Controllers()
+	return p.parallelDo(controllers, p.configureK0s)
+}
+
+// DryRun reports the controllers where the k0s configuration would be changed
+func (p *ConfigureK0s) DryRun() error {
+	if len(p.Config.Spec.K0s.Config) == 0 {
+		leader := p.Config.Spec.K0sLeader()
+		if leader.Metadata.K0sBinaryVersion == "" {
+			p.DryMsgf(leader, "generate a default k0s configuration")
+			for _, h := range p.Config.Spec.Hosts.Controllers() {
+				p.DryMsgf(h, "write k0s configuration to %s", h.K0sConfigPath())
+			}
+			return nil
+		}
+		if err := p.generateDefaultConfig(); err != nil {
 			return err
 		}
-	} else {
-		p.SetProp("default-config", false)
 	}
 
 	controllers := p.Config.Spec.Hosts.Controllers()
-	return controllers.ParallelEach(p.configureK0s)
+	return p.parallelDo(controllers, func(h *cluster.Host) error {
+		cfg, err := p.configFor(h)
+		if err != nil {
+			return err
+		}
+
+		var oldcfg string
+		if h.Configurer.FileExist(h, h.K0sConfigPath()) {
+			oldcfg, err = h.Configurer.ReadFile(h, h.K0sConfigPath())
+			if err != nil {
+				return err
+			}
+		}
+
+		if !equalConfig(oldcfg, cfg) {
+			p.DryMsgf(h, "write k0s configuration to %s", h.K0sConfigPath())
+			if h.Metadata.K0sRunningVersion != "" && !h.Metadata.NeedsUpgrade {
+				p.DryMsgf(h, "restart the k0s service")
+			}
+		}
+
+		return nil
+	})
+}
+
+func (p *ConfigureK0s) generateDefaultConfig() error {
+	leader := p.Config.Spec.K0sLeader()
+	log.WithField("host", leader).Warn("generating default configuration")
+	cfg, err := leader.ExecOutput(leader.Configurer.K0sCmdf("default-config"), exec.Sudo(leader))
+	if err != nil {
+		return err
+	}
+
+	return yaml.Unmarshal([]byte(cfg), &p.Config.Spec.K0s.Config)
 }
 
 func (p *ConfigureK0s) validateConfig(h *cluster.Host) error {
-	log.Infof("%s: validating configuration", h)
+	log.WithField("host", h).Info("validating configuration")
 	output, err := h.ExecOutput(h.Configurer.K0sCmdf(`validate config --config "%s"`, h.K0sConfigPath()), exec.Sudo(h))
 	if err != nil {
 		return fmt.Errorf("spec.k0s.config fails validation:\n%s", output)
@@ -67,14 +119,14 @@ func (p *ConfigureK0s) configureK0s(h *cluster.Host) error {
 
 		if !h.Configurer.FileContains(h, path, " generated-by-k0sctl") {
 			newpath := path + ".old"
-			log.Warnf("%s: an existing config was found and will be backed up as %s", h, newpath)
+			log.WithField("host", h).Warnf("an existing config was found and will be backed up as %s", newpath)
 			if err := h.Configurer.MoveFile(h, path, newpath); err != nil {
 				return err
 			}
 		}
 	}
 
-	log.Debugf("%s: writing k0s configuration", h)
+	log.WithField("host", h).Debug("writing k0s configuration")
 	cfg, err := p.configFor(h)
 	if err != nil {
 		return err
@@ -89,16 +141,16 @@ func (p *ConfigureK0s) configureK0s(h *cluster.Host) error {
 	}
 
 	if equalConfig(oldcfg, cfg) {
-		log.Debugf("%s: configuration did not change", h)
+		log.WithField("host", h).Debug("configuration did not change")
 	} else {
-		log.Infof("%s: configuration was changed", h)
+		log.WithField("host", h).Info("configuration was changed")
 		if h.Metadata.K0sRunningVersion != "" && !h.Metadata.NeedsUpgrade {
-			log.Infof("%s: restarting the k0s service", h)
+			log.WithField("host", h).Info("restarting the k0s service")
 			if err := h.Configurer.RestartService(h, h.K0sServiceName()); err != nil {
 				return err
 			}
 
-			log.Infof("%s: waiting for the k0s service to start", h)
+			log.WithField("host", h).Info("waiting for the k0s service to start")
 			return h.WaitK0sServiceRunning()
 		}
 	}
@@ -163,17 +215,22 @@ func (p *ConfigureK0s) configFor(h *cluster.Host) (string, error) {
 		}
 	}
 
-	var controllers cluster.Hosts = p.Config.Spec.Hosts.Controllers()
-	for _, c := range controllers {
+	// the controllers left out with --hosts are included to keep the sans of the selected controllers unchanged
+	allHosts := p.Config.Spec.AllHosts()
+	for _, c := range allHosts.Controllers() {
 		addUnlessExist(&sans, c.Address())
 		if c.PrivateAddress != "" {
 			addUnlessExist(&sans, c.PrivateAddress)
 		}
 	}
+	if u, err := url.Parse(p.Config.Spec.K0s.APIServerURL()); err == nil && u.Hostname() != "" {
+		addUnlessExist(&sans, u.Hostname())
+	}
 	addUnlessExist(&sans, "127.0.0.1")
 	cfg.DigMapping("spec", "api")["sans"] = sans
 
-	if cfg.Dig("spec", "storage", "etcd", "peerAddress") != nil || h.PrivateAddress != "" {
+	// the peer address is only used by the etcd managed by k0s
+	if p.Config.Spec.K0s.ExternalEtcd() == nil && (cfg.Dig("spec", "storage", "etcd", "peerAddress") != nil || h.PrivateAddress != "") {
 		cfg.DigMapping("spec", "storage", "etcd")["peerAddress"] = addr
 	}
 
@@ -0,0 +1,30 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/dig"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureK0sSkipsTokenLeader(t *testing.T) {
+	// the leader has no connection or configurer, configuring it would fail
+	leader := &cluster.Host{Role: "controller", Metadata: cluster.HostMetadata{K0sRunningVersion: "1.21.2+k0s.0"}}
+	worker := &cluster.Host{Role: "worker"}
+	spec := &cluster.Spec{
+		Hosts: cluster.Hosts{leader, worker},
+		K0s:   cluster.K0s{Config: dig.Mapping{"spec": dig.Mapping{"api": dig.Mapping{"port": 6443}}}},
+	}
+	_, err := spec.SelectHosts(func(h *cluster.Host) bool { return h == worker })
+	require.NoError(t, err)
+
+	p := &ConfigureK0s{}
+	require.NoError(t, p.Prepare(&config.Cluster{Spec: spec}))
+	p.SetManager(&Manager{Config: p.Config})
+	require.NoError(t, p.Before(p.Title()))
+	require.NoError(t, p.Run())
+	require.NoError(t, p.DryRun())
+	require.Empty(t, p.manager.DryMessages())
+}
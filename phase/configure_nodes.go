@@ -0,0 +1,272 @@
+package phase
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alessio/shellescape"
+	"github.com/avast/retry-go"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig/exec"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// managedLabelsAnnotation lists the keys of the node labels set by k0sctl
+	managedLabelsAnnotation = "k0sctl.k0sproject.io/managed-labels"
+	// managedTaintsAnnotation lists the key:effect of the node taints set by k0sctl
+	managedTaintsAnnotation = "k0sctl.k0sproject.io/managed-taints"
+)
+
+// ConfigureNodes sets the spec.hosts[*].labels and taints on the kubernetes nodes of the hosts. The labels and
+// taints that were set by k0sctl earlier but have since been removed from the configuration are removed.
+type ConfigureNodes struct {
+	GenericPhase
+	hosts  cluster.Hosts
+	leader *cluster.Host
+}
+
+type kubeNode struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Taints []cluster.Taint `json:"taints"`
+	} `json:"spec"`
+}
+
+type kubeNodeList struct {
+	Items []kubeNode `json:"items"`
+}
+
+// nodeChanges are the kubectl arguments for updating the labels, taints and the annotations of a node
+type nodeChanges struct {
+	labels      []string
+	taints      []string
+	annotations []string
+}
+
+func (c nodeChanges) empty() bool {
+	return len(c.labels) == 0 && len(c.taints) == 0 && len(c.annotations) == 0
+}
+
+// Title for the phase
+func (p *ConfigureNodes) Title() string {
+	return "Configure node labels and taints"
+}
+
+// Prepare the phase
+func (p *ConfigureNodes) Prepare(config *config.Cluster) error {
+	p.Config = config
+	p.hosts = p.Config.Spec.Hosts.Filter(func(h *cluster.Host) bool {
+		return h.HasKubelet()
+	})
+	p.leader = p.Config.Spec.K0sLeader()
+	return nil
+}
+
+// ShouldRun is true when there are hosts with a kubernetes node, the nodes without labels or taints in the
+// configuration are checked for the ones k0sctl has set before
+func (p *ConfigureNodes) ShouldRun() bool {
+	return len(p.hosts) > 0
+}
+
+// DryRun reports the changes that would be made to the nodes that already exist
+func (p *ConfigureNodes) DryRun() error {
+	for _, h := range p.hosts {
+		var node *kubeNode
+		if p.leader.Metadata.K0sRunningVersion != "" {
+			n, err := p.getNode(h)
+			if err != nil {
+				return err
+			}
+			node = n
+		}
+		if node == nil {
+			if len(h.Labels) > 0 || len(h.Taints) > 0 {
+				p.DryMsgf(h, "set the node labels and taints once the node has joined the cluster")
+			}
+			continue
+		}
+		changes := nodeConfigChanges(h, node)
+		if len(changes.labels) > 0 {
+			p.DryMsgf(h, "update node labels: %s", strings.Join(changes.labels, " "))
+		}
+		if len(changes.taints) > 0 {
+			p.DryMsgf(h, "update node taints: %s", strings.Join(changes.taints, " "))
+		}
+	}
+	return nil
+}
+
+// Run the phase
+func (p *ConfigureNodes) Run() error {
+	return p.parallelDo(p.hosts, func(h *cluster.Host) error {
+		node, err := p.getNode(h)
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			if len(h.Labels) == 0 && len(h.Taints) == 0 {
+				log.WithField("host", h).Debug("skipping node configuration, the node has not joined the cluster")
+				return nil
+			}
+			log.WithField("host", h).Info("waiting for the node to join the cluster")
+			if node, err = p.waitNode(h); err != nil {
+				return err
+			}
+		}
+
+		changes := nodeConfigChanges(h, node)
+		if changes.empty() {
+			return nil
+		}
+		return p.updateNode(h, node.Metadata.Name, changes)
+	})
+}
+
+// getNode returns the node of the host or nil when the node does not exist yet
+func (p *ConfigureNodes) getNode(h *cluster.Host) (*kubeNode, error) {
+	output, err := p.leader.ExecOutput(p.leader.KubectlCmdf("get node -l %s -o json", shellescape.Quote("kubernetes.io/hostname="+h.Metadata.Hostname)), exec.HideOutput(), exec.Sudo(p.leader))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the node of %s: %w", h, err)
+	}
+	nodes := kubeNodeList{}
+	if err := json.Unmarshal([]byte(output), &nodes); err != nil {
+		return nil, fmt.Errorf("failed to decode kubectl output: %s", err.Error())
+	}
+	if len(nodes.Items) == 0 {
+		return nil, nil
+	}
+	return &nodes.Items[0], nil
+}
+
+// waitNode waits for the node of the host to be registered
+func (p *ConfigureNodes) waitNode(h *cluster.Host) (*kubeNode, error) {
+	var node *kubeNode
+	err := retry.Do(
+		func() error {
+			n, err := p.getNode(h)
+			if err != nil {
+				return err
+			}
+			if n == nil {
+				return fmt.Errorf("node %s has not joined the cluster", h.Metadata.Hostname)
+			}
+			node = n
+			return nil
+		},
+		retry.DelayType(retry.CombineDelay(retry.FixedDelay, retry.RandomDelay)),
+		retry.MaxJitter(time.Second*2),
+		retry.Delay(time.Second*3),
+		retry.Attempts(60),
+		retry.LastErrorOnly(true),
+	)
+	return node, err
+}
+
+func (p *ConfigureNodes) updateNode(h *cluster.Host, name string, changes nodeChanges) error {
+	if len(changes.labels) > 0 {
+		log.WithField("host", h).Infof("updating node labels: %s", strings.Join(changes.labels, " "))
+		if err := p.leader.Exec(p.leader.KubectlCmdf("label node %s --overwrite %s", name, quoteArgs(changes.labels)), exec.Sudo(p.leader)); err != nil {
+			return fmt.Errorf("failed to update the labels of node %s: %w", name, err)
+		}
+	}
+	if len(changes.taints) > 0 {
+		log.WithField("host", h).Infof("updating node taints: %s", strings.Join(changes.taints, " "))
+		if err := p.leader.Exec(p.leader.KubectlCmdf("taint node %s --overwrite %s", name, quoteArgs(changes.taints)), exec.Sudo(p.leader)); err != nil {
+			return fmt.Errorf("failed to update the taints of node %s: %w", name, err)
+		}
+	}
+	if len(changes.annotations) > 0 {
+		if err := p.leader.Exec(p.leader.KubectlCmdf("annotate node %s --overwrite %s", name, quoteArgs(changes.annotations)), exec.Sudo(p.leader)); err != nil {
+			return fmt.Errorf("failed to update the annotations of node %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellescape.Quote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// splitManaged returns the items of a managed labels or taints annotation
+func splitManaged(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// annotationChange returns the kubectl annotate argument for setting the annotation to the items or
+// removing it when there are none left, the change is empty when the annotation is up to date
+func annotationChange(annotations map[string]string, key string, items []string) []string {
+	value := strings.Join(items, ",")
+	current, ok := annotations[key]
+	switch {
+	case value == "" && !ok:
+		return nil
+	case value == "":
+		return []string{key + "-"}
+	case current == value:
+		return nil
+	}
+	return []string{key + "=" + value}
+}
+
+// nodeConfigChanges compares the labels and taints of the node to the ones of the host
+func nodeConfigChanges(h *cluster.Host, node *kubeNode) nodeChanges {
+	var changes nodeChanges
+
+	for _, k := range h.Labels.Keys() {
+		if v, ok := node.Metadata.Labels[k]; !ok || v != h.Labels[k] {
+			changes.labels = append(changes.labels, k+"="+h.Labels[k])
+		}
+	}
+	for _, k := range splitManaged(node.Metadata.Annotations[managedLabelsAnnotation]) {
+		if _, ok := h.Labels[k]; ok {
+			continue
+		}
+		if _, ok := node.Metadata.Labels[k]; ok {
+			changes.labels = append(changes.labels, k+"-")
+		}
+	}
+
+	current := make(map[string]cluster.Taint, len(node.Spec.Taints))
+	for _, t := range node.Spec.Taints {
+		current[t.ID()] = t
+	}
+	wanted := make(map[string]struct{}, len(h.Taints))
+	taintIDs := make([]string, 0, len(h.Taints))
+	for _, t := range h.Taints {
+		wanted[t.ID()] = struct{}{}
+		taintIDs = append(taintIDs, t.ID())
+		if c, ok := current[t.ID()]; !ok || c.Value != t.Value {
+			changes.taints = append(changes.taints, t.String())
+		}
+	}
+	for _, id := range splitManaged(node.Metadata.Annotations[managedTaintsAnnotation]) {
+		if _, ok := wanted[id]; ok {
+			continue
+		}
+		if _, ok := current[id]; ok {
+			changes.taints = append(changes.taints, id+"-")
+		}
+	}
+	sort.Strings(taintIDs)
+
+	changes.annotations = append(changes.annotations, annotationChange(node.Metadata.Annotations, managedLabelsAnnotation, h.Labels.Keys())...)
+	changes.annotations = append(changes.annotations, annotationChange(node.Metadata.Annotations, managedTaintsAnnotation, taintIDs)...)
+
+	return changes
+}
@@ -0,0 +1,44 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeConfigChanges(t *testing.T) {
+	h := &cluster.Host{
+		Labels: cluster.NodeLabels{"zone": "a", "tier": "db"},
+		Taints: []cluster.Taint{{Key: "dedicated", Value: "db", Effect: "NoSchedule"}},
+	}
+
+	node := &kubeNode{}
+	changes := nodeConfigChanges(h, node)
+	require.Equal(t, []string{"tier=db", "zone=a"}, changes.labels)
+	require.Equal(t, []string{"dedicated=db:NoSchedule"}, changes.taints)
+	require.Equal(t, []string{managedLabelsAnnotation + "=tier,zone", managedTaintsAnnotation + "=dedicated:NoSchedule"}, changes.annotations)
+
+	node.Metadata.Labels = map[string]string{"tier": "db", "zone": "a"}
+	node.Metadata.Annotations = map[string]string{managedLabelsAnnotation: "tier,zone", managedTaintsAnnotation: "dedicated:NoSchedule"}
+	node.Spec.Taints = []cluster.Taint{{Key: "dedicated", Value: "db", Effect: "NoSchedule"}}
+	require.True(t, nodeConfigChanges(h, node).empty())
+
+	t.Run("removed from config", func(t *testing.T) {
+		node.Metadata.Labels["manual"] = "yes"
+		h := &cluster.Host{Labels: cluster.NodeLabels{"zone": "b"}}
+		changes := nodeConfigChanges(h, node)
+		require.Equal(t, []string{"zone=b", "tier-"}, changes.labels)
+		require.Equal(t, []string{"dedicated:NoSchedule-"}, changes.taints)
+		require.Equal(t, []string{managedLabelsAnnotation + "=zone", managedTaintsAnnotation + "-"}, changes.annotations)
+	})
+
+	t.Run("removed from node", func(t *testing.T) {
+		node := &kubeNode{}
+		node.Metadata.Annotations = map[string]string{managedLabelsAnnotation: "tier", managedTaintsAnnotation: "dedicated:NoSchedule"}
+		changes := nodeConfigChanges(&cluster.Host{}, node)
+		require.Empty(t, changes.labels)
+		require.Empty(t, changes.taints)
+		require.Equal(t, []string{managedLabelsAnnotation + "-", managedTaintsAnnotation + "-"}, changes.annotations)
+	})
+}
@@ -1,7 +1,11 @@
 package phase
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"strings"
+	"syscall"
 	"time"
 
 	retry "github.com/avast/retry-go"
@@ -9,9 +13,58 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// ConnectTimeout is the default time a single connection attempt to a host may take, overridden by the host
+// connectTimeout. Zero means no timeout.
+var ConnectTimeout = 30 * time.Second
+
+// connectTimeoutError is returned when connecting to a host takes longer than the connect timeout
+type connectTimeoutError struct {
+	address string
+	timeout time.Duration
+}
+
+func (e *connectTimeoutError) Error() string {
+	return fmt.Sprintf("timed out connecting to %s after %s", e.address, e.timeout)
+}
+
+// dialHostTimeout connects to the host, giving up when the connection is not made within the timeout. The
+// attempt that timed out is not retried, as it can't be interrupted and goes on in the background, the
+// connection is closed if it is made after all.
+func dialHostTimeout(h *cluster.Host, timeout time.Duration) error {
+	if timeout <= 0 {
+		return dialHost(h)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- dialHost(h)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		go func() {
+			if err := <-done; err == nil {
+				log.WithField("host", h).Debug("closing a connection that was made after the connect timeout")
+				h.Disconnect()
+			}
+		}()
+		return &connectTimeoutError{address: h.Address(), timeout: timeout}
+	}
+}
+
 // Connect connects to each of the hosts
 type Connect struct {
 	GenericPhase
+
+	// Retries is the number of times a failed connection attempt is retried
+	Retries uint
+	// RetryInterval is the delay before the first retry, the delay is doubled for each following retry
+	RetryInterval time.Duration
 }
 
 // Title for the phase
@@ -19,41 +72,86 @@ func (p *Connect) Title() string {
 	return "Connect to hosts"
 }
 
-var retries = uint(60)
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *Connect) ReadOnly() bool {
+	return true
+}
+
+// Mandatory is true, the phase can not be skipped
+func (p *Connect) Mandatory() bool {
+	return true
+}
 
 // Run the phase
 func (p *Connect) Run() error {
-	return p.Config.Spec.Hosts.ParallelEach(func(h *cluster.Host) error {
+	interval := p.RetryInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	return p.parallelDo(p.Config.Spec.ConnectHosts(), func(h *cluster.Host) error {
+		if h.WinRM != nil && h.WinRM.UseHTTPS && h.WinRM.Insecure {
+			log.WithField("host", h).Warn("connecting without verifying the WinRM TLS certificate")
+		}
+
 		err := retry.Do(
 			func() error {
-				return h.Connect()
+				return connectHost(h)
 			},
 			retry.OnRetry(
 				func(n uint, err error) {
-					log.Errorf("%s: attempt %d of %d.. failed to connect: %s", h, n+1, retries, err.Error())
-				},
-			),
-			retry.RetryIf(
-				func(err error) bool {
-					return !strings.Contains(err.Error(), "no supported methods remain")
+					log.WithField("host", h).Debugf("connection attempt %d failed, %d retries remaining: %s", n+1, p.Retries-n, err.Error())
 				},
 			),
-			retry.DelayType(retry.CombineDelay(retry.FixedDelay, retry.RandomDelay)),
-			retry.MaxJitter(time.Second*2),
-			retry.Delay(time.Second*3),
-			retry.Attempts(retries),
+			retry.RetryIf(isRetryableConnectError),
+			retry.DelayType(retry.BackOffDelay),
+			retry.Delay(interval),
+			retry.Attempts(p.Retries+1),
 			retry.LastErrorOnly(true),
+			retry.Context(p.Context()),
 		)
 
 		if err != nil {
-			log.Errorf("%s: failed to connect: %s", h, err.Error())
+			log.WithField("host", h).Errorf("failed to connect: %s", err.Error())
 			p.IncProp("fail-" + h.Protocol())
 			return err
 		}
 
-		log.Infof("%s: connected", h)
+		if err := h.CheckSudoPassword(); err != nil {
+			return err
+		}
+
+		log.WithField("host", h).Info("connected")
 		p.IncProp("success-" + h.Protocol())
 
 		return nil
 	})
 }
+
+// isRetryableConnectError returns true for errors that are likely to be transient, such as the
+// host not yet accepting connections. Authentication failures are not retried.
+func isRetryableConnectError(err error) bool {
+	var timeoutErr *connectTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// rig does not wrap the underlying errors
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"connection refused", "i/o timeout", "timed out", "timeout"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package phase
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryableConnectError(t *testing.T) {
+	require.True(t, isRetryableConnectError(fmt.Errorf("dial: %w", syscall.ECONNREFUSED)))
+	require.True(t, isRetryableConnectError(fmt.Errorf("dial tcp 10.0.0.1:22: i/o timeout")))
+	require.False(t, isRetryableConnectError(fmt.Errorf("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain")))
+}
+
+func TestDialHostTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	// accept the connections but never send the ssh banner
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	h := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "127.0.0.1", Port: port, User: "root"}}}
+	start := time.Now()
+	err = dialHostTimeout(h, 100*time.Millisecond)
+	require.Error(t, err)
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	require.Equal(t, "timed out connecting to 127.0.0.1 after 100ms", err.Error())
+	require.False(t, isRetryableConnectError(err))
+}
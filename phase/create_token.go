@@ -0,0 +1,60 @@
+package phase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateToken is a phase to create a new join token on the k0s leader, the result is stored in Token
+type CreateToken struct {
+	GenericPhase
+	Role   string
+	Expiry time.Duration
+	Token  string
+
+	leader *cluster.Host
+}
+
+// Title for the phase
+func (p *CreateToken) Title() string {
+	return "Create join token"
+}
+
+// Sequential is true, the token is created on the leader
+func (p *CreateToken) Sequential() bool {
+	return true
+}
+
+// Prepare the phase
+func (p *CreateToken) Prepare(config *config.Cluster) error {
+	p.Config = config
+	p.leader = p.Config.Spec.K0sLeader()
+	return nil
+}
+
+// Run the phase
+func (p *CreateToken) Run() error {
+	h := p.leader
+	if !h.Configurer.ServiceIsRunning(h, h.K0sServiceName()) {
+		return fmt.Errorf("k0s is not running on %s, a join token can only be created on a running controller", h)
+	}
+
+	log.WithField("host", h).Infof("creating a %s join token with expiry %s", p.Role, p.Expiry)
+	token, err := p.Config.Spec.K0s.GenerateToken(h, p.Role, p.Expiry)
+	if err != nil {
+		return fmt.Errorf("failed to create a join token: %w", err)
+	}
+
+	tokenID, err := cluster.TokenID(token)
+	if err != nil {
+		return err
+	}
+	log.WithField("host", h).Debugf("join token ID: %s", tokenID)
+
+	p.Token = token
+	return nil
+}
@@ -0,0 +1,145 @@
+package phase
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig/exec"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// HostReachable is the status of a host that was connected to
+	HostReachable = "reachable"
+	// HostUnreachable is the status of a host that could not be connected to
+	HostUnreachable = "unreachable"
+)
+
+// HostDescription is the inventory information of a host
+type HostDescription struct {
+	Address    string `json:"address" yaml:"address"`
+	Role       string `json:"role" yaml:"role"`
+	Status     string `json:"status" yaml:"status"`
+	Hostname   string `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	OS         string `json:"os,omitempty" yaml:"os,omitempty"`
+	Arch       string `json:"arch,omitempty" yaml:"arch,omitempty"`
+	Kernel     string `json:"kernel,omitempty" yaml:"kernel,omitempty"`
+	Uptime     int64  `json:"uptime,omitempty" yaml:"uptime,omitempty"`
+	K0sVersion string `json:"k0sVersion,omitempty" yaml:"k0sVersion,omitempty"`
+	K0sRunning bool   `json:"k0sRunning" yaml:"k0sRunning"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Describe connects to the hosts and gathers their inventory information. Unlike the other phases
+// it does not fail when a host can't be reached or inspected, the problem is recorded in the
+// description of the host instead.
+type Describe struct {
+	GenericPhase
+
+	// Hosts are the descriptions of the hosts in the order of the configuration
+	Hosts []*HostDescription
+}
+
+// Title for the phase
+func (p *Describe) Title() string {
+	return "Describe hosts"
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *Describe) ReadOnly() bool {
+	return true
+}
+
+// Run the phase
+func (p *Describe) Run() error {
+	descriptions := make(map[*cluster.Host]*HostDescription, len(p.Config.Spec.Hosts))
+	p.Hosts = make([]*HostDescription, 0, len(p.Config.Spec.Hosts))
+	for _, h := range p.Config.Spec.Hosts {
+		d := &HostDescription{Address: h.Address(), Role: h.Role}
+		descriptions[h] = d
+		p.Hosts = append(p.Hosts, d)
+	}
+
+	return p.parallelDo(p.Config.Spec.Hosts, func(h *cluster.Host) error {
+		d := descriptions[h]
+		if err := connectHost(h); err != nil {
+			log.WithField("host", h).Warnf("failed to connect: %s", err.Error())
+			d.Status = HostUnreachable
+			d.Error = err.Error()
+			return nil
+		}
+		d.Status = HostReachable
+
+		if err := describeHost(h, d); err != nil {
+			log.WithField("host", h).Warnf("failed to gather facts: %s", err.Error())
+			d.Error = err.Error()
+		}
+		return nil
+	})
+}
+
+func describeHost(h *cluster.Host, d *HostDescription) error {
+	d.OS = h.OSVersion.String()
+	if h.OSIDOverride != "" {
+		h.OSVersion.ID = h.OSIDOverride
+	}
+	if err := h.ResolveConfigurer(); err != nil {
+		return err
+	}
+
+	d.Hostname = h.Configurer.Hostname(h)
+
+	arch, err := h.Configurer.Arch(h)
+	if err != nil {
+		return err
+	}
+	d.Arch = arch
+
+	if kernel, err := h.ExecOutput("uname -r"); err == nil {
+		d.Kernel = kernel
+	}
+
+	if uptime, err := h.Configurer.ReadFile(h, "/proc/uptime"); err == nil {
+		if d.Uptime, err = parseUptime(uptime); err != nil {
+			log.WithField("host", h).Debugf("failed to parse uptime: %s", err.Error())
+		}
+	}
+
+	version, err := h.ExecOutput(h.Configurer.K0sCmdf("version"), exec.Sudo(h))
+	if err != nil {
+		log.WithField("host", h).Debug("k0s is not installed")
+		return nil
+	}
+	d.K0sVersion = strings.TrimPrefix(version, "v")
+
+	output, err := h.ExecOutput(h.K0sStatusCommand(), exec.Sudo(h))
+	if err != nil {
+		return nil
+	}
+	status := k0sstatus{}
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return fmt.Errorf("failed to decode k0s status output: %w", err)
+	}
+	d.K0sRunning = status.Pid != 0
+	if d.K0sRunning && status.Version != "" {
+		d.K0sVersion = strings.TrimPrefix(status.Version, "v")
+	}
+
+	return nil
+}
+
+// parseUptime returns the uptime in seconds from the contents of /proc/uptime
+func parseUptime(s string) (int64, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty uptime")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(seconds), nil
+}
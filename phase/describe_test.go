@@ -0,0 +1,19 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUptime(t *testing.T) {
+	uptime, err := parseUptime("350735.47 234388.90\n")
+	require.NoError(t, err)
+	require.Equal(t, int64(350735), uptime)
+
+	_, err = parseUptime("")
+	require.Error(t, err)
+
+	_, err = parseUptime("foo bar")
+	require.Error(t, err)
+}
@@ -23,11 +23,21 @@ func (p *DetectOS) Title() string {
 	return "Detect host operating systems"
 }
 
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *DetectOS) ReadOnly() bool {
+	return true
+}
+
+// Mandatory is true, the phase can not be skipped
+func (p *DetectOS) Mandatory() bool {
+	return true
+}
+
 // Run the phase
 func (p *DetectOS) Run() error {
-	return p.Config.Spec.Hosts.ParallelEach(func(h *cluster.Host) error {
+	return p.parallelDo(p.Config.Spec.ConnectHosts(), func(h *cluster.Host) error {
 		if h.OSIDOverride != "" {
-			log.Infof("%s: overriding OS to %s", h, h.OSIDOverride)
+			log.WithField("host", h).Infof("overriding OS to %s", h.OSIDOverride)
 			h.OSVersion.ID = h.OSIDOverride
 		}
 		if err := h.ResolveConfigurer(); err != nil {
@@ -36,7 +46,10 @@ func (p *DetectOS) Run() error {
 		}
 		os := h.OSVersion.String()
 		p.IncProp(os)
-		log.Infof("%s: is running %s", h, os)
+		log.WithField("host", h).Infof("is running %s", os)
+		if h.Configurer.Kind() != "windows" {
+			log.WithField("host", h).Debugf("running commands with %s", h.ExecShell())
+		}
 
 		return nil
 	})
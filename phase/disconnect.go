@@ -14,9 +14,19 @@ func (p *Disconnect) Title() string {
 	return "Disconnect from hosts"
 }
 
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *Disconnect) ReadOnly() bool {
+	return true
+}
+
+// Mandatory is true, the phase can not be skipped
+func (p *Disconnect) Mandatory() bool {
+	return true
+}
+
 // Run the phase
 func (p *Disconnect) Run() error {
-	return p.Config.Spec.Hosts.ParallelEach(func(h *cluster.Host) error {
+	return p.parallelDo(p.Config.Spec.ConnectHosts(), func(h *cluster.Host) error {
 		h.Disconnect()
 		return nil
 	})
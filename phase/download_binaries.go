@@ -5,6 +5,9 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/k0sproject/k0sctl/cache"
 	"github.com/k0sproject/k0sctl/config"
@@ -12,9 +15,26 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// NoBinaryCache makes the k0s binaries always be downloaded, the copies in the local cache are replaced
+var NoBinaryCache bool
+
+// ClearBinaryCache removes the cached k0s binaries from the local cache directory
+func ClearBinaryCache() error {
+	dir := cache.File("k0s")
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear the k0s binary cache: %w", err)
+	}
+	log.Infof("cleared the k0s binary cache in %s", dir)
+	return nil
+}
+
 // DownloadBinaries downloads k0s binaries to localohost temp files
 type DownloadBinaries struct {
 	GenericPhase
+
+	// BinaryDir is a local directory with pre-staged k0s binaries, overrides spec.k0s.binaryDir
+	BinaryDir string
+
 	hosts []*cluster.Host
 }
 
@@ -23,34 +43,82 @@ func (p *DownloadBinaries) Title() string {
 	return "Download k0s binaries to local host"
 }
 
+// Sequential is true, the binaries are downloaded on the local host
+func (p *DownloadBinaries) Sequential() bool {
+	return true
+}
+
 // Prepare the phase
 func (p *DownloadBinaries) Prepare(config *config.Cluster) error {
 	p.Config = config
+	if p.BinaryDir == "" {
+		p.BinaryDir = config.Spec.K0s.BinaryDir
+	}
 	p.hosts = p.Config.Spec.Hosts.Filter(func(h *cluster.Host) bool {
-		return h.UploadBinary && h.Metadata.K0sBinaryVersion != config.Spec.K0s.Version
+		// when a binary directory is given, the binaries are uploaded to all hosts
+		return (h.UploadBinary || p.BinaryDir != "") && (Force || h.Metadata.K0sBinaryVersion != config.Spec.K0sVersionFor(h))
 	})
 	return nil
 }
 
+// localBinary returns the path to the binary in the binary directory or an empty string if it does not exist
+func (p *DownloadBinaries) localBinary(b *binary) string {
+	if p.BinaryDir == "" {
+		return ""
+	}
+	path := filepath.Join(p.BinaryDir, b.filename())
+	if _, err := os.Stat(path); err != nil {
+		log.Debugf("k0s binary %s not found in %s", b.filename(), p.BinaryDir)
+		return ""
+	}
+	return path
+}
+
 // ShouldRun is true when the phase should be run
 func (p *DownloadBinaries) ShouldRun() bool {
 	return len(p.hosts) > 0
 }
 
+// DryRun reports the binaries that would be downloaded and uploaded
+func (p *DownloadBinaries) DryRun() error {
+	var bins binaries
+	for _, h := range p.hosts {
+		if h.K0sBinaryPath != "" {
+			p.DryMsgf(h, "upload k0s binary from %s", h.K0sBinaryPath)
+			continue
+		}
+		bin := bins.find(h.Configurer.Kind(), h.Metadata.Arch, p.Config.Spec.K0sVersionFor(h))
+		if bin == nil {
+			bin = &binary{arch: h.Metadata.Arch, os: h.Configurer.Kind(), version: p.Config.Spec.K0sVersionFor(h), urlBase: p.Config.Spec.K0s.DownloadURLBase}
+			bin.path = p.localBinary(bin)
+			bins = append(bins, bin)
+			if bin.path == "" {
+				p.DryMsgf(nil, "download k0s %s binary for %s-%s to local host", p.Config.Spec.K0sVersionFor(h), h.Configurer.Kind(), h.Metadata.Arch)
+			}
+		}
+		if bin.path != "" {
+			p.DryMsgf(h, "upload k0s %s binary from %s", p.Config.Spec.K0sVersionFor(h), bin.path)
+		} else {
+			p.DryMsgf(h, "upload k0s %s binary", p.Config.Spec.K0sVersionFor(h))
+		}
+	}
+	return nil
+}
+
 // Run the phase
 func (p *DownloadBinaries) Run() error {
 	var bins binaries
 
 	for _, h := range p.hosts {
-		if bin := bins.find(h.Configurer.Kind(), h.Metadata.Arch); bin != nil {
+		if bin := bins.find(h.Configurer.Kind(), h.Metadata.Arch, p.Config.Spec.K0sVersionFor(h)); bin != nil {
 			continue
 		}
 
-		bin := &binary{arch: h.Metadata.Arch, os: h.Configurer.Kind(), version: p.Config.Spec.K0s.Version}
+		bin := &binary{arch: h.Metadata.Arch, os: h.Configurer.Kind(), version: p.Config.Spec.K0sVersionFor(h), urlBase: p.Config.Spec.K0s.DownloadURLBase}
 
 		// find configuration defined binpaths and use instead of downloading a new one
 		for _, v := range p.hosts {
-			if v.Metadata.Arch == bin.arch && v.Configurer.Kind() == bin.os && v.K0sBinaryPath != "" {
+			if v.Metadata.Arch == bin.arch && v.Configurer.Kind() == bin.os && p.Config.Spec.K0sVersionFor(v) == bin.version && v.K0sBinaryPath != "" {
 				bin.path = h.K0sBinaryPath
 			}
 		}
@@ -62,14 +130,26 @@ func (p *DownloadBinaries) Run() error {
 		if bin.path != "" {
 			continue
 		}
-		if err := bin.download(); err != nil {
+		if path := p.localBinary(bin); path != "" {
+			log.Infof("using k0s binary from %s for %s-%s", path, bin.os, bin.arch)
+			bin.path = path
+			continue
+		}
+		expected, err := p.expectedChecksum(bin)
+		if err != nil {
+			return err
+		}
+		if err := bin.download(expected); err != nil {
+			if p.BinaryDir != "" {
+				return fmt.Errorf("k0s binary %s was not found in %s and downloading it failed: %w", bin.filename(), p.BinaryDir, err)
+			}
 			return err
 		}
 	}
 
 	for _, h := range p.hosts {
 		if h.K0sBinaryPath == "" {
-			if bin := bins.find(h.Configurer.Kind(), h.Metadata.Arch); bin != nil {
+			if bin := bins.find(h.Configurer.Kind(), h.Metadata.Arch, p.Config.Spec.K0sVersionFor(h)); bin != nil {
 				h.UploadBinaryPath = bin.path
 			}
 		} else {
@@ -80,18 +160,62 @@ func (p *DownloadBinaries) Run() error {
 	return nil
 }
 
+// expectedChecksum returns the expected checksum of the binary of the first host it is for
+func (p *DownloadBinaries) expectedChecksum(b *binary) (string, error) {
+	for _, h := range p.hosts {
+		if h.Metadata.Arch == b.arch && h.Configurer.Kind() == b.os && p.Config.Spec.K0sVersionFor(h) == b.version {
+			return expectedChecksum(p.Config, h)
+		}
+	}
+	return "", nil
+}
+
 type binary struct {
 	arch    string
 	os      string
 	version string
 	path    string
+	// urlBase replaces the github releases location in the download url, see spec.k0s.downloadURLBase
+	urlBase string
 }
 
-func (b *binary) download() error {
-	path, err := cache.GetOrCreate(b.downloadTo, "k0s", b.os, b.arch, "k0s-"+b.version+b.ext())
+// download gets the binary from the local cache or downloads it to the cache. A cached binary that does
+// not match the expected checksum is downloaded again.
+func (b *binary) download(expected string) error {
+	parts := []string{"k0s", b.os, b.arch, "k0s-" + b.version + b.ext()}
+
+	cached, err := cache.GetFile(parts...)
+	switch {
+	case err != nil:
+		cached = ""
+	case NoBinaryCache:
+		log.Debugf("not using the cached k0s binary %s because of --no-binary-cache", cached)
+		if err := os.Remove(cached); err != nil {
+			return fmt.Errorf("failed to remove the cached k0s binary: %w", err)
+		}
+		cached = ""
+	case expected != "":
+		if err := checkBinaryChecksum(cached, expected); err != nil {
+			log.Warnf("cached k0s binary %s is not valid, downloading it again: %s", cached, err.Error())
+			if err := os.Remove(cached); err != nil {
+				return fmt.Errorf("failed to remove the cached k0s binary: %w", err)
+			}
+			cached = ""
+		}
+	}
+
+	path, err := cache.GetOrCreate(b.downloadTo, parts...)
 	if err != nil {
 		return err
 	}
+	if cached == "" && expected != "" {
+		if err := checkBinaryChecksum(path, expected); err != nil {
+			if rerr := os.Remove(path); rerr != nil {
+				log.Warnf("failed to remove broken download at %s: %s", path, rerr.Error())
+			}
+			return fmt.Errorf("downloaded k0s %s binary for %s-%s is not valid: %w", b.version, b.os, b.arch, err)
+		}
+	}
 
 	b.path = path
 	log.Infof("using k0s binary from %s for %s-%s", b.path, b.os, b.arch)
@@ -99,6 +223,18 @@ func (b *binary) download() error {
 	return nil
 }
 
+// checkBinaryChecksum compares the sha256 checksum of a local file to the expected one
+func checkBinaryChecksum(path, expected string) error {
+	actual, err := fileSHA256(path, -1)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch: expected sha256 %s, got %s", expected, actual)
+	}
+	return nil
+}
+
 func (b binary) ext() string {
 	if b.os == "windows" {
 		return ".exe"
@@ -106,8 +242,13 @@ func (b binary) ext() string {
 	return ""
 }
 
+// filename returns the name of the binary as in the k0s github releases
+func (b binary) filename() string {
+	return fmt.Sprintf("k0s-v%s-%s%s", b.version, b.arch, b.ext())
+}
+
 func (b binary) url() string {
-	return fmt.Sprintf("https://github.com/k0sproject/k0s/releases/download/v%s/k0s-v%s-%s%s", b.version, b.version, b.arch, b.ext())
+	return cluster.K0s{DownloadURLBase: b.urlBase}.DownloadURL(b.version, b.filename())
 }
 
 func (b binary) downloadTo(path string) error {
@@ -129,7 +270,8 @@ func (b binary) downloadTo(path string) error {
 		}
 	}()
 
-	resp, err := http.Get(b.url())
+	log.Debugf("downloading %s", b.url())
+	resp, err := downloadClient(0).Get(b.url())
 	if err != nil {
 		return err
 	}
@@ -155,11 +297,57 @@ func (b binary) downloadTo(path string) error {
 
 type binaries []*binary
 
-func (b binaries) find(os, arch string) *binary {
+func (b binaries) find(os, arch, version string) *binary {
 	for _, v := range b {
-		if v.arch == arch && v.os == os {
+		if v.arch == arch && v.os == os && v.version == version {
 			return v
 		}
 	}
 	return nil
 }
+
+// k0sArchs are the architectures k0s binaries are released for
+var k0sArchs = []string{"amd64", "arm64", "arm"}
+
+// CheckDownloads makes sure that the k0s binaries of the configured versions can be found at the download
+// location. The architectures of the hosts are not known without connecting to them, so a version is
+// considered available when a binary for any of the architectures k0s is released for is found.
+func CheckDownloads(c *config.Cluster) []error {
+	versions := []string{c.Spec.K0s.Version}
+	seen := map[string]bool{c.Spec.K0s.Version: true}
+	for _, h := range c.Spec.Hosts {
+		if v := c.Spec.K0sVersionFor(h); !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+
+	var errs []error
+	client := downloadClient(30 * time.Second)
+	for _, v := range versions {
+		var err error
+		for _, arch := range k0sArchs {
+			bin := binary{arch: arch, os: "linux", version: v, urlBase: c.Spec.K0s.DownloadURLBase}
+			if err = checkDownload(client, bin.url()); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("k0s %s binaries can not be downloaded: %w", v, err))
+		}
+	}
+	return errs
+}
+
+func checkDownload(client *http.Client, url string) error {
+	log.Debugf("checking %s", url)
+	resp, err := client.Head(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned http %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package phase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/cache"
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryFilename(t *testing.T) {
+	b := binary{arch: "amd64", os: "linux", version: "1.21.2+k0s.0"}
+	require.Equal(t, "k0s-v1.21.2+k0s.0-amd64", b.filename())
+	require.Equal(t, "https://github.com/k0sproject/k0s/releases/download/v1.21.2+k0s.0/k0s-v1.21.2+k0s.0-amd64", b.url())
+
+	b.os = "windows"
+	require.Equal(t, "k0s-v1.21.2+k0s.0-amd64.exe", b.filename())
+
+	b.urlBase = "https://mirror.example.com/k0s"
+	require.Equal(t, "https://mirror.example.com/k0s/v1.21.2+k0s.0/k0s-v1.21.2+k0s.0-amd64.exe", b.url())
+}
+
+func TestCheckDownloads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == "/k0s/v1.21.2+k0s.0/k0s-v1.21.2+k0s.0-arm64" {
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := &config.Cluster{Spec: &cluster.Spec{
+		K0s: cluster.K0s{Version: "1.21.2+k0s.0", DownloadURLBase: srv.URL + "/k0s"},
+		Hosts: cluster.Hosts{
+			&cluster.Host{Role: "controller"},
+			&cluster.Host{Role: "worker", K0sVersion: "1.21.3+k0s.0"},
+		},
+	}}
+
+	errs := CheckDownloads(c)
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), "k0s 1.21.3+k0s.0 binaries can not be downloaded")
+	require.Contains(t, errs[0].Error(), "http 404")
+}
+
+func TestLocalBinary(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "k0s-v1.21.2+k0s.0-arm64"), []byte("k0s"), 0700))
+
+	p := &DownloadBinaries{BinaryDir: dir}
+	require.Equal(t, filepath.Join(dir, "k0s-v1.21.2+k0s.0-arm64"), p.localBinary(&binary{arch: "arm64", os: "linux", version: "1.21.2+k0s.0"}))
+	require.Empty(t, p.localBinary(&binary{arch: "amd64", os: "linux", version: "1.21.2+k0s.0"}))
+
+	p.BinaryDir = ""
+	require.Empty(t, p.localBinary(&binary{arch: "arm64", os: "linux", version: "1.21.2+k0s.0"}))
+}
+
+func TestBinaryDownloadCache(t *testing.T) {
+	var downloads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write([]byte("k0s"))
+	}))
+	defer srv.Close()
+
+	b := &binary{arch: "amd64", os: "linux", version: "0.0.0-cachetest", urlBase: srv.URL}
+	path := cache.File("k0s", b.os, b.arch, "k0s-"+b.version)
+	t.Cleanup(func() { os.Remove(path) })
+	hash := sha256.Sum256([]byte("k0s"))
+	sum := hex.EncodeToString(hash[:])
+
+	require.NoError(t, b.download(sum))
+	require.Equal(t, path, b.path)
+	require.Equal(t, 1, downloads)
+
+	// a valid cached binary is reused
+	require.NoError(t, b.download(sum))
+	require.Equal(t, 1, downloads)
+
+	// a corrupted cached binary is downloaded again
+	require.NoError(t, os.WriteFile(path, []byte("corrupted"), 0644))
+	require.NoError(t, b.download(sum))
+	require.Equal(t, 2, downloads)
+	require.NoError(t, checkBinaryChecksum(path, sum))
+
+	defer func() { NoBinaryCache = false }()
+	NoBinaryCache = true
+	require.NoError(t, b.download(""))
+	require.Equal(t, 3, downloads)
+	NoBinaryCache = false
+
+	// a download that does not match the checksum is not left in the cache
+	err := b.download(strings.Repeat("0", 64))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum mismatch")
+	require.Equal(t, 4, downloads)
+	require.NoFileExists(t, path)
+}
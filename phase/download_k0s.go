@@ -25,7 +25,11 @@ func (p *DownloadK0s) Title() string {
 func (p *DownloadK0s) Prepare(config *config.Cluster) error {
 	p.Config = config
 	p.hosts = p.Config.Spec.Hosts.Filter(func(h *cluster.Host) bool {
-		return h.Metadata.K0sBinaryVersion != p.Config.Spec.K0s.Version && !h.Metadata.NeedsUpgrade
+		// the binaries of these hosts are handled by the upload binaries phase
+		if h.UploadBinary || h.UploadBinaryPath != "" {
+			return false
+		}
+		return (Force || h.Metadata.K0sBinaryVersion != p.Config.Spec.K0sVersionFor(h)) && !h.Metadata.NeedsUpgrade
 	})
 	return nil
 }
@@ -35,22 +39,30 @@ func (p *DownloadK0s) ShouldRun() bool {
 	return len(p.hosts) > 0
 }
 
+// DryRun reports the hosts that would download k0s
+func (p *DownloadK0s) DryRun() error {
+	for _, h := range p.hosts {
+		p.DryMsgf(h, "download k0s %s", p.Config.Spec.K0sVersionFor(h))
+	}
+	return nil
+}
+
 // Run the phase
 func (p *DownloadK0s) Run() error {
-	return p.hosts.ParallelEach(p.downloadK0s)
+	return p.parallelDownloadDo(p.hosts, p.downloadK0s)
 }
 
 func (p *DownloadK0s) downloadK0s(h *cluster.Host) error {
-	target := p.Config.Spec.K0s.Version
-	log.Infof("%s: downloading k0s %s", h, target)
-	if err := h.Configurer.DownloadK0s(h, target, h.Metadata.Arch); err != nil {
+	target := p.Config.Spec.K0sVersionFor(h)
+	log.WithField("host", h).Infof("downloading k0s %s", target)
+	if err := h.Configurer.DownloadK0s(h, h.K0sDownloadURL(target), proxyEnv()); err != nil {
 		return err
 	}
 
 	output, err := h.ExecOutput(h.Configurer.K0sCmdf("version"), exec.Sudo(h))
 	if err != nil {
 		if err := h.Configurer.DeleteFile(h, h.Configurer.K0sBinaryPath()); err != nil {
-			log.Warnf("%s: failed to remove %s: %s", h, h.Configurer.K0sBinaryPath(), err.Error())
+			log.WithField("host", h).Warnf("failed to remove %s: %s", h.Configurer.K0sBinaryPath(), err.Error())
 		}
 		return fmt.Errorf("downloaded k0s binary is invalid: %s", err.Error())
 	}
@@ -59,6 +71,10 @@ func (p *DownloadK0s) downloadK0s(h *cluster.Host) error {
 		return fmt.Errorf("downloaded k0s binary version is %s not %s", output, target)
 	}
 
+	if err := verifyK0sBinary(p.Config, h); err != nil {
+		return err
+	}
+
 	h.Metadata.K0sBinaryVersion = target
 
 	return nil
@@ -0,0 +1,30 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadK0sHosts(t *testing.T) {
+	defer func() { Force = false }()
+
+	uploaded := &cluster.Host{UploadBinaryPath: "/tmp/k0s", Metadata: cluster.HostMetadata{K0sBinaryVersion: "1.21.2+k0s.0"}}
+	uploadBinary := &cluster.Host{UploadBinary: true, Metadata: cluster.HostMetadata{K0sBinaryVersion: "1.21.2+k0s.0"}}
+	current := &cluster.Host{Metadata: cluster.HostMetadata{K0sBinaryVersion: "1.21.2+k0s.0"}}
+	outdated := &cluster.Host{Metadata: cluster.HostMetadata{K0sBinaryVersion: "1.21.1+k0s.0"}}
+	cfg := &config.Cluster{Spec: &cluster.Spec{
+		K0s:   cluster.K0s{Version: "1.21.2+k0s.0"},
+		Hosts: cluster.Hosts{uploaded, uploadBinary, current, outdated},
+	}}
+
+	p := &DownloadK0s{}
+	require.NoError(t, p.Prepare(cfg))
+	require.Equal(t, cluster.Hosts{outdated}, p.hosts)
+
+	Force = true
+	require.NoError(t, p.Prepare(cfg))
+	require.Equal(t, cluster.Hosts{current, outdated}, p.hosts)
+}
@@ -2,6 +2,7 @@ package phase
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/k0sproject/k0sctl/config/cluster"
 	log "github.com/sirupsen/logrus"
@@ -19,22 +20,38 @@ func (p *GatherFacts) Title() string {
 	return "Gather host facts"
 }
 
-// Run the phase
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *GatherFacts) ReadOnly() bool {
+	return true
+}
+
+// Run the phase. The hosts are investigated in parallel and the errors from all of the hosts are
+// reported together.
 func (p *GatherFacts) Run() error {
-	return p.Config.Spec.Hosts.ParallelEach(p.investigateHost)
+	start := time.Now()
+	defer func() {
+		log.Debugf("gathered facts from %d hosts in %s", len(p.Config.Spec.Hosts), time.Since(start))
+	}()
+
+	return p.parallelDo(p.Config.Spec.ConnectHosts(), p.investigateHost)
 }
 
 func (p *GatherFacts) investigateHost(h *cluster.Host) error {
 	p.IncProp(h.Role)
 
-	output, err := h.Configurer.Arch(h)
-	if err != nil {
-		return err
+	if h.Arch != "" {
+		log.WithField("host", h).Infof("using architecture %s from configuration instead of detecting it", h.Arch)
+		h.Metadata.Arch = h.Arch
+	} else {
+		output, err := h.Configurer.Arch(h)
+		if err != nil {
+			return err
+		}
+		h.Metadata.Arch = output
 	}
-	h.Metadata.Arch = output
 	p.IncProp(h.Metadata.Arch)
 
-	extra := h.InstallFlags.GetValue("--kubelet-extra-args")
+	extra := h.K0sInstallFlags().GetValue("--kubelet-extra-args")
 	if extra != "" {
 		ef := cluster.Flags{extra}
 		if over := ef.GetValue("--hostname-override"); over != "" {
@@ -46,25 +63,25 @@ func (p *GatherFacts) investigateHost(h *cluster.Host) error {
 	}
 
 	if h.HostnameOverride != "" {
-		log.Infof("%s: using %s from configuration as hostname", h, h.HostnameOverride)
+		log.WithField("host", h).Infof("using %s from configuration as hostname", h.HostnameOverride)
 		h.Metadata.Hostname = h.HostnameOverride
 	} else {
 		h.Metadata.Hostname = h.Configurer.Hostname(h)
-		log.Infof("%s: using %s as hostname", h, h.Metadata.Hostname)
+		log.WithField("host", h).Infof("using %s as hostname", h.Metadata.Hostname)
 	}
 
 	if h.PrivateAddress == "" {
 		if h.PrivateInterface == "" {
 			if iface, err := h.Configurer.PrivateInterface(h); err == nil {
 				h.PrivateInterface = iface
-				log.Infof("%s: discovered %s as private interface", h, iface)
+				log.WithField("host", h).Infof("discovered %s as private interface", iface)
 			}
 		}
 
 		if h.PrivateInterface != "" {
 			if addr, err := h.Configurer.PrivateAddress(h, h.PrivateInterface, h.Address()); err == nil {
 				h.PrivateAddress = addr
-				log.Infof("%s: discovered %s as private address", h, addr)
+				log.WithField("host", h).Infof("discovered %s as private address", addr)
 			}
 		}
 	}
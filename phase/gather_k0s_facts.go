@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/k0sproject/dig"
@@ -30,6 +32,9 @@ type k0sstatus struct {
 type GatherK0sFacts struct {
 	GenericPhase
 	leader *cluster.Host
+
+	// configMu guards the k0s config that is read from the controllers in parallel
+	configMu sync.Mutex
 }
 
 // Title for the phase
@@ -37,10 +42,22 @@ func (p *GatherK0sFacts) Title() string {
 	return "Gather k0s facts"
 }
 
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *GatherK0sFacts) ReadOnly() bool {
+	return true
+}
+
 // Run the phase
 func (p *GatherK0sFacts) Run() error {
-	var controllers cluster.Hosts = p.Config.Spec.Hosts.Controllers()
-	if err := controllers.ParallelEach(p.investigateK0s); err != nil {
+	start := time.Now()
+	defer func() {
+		log.Debugf("gathered k0s facts from %d hosts in %s", len(p.Config.Spec.Hosts), time.Since(start))
+	}()
+
+	// the controller kept for creating the join tokens is also investigated when it was not selected
+	hosts := p.Config.Spec.ConnectHosts()
+	var controllers cluster.Hosts = hosts.Controllers()
+	if err := p.parallelDo(controllers, p.investigateK0s); err != nil {
 		return err
 	}
 	p.leader = p.Config.Spec.K0sLeader()
@@ -51,7 +68,7 @@ func (p *GatherK0sFacts) Run() error {
 	}
 
 	var workers cluster.Hosts = p.Config.Spec.Hosts.Workers()
-	if err := workers.ParallelEach(p.investigateK0s); err != nil {
+	if err := p.parallelDo(workers, p.investigateK0s); err != nil {
 		return err
 	}
 
@@ -65,19 +82,25 @@ func (p *GatherK0sFacts) investigateK0s(h *cluster.Host) error {
 	}
 
 	h.Metadata.K0sBinaryVersion = strings.TrimPrefix(output, "v")
-	log.Debugf("%s: has k0s binary version %s", h, h.Metadata.K0sBinaryVersion)
+	log.WithField("host", h).Debugf("has k0s binary version %s", h.Metadata.K0sBinaryVersion)
 
-	if h.IsController() && len(p.Config.Spec.K0s.Config) == 0 && h.Configurer.FileExist(h, h.K0sConfigPath()) {
+	if h.IsController() && p.needsExistingConfig() && h.Configurer.FileExist(h, h.K0sConfigPath()) {
 		cfg, err := h.Configurer.ReadFile(h, h.K0sConfigPath())
 		if cfg != "" && err == nil {
-			log.Infof("%s: found existing configuration", h)
-			if err := yaml.Unmarshal([]byte(cfg), &p.Config.Spec.K0s.Config); err != nil {
+			log.WithField("host", h).Info("found existing configuration")
+			p.configMu.Lock()
+			// another controller may have provided the configuration in the meantime
+			if len(p.Config.Spec.K0s.Config) == 0 {
+				err = yaml.Unmarshal([]byte(cfg), &p.Config.Spec.K0s.Config)
+			}
+			p.configMu.Unlock()
+			if err != nil {
 				return fmt.Errorf("failed to parse existing configuration: %s", err.Error())
 			}
 		}
 	}
 
-	output, err = h.ExecOutput(h.Configurer.K0sCmdf("status -o json"), exec.Sudo(h))
+	output, err = h.ExecOutput(h.K0sStatusCommand(), exec.Sudo(h))
 	if err != nil {
 		return nil
 	}
@@ -85,12 +108,12 @@ func (p *GatherK0sFacts) investigateK0s(h *cluster.Host) error {
 	status := k0sstatus{}
 
 	if err := json.Unmarshal([]byte(output), &status); err != nil {
-		log.Warnf("%s: failed to decode k0s status output: %s", h, err.Error())
+		log.WithField("host", h).Warnf("failed to decode k0s status output: %s", err.Error())
 		return nil
 	}
 
 	if status.Version == "" || status.Role == "" || status.Pid == 0 {
-		log.Debugf("%s: k0s is not running", h)
+		log.WithField("host", h).Debug("k0s is not running")
 		return nil
 	}
 
@@ -112,16 +135,16 @@ func (p *GatherK0sFacts) investigateK0s(h *cluster.Host) error {
 	h.Metadata.K0sRunningVersion = strings.TrimPrefix(status.Version, "v")
 	h.Metadata.NeedsUpgrade = p.needsUpgrade(h)
 
-	log.Infof("%s: is running k0s %s version %s", h, h.Role, h.Metadata.K0sRunningVersion)
+	log.WithField("host", h).Infof("is running k0s %s version %s", h.Role, h.Metadata.K0sRunningVersion)
 	if h.Metadata.NeedsUpgrade {
-		log.Warnf("%s: k0s will be upgraded", h)
+		log.WithField("host", h).Warn("k0s will be upgraded")
 	}
 
 	if !h.IsController() {
-		log.Infof("%s: checking if worker %s has joined", p.leader, h.Metadata.Hostname)
+		log.WithField("host", p.leader).Infof("checking if worker %s has joined", h.Metadata.Hostname)
 		ready, err := p.leader.KubeNodeReady(h)
 		if err != nil {
-			log.Debugf("%s: failed to get ready status: %s", h, err.Error())
+			log.WithField("host", h).Debugf("failed to get ready status: %s", err.Error())
 		}
 		h.Metadata.Ready = ready
 	}
@@ -129,31 +152,43 @@ func (p *GatherK0sFacts) investigateK0s(h *cluster.Host) error {
 	return nil
 }
 
+// needsExistingConfig returns true when there is no k0s config in the configuration and it should be read from the controllers
+func (p *GatherK0sFacts) needsExistingConfig() bool {
+	p.configMu.Lock()
+	defer p.configMu.Unlock()
+	return len(p.Config.Spec.K0s.Config) == 0
+}
+
 func (p *GatherK0sFacts) needsUpgrade(h *cluster.Host) bool {
 	// If supplimental files or a k0s binary have been specified explicitly,
 	// always upgrade.  This covers the scenario where a user moves from a
 	// default-install cluster to one fed by OCI image bundles (ie. airgap)
 	if len(h.Files) > 0 {
-		log.Debugf("%s: marked for upgrade because there are %d file uploads for the host", h, len(h.Files))
+		log.WithField("host", h).Debugf("marked for upgrade because there are %d file uploads for the host", len(h.Files))
 		return true
 	}
 
 	if h.K0sBinaryPath != "" {
-		log.Debugf("%s: marked for upgrade because a static k0s binary path %s", h, h.K0sBinaryPath)
+		log.WithField("host", h).Debugf("marked for upgrade because a static k0s binary path %s", h.K0sBinaryPath)
 		return true
 	}
 
-	log.Debugf("%s: checking if %s is an upgrade from %s", h, p.Config.Spec.K0s.Version, h.Metadata.K0sRunningVersion)
-	target, err := semver.NewVersion(p.Config.Spec.K0s.Version)
+	log.WithField("host", h).Debugf("checking if %s is an upgrade from %s", p.Config.Spec.K0sVersionFor(h), h.Metadata.K0sRunningVersion)
+	target, err := semver.NewVersion(p.Config.Spec.K0sVersionFor(h))
 	if err != nil {
-		log.Warnf("%s: failed to parse target version: %s", h, err.Error())
+		log.WithField("host", h).Warnf("failed to parse target version: %s", err.Error())
 		return false
 	}
 	current, err := semver.NewVersion(h.Metadata.K0sRunningVersion)
 	if err != nil {
-		log.Warnf("%s: failed to parse running version: %s", h, err.Error())
+		log.WithField("host", h).Warnf("failed to parse running version: %s", err.Error())
 		return false
 	}
 
+	if Force && target.Equal(current) {
+		log.WithField("host", h).Warnf("forcing reinstall of k0s %s because --force was given", current)
+		return true
+	}
+
 	return target.GreaterThan(current)
 }
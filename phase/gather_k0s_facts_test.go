@@ -0,0 +1,27 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNeedsUpgradeForce(t *testing.T) {
+	defer func() { Force = false }()
+
+	h := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1"}}}
+	h.Metadata.K0sRunningVersion = "1.21.2+k0s.0"
+	p := &GatherK0sFacts{GenericPhase: GenericPhase{Config: &config.Cluster{Spec: &cluster.Spec{K0s: cluster.K0s{Version: "1.21.2+k0s.0"}}}}}
+
+	require.False(t, p.needsUpgrade(h))
+
+	Force = true
+	require.True(t, p.needsUpgrade(h))
+
+	// force does not allow downgrading
+	h.Metadata.K0sRunningVersion = "1.21.3+k0s.0"
+	require.False(t, p.needsUpgrade(h))
+}
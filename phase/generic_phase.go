@@ -1,14 +1,73 @@
 package phase
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/k0sproject/k0sctl/analytics"
 	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
 )
 
 // GenericPhase is a basic phase which gets a config via prepare, sets it into p.Config
 type GenericPhase struct {
 	analytics.Phase
 	Config *config.Cluster
+
+	manager *Manager
+}
+
+// SetManager sets the phase manager that is running the phase
+func (p *GenericPhase) SetManager(m *Manager) {
+	p.manager = m
+}
+
+// Context returns the context of the manager running the phase
+func (p *GenericPhase) Context() context.Context {
+	if p.manager == nil || p.manager.ctx == nil {
+		return context.Background()
+	}
+	return p.manager.ctx
+}
+
+// parallelDo runs the functions on the hosts in parallel, honoring the manager's concurrency limit.
+// The functions are not started once the context of the manager is done.
+func (p *GenericPhase) parallelDo(hosts cluster.Hosts, funcs ...func(*cluster.Host) error) error {
+	if p.manager == nil {
+		return hosts.ParallelEach(funcs...)
+	}
+	return p.batchedDo(p.manager.Concurrency, hosts, funcs...)
+}
+
+// parallelDownloadDo is like parallelDo but for transferring the k0s binaries, the number of hosts operated on
+// at the same time is limited by the manager's ParallelDownloads instead when it is set
+func (p *GenericPhase) parallelDownloadDo(hosts cluster.Hosts, funcs ...func(*cluster.Host) error) error {
+	if p.manager == nil || p.manager.ParallelDownloads <= 0 {
+		return p.parallelDo(hosts, funcs...)
+	}
+	return p.batchedDo(p.manager.ParallelDownloads, hosts, funcs...)
+}
+
+func (p *GenericPhase) batchedDo(concurrency int, hosts cluster.Hosts, funcs ...func(*cluster.Host) error) error {
+	ctx := p.Context()
+	wrapped := make([]func(*cluster.Host) error, len(funcs))
+	for i, fn := range funcs {
+		fn := fn
+		wrapped[i] = func(h *cluster.Host) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fn(h)
+		}
+	}
+	return hosts.BatchedParallelEach(concurrency, wrapped...)
+}
+
+// DryMsgf records a change that would be made in dry-run mode
+func (p *GenericPhase) DryMsgf(host fmt.Stringer, msg string, args ...interface{}) {
+	if p.manager != nil {
+		p.manager.DryMsgf(host, msg, args...)
+	}
 }
 
 // GetConfig is an accessor to phase Config
@@ -0,0 +1,45 @@
+package phase
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func maxParallel(t *testing.T, do func(cluster.Hosts, ...func(*cluster.Host) error) error) int32 {
+	t.Helper()
+	hosts := make(cluster.Hosts, 8)
+	for i := range hosts {
+		hosts[i] = &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: fmt.Sprintf("10.0.0.%d", i+1), Port: 22}}}
+	}
+
+	var running, max int32
+	require.NoError(t, do(hosts, func(h *cluster.Host) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}))
+	return max
+}
+
+func TestParallelDownloadDo(t *testing.T) {
+	p := &GenericPhase{}
+	p.SetManager(&Manager{Concurrency: 4})
+	require.LessOrEqual(t, maxParallel(t, p.parallelDownloadDo), int32(4))
+
+	p.SetManager(&Manager{Concurrency: 4, ParallelDownloads: 2})
+	require.LessOrEqual(t, maxParallel(t, p.parallelDownloadDo), int32(2))
+	require.Greater(t, maxParallel(t, p.parallelDo), int32(2))
+}
@@ -2,14 +2,69 @@ package phase
 
 import (
 	"fmt"
+	"net/url"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
 
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-// GetKubeconfig is a phase to get and dump the admin kubeconfig
+// OIDCUser is a kubeconfig user that gets its token from an OIDC provider through the kubelogin exec
+// plugin (kubectl oidc-login) instead of authenticating with the admin client certificate
+type OIDCUser struct {
+	Name        string
+	IssuerURL   string
+	ClientID    string
+	ExtraScopes []string
+}
+
+// Validate checks that the user name and the OIDC parameters are given
+func (u *OIDCUser) Validate() error {
+	if u.Name == "" {
+		return fmt.Errorf("the user name is required")
+	}
+	if u.IssuerURL == "" {
+		return fmt.Errorf("the OIDC issuer URL is required for the user %s", u.Name)
+	}
+	issuer, err := url.Parse(u.IssuerURL)
+	if err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+		return fmt.Errorf("invalid OIDC issuer URL %q, must be an https URL", u.IssuerURL)
+	}
+	if u.ClientID == "" {
+		return fmt.Errorf("the OIDC client ID is required for the user %s", u.Name)
+	}
+	return nil
+}
+
+// authInfo returns the kubeconfig user running the kubelogin exec plugin
+func (u *OIDCUser) authInfo() *clientcmdapi.AuthInfo {
+	args := []string{"oidc-login", "get-token", "--oidc-issuer-url=" + u.IssuerURL, "--oidc-client-id=" + u.ClientID}
+	for _, scope := range u.ExtraScopes {
+		args = append(args, "--oidc-extra-scope="+scope)
+	}
+	return &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			Command:         "kubectl",
+			Args:            args,
+			InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+		},
+	}
+}
+
+// GetKubeconfig is a phase to get the admin kubeconfig, the result is stored in the cluster config metadata
 type GetKubeconfig struct {
 	GenericPhase
+	// APIAddress is the address of the kubernetes API as host[:port] or an URL, overrides spec.kubeconfig.apiAddress
 	APIAddress string
+	// ContextName is used as the cluster and context name in the kubeconfig, defaults to the cluster name
+	ContextName string
+	// CACert replaces the certificate authority data of the cluster when set, such as for connecting through
+	// a load balancer that presents its own certificate
+	CACert []byte
+	// User replaces the admin user of the kubeconfig when set, the admin client certificate is left out
+	User *OIDCUser
 }
 
 // Title for the phase
@@ -17,41 +72,67 @@ func (p *GetKubeconfig) Title() string {
 	return "Get admin kubeconfig"
 }
 
+// Sequential is true, the kubeconfig is read from the leader
+func (p *GetKubeconfig) Sequential() bool {
+	return true
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *GetKubeconfig) ReadOnly() bool {
+	return true
+}
+
 // Run the phase
 func (p *GetKubeconfig) Run() error {
-	h := p.Config.Spec.Hosts.Controllers()[0]
-	output, err := h.Configurer.ReadFile(h, h.Configurer.KubeconfigPath())
+	h := p.Config.Spec.K0sLeader()
+	output, err := h.Configurer.ReadFile(h, h.KubeconfigPath())
 	if err != nil {
 		return err
 	}
 
-	if p.APIAddress == "" {
+	port := 6443
+	if p, ok := p.Config.Spec.K0s.Config.Dig("spec", "api", "port").(int); ok {
+		port = p
+	}
+
+	address := p.APIAddress
+	if address == "" {
+		address = p.Config.Spec.Kubeconfig.APIAddress
+	}
+	if address == "" {
+		address = p.Config.Spec.K0s.APIServerAddress
+	}
+	if address == "" {
 		// the controller admin.conf is aways pointing to localhost, thus we need to change the address
 		// something usable from outside
-		address := h.Address()
+		address = h.Address()
 		if a, ok := p.Config.Spec.K0s.Config.Dig("spec", "api", "externalAddress").(string); ok {
 			address = a
 		}
+	}
 
-		port := 6443
-		if p, ok := p.Config.Spec.K0s.Config.Dig("spec", "api", "port").(int); ok {
-			port = p
-		}
+	apiURL, err := cluster.KubeconfigAPIURL(address, port)
+	if err != nil {
+		return err
+	}
 
-		p.APIAddress = fmt.Sprintf("https://%s:%d", address, port)
+	name := p.ContextName
+	if name == "" {
+		name = p.Config.Metadata.Name
 	}
 
-	cfgString, err := kubeConfig(output, p.Config.Metadata.Name, p.APIAddress)
+	cfgString, err := kubeConfig(output, name, apiURL, p.CACert, p.User)
 	if err != nil {
 		return err
 	}
-	fmt.Println(cfgString)
+	p.Config.Metadata.Kubeconfig = cfgString
 	return nil
 }
 
 // kubeConfig reads in the raw kubeconfig and changes the given address
-// and cluster name into it
-func kubeConfig(raw string, name string, address string) (string, error) {
+// and cluster name into it, the certificate authority data is replaced when caCert is not empty and
+// the admin user is replaced when user is not nil
+func kubeConfig(raw string, name string, address string, caCert []byte, user *OIDCUser) (string, error) {
 	cfg, err := clientcmd.Load([]byte(raw))
 	if err != nil {
 		return "", err
@@ -60,6 +141,9 @@ func kubeConfig(raw string, name string, address string) (string, error) {
 	cfg.Clusters[name] = cfg.Clusters["local"]
 	delete(cfg.Clusters, "local")
 	cfg.Clusters[name].Server = address
+	if len(caCert) > 0 {
+		cfg.Clusters[name].CertificateAuthorityData = caCert
+	}
 
 	cfg.Contexts[name] = cfg.Contexts["Default"]
 	delete(cfg.Contexts, "Default")
@@ -68,8 +152,13 @@ func kubeConfig(raw string, name string, address string) (string, error) {
 
 	cfg.CurrentContext = name
 
-	cfg.AuthInfos["admin"] = cfg.AuthInfos["user"]
-	delete(cfg.AuthInfos, "user")
+	if user != nil {
+		cfg.AuthInfos = map[string]*clientcmdapi.AuthInfo{user.Name: user.authInfo()}
+		cfg.Contexts[name].AuthInfo = user.Name
+	} else {
+		cfg.AuthInfos["admin"] = cfg.AuthInfos["user"]
+		delete(cfg.AuthInfos, "user")
+	}
 
 	out, err := clientcmd.Write(*cfg)
 	if err != nil {
@@ -0,0 +1,73 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestKubeConfig(t *testing.T) {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["local"] = &clientcmdapi.Cluster{Server: "https://localhost:6443", CertificateAuthorityData: []byte("cluster-ca")}
+	cfg.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	cfg.Contexts["Default"] = &clientcmdapi.Context{Cluster: "local", AuthInfo: "user"}
+	cfg.CurrentContext = "Default"
+	raw, err := clientcmd.Write(*cfg)
+	require.NoError(t, err)
+
+	out, err := kubeConfig(string(raw), "prod", "https://10.0.0.1:6443", nil, nil)
+	require.NoError(t, err)
+	res, err := clientcmd.Load([]byte(out))
+	require.NoError(t, err)
+	require.Equal(t, "https://10.0.0.1:6443", res.Clusters["prod"].Server)
+	require.Equal(t, []byte("cluster-ca"), res.Clusters["prod"].CertificateAuthorityData)
+	require.Equal(t, "admin", res.Contexts["prod"].AuthInfo)
+	require.Equal(t, "prod", res.CurrentContext)
+
+	out, err = kubeConfig(string(raw), "prod", "https://lb.example.com:6443", []byte("lb-ca"), nil)
+	require.NoError(t, err)
+	res, err = clientcmd.Load([]byte(out))
+	require.NoError(t, err)
+	require.Equal(t, "https://lb.example.com:6443", res.Clusters["prod"].Server)
+	require.Equal(t, []byte("lb-ca"), res.Clusters["prod"].CertificateAuthorityData)
+}
+
+func TestKubeConfigOIDCUser(t *testing.T) {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["local"] = &clientcmdapi.Cluster{Server: "https://localhost:6443", CertificateAuthorityData: []byte("cluster-ca")}
+	cfg.AuthInfos["user"] = &clientcmdapi.AuthInfo{ClientCertificateData: []byte("admin-cert"), ClientKeyData: []byte("admin-key")}
+	cfg.Contexts["Default"] = &clientcmdapi.Context{Cluster: "local", AuthInfo: "user"}
+	cfg.CurrentContext = "Default"
+	raw, err := clientcmd.Write(*cfg)
+	require.NoError(t, err)
+
+	user := &OIDCUser{Name: "alice", IssuerURL: "https://sso.example.com", ClientID: "k0s", ExtraScopes: []string{"email", "groups"}}
+	out, err := kubeConfig(string(raw), "prod", "https://10.0.0.1:6443", nil, user)
+	require.NoError(t, err)
+	require.NotContains(t, out, "client-certificate-data")
+	require.NotContains(t, out, "client-key-data")
+
+	res, err := clientcmd.Load([]byte(out))
+	require.NoError(t, err)
+	require.Equal(t, []byte("cluster-ca"), res.Clusters["prod"].CertificateAuthorityData)
+	require.Equal(t, "alice", res.Contexts["prod"].AuthInfo)
+	require.Len(t, res.AuthInfos, 1)
+	exec := res.AuthInfos["alice"].Exec
+	require.NotNil(t, exec)
+	require.Equal(t, "kubectl", exec.Command)
+	require.Equal(t, []string{"oidc-login", "get-token", "--oidc-issuer-url=https://sso.example.com", "--oidc-client-id=k0s", "--oidc-extra-scope=email", "--oidc-extra-scope=groups"}, exec.Args)
+}
+
+func TestOIDCUserValidate(t *testing.T) {
+	require.NoError(t, (&OIDCUser{Name: "alice", IssuerURL: "https://sso.example.com", ClientID: "k0s"}).Validate())
+
+	err := (&OIDCUser{Name: "alice", IssuerURL: "http://sso.example.com", ClientID: "k0s"}).Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must be an https URL")
+
+	err = (&OIDCUser{Name: "alice", IssuerURL: "https://sso.example.com"}).Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "the OIDC client ID is required")
+}
@@ -17,6 +17,11 @@ func (p *InitializeK0s) Title() string {
 	return "Initialize the k0s cluster"
 }
 
+// Sequential is true, the phase only installs k0s on the leader
+func (p *InitializeK0s) Sequential() bool {
+	return true
+}
+
 // Prepare the phase
 func (p *InitializeK0s) Prepare(config *config.Cluster) error {
 	p.Config = config
@@ -32,12 +37,18 @@ func (p *InitializeK0s) ShouldRun() bool {
 	return p.leader != nil
 }
 
+// DryRun reports the controller that would be used to initialize the cluster
+func (p *InitializeK0s) DryRun() error {
+	p.DryMsgf(p.leader, "install k0s %s controller and initialize the cluster", p.Config.Spec.K0sVersionFor(p.leader))
+	return nil
+}
+
 // CleanUp cleans up the environment override file
 func (p *InitializeK0s) CleanUp() {
 	h := p.leader
-	if len(h.Environment) > 0 {
+	if len(h.EnvironmentVars()) > 0 {
 		if err := h.Configurer.CleanupServiceEnvironment(h, h.K0sServiceName()); err != nil {
-			log.Warnf("%s: failed to clean up service environment: %s", h, err.Error())
+			log.WithField("host", h).Warnf("failed to clean up service environment: %s", err.Error())
 		}
 	}
 }
@@ -47,23 +58,27 @@ func (p *InitializeK0s) Run() error {
 	h := p.leader
 	h.Metadata.IsK0sLeader = true
 
-	log.Infof("%s: installing k0s controller", h)
+	log.WithField("host", h).Info("installing k0s controller")
 	if err := h.Exec(h.K0sInstallCommand()); err != nil {
 		return err
 	}
 
-	if len(h.Environment) > 0 {
-		log.Infof("%s: updating service environment", h)
-		if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.Environment); err != nil {
+	if len(h.EnvironmentVars()) > 0 {
+		log.WithField("host", h).Info("updating service environment")
+		if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.EnvironmentVars()); err != nil {
 			return err
 		}
 	}
 
+	if err := updateServiceOverrides(h); err != nil {
+		return err
+	}
+
 	if err := h.Configurer.StartService(h, h.K0sServiceName()); err != nil {
 		return err
 	}
 
-	log.Infof("%s: waiting for the k0s service to start", h)
+	log.WithField("host", h).Info("waiting for the k0s service to start")
 	if err := h.WaitK0sServiceRunning(); err != nil {
 		return err
 	}
@@ -72,13 +87,13 @@ func (p *InitializeK0s) Run() error {
 	if p, ok := p.Config.Spec.K0s.Config.Dig("spec", "api", "port").(int); ok {
 		port = p
 	}
-	log.Infof("%s: waiting for kubernetes api to respond", h)
+	log.WithField("host", h).Info("waiting for kubernetes api to respond")
 	if err := h.WaitKubeAPIReady(port); err != nil {
 		return err
 	}
 
-	h.Metadata.K0sRunningVersion = p.Config.Spec.K0s.Version
-	h.Metadata.K0sBinaryVersion = p.Config.Spec.K0s.Version
+	h.Metadata.K0sRunningVersion = p.Config.Spec.K0sVersionFor(h)
+	h.Metadata.K0sBinaryVersion = p.Config.Spec.K0sVersionFor(h)
 
 	if id, err := p.Config.Spec.K0s.GetClusterID(h); err == nil {
 		p.Config.Spec.K0s.Metadata.ClusterID = id
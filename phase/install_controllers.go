@@ -21,6 +21,11 @@ func (p *InstallControllers) Title() string {
 	return "Install controllers"
 }
 
+// Sequential is true, the controllers are joined one at a time
+func (p *InstallControllers) Sequential() bool {
+	return true
+}
+
 // Prepare the phase
 func (p *InstallControllers) Prepare(config *config.Cluster) error {
 	p.Config = config
@@ -38,12 +43,20 @@ func (p *InstallControllers) ShouldRun() bool {
 	return len(p.hosts) > 0
 }
 
+// DryRun reports the controllers that would be installed
+func (p *InstallControllers) DryRun() error {
+	for _, h := range p.hosts {
+		p.DryMsgf(h, "install k0s %s controller", p.Config.Spec.K0sVersionFor(h))
+	}
+	return nil
+}
+
 // CleanUp cleans up the environment override files on hosts
 func (p *InstallControllers) CleanUp() {
 	for _, h := range p.hosts {
-		if len(h.Environment) > 0 {
+		if len(h.EnvironmentVars()) > 0 {
 			if err := h.Configurer.CleanupServiceEnvironment(h, h.K0sServiceName()); err != nil {
-				log.Warnf("%s: failed to clean up service environment: %s", h, err.Error())
+				log.WithField("host", h).Warnf("failed to clean up service environment: %s", err.Error())
 			}
 		}
 	}
@@ -52,7 +65,7 @@ func (p *InstallControllers) CleanUp() {
 // Run the phase
 func (p *InstallControllers) Run() error {
 	for _, h := range p.hosts {
-		log.Infof("%s: generating token", p.leader)
+		log.WithField("host", p.leader).Info("generating token")
 		token, err := p.Config.Spec.K0s.GenerateToken(
 			p.leader,
 			"controller",
@@ -65,42 +78,46 @@ func (p *InstallControllers) Run() error {
 		if err != nil {
 			return err
 		}
-		log.Debugf("%s: join token ID: %s", p.leader, tokenID)
+		log.WithField("host", p.leader).Debugf("join token ID: %s", tokenID)
 		defer func() {
 			if err := p.leader.Exec(p.leader.Configurer.K0sCmdf("token invalidate %s", tokenID), exec.Sudo(p.leader), exec.RedactString(token)); err != nil {
-				log.Warnf("%s: failed to invalidate the controller join token", p.leader)
+				log.WithField("host", p.leader).Warn("failed to invalidate the controller join token")
 			}
 		}()
 
-		log.Infof("%s: writing join token", h)
+		log.WithField("host", h).Info("writing join token")
 		if err := h.Configurer.WriteFile(h, h.K0sJoinTokenPath(), token, "0640"); err != nil {
 			return err
 		}
 
 		defer func() {
 			if err := h.Configurer.DeleteFile(h, h.K0sJoinTokenPath()); err != nil {
-				log.Warnf("%s: failed to clean up the join token file at %s", h, h.K0sJoinTokenPath())
+				log.WithField("host", h).Warnf("failed to clean up the join token file at %s", h.K0sJoinTokenPath())
 			}
 		}()
 
-		log.Infof("%s: installing k0s controller", h)
+		log.WithField("host", h).Info("installing k0s controller")
 		if err := h.Exec(h.K0sInstallCommand()); err != nil {
 			return err
 		}
 
-		if len(h.Environment) > 0 {
-			log.Infof("%s: updating service environment", h)
-			if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.Environment); err != nil {
+		if len(h.EnvironmentVars()) > 0 {
+			log.WithField("host", h).Info("updating service environment")
+			if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.EnvironmentVars()); err != nil {
 				return err
 			}
 		}
 
-		log.Infof("%s: starting service", h)
+		if err := updateServiceOverrides(h); err != nil {
+			return err
+		}
+
+		log.WithField("host", h).Info("starting service")
 		if err := h.Configurer.StartService(h, h.K0sServiceName()); err != nil {
 			return err
 		}
 
-		log.Infof("%s: waiting for the k0s service to start", h)
+		log.WithField("host", h).Info("waiting for the k0s service to start")
 		if err := h.WaitK0sServiceRunning(); err != nil {
 			return err
 		}
@@ -119,6 +136,6 @@ func (p *InstallControllers) waitJoined(h *cluster.Host) error {
 		port = p
 	}
 
-	log.Infof("%s: waiting for kubernetes api to respond", h)
+	log.WithField("host", h).Info("waiting for kubernetes api to respond")
 	return h.WaitKubeAPIReady(port)
 }
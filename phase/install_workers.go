@@ -39,12 +39,20 @@ func (p *InstallWorkers) ShouldRun() bool {
 	return len(p.hosts) > 0
 }
 
+// DryRun reports the workers that would be installed
+func (p *InstallWorkers) DryRun() error {
+	for _, h := range p.hosts {
+		p.DryMsgf(h, "install k0s %s worker", p.Config.Spec.K0sVersionFor(h))
+	}
+	return nil
+}
+
 // CleanUp cleans up the environment override files on hosts
 func (p *InstallWorkers) CleanUp() {
 	for _, h := range p.hosts {
-		if len(h.Environment) > 0 {
+		if len(h.EnvironmentVars()) > 0 {
 			if err := h.Configurer.CleanupServiceEnvironment(h, h.K0sServiceName()); err != nil {
-				log.Warnf("%s: failed to clean up service environment: %s", h, err.Error())
+				log.WithField("host", h).Warnf("failed to clean up service environment: %s", err.Error())
 			}
 		}
 	}
@@ -52,11 +60,14 @@ func (p *InstallWorkers) CleanUp() {
 
 // Run the phase
 func (p *InstallWorkers) Run() error {
-	url := p.Config.Spec.KubeAPIURL()
+	url := p.Config.Spec.K0s.APIServerURL()
+	if url == "" {
+		url = p.Config.Spec.KubeAPIURL()
+	}
 	healthz := fmt.Sprintf("%s/healthz", url)
 
-	err := p.hosts.ParallelEach(func(h *cluster.Host) error {
-		log.Infof("%s: validating api connection to %s", h, url)
+	err := p.parallelDo(p.hosts, func(h *cluster.Host) error {
+		log.WithField("host", h).Infof("validating api connection to %s", url)
 		if err := h.WaitHTTPStatus(healthz, 200, 401); err != nil {
 			return fmt.Errorf("failed to connect from worker to kubernetes api at %s - check networking", url)
 		}
@@ -67,7 +78,7 @@ func (p *InstallWorkers) Run() error {
 		return err
 	}
 
-	log.Infof("%s: generating token", p.leader)
+	log.WithField("host", p.leader).Info("generating token")
 	token, err := p.Config.Spec.K0s.GenerateToken(
 		p.leader,
 		"worker",
@@ -81,18 +92,26 @@ func (p *InstallWorkers) Run() error {
 	if err != nil {
 		return err
 	}
-	log.Debugf("%s: join token ID: %s", p.leader, tokenID)
+	log.WithField("host", p.leader).Debugf("join token ID: %s", tokenID)
+
+	if lb := p.Config.Spec.K0s.APIServerURL(); lb != "" {
+		log.WithField("host", p.leader).Debugf("setting the join token api address to %s", lb)
+		token, err = cluster.TokenWithServer(token, lb)
+		if err != nil {
+			return err
+		}
+	}
 
 	if !NoWait {
 		defer func() {
 			if err := p.leader.Exec(p.leader.Configurer.K0sCmdf("token invalidate %s", tokenID), exec.Sudo(p.leader), exec.RedactString(token)); err != nil {
-				log.Warnf("%s: failed to invalidate the worker join token", p.leader)
+				log.WithField("host", p.leader).Warn("failed to invalidate the worker join token")
 			}
 		}()
 	}
 
-	return p.hosts.ParallelEach(func(h *cluster.Host) error {
-		log.Infof("%s: writing join token", h)
+	return p.parallelDo(p.hosts, func(h *cluster.Host) error {
+		log.WithField("host", h).Info("writing join token")
 		if err := h.Configurer.WriteFile(h, h.K0sJoinTokenPath(), token, "0640"); err != nil {
 			return err
 		}
@@ -100,14 +119,14 @@ func (p *InstallWorkers) Run() error {
 		if !NoWait {
 			defer func() {
 				if err := h.Configurer.DeleteFile(h, h.K0sJoinTokenPath()); err != nil {
-					log.Warnf("%s: failed to clean up the join token file at %s", h, h.K0sJoinTokenPath())
+					log.WithField("host", h).Warnf("failed to clean up the join token file at %s", h.K0sJoinTokenPath())
 				}
 			}()
 		}
 
 		if sp, err := h.Configurer.ServiceScriptPath(h, h.K0sServiceName()); err == nil {
 			if h.Configurer.ServiceIsRunning(h, h.K0sServiceName()) {
-				log.Infof("%s: stopping service", h)
+				log.WithField("host", h).Info("stopping service")
 				if err := h.Configurer.StopService(h, h.K0sServiceName()); err != nil {
 					return err
 				}
@@ -120,34 +139,41 @@ func (p *InstallWorkers) Run() error {
 			}
 		}
 
-		log.Infof("%s: installing k0s worker", h)
+		log.WithField("host", h).Info("installing k0s worker")
 		if err := h.Exec(h.K0sInstallCommand()); err != nil {
 			return err
 		}
 
-		if len(h.Environment) > 0 {
-			log.Infof("%s: updating service environment", h)
-			if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.Environment); err != nil {
+		if len(h.EnvironmentVars()) > 0 {
+			log.WithField("host", h).Info("updating service environment")
+			if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.EnvironmentVars()); err != nil {
 				return err
 			}
 		}
 
-		log.Infof("%s: starting service", h)
+		if err := updateServiceOverrides(h); err != nil {
+			return err
+		}
+
+		log.WithField("host", h).Info("starting service")
 		if err := h.Configurer.StartService(h, h.K0sServiceName()); err != nil {
 			return err
 		}
 
 		if NoWait {
-			log.Debugf("%s: not waiting because --no-wait given", h)
+			log.WithField("host", h).Info("waiting for the k0s service to start, not waiting for the node to become ready because --no-wait given")
+			if err := h.WaitK0sServiceRunning(); err != nil {
+				return err
+			}
 		} else {
-			log.Infof("%s: waiting for node to become ready", h)
+			log.WithField("host", h).Info("waiting for node to become ready")
 			if err := p.Config.Spec.K0sLeader().WaitKubeNodeReady(h); err != nil {
 				return err
 			}
 			h.Metadata.Ready = true
 		}
 
-		h.Metadata.K0sRunningVersion = p.Config.Spec.K0s.Version
+		h.Metadata.K0sRunningVersion = p.Config.Spec.K0sVersionFor(h)
 
 		return nil
 	})
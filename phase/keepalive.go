@@ -0,0 +1,109 @@
+package phase
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHKeepAlive is the default interval of the ssh connection keepalive requests, zero disables the keepalives
+var SSHKeepAlive = 30 * time.Second
+
+const keepAliveRequest = "keepalive@openssh.com"
+
+// lostConnections holds the errors of the ssh connections that stopped answering the keepalive requests
+var lostConnections sync.Map
+
+type keepAliveClient interface {
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+	Close() error
+}
+
+// sshClient returns the client of a connected rig ssh connection. The connection does not expose the
+// client, so it is read from the unexported field.
+func sshClient(c *rig.SSH) *ssh.Client {
+	f := reflect.ValueOf(c).Elem().FieldByName("client")
+	if !f.IsValid() || f.Type() != reflect.TypeOf((*ssh.Client)(nil)) || f.IsNil() {
+		return nil
+	}
+	return (*ssh.Client)(unsafe.Pointer(f.Pointer()))
+}
+
+// startKeepAlive starts sending keepalive requests over the host ssh connection
+func startKeepAlive(h *cluster.Host) {
+	if h.SSH == nil {
+		return
+	}
+	interval := h.SSHKeepAlive.IntervalOr(SSHKeepAlive)
+	if interval <= 0 {
+		return
+	}
+	client := sshClient(h.SSH)
+	if client == nil {
+		log.WithField("host", h).Debug("ssh client not available, keepalives disabled")
+		return
+	}
+	lostConnections.Delete(h)
+	go keepAlive(h, client, interval, h.SSHKeepAlive.Count())
+}
+
+// keepAlive sends a keepalive request every interval until the connection is closed. The connection is
+// considered dead and is closed when countMax requests in a row are not answered within the interval.
+func keepAlive(h *cluster.Host, client keepAliveClient, interval time.Duration, countMax int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var missed int
+	for range ticker.C {
+		reply := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest(keepAliveRequest, true, nil)
+			reply <- err
+		}()
+
+		select {
+		case err := <-reply:
+			if err != nil {
+				// the connection has been closed
+				return
+			}
+			missed = 0
+		case <-time.After(interval):
+			missed++
+			log.WithField("host", h).Debugf("ssh keepalive %d/%d not answered", missed, countMax)
+			if missed >= countMax {
+				err := fmt.Errorf("no response to %d keepalive requests in %s", missed, time.Duration(missed)*interval)
+				log.WithField("host", h).Errorf("ssh connection lost: %s", err.Error())
+				lostConnections.Store(h, err)
+				// closing the connection makes the commands running on the host return
+				_ = client.Close()
+				return
+			}
+		}
+	}
+}
+
+// resetLostConnections forgets the previously lost connections
+func resetLostConnections() {
+	lostConnections.Range(func(k, _ interface{}) bool {
+		lostConnections.Delete(k)
+		return true
+	})
+}
+
+// connectionLostError adds the host that lost its ssh connection to the error of a failed phase
+func connectionLostError(hosts cluster.Hosts, title string, err error) error {
+	for _, h := range hosts {
+		if lost, ok := lostConnections.Load(h); ok {
+			return fmt.Errorf("ssh connection to %s was lost during phase '%s' (%s): %w", h, title, lost, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package phase
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+type fakeKeepAliveClient struct {
+	requests int32
+	closed   int32
+	reply    func() error
+}
+
+func (c *fakeKeepAliveClient) SendRequest(name string, _ bool, _ []byte) (bool, []byte, error) {
+	atomic.AddInt32(&c.requests, 1)
+	if name != keepAliveRequest {
+		return false, nil, errors.New("unexpected request")
+	}
+	return false, nil, c.reply()
+}
+
+func (c *fakeKeepAliveClient) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func TestSSHClientField(t *testing.T) {
+	// sshClient depends on the unexported field of the rig ssh connection
+	f, ok := reflect.TypeOf(rig.SSH{}).FieldByName("client")
+	require.True(t, ok)
+	require.Equal(t, reflect.TypeOf((*ssh.Client)(nil)), f.Type)
+	require.Nil(t, sshClient(&rig.SSH{}))
+}
+
+func TestKeepAlive(t *testing.T) {
+	t.Run("lost connection", func(t *testing.T) {
+		h := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+		block := make(chan struct{})
+		defer close(block)
+		client := &fakeKeepAliveClient{reply: func() error { <-block; return nil }}
+
+		keepAlive(h, client, 5*time.Millisecond, 2)
+		require.Equal(t, int32(1), atomic.LoadInt32(&client.closed))
+		lost, ok := lostConnections.Load(h)
+		require.True(t, ok)
+		require.Contains(t, lost.(error).Error(), "no response to 2 keepalive requests")
+		resetLostConnections()
+	})
+
+	t.Run("closed connection", func(t *testing.T) {
+		h := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2", Port: 22}}}
+		var answered int32
+		client := &fakeKeepAliveClient{reply: func() error {
+			if atomic.AddInt32(&answered, 1) > 3 {
+				return errors.New("EOF")
+			}
+			return nil
+		}}
+
+		keepAlive(h, client, time.Millisecond, 1)
+		require.Equal(t, int32(4), atomic.LoadInt32(&client.requests))
+		require.Equal(t, int32(0), atomic.LoadInt32(&client.closed))
+		_, ok := lostConnections.Load(h)
+		require.False(t, ok)
+	})
+}
+
+func TestConnectionLostError(t *testing.T) {
+	h1 := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+	h2 := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2", Port: 22}}}
+	phaseErr := errors.New("EOF")
+
+	require.Equal(t, phaseErr, connectionLostError(cluster.Hosts{h1, h2}, "Upload files", phaseErr))
+
+	lostConnections.Store(h2, errors.New("no response to 3 keepalive requests in 1m30s"))
+	defer resetLostConnections()
+
+	err := connectionLostError(cluster.Hosts{h1, h2}, "Upload files", phaseErr)
+	require.True(t, errors.Is(err, phaseErr))
+	require.Equal(t, "ssh connection to [ssh] 10.0.0.2:22 was lost during phase 'Upload files' (no response to 3 keepalive requests in 1m30s): EOF", err.Error())
+}
@@ -0,0 +1,91 @@
+package phase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	log "github.com/sirupsen/logrus"
+)
+
+// localHooksFor returns the local hooks configured for the stage of the phase, the phase titles are
+// matched case-insensitively
+func (m *Manager) localHooksFor(title, stage string) []cluster.Hook {
+	if m.Config == nil || m.Config.Spec == nil {
+		return nil
+	}
+	var hooks []cluster.Hook
+	for name, stages := range m.Config.Spec.LocalHooks {
+		if strings.EqualFold(strings.TrimSpace(name), title) {
+			hooks = append(hooks, stages[stage]...)
+		}
+	}
+	return hooks
+}
+
+// localHookEnv returns the environment for the local hook processes
+func (m *Manager) localHookEnv(title, stage string) []string {
+	env := append(os.Environ(), "K0SCTL_PHASE="+title, "K0SCTL_HOOK_STAGE="+stage)
+	if m.Config.Metadata != nil {
+		env = append(env, "K0SCTL_CLUSTER_NAME="+m.Config.Metadata.Name)
+	}
+	if id := m.Config.Spec.K0s.Metadata.ClusterID; id != "" {
+		env = append(env, "K0SCTL_CLUSTER_ID="+id)
+	}
+	return env
+}
+
+// runLocalHooks runs the local hooks of the stage of the phase on the machine running k0sctl. The output
+// of the commands is logged.
+func (m *Manager) runLocalHooks(title, stage string) error {
+	hooks := m.localHooksFor(title, stage)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for _, hook := range hooks {
+		entry := log.WithField("hook", "local")
+		entry.Infof("running local %s hook for phase '%s': %s", stage, title, hook.Cmd)
+		if err := runLocalCommand(ctx, hook.Cmd, m.localHookEnv(title, stage), entry); err != nil {
+			if hook.IgnoreErrors {
+				entry.Warnf("local %s hook for phase '%s' failed, ignoring: %s", stage, title, err.Error())
+				continue
+			}
+			return fmt.Errorf("local %s hook for phase '%s' failed: %w", stage, title, err)
+		}
+	}
+	return nil
+}
+
+// dryRunLocalHooks reports the local hooks of the stage of the phase instead of running them
+func (m *Manager) dryRunLocalHooks(title, stage string) {
+	for _, hook := range m.localHooksFor(title, stage) {
+		m.DryMsgf(nil, "run local %s hook for phase '%s': %s", stage, title, hook.Cmd)
+	}
+}
+
+// runLocalCommand runs the command with the system shell and logs the stdout and stderr lines
+func runLocalCommand(ctx context.Context, command string, env []string, entry *log.Entry) error {
+	var cmd *osexec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = osexec.CommandContext(ctx, "cmd.exe", "/c", command)
+	} else {
+		cmd = osexec.CommandContext(ctx, "sh", "-c", command)
+	}
+	out := &logWriter{log: entry}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = env
+	err := cmd.Run()
+	out.Flush()
+	return err
+}
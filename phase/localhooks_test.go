@@ -0,0 +1,78 @@
+package phase
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerLocalHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook commands need sh")
+	}
+
+	out := filepath.Join(t.TempDir(), "hooks.log")
+	c := &config.Cluster{
+		Metadata: &config.ClusterMetadata{Name: "test-cluster"},
+		Spec: &cluster.Spec{LocalHooks: cluster.Hooks{
+			"Config Phase": {
+				"before": {{Cmd: `echo "before $K0SCTL_PHASE $K0SCTL_HOOK_STAGE $K0SCTL_CLUSTER_NAME" >> ` + out}},
+				"after": {
+					{Cmd: "exit 3", IgnoreErrors: true},
+					{Cmd: `echo "after $K0SCTL_PHASE" >> ` + out},
+				},
+			},
+		}},
+	}
+
+	m := Manager{Config: c}
+	m.AddPhase(&configPhase{})
+	require.NoError(t, m.Run())
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "before config phase before test-cluster\nafter config phase\n", string(data))
+
+	t.Run("failing hook", func(t *testing.T) {
+		c.Spec.LocalHooks["Config Phase"]["after"] = []cluster.Hook{{Cmd: "exit 3"}}
+		m := Manager{Config: c}
+		m.AddPhase(&configPhase{}, &conditionalPhase{})
+		err := m.Run()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "local after hook for phase 'config phase' failed")
+		require.Len(t, m.Results, 1)
+		require.Equal(t, err, m.Results[0].Err)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		m := Manager{Config: c, DryRun: true}
+		m.AddPhase(&configPhase{})
+		require.NoError(t, m.Run())
+		require.Contains(t, m.DryMessages(), "run local after hook for phase 'config phase': exit 3")
+	})
+
+	t.Run("dry run read-only phase", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "ran")
+		c := &config.Cluster{Spec: &cluster.Spec{LocalHooks: cluster.Hooks{
+			"dry-run phase": {
+				"before": {{Cmd: "touch " + marker}},
+				"after":  {{Cmd: "touch " + marker}},
+			},
+		}}}
+		m := Manager{Config: c, DryRun: true}
+		ro := &dryRunPhase{readOnly: true}
+		m.AddPhase(ro)
+		require.NoError(t, m.Run())
+		require.True(t, ro.runCalled)
+		require.NoFileExists(t, marker, "local hooks were run in dry-run mode")
+		require.Equal(t, []string{
+			"run local before hook for phase 'dry-run phase': touch " + marker,
+			"run local after hook for phase 'dry-run phase': touch " + marker,
+		}, m.DryMessages())
+	})
+}
@@ -1,15 +1,29 @@
 package phase
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
 	"github.com/logrusorgru/aurora"
 	log "github.com/sirupsen/logrus"
 )
 
 // NoWait is used by various phases to decide if node ready state should be waited for or not
 var NoWait bool
+
+// Force is used by the install phases to reinstall k0s even when the desired version is already installed
+var Force bool
 var Colorize = aurora.NewAurora(false)
 
+// cancelGracePeriod is how long an interrupted phase is waited for to return after the hosts have been disconnected
+var cancelGracePeriod = 10 * time.Second
+
 type phase interface {
 	Run() error
 	Title() string
@@ -41,10 +55,185 @@ type withcleanup interface {
 	CleanUp()
 }
 
+type withmanager interface {
+	SetManager(*Manager)
+}
+
+// readonly phases do not make changes to the hosts and are run also in dry-run mode
+type readonly interface {
+	ReadOnly() bool
+}
+
+// mandatory phases are always run, they can't be excluded with SkipPhases or OnlyPhases
+type mandatory interface {
+	Mandatory() bool
+}
+
+// uninterruptible phases are not interrupted by disconnecting from the hosts when the run is cancelled,
+// the manager waits for them to return instead. They are expected to stop at a safe point, such as
+// between hosts, when the context is done.
+type uninterruptible interface {
+	Uninterruptible() bool
+}
+
+func isUninterruptible(p phase) bool {
+	u, ok := p.(uninterruptible)
+	return ok && u.Uninterruptible()
+}
+
+// sequential phases operate on one host at a time or only on the leader, the Concurrency limit does not apply to them
+type sequential interface {
+	Sequential() bool
+}
+
+func isSequential(p phase) bool {
+	s, ok := p.(sequential)
+	return ok && s.Sequential()
+}
+
+// dryrunner phases can report the changes they would make in dry-run mode
+type dryrunner interface {
+	DryRun() error
+}
+
+// Result describes the outcome of a single phase
+type Result struct {
+	Title    string
+	Duration time.Duration
+	Skipped  bool
+	Err      error
+}
+
 // Manager executes phases to construct the cluster
 type Manager struct {
 	phases []phase
 	Config *config.Cluster
+
+	// DryRun makes the manager skip the phases that are not read-only. Phases that implement DryRun() are asked to report the changes they would make.
+	DryRun bool
+
+	// Concurrency limits the number of hosts operated on at the same time in the phases that run in parallel, 0 means no limit
+	Concurrency int
+
+	// ParallelDownloads limits the number of hosts downloading or uploading the k0s binary at the same time, 0 means the Concurrency limit is used
+	ParallelDownloads int
+
+	// Results holds the outcome of each of the phases processed during Run
+	Results []Result
+
+	// SkipPhases are the titles of phases that are not run
+	SkipPhases []string
+	// OnlyPhases are the titles of the phases to run, when set the other phases are skipped except for the mandatory ones
+	OnlyPhases []string
+
+	// Audit is called for each of the commands run and files uploaded on the hosts during the phases that make
+	// changes to the hosts and their clean-ups
+	Audit func(phase string, h *cluster.Host, cmd string, err error)
+
+	dryMessages []string
+	dryMu       sync.Mutex
+
+	ctx context.Context
+}
+
+// DryMsgf records a change that would be made on a host in dry-run mode. The host can be nil for changes that are not host specific.
+func (m *Manager) DryMsgf(host fmt.Stringer, msg string, args ...interface{}) {
+	text := fmt.Sprintf(msg, args...)
+	if host != nil {
+		text = fmt.Sprintf("%s: %s", host, text)
+	}
+	m.dryMu.Lock()
+	m.dryMessages = append(m.dryMessages, text)
+	m.dryMu.Unlock()
+}
+
+// DryMessages returns the changes recorded in dry-run mode
+func (m *Manager) DryMessages() []string {
+	m.dryMu.Lock()
+	defer m.dryMu.Unlock()
+	return append([]string{}, m.dryMessages...)
+}
+
+// PhaseInfo describes a phase added to the manager
+type PhaseInfo struct {
+	Title     string
+	ReadOnly  bool
+	Mandatory bool
+	Parallel  bool
+}
+
+// Phases returns the descriptions of the added phases in the order they are run
+func (m *Manager) Phases() []PhaseInfo {
+	infos := make([]PhaseInfo, len(m.phases))
+	for i, p := range m.phases {
+		infos[i] = PhaseInfo{Title: p.Title(), ReadOnly: isReadOnly(p), Mandatory: isMandatory(p), Parallel: !isSequential(p)}
+	}
+	return infos
+}
+
+func isReadOnly(p phase) bool {
+	r, ok := p.(readonly)
+	return ok && r.ReadOnly()
+}
+
+func isMandatory(p phase) bool {
+	r, ok := p.(mandatory)
+	return ok && r.Mandatory()
+}
+
+// validatePhaseSelection checks that the SkipPhases and OnlyPhases refer to the titles of the added phases
+func (m *Manager) validatePhaseSelection() error {
+	if len(m.SkipPhases) > 0 && len(m.OnlyPhases) > 0 {
+		return fmt.Errorf("skip phases and only phases can not be used together")
+	}
+
+	titles := make([]string, 0, len(m.phases))
+	for _, p := range m.phases {
+		titles = append(titles, p.Title())
+	}
+
+	for _, name := range append(m.SkipPhases, m.OnlyPhases...) {
+		p := m.findPhase(name)
+		if p == nil {
+			return fmt.Errorf("unknown phase %q, valid phases are: %s", name, strings.Join(titles, ", "))
+		}
+		if isMandatory(p) && len(m.SkipPhases) > 0 {
+			return fmt.Errorf("phase %q can not be skipped", p.Title())
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) findPhase(name string) phase {
+	name = strings.TrimSpace(name)
+	for _, p := range m.phases {
+		if strings.EqualFold(p.Title(), name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// selected returns false when the phase has been excluded with SkipPhases or OnlyPhases
+func (m *Manager) selected(p phase) bool {
+	if isMandatory(p) {
+		return true
+	}
+	for _, name := range m.SkipPhases {
+		if m.findPhase(name) == p {
+			return false
+		}
+	}
+	if len(m.OnlyPhases) == 0 {
+		return true
+	}
+	for _, name := range m.OnlyPhases {
+		if m.findPhase(name) == p {
+			return true
+		}
+	}
+	return false
 }
 
 // AddPhase adds a Phase to Manager
@@ -54,6 +243,18 @@ func (m *Manager) AddPhase(p ...phase) {
 
 // Run executes all the added Phases in order
 func (m *Manager) Run() error {
+	return m.RunContext(context.Background())
+}
+
+// RunContext executes all the added phases in order. When the context is cancelled or its deadline
+// expires, the running phase is interrupted by disconnecting from the hosts and an error naming the
+// phase is returned.
+func (m *Manager) RunContext(ctx context.Context) error {
+	m.ctx = ctx
+	if err := m.validatePhaseSelection(); err != nil {
+		return err
+	}
+
 	var ran []phase
 	var result error
 
@@ -62,7 +263,9 @@ func (m *Manager) Run() error {
 			for _, p := range ran {
 				if c, ok := p.(withcleanup); ok {
 					log.Infof(Colorize.Red("* Running clean-up for phase: %s").String(), p.Title())
+					stopAudit := m.auditPhase(p)
 					c.CleanUp()
+					stopAudit()
 				}
 			}
 		}
@@ -71,22 +274,58 @@ func (m *Manager) Run() error {
 	for _, p := range m.phases {
 		title := p.Title()
 
+		if err := ctx.Err(); err != nil {
+			result = interruptedError(title, err)
+			m.Results = append(m.Results, Result{Title: title, Err: result})
+			m.disconnect()
+			return result
+		}
+
+		if !m.selected(p) {
+			log.Debugf("Skipping phase '%s' as requested", title)
+			m.Results = append(m.Results, Result{Title: title, Skipped: true})
+			continue
+		}
+
+		if p, ok := p.(withmanager); ok {
+			p.SetManager(m)
+		}
+
 		if p, ok := p.(withconfig); ok {
 			log.Debugf("Preparing phase '%s'", p.Title())
 			if err := p.Prepare(m.Config); err != nil {
+				m.Results = append(m.Results, Result{Title: title, Err: err})
 				return err
 			}
 		}
 
 		if p, ok := p.(conditional); ok {
 			if !p.ShouldRun() {
+				m.Results = append(m.Results, Result{Title: title, Skipped: true})
 				continue
 			}
 		}
 
+		if m.DryRun && !isReadOnly(p) {
+			if p, ok := p.(dryrunner); ok {
+				log.Debugf("Running dry-run for phase '%s'", title)
+				if err := p.DryRun(); err != nil {
+					m.Results = append(m.Results, Result{Title: title, Err: err})
+					return err
+				}
+			} else {
+				log.Debugf("Skipping phase '%s' in dry-run mode", title)
+			}
+			m.dryRunLocalHooks(title, "before")
+			m.dryRunLocalHooks(title, "after")
+			m.Results = append(m.Results, Result{Title: title, Skipped: true})
+			continue
+		}
+
 		if p, ok := p.(beforehook); ok {
 			if err := p.Before(title); err != nil {
 				log.Debugf("before hook failed '%s'", err.Error())
+				m.Results = append(m.Results, Result{Title: title, Err: err})
 				return err
 			}
 		}
@@ -97,10 +336,26 @@ func (m *Manager) Run() error {
 			}
 		}
 
+		// the read-only phases are run also in dry-run mode, their local hooks are only reported
+		if m.DryRun {
+			m.dryRunLocalHooks(title, "before")
+		} else if err := m.runLocalHooks(title, "before"); err != nil {
+			m.Results = append(m.Results, Result{Title: title, Err: err})
+			return err
+		}
+
 		text := Colorize.Green("==> Running phase: %s").String()
 		log.Infof(text, title)
-		result = p.Run()
+		start := time.Now()
+		resetLostConnections()
+		stopAudit := m.auditPhase(p)
+		result = m.runPhase(ctx, p)
+		stopAudit()
+		if result != nil && m.Config != nil && m.Config.Spec != nil {
+			result = connectionLostError(m.Config.Spec.ConnectHosts(), title, result)
+		}
 		ran = append(ran, p)
+		m.Results = append(m.Results, Result{Title: title, Duration: time.Since(start), Err: result})
 
 		if p, ok := p.(afterhook); ok {
 			if err := p.After(result); err != nil {
@@ -112,7 +367,86 @@ func (m *Manager) Run() error {
 		if result != nil {
 			return result
 		}
+
+		if m.DryRun {
+			m.dryRunLocalHooks(title, "after")
+		} else if err := m.runLocalHooks(title, "after"); err != nil {
+			result = err
+			m.Results[len(m.Results)-1].Err = err
+			return result
+		}
 	}
 
 	return nil
 }
+
+// auditPhase reports the commands run on the hosts to the Audit function while a phase that makes changes
+// to the hosts is running, the returned function stops the reporting
+func (m *Manager) auditPhase(p phase) func() {
+	if m.Audit == nil || isReadOnly(p) {
+		return func() {}
+	}
+	title := p.Title()
+	cluster.ExecObserver = func(h *cluster.Host, cmd string, err error) {
+		m.Audit(title, h, cmd, err)
+	}
+	return func() {
+		cluster.ExecObserver = nil
+	}
+}
+
+// runPhase runs the phase and interrupts it when the context is done
+func (m *Manager) runPhase(ctx context.Context, p phase) error {
+	if ctx.Done() == nil {
+		return p.Run()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	err := interruptedError(p.Title(), ctx.Err())
+	if isUninterruptible(p) {
+		log.Errorf("%s, waiting for the phase to reach a safe point", err.Error())
+		if perr := <-done; perr != nil && !errors.Is(perr, context.Canceled) && !errors.Is(perr, context.DeadlineExceeded) {
+			log.Errorf("phase '%s' failed: %s", p.Title(), perr.Error())
+		}
+		m.disconnect()
+		return err
+	}
+
+	log.Errorf("%s, disconnecting from hosts", err.Error())
+	// closing the connections makes the commands running on the hosts return
+	m.disconnect()
+
+	select {
+	case <-done:
+	case <-time.After(cancelGracePeriod):
+		log.Warnf("phase '%s' did not return in %s after disconnecting from the hosts", p.Title(), cancelGracePeriod)
+	}
+
+	return err
+}
+
+func (m *Manager) disconnect() {
+	if m.Config == nil || m.Config.Spec == nil {
+		return
+	}
+	for _, h := range m.Config.Spec.ConnectHosts() {
+		h.Disconnect()
+	}
+}
+
+func interruptedError(title string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out while running phase '%s': %w", title, err)
+	}
+	return fmt.Errorf("interrupted while running phase '%s': %w", title, err)
+}
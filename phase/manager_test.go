@@ -1,8 +1,11 @@
 package phase
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/k0sproject/k0sctl/config"
 	"github.com/k0sproject/k0sctl/config/cluster"
@@ -96,3 +99,211 @@ func TestHookedPhase(t *testing.T) {
 	require.True(t, p.afterCalled, "after hook was not called")
 	require.EqualError(t, p.err, "run failed")
 }
+
+func TestManagerResults(t *testing.T) {
+	m := Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}}
+	m.AddPhase(&conditionalPhase{}, &configPhase{}, &hookedPhase{}, &configPhase{})
+	require.Error(t, m.Run())
+	require.Len(t, m.Results, 3)
+	require.Equal(t, "conditional phase", m.Results[0].Title)
+	require.True(t, m.Results[0].Skipped)
+	require.Equal(t, "config phase", m.Results[1].Title)
+	require.NoError(t, m.Results[1].Err)
+	require.Equal(t, "hooked phase", m.Results[2].Title)
+	require.EqualError(t, m.Results[2].Err, "run failed")
+}
+
+type dryRunPhase struct {
+	GenericPhase
+	readOnly  bool
+	runCalled bool
+}
+
+func (p *dryRunPhase) Title() string {
+	return "dry-run phase"
+}
+
+func (p *dryRunPhase) ReadOnly() bool {
+	return p.readOnly
+}
+
+func (p *dryRunPhase) DryRun() error {
+	p.DryMsgf(nil, "would change something")
+	return nil
+}
+
+func (p *dryRunPhase) Run() error {
+	p.runCalled = true
+	return nil
+}
+
+func TestManagerDryRun(t *testing.T) {
+	m := Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}, DryRun: true}
+	ro := &dryRunPhase{readOnly: true}
+	rw := &dryRunPhase{}
+	m.AddPhase(ro, rw)
+	require.NoError(t, m.Run())
+	require.True(t, ro.runCalled, "read-only phase was not run")
+	require.False(t, rw.runCalled, "mutating phase was run")
+	require.Equal(t, []string{"would change something"}, m.DryMessages())
+}
+
+type blockingPhase struct {
+	release       chan struct{}
+	cleanupCalled bool
+}
+
+func (p *blockingPhase) Title() string {
+	return "blocking phase"
+}
+
+func (p *blockingPhase) Run() error {
+	<-p.release
+	return fmt.Errorf("disconnected")
+}
+
+func (p *blockingPhase) CleanUp() {
+	p.cleanupCalled = true
+}
+
+func TestManagerTimeout(t *testing.T) {
+	defer func(d time.Duration) { cancelGracePeriod = d }(cancelGracePeriod)
+	cancelGracePeriod = 10 * time.Millisecond
+
+	m := Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}}
+	p := &blockingPhase{release: make(chan struct{})}
+	next := &configPhase{}
+	m.AddPhase(p, next)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := m.RunContext(ctx)
+	close(p.release)
+
+	require.EqualError(t, err, "timed out while running phase 'blocking phase': context deadline exceeded")
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.True(t, p.cleanupCalled, "cleanup was not called")
+	require.False(t, next.receivedConfig, "the next phase was prepared")
+}
+
+type uninterruptiblePhase struct {
+	ctx      context.Context
+	finished bool
+}
+
+func (p *uninterruptiblePhase) Title() string {
+	return "uninterruptible phase"
+}
+
+func (p *uninterruptiblePhase) Uninterruptible() bool {
+	return true
+}
+
+func (p *uninterruptiblePhase) Run() error {
+	<-p.ctx.Done()
+	time.Sleep(20 * time.Millisecond)
+	p.finished = true
+	return nil
+}
+
+func TestManagerInterruptUninterruptible(t *testing.T) {
+	defer func(d time.Duration) { cancelGracePeriod = d }(cancelGracePeriod)
+	cancelGracePeriod = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}}
+	p := &uninterruptiblePhase{ctx: ctx}
+	next := &configPhase{}
+	m.AddPhase(p, next)
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := m.RunContext(ctx)
+	require.EqualError(t, err, "interrupted while running phase 'uninterruptible phase': context canceled")
+	require.True(t, p.finished, "the phase was not waited for")
+	require.False(t, next.receivedConfig, "the next phase was prepared")
+}
+
+type namedPhase struct {
+	title     string
+	mandatory bool
+	runCalled bool
+}
+
+func (p *namedPhase) Title() string {
+	return p.title
+}
+
+func (p *namedPhase) Mandatory() bool {
+	return p.mandatory
+}
+
+func (p *namedPhase) Run() error {
+	p.runCalled = true
+	return nil
+}
+
+func TestManagerPhaseSelection(t *testing.T) {
+	newPhases := func() (*namedPhase, *namedPhase, *namedPhase) {
+		return &namedPhase{title: "Connect to hosts", mandatory: true}, &namedPhase{title: "Download k0s"}, &namedPhase{title: "Install workers"}
+	}
+
+	connect, download, install := newPhases()
+	m := Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}, OnlyPhases: []string{"install workers"}}
+	m.AddPhase(connect, download, install)
+	require.NoError(t, m.Run())
+	require.True(t, connect.runCalled)
+	require.False(t, download.runCalled)
+	require.True(t, install.runCalled)
+	require.True(t, m.Results[1].Skipped)
+
+	connect, download, install = newPhases()
+	m = Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}, SkipPhases: []string{"Download k0s"}}
+	m.AddPhase(connect, download, install)
+	require.NoError(t, m.Run())
+	require.True(t, connect.runCalled)
+	require.False(t, download.runCalled)
+	require.True(t, install.runCalled)
+
+	connect, download, install = newPhases()
+	m = Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}, SkipPhases: []string{"Install all the things"}}
+	m.AddPhase(connect, download, install)
+	require.EqualError(t, m.Run(), `unknown phase "Install all the things", valid phases are: Connect to hosts, Download k0s, Install workers`)
+	require.False(t, connect.runCalled)
+
+	m = Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}, SkipPhases: []string{"Connect to hosts"}}
+	m.AddPhase(newPhases())
+	require.EqualError(t, m.Run(), `phase "Connect to hosts" can not be skipped`)
+}
+
+// auditedPhase reports a command to the exec observer like a host running a command would
+type auditedPhase struct {
+	title    string
+	readOnly bool
+}
+
+func (p *auditedPhase) Title() string {
+	return p.title
+}
+
+func (p *auditedPhase) ReadOnly() bool {
+	return p.readOnly
+}
+
+func (p *auditedPhase) Run() error {
+	if observe := cluster.ExecObserver; observe != nil {
+		observe(&cluster.Host{}, "true", nil)
+	}
+	return nil
+}
+
+func TestManagerAudit(t *testing.T) {
+	var audited []string
+	m := Manager{Config: &config.Cluster{Spec: &cluster.Spec{}}}
+	m.Audit = func(phase string, h *cluster.Host, cmd string, err error) {
+		audited = append(audited, phase+": "+cmd)
+	}
+	m.AddPhase(&auditedPhase{title: "Gather facts", readOnly: true}, &auditedPhase{title: "Install workers"})
+	require.NoError(t, m.Run())
+	require.Equal(t, []string{"Install workers: true"}, audited)
+	require.Nil(t, cluster.ExecObserver)
+}
@@ -0,0 +1,108 @@
+package phase
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
+	"github.com/k0sproject/k0sctl/config/cluster"
+)
+
+// The actions of the hosts in an apply plan
+const (
+	PlanInstall   = "install"
+	PlanUpgrade   = "upgrade"
+	PlanDowngrade = "downgrade"
+	PlanNone      = "none"
+)
+
+// HostPlan is the k0s version change planned for a host
+type HostPlan struct {
+	Address string `json:"address"`
+	Role    string `json:"role"`
+	Current string `json:"current,omitempty"`
+	Target  string `json:"target"`
+	Action  string `json:"action"`
+	// Blocked is the reason apply would refuse to make the change
+	Blocked string `json:"blocked,omitempty"`
+}
+
+// Plan compares the k0s versions running on the hosts to the configured ones, it runs after the facts
+// have been gathered
+type Plan struct {
+	GenericPhase
+
+	// AllowDowngrade is true when the downgrade check is disabled
+	AllowDowngrade bool
+	// AllowVersionSkip is true when upgrades may skip minor versions
+	AllowVersionSkip bool
+
+	// Hosts are the plans of the hosts in the order of the configuration
+	Hosts []*HostPlan
+}
+
+// Title for the phase
+func (p *Plan) Title() string {
+	return "Plan k0s version changes"
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *Plan) ReadOnly() bool {
+	return true
+}
+
+// Sequential is true, the phase only compares the gathered facts
+func (p *Plan) Sequential() bool {
+	return true
+}
+
+// Run the phase
+func (p *Plan) Run() error {
+	p.Hosts = make([]*HostPlan, 0, len(p.Config.Spec.Hosts))
+	for _, h := range p.Config.Spec.Hosts {
+		hp, err := p.hostPlan(h)
+		if err != nil {
+			return err
+		}
+		p.Hosts = append(p.Hosts, hp)
+	}
+	return nil
+}
+
+func (p *Plan) hostPlan(h *cluster.Host) (*HostPlan, error) {
+	hp := &HostPlan{
+		Address: h.Address(),
+		Role:    h.Role,
+		Current: h.Metadata.K0sRunningVersion,
+		Target:  p.Config.Spec.K0sVersionFor(h),
+		Action:  PlanNone,
+	}
+	if hp.Current == "" {
+		hp.Action = PlanInstall
+		return hp, nil
+	}
+
+	current, err := semver.NewVersion(hp.Current)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid running k0s version %q: %w", h, hp.Current, err)
+	}
+	target, err := semver.NewVersion(hp.Target)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid k0s version %q: %w", h, hp.Target, err)
+	}
+
+	switch {
+	case target.LessThan(current):
+		hp.Action = PlanDowngrade
+		if !p.AllowDowngrade {
+			hp.Blocked = fmt.Sprintf("can't perform a downgrade: %s > %s", current, target)
+		}
+	case h.Metadata.NeedsUpgrade:
+		hp.Action = PlanUpgrade
+		if !p.AllowVersionSkip {
+			if err := checkVersionSkip(hp.Current, hp.Target); err != nil {
+				hp.Blocked = err.Error()
+			}
+		}
+	}
+	return hp, nil
+}
@@ -0,0 +1,46 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	planHost := func(addr, running string, needsUpgrade bool) *cluster.Host {
+		return &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: addr, Port: 22}}, Role: "worker", Metadata: cluster.HostMetadata{K0sRunningVersion: running, NeedsUpgrade: needsUpgrade}}
+	}
+	hosts := cluster.Hosts{
+		planHost("10.0.0.1", "", false),
+		planHost("10.0.0.2", "1.24.1+k0s.0", true),
+		planHost("10.0.0.3", "1.25.0+k0s.0", false),
+		planHost("10.0.0.4", "1.26.0+k0s.0", false),
+		planHost("10.0.0.5", "1.23.1+k0s.0", true),
+	}
+	p := &Plan{GenericPhase: GenericPhase{Config: &config.Cluster{Spec: &cluster.Spec{
+		K0s:   cluster.K0s{Version: "1.25.0+k0s.0"},
+		Hosts: hosts,
+	}}}}
+
+	require.NoError(t, p.Run())
+	require.Len(t, p.Hosts, 5)
+	require.Equal(t, &HostPlan{Address: "10.0.0.1", Role: "worker", Target: "1.25.0+k0s.0", Action: PlanInstall}, p.Hosts[0])
+	require.Equal(t, &HostPlan{Address: "10.0.0.2", Role: "worker", Current: "1.24.1+k0s.0", Target: "1.25.0+k0s.0", Action: PlanUpgrade}, p.Hosts[1])
+	require.Equal(t, PlanNone, p.Hosts[2].Action)
+	require.Equal(t, PlanDowngrade, p.Hosts[3].Action)
+	require.Contains(t, p.Hosts[3].Blocked, "can't perform a downgrade")
+	require.Equal(t, PlanUpgrade, p.Hosts[4].Action)
+	require.Contains(t, p.Hosts[4].Blocked, "--allow-version-skip")
+
+	p.AllowDowngrade = true
+	p.AllowVersionSkip = true
+	require.NoError(t, p.Run())
+	require.Empty(t, p.Hosts[3].Blocked)
+	require.Empty(t, p.Hosts[4].Blocked)
+
+	hosts[1].Metadata.K0sRunningVersion = "foo"
+	require.Error(t, p.Run())
+}
@@ -1,6 +1,7 @@
 package phase
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/k0sproject/k0sctl/config/cluster"
@@ -8,6 +9,53 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// SkipPrerequisites disables installing the packages needed by k0s and the spec.hosts[*].prerequisites.packages
+var SkipPrerequisites bool
+
+// prerequisite is a command needed by k0s and the package that provides it
+type prerequisite struct {
+	cmd  string
+	pkg  string
+	need func(*cluster.Host) bool
+}
+
+var prerequisites = []prerequisite{
+	{cmd: "curl", pkg: "curl", need: (*cluster.Host).NeedCurl},
+	{cmd: "iptables", pkg: "iptables", need: (*cluster.Host).NeedIPTables},
+	{cmd: "hostname", pkg: "inetutils", need: (*cluster.Host).NeedInetUtils},
+}
+
+// missingPrerequisites returns the prerequisites of k0s that are not present on the host
+func missingPrerequisites(h *cluster.Host) []prerequisite {
+	var missing []prerequisite
+	for _, p := range prerequisites {
+		if p.need(h) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// prerequisitePackages returns the packages of the missing prerequisites followed by the packages
+// listed in the host configuration
+func prerequisitePackages(h *cluster.Host, missing []prerequisite) []string {
+	var pkgs []string
+	seen := make(map[string]struct{})
+	add := func(pkg string) {
+		if _, ok := seen[pkg]; !ok {
+			seen[pkg] = struct{}{}
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	for _, p := range missing {
+		add(p.pkg)
+	}
+	for _, pkg := range h.Prerequisites.Packages {
+		add(pkg)
+	}
+	return pkgs
+}
+
 // PrepareHosts installs required packages and so on on the hosts.
 type PrepareHosts struct {
 	GenericPhase
@@ -20,7 +68,25 @@ func (p *PrepareHosts) Title() string {
 
 // Run the phase
 func (p *PrepareHosts) Run() error {
-	return p.Config.Spec.Hosts.ParallelEach(p.prepareHost)
+	return p.parallelDo(p.Config.Spec.Hosts, p.prepareHost)
+}
+
+// DryRun reports the changes that would be made on the hosts
+func (p *PrepareHosts) DryRun() error {
+	return p.parallelDo(p.Config.Spec.Hosts, func(h *cluster.Host) error {
+		if len(h.EnvironmentVars()) > 0 {
+			p.DryMsgf(h, "update environment variables")
+		}
+
+		if SkipPrerequisites {
+			return nil
+		}
+		if pkgs := prerequisitePackages(h, missingPrerequisites(h)); len(pkgs) > 0 {
+			p.DryMsgf(h, "install packages (%s)", strings.Join(pkgs, ", "))
+		}
+
+		return nil
+	})
 }
 
 type prepare interface {
@@ -34,40 +100,52 @@ func (p *PrepareHosts) prepareHost(h *cluster.Host) error {
 		}
 	}
 
-	if len(h.Environment) > 0 {
-		log.Infof("%s: updating environment", h)
-		if err := h.Configurer.UpdateEnvironment(h, h.Environment); err != nil {
+	if len(h.EnvironmentVars()) > 0 {
+		log.WithField("host", h).Info("updating environment")
+		if err := h.Configurer.UpdateEnvironment(h, h.EnvironmentVars()); err != nil {
 			return err
 		}
 	}
 
-	var pkgs []string
+	if err := installPrerequisites(h); err != nil {
+		return err
+	}
 
-	if h.NeedCurl() {
-		pkgs = append(pkgs, "curl")
+	if h.Configurer.IsContainer(h) {
+		log.WithField("host", h).Info("is a container, applying a fix")
+		if err := h.Configurer.FixContainer(h); err != nil {
+			return err
+		}
 	}
 
-	if h.NeedIPTables() {
-		pkgs = append(pkgs, "iptables")
+	return nil
+}
+
+// installPrerequisites installs the missing k0s prerequisites and the packages listed in the host configuration.
+// An error is returned when a prerequisite is still missing after the installation.
+func installPrerequisites(h *cluster.Host) error {
+	missing := missingPrerequisites(h)
+	if SkipPrerequisites {
+		for _, p := range missing {
+			log.WithField("host", h).Warnf("%s is needed by k0s but it was not found, not installing the %s package because --skip-prerequisites given", p.cmd, p.pkg)
+		}
+		return nil
 	}
 
-	if h.NeedInetUtils() {
-		pkgs = append(pkgs, "inetutils")
+	pkgs := prerequisitePackages(h, missing)
+	if len(pkgs) == 0 {
+		return nil
 	}
 
-	if len(pkgs) > 0 {
-		log.Infof("%s: installing packages (%s)", h, strings.Join(pkgs, ", "))
-		if err := h.Configurer.InstallPackage(h, pkgs...); err != nil {
-			return err
-		}
+	log.WithField("host", h).Infof("installing packages (%s)", strings.Join(pkgs, ", "))
+	if err := h.Configurer.InstallPackage(h, pkgs...); err != nil {
+		return fmt.Errorf("failed to install packages (%s), check that the package repositories can be reached from the host: %w", strings.Join(pkgs, ", "), err)
 	}
 
-	if h.Configurer.IsContainer(h) {
-		log.Infof("%s: is a container, applying a fix", h)
-		if err := h.Configurer.FixContainer(h); err != nil {
-			return err
+	for _, p := range missing {
+		if p.need(h) {
+			return fmt.Errorf("%s is needed by k0s but it was still not found after installing the %s package", p.cmd, p.pkg)
 		}
 	}
-
 	return nil
 }
@@ -0,0 +1,18 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrerequisitePackages(t *testing.T) {
+	h := &cluster.Host{Prerequisites: cluster.Prerequisites{Packages: []string{"socat", "curl", "conntrack"}}}
+	require.Equal(t, []string{"socat", "curl", "conntrack"}, prerequisitePackages(h, nil))
+
+	missing := []prerequisite{prerequisites[0], prerequisites[2]}
+	require.Equal(t, []string{"curl", "inetutils", "socat", "conntrack"}, prerequisitePackages(h, missing))
+
+	require.Empty(t, prerequisitePackages(&cluster.Host{}, nil))
+}
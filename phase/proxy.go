@@ -0,0 +1,59 @@
+package phase
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// DownloadProxy is the proxy used for downloading the k0s binaries, it overrides the HTTP_PROXY and HTTPS_PROXY environment variables
+var DownloadProxy string
+
+// proxyConfig returns the proxy settings from the environment with the DownloadProxy override applied
+func proxyConfig() *httpproxy.Config {
+	cfg := httpproxy.FromEnvironment()
+	if DownloadProxy != "" {
+		cfg.HTTPProxy = DownloadProxy
+		cfg.HTTPSProxy = DownloadProxy
+	}
+	return cfg
+}
+
+// downloadClient returns a http client for downloading artifacts using the proxy settings. NO_PROXY is honored.
+func downloadClient(timeout time.Duration) *http.Client {
+	proxyFunc := proxyConfig().ProxyFunc()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		proxy, err := proxyFunc(req.URL)
+		if err != nil {
+			return nil, err
+		}
+		if proxy == nil {
+			log.Debugf("connecting to %s without a proxy", req.URL.Host)
+		} else {
+			log.Debugf("connecting to %s through proxy %s", req.URL.Host, proxy.Redacted())
+		}
+		return proxy, nil
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// proxyEnv returns the proxy settings as environment variables for the downloads performed on the hosts.
+// The lower case names are used because curl ignores the upper case HTTP_PROXY.
+func proxyEnv() map[string]string {
+	cfg := proxyConfig()
+	env := make(map[string]string)
+	if cfg.HTTPProxy != "" {
+		env["http_proxy"] = cfg.HTTPProxy
+	}
+	if cfg.HTTPSProxy != "" {
+		env["https_proxy"] = cfg.HTTPSProxy
+	}
+	if len(env) > 0 && cfg.NoProxy != "" {
+		env["no_proxy"] = cfg.NoProxy
+	}
+	return env
+}
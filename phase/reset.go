@@ -10,10 +10,28 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// ResetRoleFilter returns the filter for the hosts reset with --role, the controller role includes the
+// controller+worker hosts and the worker role only the dedicated workers. Returns nil for all hosts.
+func ResetRoleFilter(role string) func(*cluster.Host) bool {
+	switch role {
+	case "controller":
+		return func(h *cluster.Host) bool { return h.IsController() }
+	case "worker":
+		return func(h *cluster.Host) bool { return !h.IsController() }
+	}
+	return nil
+}
+
 // Reset uninstalls k0s from the hosts
 type Reset struct {
 	GenericPhase
-	hosts cluster.Hosts
+
+	// Role limits the reset to the controllers or the workers, all hosts are reset when empty. The worker
+	// nodes are drained and deleted through the leader controller when only the workers are reset.
+	Role string
+
+	hosts  cluster.Hosts
+	leader *cluster.Host
 }
 
 // Title for the phase
@@ -24,8 +42,9 @@ func (p *Reset) Title() string {
 // Prepare the phase
 func (p *Reset) Prepare(config *config.Cluster) error {
 	p.Config = config
+	only := ResetRoleFilter(p.Role)
 	var hosts cluster.Hosts = p.Config.Spec.Hosts.Filter(func(h *cluster.Host) bool {
-		return h.Metadata.K0sBinaryVersion != ""
+		return h.Metadata.K0sBinaryVersion != "" && (only == nil || only(h))
 	})
 	c, _ := semver.NewConstraint("< 0.11.0-rc1")
 
@@ -41,30 +60,85 @@ func (p *Reset) Prepare(config *config.Cluster) error {
 	}
 
 	p.hosts = hosts
+	if p.Role == "worker" {
+		p.leader = p.Config.Spec.K0sLeader()
+		if p.leader.Metadata.K0sRunningVersion == "" {
+			log.Warnf("k0s is not running on the leader controller %s, the worker nodes can not be drained and deleted", p.leader)
+			p.leader = nil
+		}
+	}
 
 	return nil
 }
 
 // Run the phase
 func (p *Reset) Run() error {
-	return p.hosts.ParallelEach(func(h *cluster.Host) error {
-		log.Infof("%s: cleaning up service environment", h)
-		if err := h.Configurer.CleanupServiceEnvironment(h, h.K0sServiceName()); err != nil {
-			return err
+	return p.parallelDo(p.hosts, func(h *cluster.Host) error {
+		if h.IsController() && p.Role == "worker" {
+			return fmt.Errorf("refusing to reset the controller %s when resetting the workers", h)
 		}
 
-		if h.Configurer.ServiceIsRunning(h, h.K0sServiceName()) {
-			log.Infof("%s: stopping k0s", h)
-			if err := h.Configurer.StopService(h, h.K0sServiceName()); err != nil {
-				return err
-			}
-			log.Infof("%s: waiting for k0s to stop", h)
-			if err := h.WaitK0sServiceStopped(); err != nil {
+		var node bool
+		if p.leader != nil {
+			n, err := p.drainNode(h)
+			if err != nil {
 				return err
 			}
+			node = n
+		}
+
+		if err := p.resetHost(h); err != nil {
+			return err
 		}
 
-		log.Infof("%s: running k0s reset", h)
-		return h.Exec(h.Configurer.K0sCmdf("reset"), exec.Sudo(h))
+		if node {
+			log.WithField("host", h).Infof("deleting node %s", h.Metadata.Hostname)
+			if err := p.leader.DeleteNode(h); err != nil {
+				return fmt.Errorf("failed to delete node %s: %w", h.Metadata.Hostname, err)
+			}
+		}
+		return nil
 	})
 }
+
+// drainNode drains the node of the worker and returns true when the node exists
+func (p *Reset) drainNode(h *cluster.Host) (bool, error) {
+	if h.HostnameOverride != "" {
+		h.Metadata.Hostname = h.HostnameOverride
+	} else {
+		h.Metadata.Hostname = h.Configurer.Hostname(h)
+	}
+	if err := p.leader.Exec(p.leader.KubectlCmdf("get node %s", h.Metadata.Hostname), exec.HideOutput(), exec.Sudo(p.leader)); err != nil {
+		log.WithField("host", h).Infof("node %s not found in the cluster, skipping drain", h.Metadata.Hostname)
+		return false, nil
+	}
+
+	upgrade := p.Config.Spec.K0s.Upgrade
+	log.WithField("host", h).Info("draining node")
+	return true, p.leader.DrainNode(h, upgrade.DrainGracePeriod, upgrade.DrainTimeout)
+}
+
+func (p *Reset) resetHost(h *cluster.Host) error {
+	log.WithField("host", h).Info("cleaning up service environment")
+	if err := h.Configurer.CleanupServiceEnvironment(h, h.K0sServiceName()); err != nil {
+		return err
+	}
+
+	if err := cleanupServiceOverrides(h); err != nil {
+		return err
+	}
+
+	if h.Configurer.ServiceIsRunning(h, h.K0sServiceName()) {
+		log.WithField("host", h).Info("stopping k0s")
+		if err := h.Configurer.StopService(h, h.K0sServiceName()); err != nil {
+			return err
+		}
+		log.WithField("host", h).Info("waiting for k0s to stop")
+		if err := h.WaitK0sServiceStopped(); err != nil {
+			return err
+		}
+	}
+
+	log.WithField("host", h).Info("running k0s reset")
+	return h.Exec(h.K0sResetCommand(), exec.Sudo(h))
+}
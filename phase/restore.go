@@ -2,9 +2,14 @@ package phase
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/hashicorp/go-version"
 	"github.com/k0sproject/k0sctl/config"
 	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/k0sctl/integration/s3"
 	"github.com/k0sproject/rig/exec"
 	log "github.com/sirupsen/logrus"
 )
@@ -12,8 +17,15 @@ import (
 type Restore struct {
 	GenericPhase
 
+	// RestoreFrom is a path to a local backup archive or a s3://bucket/key URL
 	RestoreFrom string
-	leader      *cluster.Host
+	// S3Options are the options for downloading a backup from s3
+	S3Options s3.Options
+	leader    *cluster.Host
+
+	// localFile is the fetched backup archive
+	localFile string
+	tempDir   string
 }
 
 // Title for the phase
@@ -21,11 +33,22 @@ func (p *Restore) Title() string {
 	return "Restore cluster state"
 }
 
+// Sequential is true, the backup is restored on the leader
+func (p *Restore) Sequential() bool {
+	return true
+}
+
 // ShouldRun is true when there path to backup file
 func (p *Restore) ShouldRun() bool {
 	return p.RestoreFrom != "" && p.leader.Metadata.K0sRunningVersion == ""
 }
 
+// DryRun reports the backup that would be restored
+func (p *Restore) DryRun() error {
+	p.DryMsgf(p.leader, "restore cluster state from %s", p.RestoreFrom)
+	return nil
+}
+
 // Prepare the phase
 func (p *Restore) Prepare(config *config.Cluster) error {
 	log.Tracef("restore from: %s", p.RestoreFrom)
@@ -43,22 +66,153 @@ func (p *Restore) Prepare(config *config.Cluster) error {
 
 // Run the phase
 func (p *Restore) Run() error {
-	// Push the backup file to controller
 	h := p.leader
+	defer p.CleanUp()
+
+	if p.localFile == "" {
+		if err := p.fetch(p.Config); err != nil {
+			return err
+		}
+	}
+
+	// Push the backup file to controller
 	tmpDir, err := h.Configurer.TempDir(h)
 	if err != nil {
 		return err
 	}
 	dstFile := fmt.Sprintf("%s/k0s_backup.tar.gz", tmpDir)
-	if err := h.Upload(p.RestoreFrom, dstFile); err != nil {
+	if err := h.Upload(p.localFile, dstFile); err != nil {
 		return err
 	}
 
 	// Run restore
-	log.Infof("%s: restoring cluster state", h)
+	log.WithField("host", h).Info("restoring cluster state")
+	if p.Config.Spec.K0s.ExternalEtcd() != nil {
+		log.WithField("host", h).Warn("the cluster uses an external etcd, the etcd data is not restored from the backup")
+	}
 	if err := h.Exec(h.K0sRestoreCommand(dstFile), exec.Sudo(h)); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// CleanUp removes the downloaded backup archive
+func (p *Restore) CleanUp() {
+	if p.tempDir == "" {
+		return
+	}
+	if err := os.RemoveAll(p.tempDir); err != nil {
+		log.Debugf("failed to remove %s: %s", p.tempDir, err.Error())
+	}
+	p.tempDir = ""
+	p.localFile = ""
+}
+
+// fetch downloads the backup archive when it is in s3 and checks that its k0s version is compatible
+// with the version being installed
+func (p *Restore) fetch(config *config.Cluster) error {
+	localFile := p.RestoreFrom
+	if s3.IsS3URL(p.RestoreFrom) {
+		dir, err := os.MkdirTemp("", "k0sctl-restore")
+		if err != nil {
+			return err
+		}
+		p.tempDir = dir
+
+		localFile = filepath.Join(dir, "k0s_backup.tar.gz")
+		log.Infof("downloading backup from %s", p.RestoreFrom)
+		if err := s3.Download(p.RestoreFrom, localFile, p.S3Options); err != nil {
+			return err
+		}
+		if err := s3.Download(p.RestoreFrom+backupVersionSuffix, localFile+backupVersionSuffix, p.S3Options); err != nil {
+			if !s3.IsNotFound(err) {
+				log.Warnf("failed to download the k0s version file of the backup: %s", err.Error())
+			}
+		}
+	}
+
+	backupVersion, err := readBackupVersion(localFile)
+	if err != nil {
+		return err
+	}
+	if backupVersion == "" {
+		log.Debugf("no k0s version information found for the backup archive %s, skipping the version check", p.RestoreFrom)
+	} else if err := checkBackupVersion(backupVersion, config.Spec.K0sVersionFor(config.Spec.K0sLeader())); err != nil {
+		return err
+	}
+
+	p.localFile = localFile
+	return nil
+}
+
+// ValidateRestore fetches the backup archive of the restore phase and checks its k0s version before any
+// changes are made to the hosts
+type ValidateRestore struct {
+	GenericPhase
+
+	Restore *Restore
+}
+
+// Title for the phase
+func (p *ValidateRestore) Title() string {
+	return "Validate backup archive"
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *ValidateRestore) ReadOnly() bool {
+	return true
+}
+
+// ShouldRun is true when a backup is going to be restored
+func (p *ValidateRestore) ShouldRun() bool {
+	return p.Restore != nil && p.Restore.RestoreFrom != ""
+}
+
+// Run the phase
+func (p *ValidateRestore) Run() error {
+	return p.Restore.fetch(p.Config)
+}
+
+// CleanUp removes the downloaded backup archive
+func (p *ValidateRestore) CleanUp() {
+	p.Restore.CleanUp()
+}
+
+// readBackupVersion returns the k0s version recorded next to the backup archive or an empty string when
+// there is no version file
+func readBackupVersion(archive string) (string, error) {
+	data, err := os.ReadFile(archive + backupVersionSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read the k0s version of the backup: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// checkBackupVersion returns an error when the backup was taken with a newer k0s version than the one
+// being installed and warns when the minor versions differ
+func checkBackupVersion(backup, target string) error {
+	bv, err := version.NewVersion(backup)
+	if err != nil {
+		log.Warnf("failed to parse the k0s version %q of the backup, skipping the version check: %s", backup, err.Error())
+		return nil
+	}
+	tv, err := version.NewVersion(target)
+	if err != nil {
+		return fmt.Errorf("failed to parse the target k0s version %q: %w", target, err)
+	}
+
+	if bv.GreaterThan(tv) {
+		return fmt.Errorf("the backup was taken with k0s version %s which is newer than the target version %s", bv, tv)
+	}
+
+	bs, ts := bv.Segments(), tv.Segments()
+	if bs[0] != ts[0] || bs[1] != ts[1] {
+		log.Warnf("the backup was taken with k0s version %s, restoring it to version %s may not work", bv, tv)
+	}
+
+	return nil
+}
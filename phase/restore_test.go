@@ -0,0 +1,64 @@
+package phase
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupVersion(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, os.WriteFile(fn, []byte("archive"), 0600))
+
+	v, err := readBackupVersion(fn)
+	require.NoError(t, err)
+	require.Equal(t, "", v)
+
+	require.NoError(t, writeBackupVersion(fn, "1.23.3+k0s.0"))
+	v, err = readBackupVersion(fn)
+	require.NoError(t, err)
+	require.Equal(t, "1.23.3+k0s.0", v)
+
+	// the archive is not modified
+	content, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.Equal(t, "archive", string(content))
+}
+
+func TestValidateRestore(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, os.WriteFile(fn, []byte("archive"), 0600))
+	require.NoError(t, writeBackupVersion(fn, "1.24.0+k0s.0"))
+
+	cfg := &config.Cluster{Spec: &cluster.Spec{
+		K0s:   cluster.K0s{Version: "1.23.3+k0s.0"},
+		Hosts: cluster.Hosts{&cluster.Host{Role: "controller"}},
+	}}
+	restore := &Restore{RestoreFrom: fn}
+	p := &ValidateRestore{Restore: restore}
+	require.NoError(t, p.Prepare(cfg))
+	require.True(t, p.ShouldRun())
+	err := p.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "newer than the target version")
+
+	cfg.Spec.K0s.Version = "1.24.1+k0s.0"
+	require.NoError(t, p.Run())
+	require.Equal(t, fn, restore.localFile)
+
+	require.False(t, (&ValidateRestore{Restore: &Restore{}}).ShouldRun())
+}
+
+func TestCheckBackupVersion(t *testing.T) {
+	require.NoError(t, checkBackupVersion("1.23.3+k0s.0", "1.23.3+k0s.0"))
+	require.NoError(t, checkBackupVersion("1.23.1+k0s.0", "1.23.3+k0s.0"))
+	require.NoError(t, checkBackupVersion("1.22.4+k0s.0", "1.23.3+k0s.0"))
+
+	err := checkBackupVersion("1.24.0+k0s.0", "1.23.3+k0s.0")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "newer than the target version")
+}
@@ -1,20 +1,27 @@
 package phase
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/k0sproject/k0sctl/config"
 	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig/exec"
+	log "github.com/sirupsen/logrus"
 )
 
 var _ phase = &RunHooks{}
 
-// RunHooks phase runs a set of hooks configured for the host
+// RunHooks phase runs a set of hooks configured for the cluster and the host
 type RunHooks struct {
+	GenericPhase
 	Action string
 	Stage  string
-	hosts  cluster.Hosts
+	// Only limits the hooks to the hosts it returns true for, such as the hosts reset with --role
+	Only  func(*cluster.Host) bool
+	hosts cluster.Hosts
 }
 
 // Title for the phase
@@ -24,30 +31,84 @@ func (p *RunHooks) Title() string {
 
 // Prepare digs out the hosts with steps from the config
 func (p *RunHooks) Prepare(config *config.Cluster) error {
+	p.Config = config
 	p.hosts = config.Spec.Hosts.Filter(func(h *cluster.Host) bool {
-		return len(h.Hooks.ForActionAndStage(p.Action, p.Stage)) > 0
+		return (p.Only == nil || p.Only(h)) && len(p.hooksFor(h)) > 0
 	})
 
 	return nil
 }
 
+// hooksFor returns the cluster-wide hooks followed by the hooks of the host
+func (p *RunHooks) hooksFor(h *cluster.Host) []cluster.Hook {
+	hooks := append([]cluster.Hook{}, p.Config.Spec.Hooks.ForActionAndStage(p.Action, p.Stage)...)
+	return append(hooks, h.Hooks.ForActionAndStage(p.Action, p.Stage)...)
+}
+
 // ShouldRun is true when there are hosts that need to be connected
 func (p *RunHooks) ShouldRun() bool {
 	return len(p.hosts) > 0
 }
 
+// DryRun reports the hooks that would be run
+func (p *RunHooks) DryRun() error {
+	for _, h := range p.hosts {
+		for _, step := range p.hooksFor(h) {
+			p.DryMsgf(h, "run %s %s hook: %s", p.Stage, p.Action, step)
+		}
+	}
+	return nil
+}
+
 // Run does all the prep work on the hosts in parallel
 func (p *RunHooks) Run() error {
-	return p.hosts.ParallelEach(p.runHooksForHost)
+	return p.parallelDo(p.hosts, p.runHooksForHost)
 }
 
 func (p *RunHooks) runHooksForHost(h *cluster.Host) error {
-	steps := h.Hooks.ForActionAndStage(p.Action, p.Stage)
-	for _, s := range steps {
-		err := h.Exec(s)
+	for _, hook := range p.hooksFor(h) {
+		out := &logWriter{log: log.WithField("host", h)}
+		err := h.Exec(hook.Cmd, exec.Writer(out))
+		out.Flush()
 		if err != nil {
-			return err
+			if hook.IgnoreErrors {
+				log.WithField("host", h).Warnf("%s %s hook failed, ignoring: %s", p.Stage, p.Action, err.Error())
+				continue
+			}
+			return fmt.Errorf("%s %s hook failed: %w", p.Stage, p.Action, err)
 		}
 	}
 	return nil
 }
+
+// logWriter logs each line written to it at the info level
+type logWriter struct {
+	log *log.Entry
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(b)
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx == -1 {
+			break
+		}
+		line := w.buf.Next(idx + 1)
+		w.log.Info(strings.TrimRight(string(line), "\r\n"))
+	}
+	return len(b), nil
+}
+
+// Flush logs the remaining partial line
+func (w *logWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 {
+		w.log.Info(strings.TrimRight(w.buf.String(), "\r\n"))
+		w.buf.Reset()
+	}
+}
@@ -0,0 +1,46 @@
+package phase
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHooksFor(t *testing.T) {
+	h := &cluster.Host{Hooks: cluster.Hooks{"apply": {"after": {{Cmd: "host hook"}}}}}
+	other := &cluster.Host{}
+	cfg := &config.Cluster{Spec: &cluster.Spec{
+		Hosts: cluster.Hosts{h, other},
+		Hooks: cluster.Hooks{"apply": {"after": {{Cmd: "cluster hook"}}}},
+	}}
+
+	p := &RunHooks{Action: "apply", Stage: "after"}
+	require.NoError(t, p.Prepare(cfg))
+	require.Len(t, p.hosts, 2)
+	require.Equal(t, []cluster.Hook{{Cmd: "cluster hook"}, {Cmd: "host hook"}}, p.hooksFor(h))
+	require.Equal(t, []cluster.Hook{{Cmd: "cluster hook"}}, p.hooksFor(other))
+
+	p = &RunHooks{Action: "reset", Stage: "before"}
+	require.NoError(t, p.Prepare(cfg))
+	require.False(t, p.ShouldRun())
+}
+
+func TestLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New()
+	logger.Out = &buf
+	logger.Formatter = &log.TextFormatter{DisableTimestamp: true, DisableColors: true}
+
+	w := &logWriter{log: log.NewEntry(logger).WithField("host", "h1")}
+	_, err := w.Write([]byte("first line\nsecond "))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("line\r\npartial"))
+	require.NoError(t, err)
+	w.Flush()
+
+	require.Equal(t, "level=info msg=\"first line\" host=h1\nlevel=info msg=\"second line\" host=h1\nlevel=info msg=partial host=h1\n", buf.String())
+}
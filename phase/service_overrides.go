@@ -0,0 +1,47 @@
+package phase
+
+import (
+	"github.com/k0sproject/k0sctl/config/cluster"
+	log "github.com/sirupsen/logrus"
+)
+
+// hasSystemd returns true when the host uses systemd as the init system
+func hasSystemd(h *cluster.Host) bool {
+	return h.Configurer.Kind() != "windows" && h.Configurer.CommandExist(h, "systemctl")
+}
+
+// updateServiceOverrides writes the spec.k0s.serviceOverrides drop-in for the k0s service and reloads
+// the systemd configuration. Hosts without systemd are skipped.
+func updateServiceOverrides(h *cluster.Host) error {
+	overrides := h.ServiceOverrides()
+	if overrides.IsZero() {
+		return nil
+	}
+	if !hasSystemd(h) {
+		log.WithField("host", h).Warn("skipping service overrides, the host does not use systemd")
+		return nil
+	}
+
+	fp := h.K0sServiceOverridePath()
+	log.WithField("host", h).Infof("writing service overrides to %s", fp)
+	if err := h.Configurer.WriteFile(h, fp, overrides.Content(), "0644"); err != nil {
+		return err
+	}
+	return h.Configurer.DaemonReload(h)
+}
+
+// cleanupServiceOverrides removes the drop-in written by updateServiceOverrides
+func cleanupServiceOverrides(h *cluster.Host) error {
+	if !hasSystemd(h) {
+		return nil
+	}
+	fp := h.K0sServiceOverridePath()
+	if !h.Configurer.FileExist(h, fp) {
+		return nil
+	}
+	log.WithField("host", h).Infof("removing service overrides from %s", fp)
+	if err := h.Configurer.DeleteFile(h, fp); err != nil {
+		return err
+	}
+	return h.Configurer.DaemonReload(h)
+}
@@ -0,0 +1,242 @@
+package phase
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// authClient is a rig ssh connection that authenticates with the ssh.authMethods of the host. The rig
+// connection only supports the key file and the ssh agent, the handshake is made here instead and the
+// resulting client is handed over to the rig connection.
+type authClient struct {
+	*rig.SSH
+	host *cluster.Host
+}
+
+// Connect makes the ssh connection using the authentication methods of the host
+func (c *authClient) Connect() error {
+	client, err := dialSSHAuth(c.host, c.SSH, c.host.SSHAuth)
+	if err != nil {
+		return err
+	}
+	if !setSSHClient(c.SSH, client) {
+		client.Close()
+		return fmt.Errorf("failed to set the ssh client of %s", c.host)
+	}
+	return nil
+}
+
+// useSSHAuth makes the host connection authenticate with the ssh.authMethods of the host when they are
+// set. The rig connection does not allow setting its client, so it is set to the unexported field.
+func useSSHAuth(h *cluster.Host) error {
+	if h.SSH == nil || len(h.SSHAuth.Methods) == 0 {
+		return nil
+	}
+	h.SSH.SetDefaults()
+
+	f := reflect.ValueOf(&h.Connection).Elem().FieldByName("client")
+	c := reflect.ValueOf(&authClient{SSH: h.SSH, host: h})
+	if !f.IsValid() || !c.Type().AssignableTo(f.Type()) {
+		return fmt.Errorf("the ssh.authMethods are not supported by the ssh client")
+	}
+	reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(c)
+	return nil
+}
+
+// setSSHClient sets the client of a rig ssh connection
+func setSSHClient(c *rig.SSH, client *ssh.Client) bool {
+	f := reflect.ValueOf(c).Elem().FieldByName("client")
+	if !f.IsValid() || f.Type() != reflect.TypeOf(client) {
+		return false
+	}
+	reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(reflect.ValueOf(client))
+	return true
+}
+
+// authAttempt records how an authentication method fared during the handshake
+type authAttempt struct {
+	method string
+	// err is the reason the method could not be offered to the server, such as a missing key file
+	err   error
+	tried bool
+}
+
+// sshAuthMethods returns the ssh client authentication methods for the methods that can be offered to
+// the server, in the order of the methods list. The returned function releases the resources, such as
+// the ssh agent connection, once the handshake is over.
+func sshAuthMethods(s *rig.SSH, a cluster.SSHAuth, attempts []*authAttempt, tried *[]string) ([]ssh.AuthMethod, func()) {
+	var methods []ssh.AuthMethod
+	var closers []func()
+	done := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+	mark := func(attempt *authAttempt) {
+		attempt.tried = true
+		*tried = append(*tried, attempt.method)
+	}
+
+	for _, attempt := range attempts {
+		attempt := attempt
+		switch attempt.method {
+		case cluster.SSHAuthKey:
+			key, err := os.ReadFile(s.KeyPath)
+			if err != nil {
+				attempt.err = fmt.Errorf("failed to read the key %s: %w", s.KeyPath, err)
+				continue
+			}
+			signer, err := ssh.ParsePrivateKey(key)
+			if err != nil {
+				attempt.err = fmt.Errorf("failed to parse the key %s: %w", s.KeyPath, err)
+				continue
+			}
+			methods = append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				mark(attempt)
+				return []ssh.Signer{signer}, nil
+			}))
+		case cluster.SSHAuthAgent:
+			sock := os.Getenv("SSH_AUTH_SOCK")
+			if sock == "" {
+				attempt.err = fmt.Errorf("SSH_AUTH_SOCK is not set")
+				continue
+			}
+			conn, err := net.Dial("unix", sock)
+			if err != nil {
+				attempt.err = fmt.Errorf("failed to connect to the ssh agent: %w", err)
+				continue
+			}
+			closers = append(closers, func() { conn.Close() })
+			signers, err := agent.NewClient(conn).Signers()
+			if err != nil {
+				attempt.err = fmt.Errorf("failed to list the ssh agent keys: %w", err)
+				continue
+			}
+			if len(signers) == 0 {
+				attempt.err = fmt.Errorf("the ssh agent has no keys")
+				continue
+			}
+			methods = append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+				mark(attempt)
+				return signers, nil
+			}))
+		case cluster.SSHAuthPassword:
+			methods = append(methods, ssh.PasswordCallback(func() (string, error) {
+				mark(attempt)
+				return a.Password, nil
+			}))
+		case cluster.SSHAuthKeyboardInteractive:
+			methods = append(methods, ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+				if !attempt.tried {
+					mark(attempt)
+				}
+				// every prompt is answered with the password
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = a.Password
+				}
+				return answers, nil
+			}))
+		}
+	}
+
+	return methods, done
+}
+
+// sshAuthError lists the reason each of the methods failed
+func sshAuthError(s *rig.SSH, attempts []*authAttempt) error {
+	reasons := make([]string, len(attempts))
+	for i, attempt := range attempts {
+		switch {
+		case attempt.err != nil:
+			reasons[i] = fmt.Sprintf("%s: %s", attempt.method, attempt.err.Error())
+		case attempt.tried:
+			reasons[i] = fmt.Sprintf("%s: rejected by the server", attempt.method)
+		default:
+			reasons[i] = fmt.Sprintf("%s: not accepted by the server", attempt.method)
+		}
+	}
+	return fmt.Errorf("ssh: unable to authenticate to %s: %s", s, strings.Join(reasons, "; "))
+}
+
+// dialSSHAuth makes the ssh connection trying the authentication methods in order until one of them
+// succeeds. The bastion connection is made by rig as usual.
+func dialSSHAuth(h *cluster.Host, s *rig.SSH, a cluster.SSHAuth) (*ssh.Client, error) {
+	attempts := make([]*authAttempt, len(a.Methods))
+	for i, m := range a.Methods {
+		attempts[i] = &authAttempt{method: m}
+	}
+	var tried []string
+	methods, done := sshAuthMethods(s, a, attempts, &tried)
+	defer done()
+	if len(methods) == 0 {
+		return nil, sshAuthError(s, attempts)
+	}
+
+	config := &ssh.ClientConfig{
+		User:            s.User,
+		Auth:            methods,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	if s.HostKey != "" {
+		config.HostKeyCallback = trustedHostKey(s.HostKey)
+	}
+
+	dst := cluster.JoinHostPort(s.Address, s.Port)
+	var conn net.Conn
+	if s.Bastion == nil {
+		c, err := net.Dial("tcp", dst)
+		if err != nil {
+			return nil, err
+		}
+		conn = c
+	} else {
+		if err := s.Bastion.Connect(); err != nil {
+			return nil, err
+		}
+		bastion := sshClient(s.Bastion)
+		if bastion == nil {
+			return nil, fmt.Errorf("ssh client of the bastion %s not available", s.Bastion)
+		}
+		c, err := bastion.Dial("tcp", dst)
+		if err != nil {
+			return nil, err
+		}
+		conn = c
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, dst, config)
+	if err != nil {
+		conn.Close()
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, sshAuthError(s, attempts)
+		}
+		return nil, err
+	}
+	if len(tried) > 0 {
+		log.WithField("host", h).Debugf("authenticated using the %s ssh auth method", tried[len(tried)-1])
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// trustedHostKey accepts only the given host key, which is in the authorized_keys format without the
+// comment like the rig ssh connection hostKey
+func trustedHostKey(trusted string) ssh.HostKeyCallback {
+	return func(_ string, _ net.Addr, k ssh.PublicKey) error {
+		if k.Type()+" "+base64.StdEncoding.EncodeToString(k.Marshal()) != trusted {
+			return fmt.Errorf("ssh host key verification failed")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package phase
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// startPasswordSSHServer starts an ssh server that only accepts the password and returns its port
+func startPasswordSSHServer(t *testing.T, password string) int {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if string(pass) == password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("wrong password")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					_ = ch.Reject(ssh.Prohibited, "no channels")
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestDialSSHAuth(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	port := startPasswordSSHServer(t, "secret")
+	s := &rig.SSH{Address: "127.0.0.1", Port: port, User: "root", KeyPath: filepath.Join(t.TempDir(), "id_rsa")}
+	h := &cluster.Host{Connection: rig.Connection{SSH: s}}
+
+	t.Run("fallback", func(t *testing.T) {
+		client, err := dialSSHAuth(h, s, cluster.SSHAuth{Methods: []string{cluster.SSHAuthKey, cluster.SSHAuthAgent, cluster.SSHAuthPassword}, Password: "secret"})
+		require.NoError(t, err)
+		client.Close()
+	})
+
+	t.Run("all fail", func(t *testing.T) {
+		_, err := dialSSHAuth(h, s, cluster.SSHAuth{Methods: []string{cluster.SSHAuthKey, cluster.SSHAuthAgent, cluster.SSHAuthPassword, cluster.SSHAuthKeyboardInteractive}, Password: "wrong"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unable to authenticate")
+		require.Contains(t, err.Error(), "key: failed to read the key")
+		require.Contains(t, err.Error(), "agent: SSH_AUTH_SOCK is not set")
+		require.Contains(t, err.Error(), "password: rejected by the server")
+		require.Contains(t, err.Error(), "keyboard-interactive: not accepted by the server")
+		require.NotContains(t, err.Error(), "wrong")
+		require.True(t, isAuthError(err))
+		require.False(t, isRetryableConnectError(err))
+	})
+}
+
+func TestUseSSHAuth(t *testing.T) {
+	h := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}}
+	require.NoError(t, useSSHAuth(h))
+	f := reflect.ValueOf(&h.Connection).Elem().FieldByName("client")
+	require.True(t, f.IsNil())
+
+	h.SSHAuth = cluster.SSHAuth{Methods: []string{cluster.SSHAuthPassword}, Password: "secret"}
+	require.NoError(t, useSSHAuth(h))
+	require.False(t, f.IsNil())
+	require.Equal(t, "[ssh] 10.0.0.1:22", h.String())
+}
+
+func TestAuthClientConnect(t *testing.T) {
+	port := startPasswordSSHServer(t, "secret")
+	s := &rig.SSH{Address: "127.0.0.1", Port: port, User: "root"}
+	h := &cluster.Host{Connection: rig.Connection{SSH: s}, SSHAuth: cluster.SSHAuth{Methods: []string{cluster.SSHAuthPassword}, Password: "secret"}}
+
+	c := &authClient{SSH: s, host: h}
+	require.NoError(t, c.Connect())
+	require.True(t, s.IsConnected())
+	require.NotNil(t, sshClient(s))
+	s.Disconnect()
+}
@@ -0,0 +1,130 @@
+package phase
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+
+	"github.com/k0sproject/k0sctl/config/cluster"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/proxy"
+)
+
+// SSHProxy is a socks5://[user:password@]host:port URL of a proxy the ssh connections are made through
+var SSHProxy string
+
+// ParseSSHProxy validates a SOCKS5 proxy URL
+func ParseSSHProxy(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "socks5" && u.Scheme != "socks5h") || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ssh proxy %q, must be an url such as socks5://proxy.example.com:1080", s)
+	}
+	return u, nil
+}
+
+// connectHost connects to the host directly or through the SSHProxy within the connect timeout of the host
+// and starts the ssh keepalives
+func connectHost(h *cluster.Host) error {
+	if err := dialHostTimeout(h, h.ConnectTimeoutOr(ConnectTimeout)); err != nil {
+		return err
+	}
+	startKeepAlive(h)
+	return nil
+}
+
+func dialHost(h *cluster.Host) error {
+	if err := useSSHAuth(h); err != nil {
+		return err
+	}
+	if err := useAgentForwarding(h); err != nil {
+		return err
+	}
+	if SSHProxy == "" || h.SSH == nil {
+		if SSHProxy != "" && h.WinRM != nil {
+			log.WithField("host", h).Warn("the ssh proxy is not used for WinRM connections")
+		}
+		return h.Connect()
+	}
+
+	u, err := ParseSSHProxy(SSHProxy)
+	if err != nil {
+		return err
+	}
+	dialer, err := proxy.FromURL(u, proxy.Direct)
+	if err != nil {
+		return err
+	}
+
+	return connectThroughProxy(h, dialer)
+}
+
+// connectThroughProxy makes the first ssh hop of the host through the dialer. The ssh client can't be
+// given a dialer, instead the connection is made through a local listener that accepts a single
+// connection and forwards it to a connection made through the proxy. The host name is sent to the
+// proxy unresolved, so the name resolution happens on the proxy side.
+func connectThroughProxy(h *cluster.Host, dialer proxy.Dialer) error {
+	// the bastion is the first hop when there is one
+	target := h.SSH
+	if target.Bastion != nil {
+		target = target.Bastion
+	}
+	// the connection names are cached on first use, make sure they show the real addresses
+	_ = h.String()
+	_ = target.String()
+
+	dst := cluster.JoinHostPort(target.Address, target.Port)
+	log.WithField("host", h).Debugf("connecting to %s through proxy %s", dst, redactURL(SSHProxy))
+	upstream, err := dialer.Dial("tcp", dst)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s through the ssh proxy: %w", dst, err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		upstream.Close()
+		return err
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		pipe(conn, upstream)
+	}()
+
+	addr, port := target.Address, target.Port
+	target.Address = "127.0.0.1"
+	target.Port = listener.Addr().(*net.TCPAddr).Port
+	defer func() {
+		target.Address, target.Port = addr, port
+	}()
+
+	return h.Connect()
+}
+
+// pipe copies the data between the connections until either of them is closed and then closes both
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyConn := func(dst, src net.Conn) {
+		_, _ = io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	<-done
+	a.Close()
+	b.Close()
+	<-done
+}
+
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.Redacted()
+}
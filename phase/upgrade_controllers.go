@@ -22,6 +22,11 @@ func (p *UpgradeControllers) Title() string {
 	return "Upgrade controllers"
 }
 
+// Sequential is true, the controllers are upgraded one at a time
+func (p *UpgradeControllers) Sequential() bool {
+	return true
+}
+
 // Prepare the phase
 func (p *UpgradeControllers) Prepare(config *config.Cluster) error {
 	log.Debugf("UpgradeControllers phase prep starting")
@@ -40,12 +45,20 @@ func (p *UpgradeControllers) ShouldRun() bool {
 	return len(p.hosts) > 0
 }
 
+// DryRun reports the controllers that would be upgraded
+func (p *UpgradeControllers) DryRun() error {
+	for _, h := range p.hosts {
+		p.DryMsgf(h, "upgrade k0s controller from %s to %s", h.Metadata.K0sRunningVersion, p.Config.Spec.K0sVersionFor(h))
+	}
+	return nil
+}
+
 // CleanUp cleans up the environment override files on hosts
 func (p *UpgradeControllers) CleanUp() {
 	for _, h := range p.hosts {
-		if len(h.Environment) > 0 {
+		if len(h.EnvironmentVars()) > 0 {
 			if err := h.Configurer.CleanupServiceEnvironment(h, h.K0sServiceName()); err != nil {
-				log.Warnf("%s: failed to clean up service environment: %s", h, err.Error())
+				log.WithField("host", h).Warnf("failed to clean up service environment: %s", err.Error())
 			}
 		}
 	}
@@ -54,7 +67,7 @@ func (p *UpgradeControllers) CleanUp() {
 // Run the phase
 func (p *UpgradeControllers) Run() error {
 	for _, h := range p.hosts {
-		log.Infof("%s: starting upgrade", h)
+		log.WithField("host", h).Info("starting upgrade")
 		if p.needsMigration(h) {
 			if err := p.migrateService(h); err != nil {
 				return err
@@ -67,21 +80,28 @@ func (p *UpgradeControllers) Run() error {
 		if err := h.WaitK0sServiceStopped(); err != nil {
 			return err
 		}
-		if err := h.UpdateK0sBinary(p.Config.Spec.K0s.Version); err != nil {
+		if err := h.UpdateK0sBinary(p.Config.Spec.K0sVersionFor(h), proxyEnv()); err != nil {
+			return err
+		}
+		if err := verifyK0sBinary(p.Config, h); err != nil {
 			return err
 		}
 
-		if len(h.Environment) > 0 {
-			log.Infof("%s: updating service environment", h)
-			if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.Environment); err != nil {
+		if len(h.EnvironmentVars()) > 0 {
+			log.WithField("host", h).Info("updating service environment")
+			if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.EnvironmentVars()); err != nil {
 				return err
 			}
 		}
 
+		if err := updateServiceOverrides(h); err != nil {
+			return err
+		}
+
 		if err := h.Configurer.StartService(h, h.K0sServiceName()); err != nil {
 			return err
 		}
-		log.Infof("%s: waiting for the k0s service to start", h)
+		log.WithField("host", h).Info("waiting for the k0s service to start")
 		if err := h.WaitK0sServiceRunning(); err != nil {
 			return err
 		}
@@ -98,11 +118,11 @@ func (p *UpgradeControllers) Run() error {
 }
 
 func (p *UpgradeControllers) needsMigration(h *cluster.Host) bool {
-	log.Debugf("%s: checking need for 0.10 --> 0.11 migration", h)
+	log.WithField("host", h).Debug("checking need for 0.10 --> 0.11 migration")
 	c, _ := semver.NewConstraint("< 0.11-0")
 	current, err := semver.NewVersion(h.Metadata.K0sRunningVersion)
 	if err != nil {
-		log.Warnf("%s: failed to parse version info: %s", h, err.Error())
+		log.WithField("host", h).Warnf("failed to parse version info: %s", err.Error())
 		return false
 	}
 
@@ -111,7 +131,7 @@ func (p *UpgradeControllers) needsMigration(h *cluster.Host) bool {
 
 func (p *UpgradeControllers) migrateService(h *cluster.Host) error {
 
-	log.Infof("%s: updating legacy 'k0sserver' service to '%s'", h, h.K0sServiceName())
+	log.WithField("host", h).Infof("updating legacy 'k0sserver' service to '%s'", h.K0sServiceName())
 	if err := h.Configurer.StopService(h, "k0sserver"); err != nil {
 		return err
 	}
@@ -122,7 +142,7 @@ func (p *UpgradeControllers) migrateService(h *cluster.Host) error {
 	if sp == "" {
 		return fmt.Errorf("service script path resolved to empty string")
 	}
-	log.Debugf("%s: found old service path: %s", h, sp)
+	log.WithField("host", h).Debugf("found old service path: %s", sp)
 	newPath := strings.Replace(sp, "k0sserver", h.K0sServiceName(), 1)
 	if err != nil {
 		return err
@@ -0,0 +1,22 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpgradeControllersSkipsTokenLeader(t *testing.T) {
+	leader := &cluster.Host{Role: "controller", Metadata: cluster.HostMetadata{K0sRunningVersion: "1.21.1+k0s.0", NeedsUpgrade: true}}
+	worker := &cluster.Host{Role: "worker", Metadata: cluster.HostMetadata{K0sRunningVersion: "1.21.1+k0s.0", NeedsUpgrade: true}}
+	spec := &cluster.Spec{Hosts: cluster.Hosts{leader, worker}}
+	_, err := spec.SelectHosts(func(h *cluster.Host) bool { return h == worker })
+	require.NoError(t, err)
+	require.Equal(t, leader, spec.K0sLeader())
+
+	p := &UpgradeControllers{}
+	require.NoError(t, p.Prepare(&config.Cluster{Spec: spec}))
+	require.False(t, p.ShouldRun(), "the controller not selected with --hosts is upgraded")
+}
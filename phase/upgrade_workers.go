@@ -2,9 +2,7 @@ package phase
 
 import (
 	"fmt"
-	"math"
 
-	"github.com/gammazero/workerpool"
 	"github.com/k0sproject/k0sctl/config"
 	"github.com/k0sproject/k0sctl/config/cluster"
 	log "github.com/sirupsen/logrus"
@@ -15,6 +13,10 @@ type UpgradeWorkers struct {
 	GenericPhase
 
 	NoDrain bool
+	// NoDrainOnFailure leaves the nodes drained during the run cordoned when the apply fails
+	NoDrainOnFailure bool
+	// BatchSize overrides the number of workers upgraded at a time from the configuration
+	BatchSize int
 
 	hosts  cluster.Hosts
 	leader *cluster.Host
@@ -44,92 +46,149 @@ func (p *UpgradeWorkers) ShouldRun() bool {
 	return len(p.hosts) > 0
 }
 
-// CleanUp cleans up the environment override files on hosts
+// DryRun reports the workers that would be upgraded
+func (p *UpgradeWorkers) DryRun() error {
+	for _, h := range p.hosts {
+		if p.drain() {
+			p.DryMsgf(h, "drain the node")
+		}
+		p.DryMsgf(h, "upgrade k0s worker from %s to %s", h.Metadata.K0sRunningVersion, p.Config.Spec.K0sVersionFor(h))
+	}
+	return nil
+}
+
+// CleanUp cleans up the environment override files on hosts and uncordons the nodes left cordoned
 func (p *UpgradeWorkers) CleanUp() {
+	p.uncordonNodes()
 	for _, h := range p.hosts {
-		if len(h.Environment) > 0 {
+		if len(h.EnvironmentVars()) > 0 {
 			if err := h.Configurer.CleanupServiceEnvironment(h, h.K0sServiceName()); err != nil {
-				log.Warnf("%s: failed to clean up service environment: %s", h, err.Error())
+				log.WithField("host", h).Warnf("failed to clean up service environment: %s", err.Error())
 			}
 		}
 	}
 }
 
+// uncordonNodes marks the nodes that were drained during the run schedulable again, unless the drained
+// state is to be left for inspection
+func (p *UpgradeWorkers) uncordonNodes() {
+	for _, h := range p.hosts {
+		if !h.Metadata.Cordoned {
+			continue
+		}
+		if p.NoDrainOnFailure {
+			log.WithField("host", h).Warnf("leaving node %s cordoned because --no-drain-on-failure given", h.Metadata.Hostname)
+			continue
+		}
+		log.WithField("host", h).Infof("uncordoning node %s", h.Metadata.Hostname)
+		if err := p.leader.UncordonNode(h); err != nil {
+			log.WithField("host", h).Warnf("failed to uncordon node %s: %s", h.Metadata.Hostname, err.Error())
+			continue
+		}
+		h.Metadata.Cordoned = false
+	}
+}
+
 // Run the phase
 func (p *UpgradeWorkers) Run() error {
-	// Upgrade worker hosts parallelly in 10% chunks
-	concurrentUpgrades := int(math.Floor(float64(len(p.hosts)) * 0.10))
-	if concurrentUpgrades == 0 {
-		concurrentUpgrades = 1
-	}
-	log.Infof("Upgrading %d workers in parallel", concurrentUpgrades)
-	wp := workerpool.New(concurrentUpgrades)
-	errors := make(map[string]error)
-	for _, w := range p.hosts {
-		h := w
-		wp.Submit(func() {
-			err := p.upgradeWorker(h)
-			if err != nil {
-				errors[h.String()] = err
-				log.Errorf("%s: upgrade failed: %s", h, err.Error())
-			}
-		})
-	}
-	wp.StopWait()
+	batchSize := p.batchSize()
+	batches := (len(p.hosts) + batchSize - 1) / batchSize
+	log.Infof("Upgrading %d workers in batches of %d", len(p.hosts), batchSize)
+
+	for i := 0; i < len(p.hosts); i += batchSize {
+		end := i + batchSize
+		if end > len(p.hosts) {
+			end = len(p.hosts)
+		}
+		batch := p.hosts[i:end]
+		log.Infof("Upgrading batch %d/%d (%d workers)", i/batchSize+1, batches, len(batch))
 
-	if len(errors) > 0 {
-		return fmt.Errorf("upgrading %d workers failed", len(errors))
+		if err := p.parallelDo(batch, p.upgradeWorker); err != nil {
+			// do not continue the rollout when a batch fails to avoid taking down more capacity
+			return fmt.Errorf("worker upgrade halted at batch %d/%d: %w", i/batchSize+1, batches, err)
+		}
 	}
+
 	return nil
 }
 
-func (p *UpgradeWorkers) upgradeWorker(h *cluster.Host) error {
-	log.Infof("%s: upgrade starting", h)
+// batchSize returns the number of workers to upgrade at a time
+func (p *UpgradeWorkers) batchSize() int {
+	if p.BatchSize > 0 {
+		return p.BatchSize
+	}
+	if p.Config.Spec.K0s.Upgrade.BatchSize > 0 {
+		return p.Config.Spec.K0s.Upgrade.BatchSize
+	}
+	return 1
+}
+
+// drain returns true when the workers should be drained before upgrading
+func (p *UpgradeWorkers) drain() bool {
+	return !p.NoDrain && p.Config.Spec.K0s.Upgrade.DrainEnabled()
+}
 
-	if !p.NoDrain {
-		log.Debugf("%s: draining...", h)
-		if err := p.leader.DrainNode(h); err != nil {
+func (p *UpgradeWorkers) upgradeWorker(h *cluster.Host) error {
+	log.WithField("host", h).Info("upgrade starting")
+
+	if p.drain() {
+		upgrade := p.Config.Spec.K0s.Upgrade
+		log.WithField("host", h).Info("draining node")
+		// the node is cordoned before the pods are evicted, also when the drain fails
+		h.Metadata.Cordoned = true
+		if err := p.leader.DrainNode(h, upgrade.DrainGracePeriod, upgrade.DrainTimeout); err != nil {
 			return err
 		}
-		log.Debugf("%s: draining complete", h)
+		log.WithField("host", h).Debug("draining complete")
 	}
 
-	log.Debugf("%s: Update and restart service", h)
+	log.WithField("host", h).Debug("Update and restart service")
 	if err := h.Configurer.StopService(h, h.K0sServiceName()); err != nil {
 		return err
 	}
 	if err := h.WaitK0sServiceStopped(); err != nil {
 		return err
 	}
-	if err := h.UpdateK0sBinary(p.Config.Spec.K0s.Version); err != nil {
+	if err := h.UpdateK0sBinary(p.Config.Spec.K0sVersionFor(h), proxyEnv()); err != nil {
+		return err
+	}
+	if err := verifyK0sBinary(p.Config, h); err != nil {
 		return err
 	}
 
-	if len(h.Environment) > 0 {
-		log.Infof("%s: updating service environment", h)
-		if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.Environment); err != nil {
+	if len(h.EnvironmentVars()) > 0 {
+		log.WithField("host", h).Info("updating service environment")
+		if err := h.Configurer.UpdateServiceEnvironment(h, h.K0sServiceName(), h.EnvironmentVars()); err != nil {
 			return err
 		}
 	}
 
+	if err := updateServiceOverrides(h); err != nil {
+		return err
+	}
+
 	if err := h.Configurer.StartService(h, h.K0sServiceName()); err != nil {
 		return err
 	}
-	if !p.NoDrain {
-		log.Debugf("%s: marking node schedulable again", h)
+	if p.drain() {
+		log.WithField("host", h).Debug("marking node schedulable again")
 		if err := p.leader.UncordonNode(h); err != nil {
 			return err
 		}
+		h.Metadata.Cordoned = false
 	}
 	if NoWait {
-		log.Debugf("%s: not waiting because --no-wait given", h)
+		log.WithField("host", h).Info("waiting for the k0s service to start, not waiting for the node to become ready because --no-wait given")
+		if err := h.WaitK0sServiceRunning(); err != nil {
+			return err
+		}
 	} else {
-		log.Infof("%s: waiting for node to become ready again", h)
+		log.WithField("host", h).Info("waiting for node to become ready again")
 		if err := p.Config.Spec.K0sLeader().WaitKubeNodeReady(h); err != nil {
-			return err
+			return fmt.Errorf("node did not become ready: %w", err)
 		}
 		h.Metadata.Ready = true
 	}
-	log.Infof("%s: upgrade successful", h)
+	log.WithField("host", h).Info("upgrade successful")
 	return nil
 }
@@ -0,0 +1,82 @@
+package phase
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	cfg "github.com/k0sproject/k0sctl/configurer"
+	"github.com/k0sproject/k0sctl/configurer/linux"
+	"github.com/k0sproject/rig"
+	"github.com/k0sproject/rig/exec"
+	"github.com/stretchr/testify/require"
+)
+
+type kubectlConfigurer struct {
+	cfg.Linux
+	linux.Ubuntu
+}
+
+func (c kubectlConfigurer) KubectlCmdf(s string, args ...interface{}) string {
+	return "kubectl " + fmt.Sprintf(s, args...)
+}
+
+// recordingClient is a rig connection client that records the commands and fails the ones in fail
+type recordingClient struct {
+	rig.Localhost
+	commands []string
+	fail     map[string]bool
+}
+
+func (c *recordingClient) IsConnected() bool { return true }
+
+func (c *recordingClient) Exec(cmd string, opts ...exec.Option) error {
+	cmd, err := exec.Build(opts...).Command(cmd)
+	if err != nil {
+		return err
+	}
+	c.commands = append(c.commands, cmd)
+	if c.fail[cmd] {
+		return fmt.Errorf("command failed")
+	}
+	return nil
+}
+
+// setConnectionField sets an unexported field of the rig connection of the host
+func setConnectionField(t *testing.T, h *cluster.Host, name string, value interface{}) {
+	f := reflect.ValueOf(&h.Connection).Elem().FieldByName(name)
+	require.True(t, f.IsValid())
+	reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem().Set(reflect.ValueOf(value))
+}
+
+func TestUpgradeWorkersUncordonNodes(t *testing.T) {
+	client := &recordingClient{fail: map[string]bool{}}
+	leader := &cluster.Host{Role: "controller", Configurer: &kubectlConfigurer{}}
+	setConnectionField(t, leader, "client", client)
+	setConnectionField(t, leader, "sudofunc", func(cmd string) string { return cmd })
+
+	drained := &cluster.Host{Role: "worker", Metadata: cluster.HostMetadata{Hostname: "worker1", NeedsUpgrade: true, Cordoned: true}}
+	failing := &cluster.Host{Role: "worker", Metadata: cluster.HostMetadata{Hostname: "worker2", NeedsUpgrade: true, Cordoned: true}}
+	upgraded := &cluster.Host{Role: "worker", Metadata: cluster.HostMetadata{Hostname: "worker3", NeedsUpgrade: true}}
+
+	p := &UpgradeWorkers{NoDrainOnFailure: true}
+	require.NoError(t, p.Prepare(&config.Cluster{Spec: &cluster.Spec{Hosts: cluster.Hosts{leader, drained, failing, upgraded}}}))
+	p.leader = leader
+	p.uncordonNodes()
+	require.Empty(t, client.commands, "the nodes are left cordoned with --no-drain-on-failure")
+	require.True(t, drained.Metadata.Cordoned)
+
+	p.NoDrainOnFailure = false
+	client.fail["/bin/sh -c 'kubectl uncordon worker2'"] = true
+	p.uncordonNodes()
+	require.Equal(t, []string{
+		"/bin/sh -c 'kubectl uncordon worker1'",
+		"/bin/sh -c 'kubectl uncordon worker2'",
+	}, client.commands, "only the nodes cordoned during the run are uncordoned")
+	require.False(t, drained.Metadata.Cordoned)
+	require.True(t, failing.Metadata.Cordoned, "a failed uncordon leaves the node marked cordoned")
+	require.False(t, upgraded.Metadata.Cordoned)
+}
@@ -1,16 +1,35 @@
 package phase
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
 	"github.com/k0sproject/k0sctl/config"
 	"github.com/k0sproject/k0sctl/config/cluster"
 	"github.com/k0sproject/rig/exec"
 	log "github.com/sirupsen/logrus"
 )
 
+// UploadRetries is the number of times a failed k0s binary upload is retried
+var UploadRetries = 3
+
+// uploadChunkSize is the size of the chunks the k0s binary is uploaded in, a failed upload is resumed
+// from the last completed chunk
+const uploadChunkSize = 32 * 1024 * 1024
+
 // UploadBinaries uploads k0s binaries from localhost to target
 type UploadBinaries struct {
 	GenericPhase
 	hosts cluster.Hosts
+
+	mu   sync.Mutex
+	sums map[string]string
 }
 
 // Title for the phase
@@ -22,7 +41,7 @@ func (p *UploadBinaries) Title() string {
 func (p *UploadBinaries) Prepare(config *config.Cluster) error {
 	p.Config = config
 	p.hosts = p.Config.Spec.Hosts.Filter(func(h *cluster.Host) bool {
-		return h.UploadBinaryPath != "" && h.Metadata.K0sBinaryVersion != p.Config.Spec.K0s.Version && !h.Metadata.NeedsUpgrade
+		return h.UploadBinaryPath != "" && (Force || h.Metadata.K0sBinaryVersion != p.Config.Spec.K0sVersionFor(h)) && !h.Metadata.NeedsUpgrade
 	})
 	return nil
 }
@@ -34,20 +53,207 @@ func (p *UploadBinaries) ShouldRun() bool {
 
 // Run the phase
 func (p *UploadBinaries) Run() error {
-	return p.hosts.ParallelEach(p.uploadBinary)
+	return p.parallelDownloadDo(p.hosts, p.uploadBinary)
+}
+
+// localSum returns the sha256 checksum of a local binary, the checksums are calculated once per file
+func (p *UploadBinaries) localSum(path string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if sum, ok := p.sums[path]; ok {
+		return sum, nil
+	}
+	sum, err := fileSHA256(path, -1)
+	if err != nil {
+		return "", err
+	}
+	if p.sums == nil {
+		p.sums = make(map[string]string)
+	}
+	p.sums[path] = sum
+	return sum, nil
 }
 
 func (p *UploadBinaries) uploadBinary(h *cluster.Host) error {
-	log.Infof("%s: uploading k0s binary from %s", h, h.UploadBinaryPath)
-	if err := h.Upload(h.UploadBinaryPath, h.Configurer.K0sBinaryPath(), exec.Sudo(h)); err != nil {
+	dst := h.Configurer.K0sBinaryPath()
+	sum, err := p.localSum(h.UploadBinaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to calculate the checksum of %s: %w", h.UploadBinaryPath, err)
+	}
+
+	if remote, err := h.Configurer.Sha256sum(h, dst); err == nil && remote == sum {
+		log.WithField("host", h).Infof("k0s binary %s is already up to date, not uploading", dst)
+	} else {
+		part := dst + ".part"
+		err := retry.Do(
+			func() error {
+				return uploadResumable(h, h.UploadBinaryPath, part, sum)
+			},
+			retry.OnRetry(func(n uint, err error) {
+				log.WithField("host", h).Warnf("k0s binary upload failed, retrying (%d/%d): %s", n+1, UploadRetries, err.Error())
+			}),
+			retry.DelayType(retry.BackOffDelay),
+			retry.Delay(2*time.Second),
+			retry.Attempts(uint(UploadRetries)+1),
+			retry.LastErrorOnly(true),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to upload the k0s binary: %w", err)
+		}
+
+		if err := h.Configurer.MoveFile(h, part, dst); err != nil {
+			return err
+		}
+	}
+
+	if err := h.Configurer.Chmod(h, dst, "0700"); err != nil {
 		return err
 	}
 
-	if err := h.Configurer.Chmod(h, h.Configurer.K0sBinaryPath(), "0700"); err != nil {
+	if err := verifyK0sBinary(p.Config, h); err != nil {
 		return err
 	}
 
-	h.Metadata.K0sBinaryVersion = p.Config.Spec.K0s.Version
+	h.Metadata.K0sBinaryVersion = p.Config.Spec.K0sVersionFor(h)
+
+	return nil
+}
+
+// uploadResumable uploads the local file to the remote path in chunks. An existing remote file that matches the
+// beginning of the local file, such as one left behind by an earlier failed or interrupted upload, is resumed,
+// any other file is replaced. The checksum of the complete remote file is compared to the local one and the
+// remote file is removed when they do not match.
+func uploadResumable(h *cluster.Host, src, dst, sum string) error {
+	stat, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	size := stat.Size()
+
+	offset, err := resumeOffset(h, src, dst, size)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		log.WithField("host", h).Infof("resuming the k0s binary upload at %d of %d bytes", offset, size)
+	} else {
+		log.WithField("host", h).Infof("uploading k0s binary from %s", src)
+	}
+
+	chunk := dst + ".chunk"
+	defer func() {
+		if h.Configurer.FileExist(h, chunk) {
+			_ = h.Configurer.DeleteFile(h, chunk)
+		}
+	}()
+
+	for offset < size {
+		n := size - offset
+		if n > uploadChunkSize {
+			n = uploadChunkSize
+		}
+		if err := uploadChunk(h, src, chunk, offset, n); err != nil {
+			return err
+		}
+		if err := h.Configurer.AppendFile(h, chunk, dst); err != nil {
+			return err
+		}
+		offset += n
+		log.WithField("host", h).Debugf("uploaded %d of %d bytes of the k0s binary", offset, size)
+	}
+
+	remote, err := h.Configurer.Sha256sum(h, dst)
+	if err != nil {
+		return err
+	}
+	if remote != sum {
+		if err := h.Configurer.DeleteFile(h, dst); err != nil {
+			log.WithField("host", h).Warnf("failed to remove %s: %s", dst, err.Error())
+		}
+		return fmt.Errorf("uploaded file checksum mismatch: expected sha256 %s, got %s", sum, remote)
+	}
 
 	return nil
 }
+
+// resumeOffset returns the size of the remote file when it matches the beginning of the local file, otherwise
+// the remote file is removed and 0 is returned
+func resumeOffset(h *cluster.Host, src, dst string, size int64) (int64, error) {
+	if !h.Configurer.FileExist(h, dst) {
+		return 0, nil
+	}
+
+	remoteSize, err := h.Configurer.FileSize(h, dst)
+	if err == nil && remoteSize > 0 && remoteSize <= size {
+		remote, rerr := h.Configurer.Sha256sum(h, dst)
+		local, lerr := fileSHA256(src, remoteSize)
+		if rerr == nil && lerr == nil && remote == local {
+			return remoteSize, nil
+		}
+	}
+
+	log.WithField("host", h).Debugf("removing a partial upload %s that does not match the local file", dst)
+	if err := h.Configurer.DeleteFile(h, dst); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// uploadChunk uploads n bytes of the local file starting at offset to the remote path
+func uploadChunk(h *cluster.Host, src, dst string, offset, n int64) error {
+	tmp, err := writeChunk(src, offset, n)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	return h.Upload(tmp, dst, exec.Sudo(h))
+}
+
+// writeChunk copies n bytes of the file starting at offset to a temporary file and returns its path
+func writeChunk(src string, offset, n int64) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	if _, err := in.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	out, err := os.CreateTemp("", "k0sctl-upload")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.CopyN(out, in, n); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// fileSHA256 returns the hex encoded sha256 checksum of the first n bytes of a local file, or of the whole
+// file when n is negative
+func fileSHA256(path string, n int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if n >= 0 {
+		r = io.LimitReader(f, n)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
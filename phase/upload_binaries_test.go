@@ -0,0 +1,41 @@
+package phase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSHA256(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "k0s")
+	require.NoError(t, os.WriteFile(fn, []byte("hello world"), 0600))
+
+	full := sha256.Sum256([]byte("hello world"))
+	sum, err := fileSHA256(fn, -1)
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(full[:]), sum)
+
+	prefix := sha256.Sum256([]byte("hello"))
+	sum, err = fileSHA256(fn, 5)
+	require.NoError(t, err)
+	require.Equal(t, hex.EncodeToString(prefix[:]), sum)
+}
+
+func TestWriteChunk(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "k0s")
+	require.NoError(t, os.WriteFile(fn, []byte("hello world"), 0600))
+
+	tmp, err := writeChunk(fn, 6, 5)
+	require.NoError(t, err)
+	defer os.Remove(tmp)
+	content, err := os.ReadFile(tmp)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(content))
+
+	_, err = writeChunk(fn, 6, 10)
+	require.Error(t, err)
+}
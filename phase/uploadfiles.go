@@ -1,7 +1,9 @@
 package phase
 
 import (
-	"path/filepath"
+	"fmt"
+	"os"
+	"path"
 
 	"github.com/k0sproject/k0sctl/config"
 	"github.com/k0sproject/k0sctl/config/cluster"
@@ -37,36 +39,66 @@ func (p *UploadFiles) ShouldRun() bool {
 	return len(p.hosts) > 0
 }
 
+// DryRun reports the files that would be uploaded
+func (p *UploadFiles) DryRun() error {
+	for _, h := range p.hosts {
+		for _, f := range h.Files {
+			dst := f.DestinationDir
+			if f.Destination != "" {
+				dst = f.Destination
+			}
+			p.DryMsgf(h, "upload %s to %s", f.Source, dst)
+		}
+	}
+	return nil
+}
+
 // Run the phase
 func (p *UploadFiles) Run() error {
-	return p.Config.Spec.Hosts.ParallelEach(p.uploadFiles)
+	return p.parallelDo(p.hosts, p.uploadFiles)
 }
 
 func (p *UploadFiles) uploadFiles(h *cluster.Host) error {
 	for _, f := range h.Files {
-		log.Infof("%s: starting to upload %s", h, f.Name)
-		files, err := f.Resolve()
+		name := f.Name
+		if name == "" {
+			name = f.Source
+		}
+		log.WithField("host", h).Infof("starting to upload %s", name)
+		items, err := f.Items()
 		if err != nil {
 			return err
 		}
 
-		if err := h.Execf("install -d %s -m %s", f.DestinationDir, f.PermString, exec.Sudo(h)); err != nil {
-			return err
-		}
+		dirs := make(map[string]struct{})
+		for _, item := range items {
+			dir := path.Dir(item.Destination)
+			if _, ok := dirs[dir]; !ok {
+				if err := h.Execf(`install -d "%s" -m %s`, dir, f.PermString, exec.Sudo(h)); err != nil {
+					return fmt.Errorf("failed to create directory %s: %w", dir, err)
+				}
+				dirs[dir] = struct{}{}
+			}
 
-		for _, file := range files {
-			log.Debugf("%s: uploading %s to %s", h, file, f.DestinationDir)
-			destination := filepath.Join(f.DestinationDir, filepath.Base(file))
+			log.WithField("host", h).Debugf("uploading %s to %s (%d bytes)", item.Source, item.Destination, item.Size)
+			if err := h.Upload(item.Source, item.Destination, exec.Sudo(h)); err != nil {
+				if os.IsPermission(err) {
+					return fmt.Errorf("permission denied while reading %s for upload", item.Source)
+				}
+				return fmt.Errorf("failed to upload %s to %s: %w", item.Source, item.Destination, err)
+			}
 
-			if err := h.Upload(file, destination, exec.Sudo(h)); err != nil {
+			if err := h.Configurer.Chmod(h, item.Destination, f.PermString); err != nil {
 				return err
 			}
 
-			if err := h.Configurer.Chmod(h, destination, f.PermString); err != nil {
-				return err
+			if owner := f.Owner(); owner != "" {
+				if err := h.Execf(`chown %s "%s"`, owner, item.Destination, exec.Sudo(h)); err != nil {
+					return fmt.Errorf("failed to set the owner of %s to %s: %w", item.Destination, owner, err)
+				}
 			}
 		}
-		log.Infof("%s: %s upload done", h, f.Name)
+		log.WithField("host", h).Infof("%s upload done", name)
 	}
 	return nil
 }
@@ -2,8 +2,10 @@ package phase
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/Masterminds/semver"
+	"github.com/k0sproject/k0sctl/config/cluster"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -11,15 +13,35 @@ import (
 type ValidateFacts struct {
 	GenericPhase
 	SkipDowngradeCheck bool
+	// AllowVersionSkip disables the check for upgrades skipping minor versions
+	AllowVersionSkip bool
 }
 
+// maxMinorVersionSkip is the number of minor versions k0s can be upgraded by at once, the kubernetes
+// version skew policy does not allow skipping minor versions
+const maxMinorVersionSkip = 1
+
 // Title for the phase
 func (p *ValidateFacts) Title() string {
 	return "Validate facts"
 }
 
+// Sequential is true, the phase only checks the gathered facts
+func (p *ValidateFacts) Sequential() bool {
+	return true
+}
+
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *ValidateFacts) ReadOnly() bool {
+	return true
+}
+
 // Run the phase
 func (p *ValidateFacts) Run() error {
+	if err := p.validateTokenLeader(); err != nil {
+		return err
+	}
+
 	if err := p.validateDowngrade(); err != nil {
 		return err
 	}
@@ -28,23 +50,57 @@ func (p *ValidateFacts) Run() error {
 		return err
 	}
 
+	if err := p.validateVersionSkip(); err != nil {
+		return err
+	}
+
+	p.warnMixedVersions()
+
 	return nil
 }
 
+// validateTokenLeader makes sure the controller kept for creating the join tokens when no controller is
+// selected with --hosts is running k0s, the cluster can not be initialized on a host that is not selected
+func (p *ValidateFacts) validateTokenLeader() error {
+	leader := p.Config.Spec.TokenLeader()
+	if leader == nil || leader.Metadata.K0sRunningVersion != "" {
+		return nil
+	}
+	return fmt.Errorf("the controller %s used for creating the join tokens is not running k0s, include a controller in --hosts to initialize the cluster", leader)
+}
+
+// warnMixedVersions warns when the controllers are going to run different k0s versions
+func (p *ValidateFacts) warnMixedVersions() {
+	versions := make(map[string]struct{})
+	for _, h := range p.Config.Spec.Hosts.Controllers() {
+		versions[p.Config.Spec.K0sVersionFor(h)] = struct{}{}
+	}
+	if len(versions) > 1 {
+		log.Warnf("the controllers are configured to run %d different k0s versions, running mixed versions in the control plane may be unsafe", len(versions))
+	}
+
+	for _, h := range p.Config.Spec.Hosts {
+		if h.K0sVersion != "" && p.Config.Spec.K0sVersionFor(h) != p.Config.Spec.K0s.Version {
+			log.WithField("host", h).Infof("using k0s version %s instead of %s from spec.k0s.version", p.Config.Spec.K0sVersionFor(h), p.Config.Spec.K0s.Version)
+		}
+	}
+}
+
 func (p *ValidateFacts) validateDowngrade() error {
 	if p.SkipDowngradeCheck {
 		return nil
 	}
-	if p.Config.Spec.K0sLeader().Metadata.K0sRunningVersion == "" {
+	leader := p.Config.Spec.K0sLeader()
+	if leader.Metadata.K0sRunningVersion == "" {
 		return nil
 	}
 
-	cfgV, err := semver.NewVersion(p.Config.Spec.K0s.Version)
+	cfgV, err := semver.NewVersion(p.Config.Spec.K0sVersionFor(leader))
 	if err != nil {
 		return err
 	}
 
-	runV, err := semver.NewVersion(p.Config.Spec.K0sLeader().Metadata.K0sRunningVersion)
+	runV, err := semver.NewVersion(leader.Metadata.K0sRunningVersion)
 	if err != nil {
 		return err
 	}
@@ -56,6 +112,58 @@ func (p *ValidateFacts) validateDowngrade() error {
 	return nil
 }
 
+// validateVersionSkip makes sure none of the hosts is going to be upgraded by more minor versions than
+// k0s supports
+func (p *ValidateFacts) validateVersionSkip() error {
+	if p.AllowVersionSkip {
+		return nil
+	}
+
+	var errors []cluster.HostError
+	for _, h := range p.Config.Spec.Hosts {
+		if h.Metadata.K0sRunningVersion == "" {
+			continue
+		}
+		if err := checkVersionSkip(h.Metadata.K0sRunningVersion, p.Config.Spec.K0sVersionFor(h)); err != nil {
+			errors = append(errors, cluster.HostError{Host: h.String(), Err: err})
+		}
+	}
+
+	if len(errors) > 0 {
+		return &cluster.ParallelError{Errors: errors}
+	}
+	return nil
+}
+
+// checkVersionSkip returns an error suggesting the intermediate versions to upgrade through when the upgrade
+// from the running version to the target skips minor versions. The pre-release and build metadata parts of
+// the versions are not relevant and upgrades to a new major version are not checked.
+func checkVersionSkip(running, target string) error {
+	runV, err := semver.NewVersion(running)
+	if err != nil {
+		return fmt.Errorf("invalid running k0s version %q: %w", running, err)
+	}
+	targetV, err := semver.NewVersion(target)
+	if err != nil {
+		return fmt.Errorf("invalid k0s version %q: %w", target, err)
+	}
+
+	if runV.Major() != targetV.Major() || targetV.Minor() <= runV.Minor()+maxMinorVersionSkip {
+		return nil
+	}
+
+	var steps []string
+	for minor := runV.Minor() + maxMinorVersionSkip; minor < targetV.Minor(); minor += maxMinorVersionSkip {
+		steps = append(steps, fmt.Sprintf("%d.%d.x", runV.Major(), minor))
+	}
+	suggestion := fmt.Sprintf("the latest %s release", steps[0])
+	if len(steps) > 1 {
+		suggestion = fmt.Sprintf("the latest %s and %s releases in order", strings.Join(steps[:len(steps)-1], ", "), steps[len(steps)-1])
+	}
+
+	return fmt.Errorf("can't upgrade k0s from %s to %s, k0s can only be upgraded by %d minor version at a time: upgrade to %s first or use --allow-version-skip", runV.String(), targetV.String(), maxMinorVersionSkip, suggestion)
+}
+
 func (p *ValidateFacts) validateDefaultVersion() error {
 	// Only check when running with a defaulted version
 	if !p.Config.Spec.K0s.Metadata.VersionDefaulted {
@@ -0,0 +1,63 @@
+package phase
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0sctl/config"
+	"github.com/k0sproject/k0sctl/config/cluster"
+	"github.com/k0sproject/rig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckVersionSkip(t *testing.T) {
+	require.NoError(t, checkVersionSkip("1.23.5+k0s.0", "1.23.6+k0s.0"))
+	require.NoError(t, checkVersionSkip("1.23.5+k0s.0", "1.24.1+k0s.0"))
+	require.NoError(t, checkVersionSkip("v1.23.5+k0s.1", "1.24.0-rc.1+k0s.0"))
+	require.NoError(t, checkVersionSkip("0.13.1", "1.21.2+k0s.0"))
+
+	err := checkVersionSkip("1.23.5+k0s.0", "1.25.1+k0s.0")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can't upgrade k0s from 1.23.5+k0s.0 to 1.25.1+k0s.0")
+	require.Contains(t, err.Error(), "upgrade to the latest 1.24.x release first")
+
+	err = checkVersionSkip("1.23.0-beta.1+k0s.0", "1.27.2+k0s.0")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "upgrade to the latest 1.24.x, 1.25.x and 1.26.x releases in order first")
+
+	require.Error(t, checkVersionSkip("foo", "1.27.2+k0s.0"))
+}
+
+func TestValidateVersionSkip(t *testing.T) {
+	h1 := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}, Role: "controller", Metadata: cluster.HostMetadata{K0sRunningVersion: "1.23.5+k0s.0"}}
+	h2 := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2", Port: 22}}, Role: "worker", K0sVersion: "1.24.1+k0s.0", Metadata: cluster.HostMetadata{K0sRunningVersion: "1.23.5+k0s.0"}}
+	h3 := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.3", Port: 22}}, Role: "worker"}
+	p := &ValidateFacts{GenericPhase: GenericPhase{Config: &config.Cluster{Spec: &cluster.Spec{
+		K0s:   cluster.K0s{Version: "1.25.0+k0s.0"},
+		Hosts: cluster.Hosts{h1, h2, h3},
+	}}}}
+
+	err := p.validateVersionSkip()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed on 1 hosts")
+	require.Contains(t, err.Error(), "[ssh] 10.0.0.1:22")
+
+	p.AllowVersionSkip = true
+	require.NoError(t, p.validateVersionSkip())
+}
+
+func TestValidateTokenLeader(t *testing.T) {
+	leader := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.1", Port: 22}}, Role: "controller"}
+	worker := &cluster.Host{Connection: rig.Connection{SSH: &rig.SSH{Address: "10.0.0.2", Port: 22}}, Role: "worker"}
+	spec := &cluster.Spec{Hosts: cluster.Hosts{leader, worker}}
+	p := &ValidateFacts{GenericPhase: GenericPhase{Config: &config.Cluster{Spec: spec}}}
+	require.NoError(t, p.validateTokenLeader())
+
+	_, err := spec.SelectHosts(func(h *cluster.Host) bool { return h == worker })
+	require.NoError(t, err)
+	err = p.validateTokenLeader()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "the controller [ssh] 10.0.0.1:22 used for creating the join tokens is not running k0s")
+
+	leader.Metadata.K0sRunningVersion = "1.21.2+k0s.0"
+	require.NoError(t, p.validateTokenLeader())
+}
@@ -17,6 +17,11 @@ func (p *ValidateHosts) Title() string {
 	return "Validate hosts"
 }
 
+// ReadOnly is true, the phase does not make changes to the hosts
+func (p *ValidateHosts) ReadOnly() bool {
+	return true
+}
+
 // Run the phase
 func (p *ValidateHosts) Run() error {
 	p.hncount = make(map[string]int, len(p.Config.Spec.Hosts))
@@ -24,7 +29,7 @@ func (p *ValidateHosts) Run() error {
 		p.hncount[h.Metadata.Hostname]++
 	}
 
-	return p.Config.Spec.Hosts.ParallelEach(p.validateUniqueHostname, p.validateSudo)
+	return p.parallelDo(p.Config.Spec.Hosts, p.validateUniqueHostname, p.validateSudo)
 }
 
 func (p *ValidateHosts) validateUniqueHostname(h *cluster.Host) error {